@@ -0,0 +1,86 @@
+// 他のシステムのトーク履歴を取り込むコマンド
+//
+//	go run ./cmd/import -file export.zip -mapping users.json -owner 12345 [-team T012AB3CD]
+//	go run ./cmd/import -file history.jsonl -room general -mapping users.json -owner 12345
+//
+// .zip は Slack のエクスポート、それ以外は JSONL として読み込む。何度実行してもメッセージは重複しない
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"microservices/chat/internal/svc/import_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "", "JSONL file or Slack export zip")
+	mappingFile := flag.String("mapping", "", `JSON file mapping external user ids to user ids ({"U012AB3CD": 12345})`)
+	ownerID := flag.Int("owner", 0, "user id of the owner of created rooms")
+	room := flag.String("room", "", "room for JSONL lines without a room")
+	team := flag.String("team", "", "Slack workspace id (defaults to the team_id in users.json)")
+	flag.Parse()
+
+	if *file == "" || *mappingFile == "" || *ownerID <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// .envを読み込む（環境変数で指定されている場合は不要）
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️ .envファイル読み込み失敗")
+	}
+
+	mapping, err := readUserMapping(*mappingFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var source import_svc.Source
+	if strings.EqualFold(filepath.Ext(*file), ".zip") {
+		info, err := f.Stat()
+		if err != nil {
+			log.Fatal(err)
+		}
+		source, err = import_svc.NewSlackSource(f, info.Size(), *team)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		source = import_svc.NewJSONLSource(f, *room)
+	}
+
+	mongoPkg := mongo_pkg.NewMongoPkg()
+	defer mongoPkg.Close(context.Background())
+
+	importSvc := import_svc.NewImportSvc(mongo_svc.NewMongoSvc(&mongo_pkg.RealMongoDatabase{}), mongoPkg)
+	result, err := importSvc.Import(source, mapping, *ownerID)
+	log.Printf("rooms: %d, imported: %d, already imported: %d, replies without parent: %d",
+		result.Rooms, result.Imported, result.Skipped, result.Orphans)
+	if err != nil {
+		mongoPkg.Close(context.Background())
+		log.Fatal(err)
+	}
+}
+
+func readUserMapping(name string) (import_svc.UserMapping, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return import_svc.ReadUserMapping(f)
+}
@@ -1,15 +1,36 @@
 package app
 
 import (
+	"context"
 	"microservices/chat/internal/handlers"
 	"microservices/chat/internal/middlewares"
+	"microservices/chat/internal/model"
 	"microservices/chat/internal/routings"
+	"microservices/chat/internal/svc/attachment_svc"
 	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/clock_svc"
 	"microservices/chat/internal/svc/csrf_svc"
+	"microservices/chat/internal/svc/export_svc"
 	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/internal/svc/notification_svc"
+	"microservices/chat/internal/svc/presence_svc"
+	"microservices/chat/internal/svc/purge_svc"
+	"microservices/chat/internal/svc/schedule_svc"
+	"microservices/chat/internal/svc/thumbnail_svc"
+	"microservices/chat/internal/svc/webhook_svc"
+	"microservices/chat/internal/worker"
+	"microservices/chat/pkg/command_pkg"
 	"microservices/chat/pkg/csrf_pkg"
 	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/notifier_pkg"
+	"microservices/chat/pkg/ratelimit_pkg"
+	"microservices/chat/pkg/storage_pkg"
+	"microservices/chat/pkg/ttlstore_pkg"
+	"microservices/chat/pkg/webhook_pkg"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,14 +38,85 @@ import (
 type App struct {
 	CsrfMW   gin.HandlerFunc
 	AuthMW   gin.HandlerFunc
+	UserMW   gin.HandlerFunc
 	Handlers *handlers.HandlerStruct
+	Workers  []*worker.Worker
+
+	mongoPkg *mongo_pkg.MongoPkg
+}
+
+// 環境変数から期間を取得する（未設定・不正な値の場合は fallback）
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// 環境変数からバイト数を取得する（未設定・不正な値の場合は fallback）
+func sizeFromEnv(key string, fallback int64) int64 {
+	size, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || size <= 0 {
+		return fallback
+	}
+	return size
+}
+
+// 環境変数から回数などの正の整数を取得する（未設定・不正な値の場合は fallback）
+func intFromEnv(key string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// カンマ区切りの環境変数を取得する（未設定の場合は fallback）
+func listFromEnv(key string, fallback []string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	if len(list) == 0 {
+		return fallback
+	}
+	return list
+}
+
+// 配信チャネルごとの通知の送信方法。
+// NOTIFIER_DRIVER=memory の場合は外部に送らない（開発・テスト用）。SMTP_ADDR が未設定の場合はメールを送らない
+func notifiersFromEnv() map[string]notifier_pkg.Notifier {
+	if os.Getenv("NOTIFIER_DRIVER") == "memory" {
+		memory := notifier_pkg.NewMemoryNotifier()
+		return map[string]notifier_pkg.Notifier{
+			model.NotificationChannelEmail:   memory,
+			model.NotificationChannelWebhook: memory,
+		}
+	}
+
+	notifiers := map[string]notifier_pkg.Notifier{
+		model.NotificationChannelWebhook: notifier_pkg.NewWebhookNotifier(durationFromEnv("NOTIFICATION_WEBHOOK_TIMEOUT", 10*time.Second)),
+	}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		notifiers[model.NotificationChannelEmail] = notifier_pkg.NewEmailNotifier(notifier_pkg.SMTPConfig{
+			Addr:     addr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		})
+	}
+	return notifiers
 }
 
 func NewApp() *App {
 	csrfPkg := &csrf_pkg.CsrfPkgStruct{}
 	mongoPkg := mongo_pkg.NewMongoPkg()
+	clock := clock_svc.RealClockStruct{}
 
-	verifier := csrf_svc.NewVerifier(csrfPkg, "secrets", clock_svc.RealClockStruct{})
+	verifier := csrf_svc.NewVerifier(csrfPkg, "secrets", clock)
 
 	csrfMW := middlewares.NewCSRFMiddleware(verifier)
 	authMW := middlewares.NewAuthMiddleware()
@@ -33,14 +125,140 @@ func NewApp() *App {
 
 	chatSvc := chat_svc.NewChatSvc()
 
+	storage, err := storage_pkg.NewBlobStorageFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	purgeSvc := purge_svc.NewPurgeSvc(
+		mongoSvc,
+		mongoPkg,
+		clock,
+		storage,
+		durationFromEnv("DELETED_MESSAGE_RETENTION", 30*24*time.Hour),
+	)
+	// 添付ファイルの URL の署名には JWT とは別の専用の鍵を使う。未設定の場合は起動しない
+	attachmentURLSecret := os.Getenv("ATTACHMENT_URL_SECRET")
+	if attachmentURLSecret == "" {
+		panic("ATTACHMENT_URL_SECRET is not set")
+	}
+	attachmentSvc := attachment_svc.NewAttachmentSvc(
+		storage,
+		clock,
+		attachmentURLSecret,
+		sizeFromEnv("ATTACHMENT_MAX_SIZE", 10<<20),
+		listFromEnv("ATTACHMENT_ALLOWED_TYPES", attachment_svc.DefaultAllowedTypes),
+		durationFromEnv("ATTACHMENT_URL_TTL", 5*time.Minute),
+	)
+
+	thumbnailSvc := thumbnail_svc.NewThumbnailSvc(mongoSvc, mongoPkg, storage, thumbnail_svc.DefaultSizes, 20)
+
+	notificationSvc := notification_svc.NewNotificationSvc(
+		mongoSvc,
+		mongoPkg,
+		clock,
+		notifiersFromEnv(),
+		durationFromEnv("NOTIFICATION_DIGEST_WINDOW", 5*time.Minute),
+	)
+
+	webhookSvc := webhook_svc.NewWebhookSvc(
+		mongoSvc,
+		mongoPkg,
+		clock,
+		webhook_pkg.NewSender(durationFromEnv("ROOM_WEBHOOK_TIMEOUT", 10*time.Second)),
+	)
+
+	exportSvc := export_svc.NewExportSvc(mongoSvc, mongoPkg, clock, storage)
+
+	handlers := handlers.NewHandlers(mongoSvc, mongoPkg, chatSvc)
+	handlers.AttachmentSvc = attachmentSvc
+	handlers.NotificationSvc = notificationSvc
+	handlers.WebhookSvc = webhookSvc
+	handlers.ExportSvc = exportSvc
+	handlers.IncomingWebhookLimiter = ratelimit_pkg.NewMemoryLimiter(
+		intFromEnv("INCOMING_WEBHOOK_RATE_LIMIT", 30),
+		durationFromEnv("INCOMING_WEBHOOK_RATE_WINDOW", time.Minute),
+	)
+	handlers.IncomingWebhookMaxSize = sizeFromEnv("INCOMING_WEBHOOK_MAX_SIZE", 16<<10)
+	handlers.CommandClient = command_pkg.NewClient(durationFromEnv("SLASH_COMMAND_TIMEOUT", 3*time.Second))
+	handlers.PresenceSvc = presence_svc.NewPresenceSvc(
+		ttlstore_pkg.NewMemoryStore(),
+		clock,
+		durationFromEnv("PRESENCE_TTL", 60*time.Second),
+		durationFromEnv("TYPING_TTL", 6*time.Second),
+	)
+
+	// 予約投稿は HTTP からの投稿と同じ処理で投稿する
+	scheduleSvc := schedule_svc.NewScheduleSvc(mongoSvc, mongoPkg, clock, handlers)
+
 	app := &App{
 		CsrfMW:   csrfMW.Handler(),
 		AuthMW:   authMW.Handler(),
-		Handlers: handlers.NewHandlers(mongoSvc, mongoPkg, chatSvc),
+		UserMW:   middlewares.NewUserDirectoryMiddleware(mongoSvc, mongoPkg).Handler(),
+		Handlers: handlers,
+		mongoPkg: mongoPkg,
+		Workers: []*worker.Worker{
+			worker.NewWorker(
+				"purge_deleted_chat_messages",
+				durationFromEnv("DELETED_MESSAGE_PURGE_INTERVAL", time.Hour),
+				purgeSvc.PurgeDeletedChatMessages,
+			),
+			worker.NewWorker(
+				"purge_expired_chat_messages",
+				durationFromEnv("RETENTION_PURGE_INTERVAL", time.Hour),
+				purgeSvc.PurgeExpiredChatMessages,
+			),
+			worker.NewWorker(
+				"purge_attachment_blobs",
+				durationFromEnv("ATTACHMENT_PURGE_INTERVAL", time.Minute),
+				purgeSvc.PurgeAttachmentBlobs,
+			),
+			worker.NewWorker(
+				"generate_thumbnails",
+				durationFromEnv("THUMBNAIL_INTERVAL", 5*time.Second),
+				thumbnailSvc.GenerateThumbnails,
+			),
+			worker.NewWorker(
+				"build_notification_digests",
+				durationFromEnv("NOTIFICATION_DIGEST_INTERVAL", 30*time.Second),
+				notificationSvc.BuildDigests,
+			),
+			worker.NewWorker(
+				"deliver_notifications",
+				durationFromEnv("NOTIFICATION_DELIVERY_INTERVAL", 10*time.Second),
+				notificationSvc.DeliverDigests,
+			),
+			worker.NewWorker(
+				"deliver_room_webhooks",
+				durationFromEnv("ROOM_WEBHOOK_INTERVAL", 5*time.Second),
+				webhookSvc.DeliverWebhooks,
+			),
+			worker.NewWorker(
+				"run_scheduled_messages",
+				durationFromEnv("SCHEDULED_MESSAGE_INTERVAL", 5*time.Second),
+				scheduleSvc.RunScheduledMessages,
+			),
+			worker.NewWorker(
+				"run_room_exports",
+				durationFromEnv("ROOM_EXPORT_INTERVAL", 5*time.Second),
+				exportSvc.RunRoomExports,
+			),
+		},
 	}
 	return app
 }
 
 func (a *App) InitRoutes(r *gin.Engine) {
-	routings.Routing(r, a.CsrfMW, a.AuthMW, a.Handlers)
+	routings.Routing(r, a.CsrfMW, a.AuthMW, a.UserMW, a.Handlers)
+}
+
+func (a *App) StartWorkers(ctx context.Context) {
+	for _, w := range a.Workers {
+		go w.Run(ctx)
+	}
+}
+
+// MongoDB との接続を切断する
+func (a *App) Close(ctx context.Context) error {
+	return a.mongoPkg.Close(ctx)
 }
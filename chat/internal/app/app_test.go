@@ -1,6 +1,10 @@
 package app
 
 import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/worker"
+	"microservices/chat/pkg/notifier_pkg"
 	"microservices/chat/tests/test_funcs"
 	"net/http"
 	"net/http/httptest"
@@ -13,8 +17,9 @@ import (
 
 func TestInitRoutes(t *testing.T) {
 	envs := test_funcs.Envs{
-		"JWT_SECRET": "jwt_secret_key",
-		"CSRF_TOKEN": "csrf_token",
+		"JWT_SECRET":            "jwt_secret_key",
+		"CSRF_TOKEN":            "csrf_token",
+		"ATTACHMENT_URL_SECRET": "attachment_url_secret",
 	}
 
 	test_funcs.WithEnvMap(envs, t, func() {
@@ -49,3 +54,96 @@ func TestInitRoutes(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "healthy")
 	})
 }
+
+// JWT の鍵があっても、添付ファイルの URL の署名用の鍵が無い場合は起動しない
+func TestNewAppRequiresAttachmentURLSecret(t *testing.T) {
+	envs := test_funcs.Envs{
+		"JWT_SECRET":            "jwt_secret_key",
+		"ATTACHMENT_URL_SECRET": "",
+	}
+
+	test_funcs.WithEnvMap(envs, t, func() {
+		assert.PanicsWithValue(t, "ATTACHMENT_URL_SECRET is not set", func() {
+			NewApp()
+		})
+	})
+}
+
+func TestDurationFromEnv(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_DURATION": "90m"}, t, func() {
+		assert.Equal(t, 90*time.Minute, durationFromEnv("TEST_DURATION", time.Hour))
+	})
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_DURATION": "invalid"}, t, func() {
+		assert.Equal(t, time.Hour, durationFromEnv("TEST_DURATION", time.Hour))
+	})
+}
+
+func TestStartWorkers(t *testing.T) {
+	called := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	app := &App{
+		Workers: []*worker.Worker{
+			worker.NewWorker("test", time.Millisecond, func() error {
+				select {
+				case called <- struct{}{}:
+				default:
+				}
+				return nil
+			}),
+		},
+	}
+	app.StartWorkers(ctx)
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("worker was not started")
+	}
+}
+
+func TestSizeFromEnv(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_SIZE": "2048"}, t, func() {
+		assert.Equal(t, int64(2048), sizeFromEnv("TEST_SIZE", 1024))
+	})
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_SIZE": "-1"}, t, func() {
+		assert.Equal(t, int64(1024), sizeFromEnv("TEST_SIZE", 1024))
+	})
+}
+
+func TestIntFromEnv(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_INT": "5"}, t, func() {
+		assert.Equal(t, 5, intFromEnv("TEST_INT", 30))
+	})
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_INT": "abc"}, t, func() {
+		assert.Equal(t, 30, intFromEnv("TEST_INT", 30))
+	})
+}
+
+func TestListFromEnv(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_LIST": "image/png, text/plain,"}, t, func() {
+		assert.Equal(t, []string{"image/png", "text/plain"}, listFromEnv("TEST_LIST", []string{"default"}))
+	})
+	test_funcs.WithEnvMap(test_funcs.Envs{"TEST_LIST": ""}, t, func() {
+		assert.Equal(t, []string{"default"}, listFromEnv("TEST_LIST", []string{"default"}))
+	})
+}
+
+func TestNotifiersFromEnv(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"NOTIFIER_DRIVER": "memory"}, t, func() {
+		notifiers := notifiersFromEnv()
+		assert.IsType(t, &notifier_pkg.MemoryNotifier{}, notifiers[model.NotificationChannelEmail])
+		assert.IsType(t, &notifier_pkg.MemoryNotifier{}, notifiers[model.NotificationChannelWebhook])
+	})
+	test_funcs.WithEnvMap(test_funcs.Envs{"NOTIFIER_DRIVER": "", "SMTP_ADDR": "smtp.example.com:587"}, t, func() {
+		notifiers := notifiersFromEnv()
+		assert.IsType(t, &notifier_pkg.EmailNotifier{}, notifiers[model.NotificationChannelEmail])
+		assert.IsType(t, &notifier_pkg.WebhookNotifier{}, notifiers[model.NotificationChannelWebhook])
+	})
+	// SMTP が未設定の場合はメールの配信を失敗として記録する
+	test_funcs.WithEnvMap(test_funcs.Envs{"NOTIFIER_DRIVER": "", "SMTP_ADDR": ""}, t, func() {
+		_, ok := notifiersFromEnv()[model.NotificationChannelEmail]
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// アーカイブしたルームは閲覧のみになり、ルーム一覧（all）に表示されなくなる
+func (h *HandlerStruct) ArchiveRoomHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.ArchiveRoom(roomID, time.Now(), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrRoomArchived) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Room is already archived"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive room", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Room archived successfully"})
+}
+
+func (h *HandlerStruct) UnarchiveRoomHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.UnarchiveRoom(roomID, time.Now(), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrRoomNotArchived) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Room is not archived"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unarchive room", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Room unarchived successfully"})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArchiveRoomHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		handle     func(h *HandlerStruct, c *gin.Context)
+		roomInfo   chat_svc.Room
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"archive", "ArchiveRoom", (*HandlerStruct).ArchiveRoomHandler, ownerRoomInfo, nil, http.StatusOK, "Room archived successfully"},
+		{"archive_already", "ArchiveRoom", (*HandlerStruct).ArchiveRoomHandler, ownerRoomInfo, mongo_svc.ErrRoomArchived, http.StatusConflict, "Room is already archived"},
+		{"archive_error", "ArchiveRoom", (*HandlerStruct).ArchiveRoomHandler, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to archive room"},
+		{"archive_not_owner", "ArchiveRoom", (*HandlerStruct).ArchiveRoomHandler, moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"unarchive", "UnarchiveRoom", (*HandlerStruct).UnarchiveRoomHandler, ownerRoomInfo, nil, http.StatusOK, "Room unarchived successfully"},
+		{"unarchive_not_archived", "UnarchiveRoom", (*HandlerStruct).UnarchiveRoomHandler, ownerRoomInfo, mongo_svc.ErrRoomNotArchived, http.StatusConflict, "Room is not archived"},
+		{"unarchive_error", "UnarchiveRoom", (*HandlerStruct).UnarchiveRoomHandler, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to unarchive room"},
+		{"unarchive_not_owner", "UnarchiveRoom", (*HandlerStruct).UnarchiveRoomHandler, memberRoomInfo, nil, http.StatusForbidden, "Access denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/"+tt.name, "", roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On(tt.method, "valid_room_id", mock.Anything, mongoMockPkg).Return(tt.err)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			tt.handle(NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc), c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
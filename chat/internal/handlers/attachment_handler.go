@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/attachment_svc"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 1メッセージに添付できるファイル数
+const maxAttachmentsPerMessage = 10
+
+// multipart のヘッダーやメッセージ本文のための余裕
+const multipartOverhead = 1 << 20
+
+func (h *HandlerStruct) storeAttachment(c *gin.Context, roomID string, header *multipart.FileHeader) (model.Attachment, error) {
+	file, err := header.Open()
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	defer file.Close()
+
+	return h.AttachmentSvc.Store(c.Request.Context(), roomID, header.Filename, header.Size, file)
+}
+
+// メッセージの投稿に失敗した場合などに保存済みのファイルを消す
+func (h *HandlerStruct) removeAttachments(c *gin.Context, attachments []model.Attachment) {
+	for _, attachment := range attachments {
+		h.AttachmentSvc.Remove(c.Request.Context(), attachment)
+	}
+}
+
+// files（複数可）と message（任意）を受け取り、ファイルを添付したメッセージを投稿する
+func (h *HandlerStruct) UploadAttachmentsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	room, roomInfo, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityPost)
+	if !ok {
+		return
+	}
+
+	// 上限を大きく超えるリクエストは読み込み途中で打ち切る
+	maxRequestSize := h.AttachmentSvc.MaxSize()*maxAttachmentsPerMessage + multipartOverhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestSize)
+	form, err := c.MultipartForm()
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request too large"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	files := form.File["files"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No files uploaded"})
+		return
+	}
+	if len(files) > maxAttachmentsPerMessage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many files"})
+		return
+	}
+
+	message := c.PostForm("message")
+	mentionedUserIDs, err := h.resolveMentions(roomID, room, roomInfo, userID, message)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions", "details": err.Error()})
+		return
+	}
+
+	var attachments []model.Attachment
+	for _, header := range files {
+		attachment, err := h.storeAttachment(c, roomID, header)
+		if err != nil {
+			h.removeAttachments(c, attachments)
+			switch {
+			case errors.Is(err, attachment_svc.ErrAttachmentTooLarge):
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File too large", "details": header.Filename})
+			case errors.Is(err, attachment_svc.ErrAttachmentTypeNotAllowed):
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File type not allowed", "details": err.Error()})
+			case errors.Is(err, attachment_svc.ErrAttachmentEmpty):
+				c.JSON(http.StatusBadRequest, gin.H{"error": "File is empty", "details": header.Filename})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store attachment", "details": err.Error()})
+			}
+			return
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	chatMessage := model.ChatMessage{
+		RoomID:           roomID,
+		UserID:           userID,
+		Message:          message,
+		Attachments:      attachments,
+		MentionedUserIds: mentionedUserIDs,
+		RetentionDays:    room.RetentionDays,
+	}
+	messageID, err := h.MongoSvc.PostChatMessage(chatMessage, h.MongoPkg)
+	if err != nil {
+		h.removeAttachments(c, attachments)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post chat", "details": err.Error()})
+		return
+	}
+
+	h.enqueueNotifications(messageID, chatMessage, room, 0)
+	h.publishWebhookEvent(roomID, model.WebhookMessageCreated, model.WebhookMessageData{
+		MessageID: messageID,
+		UserID:    userID,
+		Message:   message,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Attachments uploaded successfully",
+		"message_id":  messageID,
+		"attachments": attachments,
+	})
+}
+
+// ルームのメンバーに有効期限付きのダウンロードURLを発行する
+func (h *HandlerStruct) AttachmentURLHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+
+	message, _, ok := h.getMessageFor(c, c.Param("id"), int(jwtinfo.UserID), chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+	if message.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return
+	}
+	attachment, found := message.FindAttachment(c.Param("attachment_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	url, expiresAt := h.AttachmentSvc.SignURL(message.ID.Hex(), attachment.ID)
+	c.JSON(http.StatusOK, gin.H{"url": url, "expires_at": expiresAt})
+}
+
+// 署名を確認した上でダウンロード対象の添付ファイルを取得する
+func (h *HandlerStruct) getSignedAttachment(c *gin.Context, verifyErr error) (model.Attachment, bool) {
+	if errors.Is(verifyErr, attachment_svc.ErrAttachmentURLExpired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Download link has expired"})
+		return model.Attachment{}, false
+	}
+	if verifyErr != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid download link"})
+		return model.Attachment{}, false
+	}
+
+	message, err := h.MongoSvc.GetChatMessage(c.Param("message_id"), h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message"})
+		return model.Attachment{}, false
+	}
+	// URLの発行後に削除されたメッセージの添付ファイルは返さない
+	if message.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return model.Attachment{}, false
+	}
+	attachment, found := message.FindAttachment(c.Param("attachment_id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return model.Attachment{}, false
+	}
+	return attachment, true
+}
+
+// 署名付きURLからのダウンロード（認証の代わりに署名と有効期限を確認する）
+func (h *HandlerStruct) DownloadAttachmentHandler(c *gin.Context) {
+	err := h.AttachmentSvc.VerifyURL(c.Param("message_id"), c.Param("attachment_id"), c.Query("expires"), c.Query("signature"))
+	attachment, ok := h.getSignedAttachment(c, err)
+	if !ok {
+		return
+	}
+
+	body, err := h.AttachmentSvc.Open(c.Request.Context(), attachment)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open attachment", "details": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=60",
+	})
+}
+
+// 署名付きURLからサムネイルを返す（生成したPNG/JPEGのみなのでインラインで表示させる）
+func (h *HandlerStruct) DownloadThumbnailHandler(c *gin.Context) {
+	size, err := strconv.Atoi(c.Param("size"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid download link"})
+		return
+	}
+	err = h.AttachmentSvc.VerifyThumbnailURL(c.Param("message_id"), c.Param("attachment_id"), size, c.Query("expires"), c.Query("signature"))
+	attachment, ok := h.getSignedAttachment(c, err)
+	if !ok {
+		return
+	}
+	thumbnail, found := attachment.FindThumbnail(size)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+
+	body, err := h.AttachmentSvc.OpenThumbnail(c.Request.Context(), thumbnail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open thumbnail", "details": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, -1, thumbnail.ContentType, body, map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=60",
+	})
+}
+
+// メッセージ一覧のサムネイルに署名付きURLを付ける（スライスの要素を直接書き換える）
+func (h *HandlerStruct) signThumbnailURLs(messages []model.ChatMessage) {
+	for _, message := range messages {
+		for _, attachment := range message.Attachments {
+			for i := range attachment.Thumbnails {
+				attachment.Thumbnails[i].URL = h.AttachmentSvc.SignThumbnailURL(message.ID.Hex(), attachment.ID, attachment.Thumbnails[i].Size)
+			}
+		}
+	}
+}
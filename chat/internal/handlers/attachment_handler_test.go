@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/attachment_svc"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/tests/mocks/svc/mock_attachment_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newMultipartRequestContext(path string, fileNames []string, message string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, name := range fileNames {
+		part, _ := writer.CreateFormFile("files", name)
+		part.Write([]byte("content of " + name))
+	}
+	writer.WriteField("message", message)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", path, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	c.Params = params
+	c.Request = req.WithContext(ctx)
+	return c, w
+}
+
+func TestUploadAttachmentsHandler(t *testing.T) {
+	first := model.Attachment{ID: "attachment1", Name: "a.png", ContentType: "image/png"}
+	second := model.Attachment{ID: "attachment2", Name: "b.pdf", ContentType: "application/pdf"}
+	manyFiles := make([]string, maxAttachmentsPerMessage+1)
+	for i := range manyFiles {
+		manyFiles[i] = "file.txt"
+	}
+
+	tests := []struct {
+		name         string
+		roomInfo     chat_svc.Room
+		files        []string
+		secondErr    error
+		postErr      error
+		expectRemove int
+		expectCode   int
+		expect       string
+	}{
+		{"success", memberRoomInfo, []string{"a.png", "b.pdf"}, nil, nil, 0, http.StatusOK, `"message_id":"message_id"`},
+		{"read_only", readOnlyRoomInfo, []string{"a.png"}, nil, nil, 0, http.StatusForbidden, "Access denied"},
+		{"no_files", memberRoomInfo, nil, nil, nil, 0, http.StatusBadRequest, "No files uploaded"},
+		{"too_many_files", memberRoomInfo, manyFiles, nil, nil, 0, http.StatusBadRequest, "Too many files"},
+		// 途中のファイルで失敗した場合は保存済みのファイルを消す
+		{"type_not_allowed", memberRoomInfo, []string{"a.png", "b.pdf"}, attachment_svc.ErrAttachmentTypeNotAllowed, nil, 1, http.StatusUnsupportedMediaType, "File type not allowed"},
+		{"too_large", memberRoomInfo, []string{"a.png", "b.pdf"}, attachment_svc.ErrAttachmentTooLarge, nil, 1, http.StatusRequestEntityTooLarge, "File too large"},
+		{"empty", memberRoomInfo, []string{"a.png", "b.pdf"}, attachment_svc.ErrAttachmentEmpty, nil, 1, http.StatusBadRequest, "File is empty"},
+		{"store_error", memberRoomInfo, []string{"a.png", "b.pdf"}, assert.AnError, nil, 1, http.StatusInternalServerError, "Failed to store attachment"},
+		{"post_error", memberRoomInfo, []string{"a.png", "b.pdf"}, nil, assert.AnError, 2, http.StatusInternalServerError, "Failed to post chat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newMultipartRequestContext("/rooms/valid_room_id/attachments", tt.files, "see attached", roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			posted := mock.MatchedBy(func(message model.ChatMessage) bool {
+				return message.RoomID == "valid_room_id" && message.UserID == 12345 && message.Message == "see attached" &&
+					assert.ObjectsAreEqual([]model.Attachment{first, second}, message.Attachments)
+			})
+			mongoMockSvc.On("PostChatMessage", posted, mongoMockPkg).Return("message_id", tt.postErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+			attachmentMockSvc.On("MaxSize").Return(int64(1024))
+			attachmentMockSvc.On("Store", mock.Anything, "valid_room_id", "a.png", mock.Anything, mock.Anything).Return(first, nil)
+			attachmentMockSvc.On("Store", mock.Anything, "valid_room_id", "b.pdf", mock.Anything, mock.Anything).Return(second, tt.secondErr)
+			attachmentMockSvc.On("Store", mock.Anything, "valid_room_id", "file.txt", mock.Anything, mock.Anything).Return(first, nil)
+			attachmentMockSvc.On("Remove", mock.Anything, mock.Anything).Return(nil)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.AttachmentSvc = attachmentMockSvc
+			handler.UploadAttachmentsHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			attachmentMockSvc.AssertNumberOfCalls(t, "Remove", tt.expectRemove)
+		})
+	}
+}
+
+func TestUploadAttachmentsHandlerRequestTooLarge(t *testing.T) {
+	// 上限（1バイト × ファイル数 + multipart の余裕）を超える本文を送る
+	c, w := newMultipartRequestContext("/rooms/valid_room_id/attachments", []string{"a.png"}, strings.Repeat("a", multipartOverhead+100), roomIDParams)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+	attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+	attachmentMockSvc.On("MaxSize").Return(int64(1))
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AttachmentSvc = attachmentMockSvc
+	handler.UploadAttachmentsHandler(c)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	attachmentMockSvc.AssertNotCalled(t, "Store", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAttachmentURLHandler(t *testing.T) {
+	messageID := primitive.NewObjectID()
+	deletedAt := time.Now()
+	expiresAt := time.Date(2025, 1, 1, 0, 5, 0, 0, time.UTC)
+	params := gin.Params{{Key: "id", Value: messageID.Hex()}, {Key: "attachment_id", Value: "attachment1"}}
+
+	tests := []struct {
+		name       string
+		message    model.ChatMessage
+		roomInfo   chat_svc.Room
+		expectCode int
+		expect     string
+	}{
+		{"success", model.ChatMessage{ID: messageID, RoomID: "room1", Attachments: []model.Attachment{{ID: "attachment1"}}}, readOnlyRoomInfo, http.StatusOK, `"url":"/attachments/signed"`},
+		{"not_member", model.ChatMessage{ID: messageID, RoomID: "room1", Attachments: []model.Attachment{{ID: "attachment1"}}}, chat_svc.Room{}, http.StatusForbidden, "Access denied"},
+		{"deleted", model.ChatMessage{ID: messageID, RoomID: "room1", DeletedAt: &deletedAt, Attachments: []model.Attachment{{ID: "attachment1"}}}, memberRoomInfo, http.StatusGone, "Message has been deleted"},
+		{"not_found", model.ChatMessage{ID: messageID, RoomID: "room1"}, memberRoomInfo, http.StatusNotFound, "Attachment not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/messages/"+messageID.Hex()+"/attachments/attachment1", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetChatMessage", messageID.Hex(), mongoMockPkg).Return(tt.message, nil)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(model.Room{}, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+			attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+			attachmentMockSvc.On("SignURL", messageID.Hex(), "attachment1").Return("/attachments/signed", expiresAt)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.AttachmentSvc = attachmentMockSvc
+			handler.AttachmentURLHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestDownloadAttachmentHandler(t *testing.T) {
+	deletedAt := time.Now()
+	attachment := model.Attachment{ID: "attachment1", Name: "report.pdf", Size: 7, ContentType: "application/pdf", StorageKey: "rooms/room1/attachments/attachment1"}
+	params := gin.Params{{Key: "message_id", Value: "message1"}, {Key: "attachment_id", Value: "attachment1"}}
+
+	tests := []struct {
+		name       string
+		verifyErr  error
+		message    model.ChatMessage
+		getErr     error
+		openErr    error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, model.ChatMessage{Attachments: []model.Attachment{attachment}}, nil, nil, http.StatusOK, "content"},
+		{"expired", attachment_svc.ErrAttachmentURLExpired, model.ChatMessage{}, nil, nil, http.StatusForbidden, "Download link has expired"},
+		{"invalid_signature", attachment_svc.ErrInvalidSignature, model.ChatMessage{}, nil, nil, http.StatusForbidden, "Invalid download link"},
+		{"get_error", nil, model.ChatMessage{}, assert.AnError, nil, http.StatusInternalServerError, "Failed to get message"},
+		{"deleted", nil, model.ChatMessage{DeletedAt: &deletedAt, Attachments: []model.Attachment{attachment}}, nil, nil, http.StatusGone, "Message has been deleted"},
+		{"not_found", nil, model.ChatMessage{}, nil, nil, http.StatusNotFound, "Attachment not found"},
+		{"open_error", nil, model.ChatMessage{Attachments: []model.Attachment{attachment}}, nil, assert.AnError, http.StatusInternalServerError, "Failed to open attachment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 署名付きURLは認証を通さないので、ユーザー情報の無いリクエストにする
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/attachments/message1/attachment1?expires=100&signature=sig", nil)
+			c.Params = params
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetChatMessage", "message1", mongoMockPkg).Return(tt.message, tt.getErr)
+			attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+			attachmentMockSvc.On("VerifyURL", "message1", "attachment1", "100", "sig").Return(tt.verifyErr)
+			attachmentMockSvc.On("Open", mock.Anything, attachment).Return(io.NopCloser(strings.NewReader("content")), tt.openErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.AttachmentSvc = attachmentMockSvc
+			handler.DownloadAttachmentHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+				assert.Equal(t, "attachment; filename=report.pdf", w.Header().Get("Content-Disposition"))
+				assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
+
+func TestDownloadThumbnailHandler(t *testing.T) {
+	thumbnail := model.Thumbnail{Size: 160, Width: 160, Height: 90, ContentType: "image/jpeg", StorageKey: "rooms/room1/attachments/attachment1/thumbnails/160"}
+	attachment := model.Attachment{ID: "attachment1", Name: "photo.jpg", ContentType: "image/jpeg", PreviewStatus: model.PreviewReady, Thumbnails: []model.Thumbnail{thumbnail}}
+	pending := model.Attachment{ID: "attachment1", Name: "photo.jpg", ContentType: "image/jpeg", PreviewStatus: model.PreviewPending}
+
+	tests := []struct {
+		name       string
+		size       string
+		verifyErr  error
+		message    model.ChatMessage
+		openErr    error
+		expectCode int
+		expect     string
+	}{
+		{"success", "160", nil, model.ChatMessage{Attachments: []model.Attachment{attachment}}, nil, http.StatusOK, "thumbnail"},
+		{"invalid_size", "large", nil, model.ChatMessage{}, nil, http.StatusForbidden, "Invalid download link"},
+		{"invalid_signature", "160", attachment_svc.ErrInvalidSignature, model.ChatMessage{}, nil, http.StatusForbidden, "Invalid download link"},
+		{"not_generated", "160", nil, model.ChatMessage{Attachments: []model.Attachment{pending}}, nil, http.StatusNotFound, "Thumbnail not found"},
+		{"open_error", "160", nil, model.ChatMessage{Attachments: []model.Attachment{attachment}}, assert.AnError, http.StatusInternalServerError, "Failed to open thumbnail"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/attachments/message1/attachment1/thumbnails/"+tt.size+"?expires=100&signature=sig", nil)
+			c.Params = gin.Params{{Key: "message_id", Value: "message1"}, {Key: "attachment_id", Value: "attachment1"}, {Key: "size", Value: tt.size}}
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetChatMessage", "message1", mongoMockPkg).Return(tt.message, nil)
+			attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+			attachmentMockSvc.On("VerifyThumbnailURL", "message1", "attachment1", 160, "100", "sig").Return(tt.verifyErr)
+			attachmentMockSvc.On("OpenThumbnail", mock.Anything, thumbnail).Return(io.NopCloser(strings.NewReader("thumbnail")), tt.openErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.AttachmentSvc = attachmentMockSvc
+			handler.DownloadThumbnailHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+				assert.Empty(t, w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
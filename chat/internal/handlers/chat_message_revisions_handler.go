@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HandlerStruct) ChatMessageRevisionsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+
+	message, roomInfo, ok := h.getMessageFor(c, c.Param("id"), int(userID), chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+
+	if message.UserID != int(userID) && !roomInfo.Can(chat_svc.CapabilityModerate) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	revisions := message.Revisions
+	if revisions == nil {
+		revisions = []model.ChatMessageRevision{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message_id": message.ID.Hex(),
+		"current":    message.Message,
+		"edited_at":  message.EditedAt,
+		"revisions":  revisions,
+	})
+}
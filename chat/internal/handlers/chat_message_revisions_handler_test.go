@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newChatMessageRevisionsContext(userID int) (*gin.Context, *httptest.ResponseRecorder) {
+	return newUserRequestContext("GET", "/messages/valid_message_id/revisions", "", userID, gin.Params{{Key: "id", Value: "valid_message_id"}})
+}
+
+func TestChatMessageRevisionsHandler(t *testing.T) {
+	c, w := newChatMessageRevisionsContext(99999)
+
+	message := model.ChatMessage{
+		RoomID:    "valid_room_id",
+		UserID:    12345,
+		Message:   "edited",
+		Revisions: []model.ChatMessageRevision{{Message: "original", EditedBy: 12345}},
+	}
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(message, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 99999).Return(moderatorRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ChatMessageRevisionsHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "original")
+}
+
+func TestChatMessageRevisionsHandlerNoRevisions(t *testing.T) {
+	c, w := newChatMessageRevisionsContext(12345)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(readOnlyRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ChatMessageRevisionsHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"revisions":[]`)
+}
+
+func TestChatMessageRevisionsHandlerFailedGetMessage(t *testing.T) {
+	c, w := newChatMessageRevisionsContext(12345)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.ChatMessageRevisionsHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get message")
+}
+
+func TestChatMessageRevisionsHandlerFailedGetRoom(t *testing.T) {
+	c, w := newChatMessageRevisionsContext(12345)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.ChatMessageRevisionsHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get room")
+}
+
+func TestChatMessageRevisionsHandlerForbidden(t *testing.T) {
+	c, w := newChatMessageRevisionsContext(54321)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 54321).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ChatMessageRevisionsHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,9 +28,13 @@ func (h *HandlerStruct) DeleteChatMessageHandler(c *gin.Context) {
 	userID := jwtinfo.UserID
 	messageID := req.MessageID
 
-	room, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to get room", "details": err.Error()})
+	room, roomInfo, ok := h.getRoomFor(c, roomID, int(userID), chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+
+	if roomInfo.IsArchived {
+		c.JSON(403, gin.H{"error": "Room is archived"})
 		return
 	}
 
@@ -35,16 +44,39 @@ func (h *HandlerStruct) DeleteChatMessageHandler(c *gin.Context) {
 		return
 	}
 
-	if message.UserID != int(userID) && room.OwnerID != int(userID) {
+	// 他人のメッセージはモデレーター以上のみ削除できる
+	if message.UserID != int(userID) && !roomInfo.Can(chat_svc.CapabilityModerate) {
 		c.JSON(403, gin.H{"error": "You can only delete your own messages"})
 		return
 	}
 
-	err = h.MongoSvc.DeleteChatMessage(roomID, messageID, h.MongoPkg)
+	if message.DeletedAt != nil {
+		c.JSON(410, gin.H{"error": "Message has been deleted"})
+		return
+	}
+
+	err = h.MongoSvc.DeleteChatMessage(roomID, messageID, int(userID), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrChatMessageDeleted) {
+		c.JSON(410, gin.H{"error": "Message has been deleted"})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to delete message", "details": err.Error()})
 		return
 	}
 
+	// 削除したメッセージのピン留めは外す。失敗しても一覧には表示されない
+	if room.IsPinned(messageID) {
+		if err := h.MongoSvc.UnpinMessage(roomID, messageID, h.MongoPkg); err != nil && !errors.Is(err, mongo_svc.ErrPinNotFound) {
+			log.Printf("failed to unpin deleted message %s in room %s: %v", messageID, roomID, err)
+		}
+	}
+
+	h.publishWebhookEvent(roomID, model.WebhookMessageDeleted, model.WebhookMessageData{
+		MessageID: messageID,
+		UserID:    message.UserID,
+		DeletedBy: int(userID),
+	})
+
 	c.JSON(200, gin.H{"message": "Message deleted successfully"})
 }
@@ -4,15 +4,19 @@ import (
 	"context"
 	"microservices/chat/internal/model"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
 	"microservices/chat/tests/mocks/svc/mock_chat_svc"
 	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_webhook_svc"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestDeleteChatMessageHandler(t *testing.T) {
@@ -25,8 +29,9 @@ func TestDeleteChatMessageHandler(t *testing.T) {
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
 
 	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
 	mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{UserID: 12345}, nil)
-	mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", mongoMockPkg).Return(nil)
+	mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", 12345, mongoMockPkg).Return(nil)
 
 	body := strings.NewReader(`{"room_id":"valid_room_id","message_id":"valid_message_id"}`)
 	req := httptest.NewRequest("DELETE", "/delete_chat_message", body)
@@ -79,6 +84,7 @@ func TestDeleteChatMessageHandlerFaildGetMessage(t *testing.T) {
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
 
 	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
 	mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
 
 	body := strings.NewReader(`{"room_id":"valid_room_id","message_id":"valid_message_id"}`)
@@ -105,7 +111,8 @@ func TestDeleteChatMessageHandlerForbidden(t *testing.T) {
 	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
 
-	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{OwnerID: 99999}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 54321).Return(memberRoomInfo)
 	mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{UserID: 12345}, nil)
 
 	body := strings.NewReader(`{"room_id":"valid_room_id","message_id":"valid_message_id"}`)
@@ -123,6 +130,34 @@ func TestDeleteChatMessageHandlerForbidden(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "You can only delete your own messages")
 }
 
+func TestDeleteChatMessageHandlerByModerator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 54321).Return(moderatorRoomInfo)
+	mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{UserID: 12345}, nil)
+	mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", 54321, mongoMockPkg).Return(nil)
+
+	body := strings.NewReader(`{"room_id":"valid_room_id","message_id":"valid_message_id"}`)
+	req := httptest.NewRequest("DELETE", "/delete_chat_message", body)
+	req.Header.Set("Content-Type", "application/json")
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 54321)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "user@example.com")
+	c.Request = req.WithContext(ctx)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.DeleteChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Message deleted successfully")
+}
+
 func TestDeleteChatMessageHandlerFailedDelete(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -133,8 +168,9 @@ func TestDeleteChatMessageHandlerFailedDelete(t *testing.T) {
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
 
 	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
 	mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{UserID: 12345}, nil)
-	mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", mongoMockPkg).Return(assert.AnError)
+	mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", 12345, mongoMockPkg).Return(assert.AnError)
 
 	body := strings.NewReader(`{"room_id":"valid_room_id","message_id":"valid_message_id"}`)
 	req := httptest.NewRequest("DELETE", "/delete_chat_message", body)
@@ -174,3 +210,98 @@ func TestDeleteChatMessageHandlerInvalidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "Invalid request")
 }
+
+func TestDeleteChatMessageHandlerUnpinsMessage(t *testing.T) {
+	room := model.Room{Members: []int{12345}, Pins: []model.RoomPin{{MessageID: "valid_message_id"}}}
+
+	tests := []struct {
+		name     string
+		unpinErr error
+	}{
+		{"success", nil},
+		// ピン留めの解除に失敗してもメッセージの削除は成功にする
+		{"unpin_error", assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/delete_chat_message", `{"room_id":"valid_room_id","message_id":"valid_message_id"}`, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{UserID: 12345}, nil)
+			mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", 12345, mongoMockPkg).Return(nil)
+			mongoMockSvc.On("UnpinMessage", "valid_room_id", "valid_message_id", mongoMockPkg).Return(tt.unpinErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.DeleteChatMessageHandler(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mongoMockSvc.AssertCalled(t, "UnpinMessage", "valid_room_id", "valid_message_id", mongoMockPkg)
+		})
+	}
+}
+
+func TestDeleteChatMessageHandlerAlreadyDeleted(t *testing.T) {
+	deletedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		message   model.ChatMessage
+		deleteErr error
+	}{
+		{"deleted", model.ChatMessage{UserID: 12345, DeletedAt: &deletedAt}, nil},
+		// 取得した後に別のリクエストで削除された
+		{"deleted_concurrently", model.ChatMessage{UserID: 12345}, mongo_svc.ErrChatMessageDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/delete_chat_message", `{"room_id":"valid_room_id","message_id":"valid_message_id"}`, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(tt.message, nil)
+			mongoMockSvc.On("DeleteChatMessage", "valid_room_id", "valid_message_id", 12345, mongoMockPkg).Return(tt.deleteErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+			webhookMockSvc := new(mock_webhook_svc.WebhookSvcMock)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.WebhookSvc = webhookMockSvc
+			handler.DeleteChatMessageHandler(c)
+
+			assert.Equal(t, http.StatusGone, w.Code)
+			assert.Contains(t, w.Body.String(), "Message has been deleted")
+			// 削除のイベントを重複して送らない
+			webhookMockSvc.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+			if tt.message.DeletedAt != nil {
+				mongoMockSvc.AssertNotCalled(t, "DeleteChatMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestDeleteChatMessageHandlerArchived(t *testing.T) {
+	c, w := newJSONRequestContext("DELETE", "/delete_chat_message", `{"room_id":"valid_room_id","message_id":"valid_message_id"}`, nil)
+	archivedRoomInfo := memberRoomInfo
+	archivedRoomInfo.IsArchived = true
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(archivedRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.DeleteChatMessageHandler(c)
+
+	// アーカイブされたルームは読み取り専用なので、自分のメッセージも削除できない
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Room is archived")
+	mongoMockSvc.AssertNotCalled(t, "DeleteChatMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
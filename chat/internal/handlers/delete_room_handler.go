@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ルームとルーム内のメッセージをすべて削除する（オーナーのみ）
+func (h *HandlerStruct) DeleteRoomHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.DeleteRoom(roomID, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrRoomNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Room deleted successfully"})
+}
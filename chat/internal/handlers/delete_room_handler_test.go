@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteRoomHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		roomInfo   chat_svc.Room
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"success", ownerRoomInfo, nil, http.StatusOK, "Room deleted successfully"},
+		{"not_found", ownerRoomInfo, mongo_svc.ErrRoomNotFound, http.StatusNotFound, "Room not found"},
+		{"error", ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to delete room"},
+		{"not_owner", moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/rooms/valid_room_id", "", roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("DeleteRoom", "valid_room_id", mongoMockPkg).Return(tt.err)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.DeleteRoomHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusForbidden {
+				mongoMockSvc.AssertNotCalled(t, "DeleteRoom", "valid_room_id", mongoMockPkg)
+			}
+		})
+	}
+}
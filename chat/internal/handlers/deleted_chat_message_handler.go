@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 削除済みメッセージの本文を確認する（モデレーション用）
+func (h *HandlerStruct) DeletedChatMessageHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+
+	message, _, ok := h.getMessageFor(c, c.Param("id"), int(userID), chat_svc.CapabilityModerate)
+	if !ok {
+		return
+	}
+
+	if message.DeletedAt == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message is not deleted"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted_message": message})
+}
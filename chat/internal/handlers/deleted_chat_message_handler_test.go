@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDeletedChatMessageContext(userID int) (*gin.Context, *httptest.ResponseRecorder) {
+	return newUserRequestContext("GET", "/messages/valid_message_id/deleted", "", userID, gin.Params{{Key: "id", Value: "valid_message_id"}})
+}
+
+func TestDeletedChatMessageHandler(t *testing.T) {
+	c, w := newDeletedChatMessageContext(99999)
+
+	deletedAt := time.Now()
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", Message: "deleted content", DeletedAt: &deletedAt}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 99999).Return(moderatorRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.DeletedChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "deleted content")
+}
+
+func TestDeletedChatMessageHandlerNotDeleted(t *testing.T) {
+	c, w := newDeletedChatMessageContext(99999)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 99999).Return(moderatorRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.DeletedChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Message is not deleted")
+}
+
+func TestDeletedChatMessageHandlerFailedGetMessage(t *testing.T) {
+	c, w := newDeletedChatMessageContext(99999)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.DeletedChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get message")
+}
+
+func TestDeletedChatMessageHandlerFailedGetRoom(t *testing.T) {
+	c, w := newDeletedChatMessageContext(99999)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.DeletedChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get room")
+}
+
+func TestDeletedChatMessageHandlerForbidden(t *testing.T) {
+	c, w := newDeletedChatMessageContext(12345)
+
+	deletedAt := time.Now()
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, DeletedAt: &deletedAt}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.DeletedChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 自分を含めたダイレクトメッセージの最大人数
+const maxDirectRoomParticipants = 9
+
+// 1対1の場合は user_id、グループの場合は user_ids で相手を指定する
+type CreateDirectRoomRequest struct {
+	UserID  int   `form:"user_id" json:"user_id"`
+	UserIDs []int `form:"user_ids" json:"user_ids"`
+}
+
+// 同じ参加者のダイレクトメッセージがあればそれを返し、無ければ作成する
+func (h *HandlerStruct) CreateDirectRoomHandler(c *gin.Context) {
+	var req CreateDirectRoomRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	participants := []int{userID}
+	for _, id := range append(req.UserIDs, req.UserID) {
+		if id > 0 && !containsInt(participants, id) {
+			participants = append(participants, id)
+		}
+	}
+	if len(participants) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one other user is required"})
+		return
+	}
+	if len(participants) > maxDirectRoomParticipants {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many participants"})
+		return
+	}
+
+	room, created, err := h.MongoSvc.GetOrCreateDirectRoom(participants, time.Now(), h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get direct message room", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"room_id": room.ID.Hex(),
+		"members": room.Members,
+		"created": created,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateDirectRoomHandler(t *testing.T) {
+	roomID := primitive.NewObjectID()
+
+	tests := []struct {
+		name         string
+		body         string
+		participants []int
+		created      bool
+		err          error
+		expectCode   int
+		expect       string
+	}{
+		{"one_to_one", `{"user_id":99999}`, []int{12345, 99999}, true, nil, http.StatusOK, `"created":true`},
+		{"existing", `{"user_id":99999}`, []int{12345, 99999}, false, nil, http.StatusOK, `"created":false`},
+		{"group", `{"user_ids":[99999,88888,99999,12345]}`, []int{12345, 99999, 88888}, true, nil, http.StatusOK, roomID.Hex()},
+		{"only_self", `{"user_id":12345}`, nil, false, nil, http.StatusBadRequest, "At least one other user is required"},
+		{"empty", `{}`, nil, false, nil, http.StatusBadRequest, "At least one other user is required"},
+		{"too_many", `{"user_ids":[1,2,3,4,5,6,7,8,9]}`, nil, false, nil, http.StatusBadRequest, "Too many participants"},
+		{"error", `{"user_id":99999}`, []int{12345, 99999}, false, assert.AnError, http.StatusInternalServerError, "Failed to get direct message room"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/dms", tt.body, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			if tt.participants != nil {
+				room := model.Room{ID: roomID, Kind: model.RoomKindDirect, Members: model.DirectParticipants(tt.participants)}
+				mongoMockSvc.On("GetOrCreateDirectRoom", tt.participants, mock.Anything, mongoMockPkg).Return(room, tt.created, tt.err)
+			}
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.CreateDirectRoomHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.participants == nil {
+				mongoMockSvc.AssertNotCalled(t, "GetOrCreateDirectRoom", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreateDirectRoomHandlerInvalidRequest(t *testing.T) {
+	c, w := newJSONRequestContext("POST", "/dms", `{"user_id":"abc"}`, nil)
+
+	handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+	handler.CreateDirectRoomHandler(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid request")
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 投稿後に編集できる期間（MESSAGE_EDIT_WINDOW 未設定時）
+const defaultMessageEditWindow = 15 * time.Minute
+
+type EditChatMessageRequest struct {
+	Message string `form:"message" json:"message" binding:"required"`
+}
+
+func messageEditWindow() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("MESSAGE_EDIT_WINDOW"))
+	if err != nil || window <= 0 {
+		return defaultMessageEditWindow
+	}
+	return window
+}
+
+func (h *HandlerStruct) EditChatMessageHandler(c *gin.Context) {
+	var req EditChatMessageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	messageID := c.Param("id")
+
+	message, roomInfo, ok := h.getMessageFor(c, messageID, int(userID), chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+
+	if roomInfo.IsArchived {
+		c.JSON(403, gin.H{"error": "Room is archived"})
+		return
+	}
+
+	// 投稿者本人（投稿権限がある場合）かオーナーのみ編集できる
+	isEditableAuthor := message.UserID == int(userID) && roomInfo.Can(chat_svc.CapabilityPost)
+	if !isEditableAuthor && !roomInfo.Can(chat_svc.CapabilityManage) {
+		c.JSON(403, gin.H{"error": "You can only edit your own messages"})
+		return
+	}
+
+	if message.DeletedAt != nil {
+		c.JSON(410, gin.H{"error": "Message has been deleted"})
+		return
+	}
+
+	if time.Since(message.CreatedAt) > messageEditWindow() {
+		c.JSON(403, gin.H{"error": "Edit window has expired"})
+		return
+	}
+
+	err := h.MongoSvc.EditChatMessage(message, req.Message, int(userID), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrEditConflict) {
+		c.JSON(409, gin.H{"error": "Message was modified concurrently", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "Failed to edit message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "Message edited successfully"})
+}
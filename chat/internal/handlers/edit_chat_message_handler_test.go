@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"microservices/chat/tests/test_funcs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEditChatMessageContext(body string, userID int) (*gin.Context, *httptest.ResponseRecorder) {
+	return newUserRequestContext("PATCH", "/messages/valid_message_id", body, userID, gin.Params{{Key: "id", Value: "valid_message_id"}})
+}
+
+func TestEditChatMessageHandler(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+	message := model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, Message: "original", CreatedAt: time.Now()}
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(message, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+	mongoMockSvc.On("EditChatMessage", message, "edited", 12345, mongoMockPkg).Return(nil)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Message edited successfully")
+}
+
+func TestEditChatMessageHandlerByOwner(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 99999)
+
+	message := model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, Message: "original", CreatedAt: time.Now()}
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(message, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 99999).Return(ownerRoomInfo)
+	mongoMockSvc.On("EditChatMessage", message, "edited", 99999, mongoMockPkg).Return(nil)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestEditChatMessageHandlerInvalidRequest(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":""}`, 12345)
+
+	handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid request")
+}
+
+func TestEditChatMessageHandlerFailedGetMessage(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get message")
+}
+
+func TestEditChatMessageHandlerFailedGetRoom(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get room")
+}
+
+func TestEditChatMessageHandlerForbidden(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 54321)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, CreatedAt: time.Now()}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 54321).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "You can only edit your own messages")
+}
+
+func TestEditChatMessageHandlerWindowExpired(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"MESSAGE_EDIT_WINDOW": "1m"}, t, func() {
+		c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+		mongoMockPkg := &MongoPkgMock{}
+		mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+		mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, CreatedAt: time.Now().Add(-2 * time.Minute)}, nil)
+		mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+		chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+		chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+		handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+		handler.EditChatMessageHandler(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Edit window has expired")
+	})
+}
+
+func TestEditChatMessageHandlerFailedEdit(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expectCode int
+		expectBody string
+	}{
+		{"conflict", mongo_svc.ErrEditConflict, http.StatusConflict, "Message was modified concurrently"},
+		{"error", assert.AnError, http.StatusInternalServerError, "Failed to edit message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+			message := model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, CreatedAt: time.Now()}
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(message, nil)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+			mongoMockSvc.On("EditChatMessage", message, "edited", 12345, mongoMockPkg).Return(tt.err)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.EditChatMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectBody)
+		})
+	}
+}
+
+func TestMessageEditWindow(t *testing.T) {
+	test_funcs.WithEnvMap(test_funcs.Envs{"MESSAGE_EDIT_WINDOW": "30m"}, t, func() {
+		assert.Equal(t, 30*time.Minute, messageEditWindow())
+	})
+	test_funcs.WithEnvMap(test_funcs.Envs{"MESSAGE_EDIT_WINDOW": "invalid"}, t, func() {
+		assert.Equal(t, defaultMessageEditWindow, messageEditWindow())
+	})
+}
+
+func TestEditChatMessageHandlerDeleted(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+	deletedAt := time.Now()
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, CreatedAt: time.Now(), DeletedAt: &deletedAt}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "Message has been deleted")
+}
+
+func TestEditChatMessageHandlerReadOnlyAuthor(t *testing.T) {
+	c, w := newEditChatMessageContext(`{"message":"edited"}`, 12345)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, CreatedAt: time.Now()}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(readOnlyRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.EditChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "You can only edit your own messages")
+}
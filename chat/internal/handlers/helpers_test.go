@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userID のユーザーとして認証済みのリクエストのコンテキストを作る
+func newUserRequestContext(method string, path string, body string, userID int, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, userID)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	c.Params = params
+	c.Request = req.WithContext(ctx)
+	return c, w
+}
+
+// ユーザー 12345 としてのリクエストのコンテキストを作る
+func newJSONRequestContext(method string, path string, body string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	return newUserRequestContext(method, path, body, 12345, params)
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 受信 Webhook の本文の上限（IncomingWebhookMaxSize が未設定の場合）
+const defaultIncomingWebhookMaxSize = 16 << 10
+
+type CreateIncomingWebhookRequest struct {
+	Name string `json:"name" binding:"required,max=80"`
+}
+
+// ルームの受信 Webhook を作成する。トークンはこのレスポンスでのみ返す
+func (h *HandlerStruct) CreateIncomingWebhookHandler(c *gin.Context) {
+	var req CreateIncomingWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	token, err := generateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook token"})
+		return
+	}
+
+	webhookID, err := h.MongoSvc.CreateIncomingWebhook(model.IncomingWebhook{
+		RoomID:    roomID,
+		Name:      req.Name,
+		TokenHash: model.HashIncomingWebhookToken(token),
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create incoming webhook", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Incoming webhook created successfully",
+		"webhook_id": webhookID,
+		"token":      token,
+		"url":        "/hooks/" + token,
+	})
+}
+
+func (h *HandlerStruct) IncomingWebhooksHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	webhooks, err := h.MongoSvc.GetIncomingWebhooks(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get incoming webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"incoming_webhooks": webhooks})
+}
+
+func (h *HandlerStruct) DeleteIncomingWebhookHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.DeleteIncomingWebhook(roomID, c.Param("webhook_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrIncomingWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incoming webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete incoming webhook", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Incoming webhook deleted successfully"})
+}
+
+type IncomingWebhookMessageRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// 外部のシステムからの投稿を受け付ける。トークンで認証するため CSRF とログインは不要
+func (h *HandlerStruct) PostIncomingWebhookHandler(c *gin.Context) {
+	tokenHash := model.HashIncomingWebhookToken(c.Param("token"))
+	if h.IncomingWebhookLimiter != nil && !h.IncomingWebhookLimiter.Allow(tokenHash, time.Now()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+		return
+	}
+
+	maxSize := h.IncomingWebhookMaxSize
+	if maxSize <= 0 {
+		maxSize = defaultIncomingWebhookMaxSize
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+	var req IncomingWebhookMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Payload too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	webhook, err := h.MongoSvc.GetIncomingWebhookByToken(tokenHash, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrIncomingWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incoming webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get incoming webhook"})
+		return
+	}
+
+	roomID := webhook.RoomID
+	room, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room"})
+		return
+	}
+	if room.Archived() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Room is archived"})
+		return
+	}
+
+	messageID, err := h.postBotMessage(roomID, room, webhook.ID.Hex(), webhook.Name, req.Text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post chat", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chat posted successfully", "message_id": messageID})
+}
+
+// ボットとしてルームに投稿し、通常の投稿と同じように通知・Webhook を送る
+func (h *HandlerStruct) postBotMessage(roomID string, room model.Room, botID string, botName string, text string) (string, error) {
+	// ボットはメンバーの権限を持たないため @all は使えない
+	mentionedUserIDs, err := h.resolveMentions(roomID, room, chat_svc.Room{}, 0, text)
+	if err != nil {
+		return "", err
+	}
+
+	chatMessage := model.ChatMessage{
+		RoomID:           roomID,
+		Message:          text,
+		MentionedUserIds: mentionedUserIDs,
+		BotID:            botID,
+		BotName:          botName,
+		RetentionDays:    room.RetentionDays,
+	}
+	messageID, err := h.MongoSvc.PostChatMessage(chatMessage, h.MongoPkg)
+	if err != nil {
+		return "", err
+	}
+
+	h.enqueueNotifications(messageID, chatMessage, room, 0)
+	h.publishWebhookEvent(roomID, model.WebhookMessageCreated, model.WebhookMessageData{
+		MessageID: messageID,
+		Message:   chatMessage.Message,
+	})
+	return messageID, nil
+}
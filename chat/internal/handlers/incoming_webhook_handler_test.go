@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/ratelimit_pkg"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateIncomingWebhookHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		createErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"name":"CI"}`, ownerRoomInfo, nil, http.StatusOK, `"url":"/hooks/`},
+		{"missing_name", `{}`, ownerRoomInfo, nil, http.StatusBadRequest, "Invalid request"},
+		{"long_name", `{"name":"` + strings.Repeat("a", 81) + `"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Invalid request"},
+		{"not_owner", `{"name":"CI"}`, moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"create_error", `{"name":"CI"}`, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to create incoming webhook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/incoming_webhooks", tt.body, gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			var saved model.IncomingWebhook
+			mongoMockSvc.On("CreateIncomingWebhook", mock.Anything, mongoMockPkg).Run(func(args mock.Arguments) {
+				saved = args.Get(0).(model.IncomingWebhook)
+			}).Return("webhook1", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CreateIncomingWebhookHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				// 保存するのはトークンのハッシュのみ
+				assert.Equal(t, "room1", saved.RoomID)
+				assert.Equal(t, "CI", saved.Name)
+				assert.NotContains(t, w.Body.String(), saved.TokenHash)
+			}
+		})
+	}
+}
+
+func TestIncomingWebhooksHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	c, w := newJSONRequestContext("GET", "/rooms/room1/incoming_webhooks", "", gin.Params{{Key: "id", Value: "room1"}})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+	mongoMockSvc.On("GetIncomingWebhooks", "room1", mongoMockPkg).Return([]model.IncomingWebhook{{Name: "CI", TokenHash: "secret_hash"}}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.IncomingWebhooksHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"Name":"CI"`)
+	assert.NotContains(t, w.Body.String(), "secret_hash")
+}
+
+func TestDeleteIncomingWebhookHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	params := gin.Params{{Key: "id", Value: "room1"}, {Key: "webhook_id", Value: "webhook1"}}
+
+	tests := []struct {
+		name       string
+		deleteErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, "Incoming webhook deleted successfully"},
+		{"not_found", mongo_svc.ErrIncomingWebhookNotFound, http.StatusNotFound, "Incoming webhook not found"},
+		{"delete_error", assert.AnError, http.StatusInternalServerError, "Failed to delete incoming webhook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/rooms/room1/incoming_webhooks/webhook1", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("DeleteIncomingWebhook", "room1", "webhook1", mongoMockPkg).Return(tt.deleteErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.DeleteIncomingWebhookHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestPostIncomingWebhookHandler(t *testing.T) {
+	webhookID := primitive.NewObjectID()
+	webhook := model.IncomingWebhook{ID: webhookID, RoomID: "room1", Name: "CI"}
+	archivedAt := time.Now()
+	tokenHash := model.HashIncomingWebhookToken("token1")
+
+	tests := []struct {
+		name       string
+		body       string
+		limit      int
+		lookupErr  error
+		room       model.Room
+		postErr    error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"text":"build passed"}`, 10, nil, model.Room{}, nil, http.StatusOK, `"message_id":"new_message_id"`},
+		{"rate_limited", `{"text":"build passed"}`, 0, nil, model.Room{}, nil, http.StatusTooManyRequests, "Too many requests"},
+		{"too_large", `{"text":"` + strings.Repeat("a", 100) + `"}`, 10, nil, model.Room{}, nil, http.StatusRequestEntityTooLarge, "Payload too large"},
+		{"missing_text", `{}`, 10, nil, model.Room{}, nil, http.StatusBadRequest, "Invalid request"},
+		{"unknown_token", `{"text":"build passed"}`, 10, mongo_svc.ErrIncomingWebhookNotFound, model.Room{}, nil, http.StatusNotFound, "Incoming webhook not found"},
+		{"lookup_error", `{"text":"build passed"}`, 10, assert.AnError, model.Room{}, nil, http.StatusInternalServerError, "Failed to get incoming webhook"},
+		{"archived", `{"text":"build passed"}`, 10, nil, model.Room{ArchivedAt: &archivedAt}, nil, http.StatusForbidden, "Room is archived"},
+		{"post_error", `{"text":"build passed"}`, 10, nil, model.Room{}, assert.AnError, http.StatusInternalServerError, "Failed to post chat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/hooks/token1", tt.body, gin.Params{{Key: "token", Value: "token1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetIncomingWebhookByToken", tokenHash, mongoMockPkg).Return(webhook, tt.lookupErr)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(tt.room, nil)
+			mongoMockSvc.On("PostChatMessage", model.ChatMessage{
+				RoomID:  "room1",
+				Message: "build passed",
+				BotID:   webhookID.Hex(),
+				BotName: "CI",
+			}, mongoMockPkg).Return("new_message_id", tt.postErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.IncomingWebhookLimiter = ratelimit_pkg.NewMemoryLimiter(tt.limit, time.Minute)
+			handler.IncomingWebhookMaxSize = 64
+			handler.PostIncomingWebhookHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
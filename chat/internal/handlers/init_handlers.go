@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"microservices/chat/internal/svc/attachment_svc"
 	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/export_svc"
 	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/internal/svc/notification_svc"
+	"microservices/chat/internal/svc/presence_svc"
+	"microservices/chat/internal/svc/webhook_svc"
+	"microservices/chat/pkg/command_pkg"
 	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/ratelimit_pkg"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,12 +24,86 @@ type HandlersInterface interface {
 	LoadChatHandlers(c *gin.Context)
 	ReadChatMessages(c *gin.Context)
 	DeleteChatMessageHandler(c *gin.Context)
+	SearchChatMessagesHandler(c *gin.Context)
+	EditChatMessageHandler(c *gin.Context)
+	ChatMessageRevisionsHandler(c *gin.Context)
+	DeletedChatMessageHandler(c *gin.Context)
+	ThreadHandler(c *gin.Context)
+	AddReactionHandler(c *gin.Context)
+	RemoveReactionHandler(c *gin.Context)
+	ReadUpToHandler(c *gin.Context)
+	CreateRoomInviteHandler(c *gin.Context)
+	AcceptRoomInviteHandler(c *gin.Context)
+	CreateJoinRequestHandler(c *gin.Context)
+	JoinRequestsHandler(c *gin.Context)
+	ApproveJoinRequestHandler(c *gin.Context)
+	DenyJoinRequestHandler(c *gin.Context)
+	LeaveRoomHandler(c *gin.Context)
+	KickMemberHandler(c *gin.Context)
+	BanMemberHandler(c *gin.Context)
+	UnbanMemberHandler(c *gin.Context)
+	TransferOwnershipHandler(c *gin.Context)
+	SetMemberRoleHandler(c *gin.Context)
+	UpdateRoomHandler(c *gin.Context)
+	ArchiveRoomHandler(c *gin.Context)
+	UnarchiveRoomHandler(c *gin.Context)
+	DeleteRoomHandler(c *gin.Context)
+	CreateDirectRoomHandler(c *gin.Context)
+	UploadAttachmentsHandler(c *gin.Context)
+	AttachmentURLHandler(c *gin.Context)
+	DownloadAttachmentHandler(c *gin.Context)
+	DownloadThumbnailHandler(c *gin.Context)
+	MentionsHandler(c *gin.Context)
+	NotificationSettingsHandler(c *gin.Context)
+	UpdateNotificationSettingsHandler(c *gin.Context)
+	MuteRoomHandler(c *gin.Context)
+	UnmuteRoomHandler(c *gin.Context)
+	CreateRoomWebhookHandler(c *gin.Context)
+	RoomWebhooksHandler(c *gin.Context)
+	DeleteRoomWebhookHandler(c *gin.Context)
+	WebhookDeliveriesHandler(c *gin.Context)
+	RedeliverWebhookHandler(c *gin.Context)
+	CreateIncomingWebhookHandler(c *gin.Context)
+	IncomingWebhooksHandler(c *gin.Context)
+	DeleteIncomingWebhookHandler(c *gin.Context)
+	PostIncomingWebhookHandler(c *gin.Context)
+	CreateSlashCommandHandler(c *gin.Context)
+	SlashCommandsHandler(c *gin.Context)
+	DeleteSlashCommandHandler(c *gin.Context)
+	UpdatePresenceHandler(c *gin.Context)
+	TypingHandler(c *gin.Context)
+	RoomPresenceHandler(c *gin.Context)
+	PinMessageHandler(c *gin.Context)
+	UnpinMessageHandler(c *gin.Context)
+	PinsHandler(c *gin.Context)
+	SaveMessageHandler(c *gin.Context)
+	SavedMessagesHandler(c *gin.Context)
+	DeleteSavedMessageHandler(c *gin.Context)
+	ScheduleMessageHandler(c *gin.Context)
+	RemindHandler(c *gin.Context)
+	ScheduledMessagesHandler(c *gin.Context)
+	CancelScheduledMessageHandler(c *gin.Context)
+	RetentionReportHandler(c *gin.Context)
+	CreateRoomExportHandler(c *gin.Context)
+	RoomExportsHandler(c *gin.Context)
+	RoomExportHandler(c *gin.Context)
+	DownloadRoomExportHandler(c *gin.Context)
 }
 
 type HandlerStruct struct {
 	MongoSvc mongo_svc.MongoSvcInterface
 	MongoPkg mongo_pkg.MongoPkgInterface
 	ChatSvc  chat_svc.ChatSvcInterface
+
+	AttachmentSvc   attachment_svc.AttachmentSvcInterface     // 添付ファイルを扱う場合のみ設定する
+	NotificationSvc notification_svc.NotificationSvcInterface // 通知を送る場合のみ設定する
+	WebhookSvc      webhook_svc.WebhookSvcInterface           // Webhook を送る場合のみ設定する
+	PresenceSvc     presence_svc.PresenceSvcInterface
+	ExportSvc       export_svc.ExportSvcInterface // ルームのエクスポートを扱う場合のみ設定する
+
+	IncomingWebhookLimiter ratelimit_pkg.Limiter       // 未設定の場合は受信 Webhook の回数を制限しない
+	IncomingWebhookMaxSize int64                       // 受信 Webhook の本文の上限（0 の場合は既定値）
+	CommandClient          command_pkg.ClientInterface // 外部のコマンドを実行する場合のみ設定する
 }
 
 func NewHandlers(
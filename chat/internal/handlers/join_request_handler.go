@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxJoinRequestMessageLength = 500
+
+type CreateJoinRequestRequest struct {
+	Message string `form:"message" json:"message"`
+}
+
+func (h *HandlerStruct) CreateJoinRequestHandler(c *gin.Context) {
+	var req CreateJoinRequestRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if len(req.Message) > maxJoinRequestMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message is too long"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	roomID := c.Param("id")
+
+	room, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room"})
+		return
+	}
+	if room.IsDirect() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot request to join a direct message"})
+		return
+	}
+	// 公開ルームは申請なしで参加できる
+	if !room.IsPrivate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is public, join it directly"})
+		return
+	}
+	if containsInt(room.Banned, int(userID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this room"})
+		return
+	}
+	if room.Archived() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Room is archived"})
+		return
+	}
+	roomInfo := h.ChatSvc.GetRoomInfo(room, int(userID))
+	if roomInfo.Can(chat_svc.CapabilityRead) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already a member of this room"})
+		return
+	}
+
+	requestID, err := h.MongoSvc.CreateJoinRequest(model.JoinRequest{
+		RoomID:    roomID,
+		UserID:    int(userID),
+		Message:   req.Message,
+		CreatedAt: time.Now(),
+	}, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrJoinRequestExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Join request is already pending"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create join request", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join request created successfully", "request_id": requestID})
+}
+
+func (h *HandlerStruct) JoinRequestsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(userID), chat_svc.CapabilityModerate); !ok {
+		return
+	}
+
+	requests, err := h.MongoSvc.GetJoinRequests(roomID, model.JoinRequestPending, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get join requests", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"join_requests": requests})
+}
+
+func (h *HandlerStruct) ApproveJoinRequestHandler(c *gin.Context) {
+	h.decideJoinRequest(c, model.JoinRequestApproved)
+}
+
+func (h *HandlerStruct) DenyJoinRequestHandler(c *gin.Context) {
+	h.decideJoinRequest(c, model.JoinRequestDenied)
+}
+
+func (h *HandlerStruct) decideJoinRequest(c *gin.Context, status string) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	roomID := c.Param("id")
+
+	room, _, ok := h.getRoomFor(c, roomID, int(userID), chat_svc.CapabilityModerate)
+	if !ok {
+		return
+	}
+
+	if room.Archived() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Room is archived"})
+		return
+	}
+
+	request, err := h.MongoSvc.DecideJoinRequest(roomID, c.Param("request_id"), status, int(userID), h.MongoPkg)
+	switch {
+	case errors.Is(err, mongo_svc.ErrJoinRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join request not found"})
+		return
+	case errors.Is(err, mongo_svc.ErrUserBanned):
+		c.JSON(http.StatusConflict, gin.H{"error": "User is banned from this room"})
+		return
+	case errors.Is(err, mongo_svc.ErrRoomArchived):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Room is archived"})
+		return
+	case errors.Is(err, mongo_svc.ErrJoinRequestDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": "Join request has already been decided"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update join request", "details": err.Error()})
+		return
+	}
+
+	if status == model.JoinRequestApproved {
+		h.publishWebhookEvent(roomID, model.WebhookMemberJoined, model.WebhookMemberData{UserID: request.UserID, ActorID: int(userID)})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Join request " + status, "join_request": request})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateJoinRequestHandler(t *testing.T) {
+	privateRoom := model.Room{IsPrivate: true}
+
+	tests := []struct {
+		name       string
+		body       string
+		room       model.Room
+		roomInfo   chat_svc.Room
+		createErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"message":"Please let me in"}`, privateRoom, chat_svc.Room{}, nil, http.StatusOK, `"request_id":"request_id"`},
+		{"message_too_long", `{"message":"` + strings.Repeat("a", maxJoinRequestMessageLength+1) + `"}`, privateRoom, chat_svc.Room{}, nil, http.StatusBadRequest, "Message is too long"},
+		{"public_room", `{}`, model.Room{}, chat_svc.Room{}, nil, http.StatusBadRequest, "Room is public"},
+		{"direct_room", `{}`, model.Room{IsPrivate: true, Kind: model.RoomKindDirect}, chat_svc.Room{}, nil, http.StatusBadRequest, "Cannot request to join a direct message"},
+		{"already_member", `{}`, privateRoom, memberRoomInfo, nil, http.StatusConflict, "Already a member"},
+		{"already_pending", `{}`, privateRoom, chat_svc.Room{}, mongo_svc.ErrJoinRequestExists, http.StatusConflict, "Join request is already pending"},
+		{"create_error", `{}`, privateRoom, chat_svc.Room{}, assert.AnError, http.StatusInternalServerError, "Failed to create join request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/join_requests", tt.body, gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(tt.room, nil)
+			mongoMockSvc.On("CreateJoinRequest", mock.MatchedBy(func(request model.JoinRequest) bool {
+				return request.RoomID == "valid_room_id" && request.UserID == 12345
+			}), mongoMockPkg).Return("request_id", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", tt.room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CreateJoinRequestHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestJoinRequestsHandler(t *testing.T) {
+	c, w := newJSONRequestContext("GET", "/rooms/valid_room_id/join_requests", "", gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetJoinRequests", "valid_room_id", model.JoinRequestPending, mongoMockPkg).Return([]model.JoinRequest{{UserID: 99999, Status: model.JoinRequestPending}}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.JoinRequestsHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"UserID":99999`)
+}
+
+func TestJoinRequestsHandlerNotOwner(t *testing.T) {
+	c, w := newJSONRequestContext("GET", "/rooms/valid_room_id/join_requests", "", gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.JoinRequestsHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestDecideJoinRequestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		approve    bool
+		status     string
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"approve", true, model.JoinRequestApproved, nil, http.StatusOK, "Join request approved"},
+		{"deny", false, model.JoinRequestDenied, nil, http.StatusOK, "Join request denied"},
+		{"not_found", true, model.JoinRequestApproved, mongo_svc.ErrJoinRequestNotFound, http.StatusNotFound, "Join request not found"},
+		{"already_decided", false, model.JoinRequestDenied, mongo_svc.ErrJoinRequestDecided, http.StatusConflict, "already been decided"},
+		{"archived_concurrently", true, model.JoinRequestApproved, mongo_svc.ErrRoomArchived, http.StatusForbidden, "Room is archived"},
+		{"error", true, model.JoinRequestApproved, assert.AnError, http.StatusInternalServerError, "Failed to update join request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/join_requests/request_id/approve", "", gin.Params{
+				{Key: "id", Value: "valid_room_id"},
+				{Key: "request_id", Value: "request_id"},
+			})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("DecideJoinRequest", "valid_room_id", "request_id", tt.status, 12345, mongoMockPkg).Return(model.JoinRequest{Status: tt.status}, tt.err)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			if tt.approve {
+				handler.ApproveJoinRequestHandler(c)
+			} else {
+				handler.DenyJoinRequestHandler(c)
+			}
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestDecideJoinRequestHandlerArchived(t *testing.T) {
+	archivedAt := time.Now()
+	room := model.Room{ArchivedAt: &archivedAt}
+	c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/join_requests/request_id/approve", "", gin.Params{
+		{Key: "id", Value: "valid_room_id"},
+		{Key: "request_id", Value: "request_id"},
+	})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(room, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ApproveJoinRequestHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Room is archived")
+	mongoMockSvc.AssertNotCalled(t, "DecideJoinRequest", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,17 +25,50 @@ func (h *HandlerStruct) JoinRoomHandler(c *gin.Context) {
 	roomID := req.RoomID
 	userID := jwtinfo.UserID
 
-	_, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
+	room, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to get room", "details": err.Error()})
 		return
 	}
 
+	if containsInt(room.Banned, int(userID)) {
+		c.JSON(403, gin.H{"error": "You are banned from this room"})
+		return
+	}
+
+	if room.Archived() {
+		c.JSON(403, gin.H{"error": "Room is archived"})
+		return
+	}
+
+	// プライベートルームは招待か参加申請の承認が必要
+	if room.IsPrivate {
+		roomInfo := h.ChatSvc.GetRoomInfo(room, int(userID))
+		if !roomInfo.Can(chat_svc.CapabilityRead) {
+			c.JSON(403, gin.H{"error": "Invitation required to join private room"})
+			return
+		}
+	}
+
 	err = h.MongoSvc.JoinRoom(roomID, int(userID), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrUserBanned) {
+		c.JSON(403, gin.H{"error": "You are banned from this room"})
+		return
+	}
+	// 確認した後にアーカイブされた
+	if errors.Is(err, mongo_svc.ErrRoomArchived) {
+		c.JSON(403, gin.H{"error": "Room is archived"})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to join room", "details": err.Error()})
 		return
 	}
 
+	// 既に参加しているユーザーの再参加は通知しない
+	if !containsInt(room.Members, int(userID)) {
+		h.publishWebhookEvent(roomID, model.WebhookMemberJoined, model.WebhookMemberData{UserID: int(userID), ActorID: int(userID)})
+	}
+
 	c.JSON(200, gin.H{"message": "Joined room successfully"})
 }
@@ -3,13 +3,16 @@ package handlers
 import (
 	"context"
 	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
 	"microservices/chat/tests/mocks/svc/mock_chat_svc"
 	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -117,3 +120,126 @@ func TestJoinRoomHandlerInvalidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "Invalid request")
 }
+
+func TestJoinRoomHandlerPrivateRoom(t *testing.T) {
+	tests := []struct {
+		name       string
+		roomInfo   chat_svc.Room
+		expectCode int
+	}{
+		{"not_invited", chat_svc.Room{}, http.StatusForbidden},
+		{"already_member", memberRoomInfo, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			privateRoom := model.Room{IsPrivate: true}
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(privateRoom, nil)
+			mongoMockSvc.On("JoinRoom", "valid_room_id", int(12345), mongoMockPkg).Return(nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", privateRoom, 12345).Return(tt.roomInfo)
+
+			body := strings.NewReader("room_id=valid_room_id")
+			req := httptest.NewRequest("POST", "/join_room", body)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+			ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+			c.Request = req.WithContext(ctx)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.JoinRoomHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			if tt.expectCode == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), "Invitation required")
+				mongoMockSvc.AssertNotCalled(t, "JoinRoom", "valid_room_id", int(12345), mongoMockPkg)
+			}
+		})
+	}
+}
+
+func TestJoinRoomHandlerBanned(t *testing.T) {
+	tests := []struct {
+		name    string
+		room    model.Room
+		joinErr error
+	}{
+		{"banned_list", model.Room{Banned: []int{12345}}, nil},
+		{"banned_concurrently", model.Room{}, mongo_svc.ErrUserBanned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(tt.room, nil)
+			mongoMockSvc.On("JoinRoom", "valid_room_id", int(12345), mongoMockPkg).Return(tt.joinErr)
+
+			body := strings.NewReader("room_id=valid_room_id")
+			req := httptest.NewRequest("POST", "/join_room", body)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+			ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+			c.Request = req.WithContext(ctx)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.JoinRoomHandler(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "You are banned from this room")
+		})
+	}
+}
+
+func TestJoinRoomHandlerArchived(t *testing.T) {
+	archivedAt := time.Now()
+
+	tests := []struct {
+		name    string
+		room    model.Room
+		joinErr error
+	}{
+		{"archived", model.Room{ArchivedAt: &archivedAt}, nil},
+		// 取得した後にアーカイブされた
+		{"archived_concurrently", model.Room{}, mongo_svc.ErrRoomArchived},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(tt.room, nil)
+			mongoMockSvc.On("JoinRoom", "valid_room_id", int(12345), mongoMockPkg).Return(tt.joinErr)
+
+			body := strings.NewReader("room_id=valid_room_id")
+			req := httptest.NewRequest("POST", "/join_room", body)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+			ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+			c.Request = req.WithContext(ctx)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.JoinRoomHandler(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "Room is archived")
+			if tt.joinErr == nil {
+				mongoMockSvc.AssertNotCalled(t, "JoinRoom", "valid_room_id", int(12345), mongoMockPkg)
+			}
+		})
+	}
+}
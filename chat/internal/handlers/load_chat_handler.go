@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
 	"net/http"
 
@@ -18,7 +19,7 @@ func (h *HandlerStruct) LoadChatHandlers(c *gin.Context) {
 	}
 	// ルームの情報を取得
 	roomInfo := h.ChatSvc.GetRoomInfo(room, int(userID))
-	if !roomInfo.IsMember && !roomInfo.IsOwner {
+	if !roomInfo.Can(chat_svc.CapabilityRead) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -28,6 +29,7 @@ func (h *HandlerStruct) LoadChatHandlers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chat messages"})
 		return
 	}
+	h.signThumbnailURLs(messages)
 
 	c.JSON(http.StatusOK, gin.H{"room": roomInfo, "messages": messages})
 }
@@ -5,6 +5,7 @@ import (
 	"microservices/chat/internal/model"
 	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/tests/mocks/svc/mock_attachment_svc"
 	"microservices/chat/tests/mocks/svc/mock_chat_svc"
 	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestLoadChatHandlers(t *testing.T) {
@@ -26,7 +28,7 @@ func TestLoadChatHandlers(t *testing.T) {
 	mongoMockSvc.On("GetChatMessages", "valid_room_id", mongoMockPkg).Return([]model.ChatMessage{}, nil)
 
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
-	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(chat_svc.Room{IsMember: true, IsOwner: false})
+	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(memberRoomInfo)
 
 	req := httptest.NewRequest("GET", "/load_chat/valid_room_id", nil)
 	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
@@ -104,7 +106,7 @@ func TestLoadChatHandlersGetChatMessagesError(t *testing.T) {
 	mongoMockSvc.On("GetChatMessages", "valid_room_id", mongoMockPkg).Return([]model.ChatMessage{}, assert.AnError)
 
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
-	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(chat_svc.Room{IsMember: true, IsOwner: false})
+	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(memberRoomInfo)
 
 	req := httptest.NewRequest("GET", "/load_chat/valid_room_id", nil)
 	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
@@ -119,3 +121,43 @@ func TestLoadChatHandlersGetChatMessagesError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "Failed to get chat messages")
 }
+
+func TestLoadChatHandlersThumbnailURLs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	messageID := primitive.NewObjectID()
+	messages := []model.ChatMessage{{
+		ID: messageID,
+		Attachments: []model.Attachment{
+			{ID: "a1", ContentType: "image/png", PreviewStatus: model.PreviewReady, Thumbnails: []model.Thumbnail{{Size: 160, StorageKey: "key/thumbnails/160"}}},
+			{ID: "a2", ContentType: "image/webp", PreviewStatus: model.PreviewUnavailable},
+		},
+	}}
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetChatMessages", "valid_room_id", mongoMockPkg).Return(messages, nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(memberRoomInfo)
+	attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+	attachmentMockSvc.On("SignThumbnailURL", messageID.Hex(), "a1", 160).Return("/attachments/signed")
+
+	req := httptest.NewRequest("GET", "/load_chat/valid_room_id", nil)
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	c.Params = append(c.Params, gin.Param{Key: "room_id", Value: "valid_room_id"})
+	c.Request = req.WithContext(ctx)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AttachmentSvc = attachmentMockSvc
+	handler.LoadChatHandlers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"URL":"/attachments/signed"`)
+	assert.Contains(t, w.Body.String(), `"PreviewStatus":"unavailable"`)
+	assert.NotContains(t, w.Body.String(), "StorageKey")
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @here の対象にする、直近でルームのメッセージを既読にしたメンバーの期間
+const hereMentionWindow = 15 * time.Minute
+
+// 本文のメンションをルームのメンバーのユーザーIDに解決する（メンバー以外と投稿者自身は含めない）
+func (h *HandlerStruct) resolveMentions(roomID string, room model.Room, roomInfo chat_svc.Room, userID int, message string) ([]int, error) {
+	mentions := model.ParseMentions(message)
+	mentioned := map[int]bool{}
+
+	switch {
+	// @all はモデレーター以上のみ。権限が無い場合は通常の文字列として扱う
+	case mentions.All && roomInfo.Can(chat_svc.CapabilityModerate):
+		for _, id := range room.Members {
+			mentioned[id] = true
+		}
+	case mentions.Here:
+		active, err := h.MongoSvc.GetActiveReaders(roomID, time.Now().Add(-hereMentionWindow), h.MongoPkg)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range active {
+			if containsInt(room.Members, id) {
+				mentioned[id] = true
+			}
+		}
+	}
+
+	if len(mentions.Handles) > 0 {
+		users, err := h.MongoSvc.GetUsersByHandles(mentions.Handles, room.Members, h.MongoPkg)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			mentioned[user.UserID] = true
+		}
+	}
+
+	delete(mentioned, userID)
+	if len(mentioned) == 0 {
+		return nil, nil
+	}
+	userIDs := make([]int, 0, len(mentioned))
+	for id := range mentioned {
+		userIDs = append(userIDs, id)
+	}
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
+
+// メンション一覧の1件
+type MentionItem struct {
+	model.ChatMessage
+	RoomName string
+	IsUnread bool
+}
+
+// 参加中のルームで自分がメンションされたメッセージを新しい順に返す
+func (h *HandlerStruct) MentionsHandler(c *gin.Context) {
+	var req PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	page, limit := req.normalize()
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	// 退出したルームのメンションは表示しない
+	rooms, err := h.MongoSvc.GetRooms(userID, "joined", h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rooms"})
+		return
+	}
+	roomNames := map[string]string{}
+	roomIDs := []string{}
+	for _, room := range rooms {
+		roomNames[room.ID.Hex()] = room.Name
+		roomIDs = append(roomIDs, room.ID.Hex())
+	}
+
+	messages, total, err := h.MongoSvc.GetMentions(userID, roomIDs, page, limit, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get mentions"})
+		return
+	}
+	readCursors, err := h.MongoSvc.GetReadCursors(userID, roomIDs, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get read cursors"})
+		return
+	}
+
+	mentions := make([]MentionItem, 0, len(messages))
+	for _, message := range messages {
+		mentions = append(mentions, MentionItem{
+			ChatMessage: message,
+			RoomName:    roomNames[message.RoomID],
+			IsUnread:    !message.IsReadBy(userID, readCursors[message.RoomID]),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mentions": mentions,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
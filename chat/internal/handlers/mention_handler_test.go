@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveMentions(t *testing.T) {
+	room := model.Room{Members: []int{12345, 2, 3, 4}}
+
+	tests := []struct {
+		name        string
+		message     string
+		roomInfo    chat_svc.Room
+		users       []model.User
+		active      []int
+		usersErr    error
+		activeErr   error
+		expect      []int
+		returnErr   bool
+		expectUsers bool
+	}{
+		{"no_mentions", "hello", memberRoomInfo, nil, nil, nil, nil, nil, false, false},
+		{"handles", "@alice @bob", memberRoomInfo, []model.User{{UserID: 3}, {UserID: 2}}, nil, nil, nil, []int{2, 3}, false, true},
+		// 自分自身へのメンションは含めない
+		{"self", "@test", memberRoomInfo, []model.User{{UserID: 12345}}, nil, nil, nil, nil, false, true},
+		{"all_by_moderator", "@all", moderatorRoomInfo, nil, nil, nil, nil, []int{2, 3, 4}, false, false},
+		// メンバーの @all は無視する
+		{"all_by_member", "@all", memberRoomInfo, nil, nil, nil, nil, nil, false, false},
+		// メンバーではなくなったユーザーは @here の対象にしない
+		{"here", "@here", memberRoomInfo, nil, []int{3, 99}, nil, nil, []int{3}, false, false},
+		{"all_by_member_falls_back_to_here", "@all @here", memberRoomInfo, nil, []int{4}, nil, nil, []int{4}, false, false},
+		{"users_error", "@alice", memberRoomInfo, nil, nil, assert.AnError, nil, nil, true, true},
+		{"active_error", "@here", memberRoomInfo, nil, nil, nil, assert.AnError, nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetUsersByHandles", mock.Anything, room.Members, mongoMockPkg).Return(tt.users, tt.usersErr)
+			mongoMockSvc.On("GetActiveReaders", "room1", mock.MatchedBy(func(since time.Time) bool {
+				return time.Since(since) >= hereMentionWindow
+			}), mongoMockPkg).Return(tt.active, tt.activeErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, nil)
+			userIDs, err := handler.resolveMentions("room1", room, tt.roomInfo, 12345, tt.message)
+
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expect, userIDs)
+			}
+			if !tt.expectUsers {
+				mongoMockSvc.AssertNotCalled(t, "GetUsersByHandles", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestPostChatMessageHandlerMentions(t *testing.T) {
+	room := model.Room{Members: []int{12345, 2}}
+	c, w := newJSONRequestContext("POST", "/post_chat_message", `{"room_id":"valid_room_id","message":"hi @alice"}`, nil)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(room, nil)
+	mongoMockSvc.On("GetUsersByHandles", []string{"alice"}, room.Members, mongoMockPkg).Return([]model.User{{UserID: 2, Handle: "alice"}}, nil)
+	mongoMockSvc.On("PostChatMessage", model.ChatMessage{
+		RoomID:           "valid_room_id",
+		UserID:           12345,
+		Message:          "hi @alice",
+		MentionedUserIds: []int{2},
+	}, mongoMockPkg).Return("new_message_id", nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.PostChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mongoMockSvc.AssertExpectations(t)
+}
+
+func TestMentionsHandler(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	now := time.Now()
+	rooms := []model.Room{{ID: roomID, Name: "general", Members: []int{12345}}}
+	messages := []model.ChatMessage{
+		{RoomID: roomID.Hex(), Message: "new @test", CreatedAt: now, MentionedUserIds: []int{12345}},
+		{RoomID: roomID.Hex(), Message: "old @test", CreatedAt: now.Add(-time.Hour), MentionedUserIds: []int{12345}},
+	}
+	readCursors := map[string]model.ReadCursor{roomID.Hex(): {ID: primitive.NewObjectID(), LastReadAt: now.Add(-time.Minute)}}
+
+	tests := []struct {
+		name        string
+		query       string
+		roomsErr    error
+		mentionsErr error
+		cursorsErr  error
+		expectCode  int
+		expect      string
+	}{
+		{"success", "", nil, nil, nil, http.StatusOK, `"RoomName":"general","IsUnread":true`},
+		{"read_before_cursor", "", nil, nil, nil, http.StatusOK, `"RoomName":"general","IsUnread":false`},
+		{"invalid_query", "?page=abc", nil, nil, nil, http.StatusBadRequest, "Invalid request"},
+		{"rooms_error", "", assert.AnError, nil, nil, http.StatusInternalServerError, "Failed to get rooms"},
+		{"mentions_error", "", nil, assert.AnError, nil, http.StatusInternalServerError, "Failed to get mentions"},
+		{"cursors_error", "", nil, nil, assert.AnError, http.StatusInternalServerError, "Failed to get read cursors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/mentions"+tt.query, "", nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return(rooms, tt.roomsErr)
+			mongoMockSvc.On("GetMentions", 12345, []string{roomID.Hex()}, 1, defaultPageLimit, mongoMockPkg).Return(messages, int64(2), tt.mentionsErr)
+			mongoMockSvc.On("GetReadCursors", 12345, []string{roomID.Hex()}, mongoMockPkg).Return(readCursors, tt.cursorsErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.MentionsHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"total":2`)
+			}
+		})
+	}
+}
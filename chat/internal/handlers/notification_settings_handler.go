@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 投稿されたメッセージの通知を登録する。通知に失敗しても投稿は成功として扱う
+func (h *HandlerStruct) enqueueNotifications(messageID string, message model.ChatMessage, room model.Room, replyToUserID int) {
+	if h.NotificationSvc == nil {
+		return
+	}
+	if err := h.NotificationSvc.Enqueue(messageID, message, room, replyToUserID); err != nil {
+		log.Printf("failed to enqueue notifications for message %s: %v", messageID, err)
+	}
+}
+
+type NotificationSettingsRequest struct {
+	MuteAll    bool   `json:"mute_all"`
+	Channel    string `json:"channel"`
+	WebhookURL string `json:"webhook_url"`
+	DNDStart   string `json:"dnd_start"` // HH:MM
+	DNDEnd     string `json:"dnd_end"`   // HH:MM
+	TimeZone   string `json:"time_zone"` // IANA のタイムゾーン名
+}
+
+func (r NotificationSettingsRequest) validate(ctx context.Context) error {
+	switch r.Channel {
+	case "", model.NotificationChannelEmail:
+	case model.NotificationChannelWebhook:
+		if err := netguard_pkg.CheckURL(ctx, r.WebhookURL); err != nil {
+			if errors.Is(err, netguard_pkg.ErrForbiddenAddress) {
+				return fmt.Errorf("webhook_url must not point to an internal address")
+			}
+			return fmt.Errorf("webhook_url must be an http or https URL")
+		}
+	default:
+		return fmt.Errorf("unknown channel: %s", r.Channel)
+	}
+
+	if (r.DNDStart == "") != (r.DNDEnd == "") {
+		return fmt.Errorf("dnd_start and dnd_end must be set together")
+	}
+	if r.DNDStart != "" {
+		if _, ok := model.ParseClock(r.DNDStart); !ok {
+			return fmt.Errorf("dnd_start must be HH:MM")
+		}
+		if _, ok := model.ParseClock(r.DNDEnd); !ok {
+			return fmt.Errorf("dnd_end must be HH:MM")
+		}
+	}
+	if _, err := time.LoadLocation(r.TimeZone); err != nil {
+		return fmt.Errorf("unknown time_zone: %s", r.TimeZone)
+	}
+	return nil
+}
+
+// 自分の通知設定を取得する（未設定の場合は既定値）
+func (h *HandlerStruct) NotificationSettingsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	settings, err := h.MongoSvc.GetNotificationSettings([]int{userID}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification settings"})
+		return
+	}
+	s, ok := settings[userID]
+	if !ok {
+		s = model.NotificationSettings{UserID: userID}
+	}
+	s.Channel = s.DeliveryChannel()
+
+	c.JSON(http.StatusOK, gin.H{"settings": s})
+}
+
+func (h *HandlerStruct) UpdateNotificationSettingsHandler(c *gin.Context) {
+	var req NotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if err := req.validate(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+
+	err := h.MongoSvc.UpdateNotificationSettings(model.NotificationSettings{
+		UserID:     int(jwtinfo.UserID),
+		MuteAll:    req.MuteAll,
+		Channel:    req.Channel,
+		WebhookURL: req.WebhookURL,
+		DNDStart:   req.DNDStart,
+		DNDEnd:     req.DNDEnd,
+		TimeZone:   req.TimeZone,
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification settings", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification settings updated successfully"})
+}
+
+// ルームの通知をミュートする
+func (h *HandlerStruct) MuteRoomHandler(c *gin.Context) {
+	h.setRoomMuted(c, true)
+}
+
+func (h *HandlerStruct) UnmuteRoomHandler(c *gin.Context) {
+	h.setRoomMuted(c, false)
+}
+
+func (h *HandlerStruct) setRoomMuted(c *gin.Context, muted bool) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityRead); !ok {
+		return
+	}
+
+	if err := h.MongoSvc.SetRoomMuted(userID, roomID, muted, h.MongoPkg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification settings", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Room notification settings updated successfully", "muted": muted})
+}
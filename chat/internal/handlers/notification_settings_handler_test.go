@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_notification_svc"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPostChatMessageHandlerNotifications(t *testing.T) {
+	room := model.Room{Members: []int{12345, 2}}
+	parentID := primitive.NewObjectID()
+
+	tests := []struct {
+		name       string
+		enqueueErr error
+	}{
+		{"success", nil},
+		// 通知に失敗しても投稿は成功する
+		{"enqueue_error", assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/post_chat_message", `{"room_id":"valid_room_id","message":"hi","reply_to":"parent_id"}`, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "parent_id", mongoMockPkg).Return(model.ChatMessage{ID: parentID, UserID: 2}, nil)
+			expected := model.ChatMessage{
+				RoomID:       "valid_room_id",
+				UserID:       12345,
+				Message:      "hi",
+				ParentID:     parentID.Hex(),
+				ThreadRootID: parentID.Hex(),
+			}
+			mongoMockSvc.On("PostChatMessage", expected, mongoMockPkg).Return("new_message_id", nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+			notificationMockSvc := new(mock_notification_svc.NotificationSvcMock)
+			notificationMockSvc.On("Enqueue", "new_message_id", expected, room, 2).Return(tt.enqueueErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.NotificationSvc = notificationMockSvc
+			handler.PostChatMessageHandler(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			notificationMockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNotificationSettingsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		settings   map[int]model.NotificationSettings
+		getErr     error
+		expectCode int
+		expect     string
+	}{
+		{"default", map[int]model.NotificationSettings{}, nil, http.StatusOK, `"Channel":"email"`},
+		{"saved", map[int]model.NotificationSettings{12345: {UserID: 12345, Channel: "webhook", DNDStart: "22:00"}}, nil, http.StatusOK, `"DNDStart":"22:00"`},
+		{"get_error", map[int]model.NotificationSettings{}, assert.AnError, http.StatusInternalServerError, "Failed to get notification settings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/me/notification_settings", "", nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetNotificationSettings", []int{12345}, mongoMockPkg).Return(tt.settings, tt.getErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.NotificationSettingsHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestUpdateNotificationSettingsHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		updateErr   error
+		expectCode  int
+		expect      string
+		expectSaved bool
+	}{
+		{"email", `{"mute_all":true}`, nil, http.StatusOK, "Notification settings updated successfully", true},
+		{"webhook_with_dnd", `{"channel":"webhook","webhook_url":"https://example.com/hook","dnd_start":"22:00","dnd_end":"07:00","time_zone":"Asia/Tokyo"}`, nil, http.StatusOK, "Notification settings updated successfully", true},
+		{"invalid_json", `{`, nil, http.StatusBadRequest, "Invalid request", false},
+		{"unknown_channel", `{"channel":"sms"}`, nil, http.StatusBadRequest, "unknown channel", false},
+		{"webhook_without_url", `{"channel":"webhook"}`, nil, http.StatusBadRequest, "webhook_url must be an http or https URL", false},
+		{"webhook_bad_scheme", `{"channel":"webhook","webhook_url":"ftp://example.com"}`, nil, http.StatusBadRequest, "webhook_url must be an http or https URL", false},
+		{"webhook_internal", `{"channel":"webhook","webhook_url":"http://10.0.0.1/hook"}`, nil, http.StatusBadRequest, "webhook_url must not point to an internal address", false},
+		{"dnd_start_only", `{"dnd_start":"22:00"}`, nil, http.StatusBadRequest, "must be set together", false},
+		{"dnd_bad_time", `{"dnd_start":"25:00","dnd_end":"07:00"}`, nil, http.StatusBadRequest, "dnd_start must be HH:MM", false},
+		{"bad_time_zone", `{"time_zone":"Mars/Olympus"}`, nil, http.StatusBadRequest, "unknown time_zone", false},
+		{"update_error", `{}`, assert.AnError, http.StatusInternalServerError, "Failed to update notification settings", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("PUT", "/me/notification_settings", tt.body, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("UpdateNotificationSettings", mock.MatchedBy(func(s model.NotificationSettings) bool {
+				return s.UserID == 12345
+			}), mongoMockPkg).Return(tt.updateErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.UpdateNotificationSettingsHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if !tt.expectSaved {
+				mongoMockSvc.AssertNotCalled(t, "UpdateNotificationSettings", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestMuteRoomHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		muted      bool
+		roomErr    error
+		muteErr    error
+		expectCode int
+		expect     string
+	}{
+		{"mute", true, nil, nil, http.StatusOK, `"muted":true`},
+		{"unmute", false, nil, nil, http.StatusOK, `"muted":false`},
+		{"room_error", true, assert.AnError, nil, http.StatusInternalServerError, "Failed to get room"},
+		{"mute_error", true, nil, assert.AnError, http.StatusInternalServerError, "Failed to update notification settings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/mute", "", gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, tt.roomErr)
+			mongoMockSvc.On("SetRoomMuted", 12345, "room1", tt.muted, mongoMockPkg).Return(tt.muteErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			if tt.muted {
+				handler.MuteRoomHandler(c)
+			} else {
+				handler.UnmuteRoomHandler(c)
+			}
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestMuteRoomHandlerNotMember(t *testing.T) {
+	room := model.Room{Members: []int{1}}
+	c, w := newJSONRequestContext("POST", "/rooms/room1/mute", "", gin.Params{{Key: "id", Value: "room1"}})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", room, 12345).Return(chat_svc.Room{})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.MuteRoomHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mongoMockSvc.AssertNotCalled(t, "SetRoomMuted", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,22 @@
+package handlers
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type PaginationRequest struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
+}
+
+// 未指定・範囲外の値を補正したページ番号と件数を返す
+func (p PaginationRequest) normalize() (page int, limit int) {
+	page = max(p.Page, 1)
+	limit = p.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	limit = min(limit, maxPageLimit)
+	return page, limit
+}
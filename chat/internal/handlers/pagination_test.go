@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginationRequestNormalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         PaginationRequest
+		expectPage  int
+		expectLimit int
+	}{
+		{"default", PaginationRequest{}, 1, defaultPageLimit},
+		{"specified", PaginationRequest{Page: 3, Limit: 50}, 3, 50},
+		{"negative", PaginationRequest{Page: -1, Limit: -1}, 1, defaultPageLimit},
+		{"over_max", PaginationRequest{Page: 1, Limit: 1000}, 1, maxPageLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, limit := tt.req.normalize()
+			assert.Equal(t, tt.expectPage, page)
+			assert.Equal(t, tt.expectLimit, limit)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PinMessageRequest struct {
+	MessageID string `form:"message_id" json:"message_id" binding:"required"`
+}
+
+func (h *HandlerStruct) PinMessageHandler(c *gin.Context) {
+	var req PinMessageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	room, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityModerate)
+	if !ok {
+		return
+	}
+	if room.IsPinned(req.MessageID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Message is already pinned"})
+		return
+	}
+	if len(room.Pins) >= model.MaxRoomPins {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pin limit reached", "details": fmt.Sprintf("a room can have at most %d pins", model.MaxRoomPins)})
+		return
+	}
+
+	message, err := h.MongoSvc.GetChatMessageByID(roomID, req.MessageID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message", "details": err.Error()})
+		return
+	}
+	if message.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return
+	}
+
+	pin := model.RoomPin{MessageID: req.MessageID, PinnedBy: userID, PinnedAt: time.Now()}
+	err = h.MongoSvc.PinMessage(roomID, pin, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrPinConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Failed to pin message", "details": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pin message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message pinned successfully"})
+}
+
+func (h *HandlerStruct) UnpinMessageHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityModerate); !ok {
+		return
+	}
+
+	err := h.MongoSvc.UnpinMessage(roomID, c.Param("message_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrPinNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pin not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unpin message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message unpinned successfully"})
+}
+
+// ピン留め一覧の1件
+type PinnedMessageItem struct {
+	model.ChatMessage
+	PinnedBy int
+	PinnedAt time.Time
+}
+
+// 履歴のページングに関係なく、ピン留めされたメッセージを新しくピン留めした順に返す
+func (h *HandlerStruct) PinsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	room, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+
+	messageIDs := make([]string, 0, len(room.Pins))
+	for _, pin := range room.Pins {
+		messageIDs = append(messageIDs, pin.MessageID)
+	}
+	messages, err := h.MongoSvc.GetChatMessagesByIDs(messageIDs, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get pinned messages"})
+		return
+	}
+	byID := map[string]model.ChatMessage{}
+	for _, message := range messages {
+		// 他のルームのメッセージは返さない
+		if message.RoomID == roomID {
+			byID[message.ID.Hex()] = message
+		}
+	}
+
+	// 削除されたメッセージは一覧に含めない
+	pins := make([]PinnedMessageItem, 0, len(room.Pins))
+	for _, pin := range room.Pins {
+		message, ok := byID[pin.MessageID]
+		if !ok {
+			continue
+		}
+		pins = append(pins, PinnedMessageItem{ChatMessage: message, PinnedBy: pin.PinnedBy, PinnedAt: pin.PinnedAt})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pins": pins})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPinMessageHandler(t *testing.T) {
+	deletedAt := time.Now()
+	fullPins := make([]model.RoomPin, model.MaxRoomPins)
+	for i := range fullPins {
+		fullPins[i] = model.RoomPin{MessageID: fmt.Sprintf("pinned%d", i)}
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		pins       []model.RoomPin
+		roomInfo   chat_svc.Room
+		message    model.ChatMessage
+		pinErr     error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"message_id":"message1"}`, nil, moderatorRoomInfo, model.ChatMessage{RoomID: "room1"}, nil, http.StatusOK, "Message pinned successfully"},
+		{"missing_message_id", `{}`, nil, moderatorRoomInfo, model.ChatMessage{}, nil, http.StatusBadRequest, "Invalid request"},
+		{"member", `{"message_id":"message1"}`, nil, memberRoomInfo, model.ChatMessage{}, nil, http.StatusForbidden, "Access denied"},
+		{"already_pinned", `{"message_id":"message1"}`, []model.RoomPin{{MessageID: "message1"}}, moderatorRoomInfo, model.ChatMessage{}, nil, http.StatusConflict, "Message is already pinned"},
+		{"limit_reached", `{"message_id":"message1"}`, fullPins, moderatorRoomInfo, model.ChatMessage{}, nil, http.StatusConflict, "Pin limit reached"},
+		{"deleted_message", `{"message_id":"message1"}`, nil, moderatorRoomInfo, model.ChatMessage{DeletedAt: &deletedAt}, nil, http.StatusGone, "Message has been deleted"},
+		{"conflict", `{"message_id":"message1"}`, nil, moderatorRoomInfo, model.ChatMessage{}, mongo_svc.ErrPinConflict, http.StatusConflict, "Failed to pin message"},
+		{"pin_error", `{"message_id":"message1"}`, nil, moderatorRoomInfo, model.ChatMessage{}, assert.AnError, http.StatusInternalServerError, "Failed to pin message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/pins", tt.body, gin.Params{{Key: "id", Value: "room1"}})
+			room := model.Room{Members: []int{12345}, Pins: tt.pins}
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetChatMessageByID", "room1", "message1", mongoMockPkg).Return(tt.message, nil)
+			var pinned model.RoomPin
+			mongoMockSvc.On("PinMessage", "room1", mock.Anything, mongoMockPkg).Run(func(args mock.Arguments) {
+				pinned = args.Get(1).(model.RoomPin)
+			}).Return(tt.pinErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PinMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, "message1", pinned.MessageID)
+				assert.Equal(t, 12345, pinned.PinnedBy)
+			}
+		})
+	}
+}
+
+func TestUnpinMessageHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		roomInfo   chat_svc.Room
+		unpinErr   error
+		expectCode int
+		expect     string
+	}{
+		{"success", moderatorRoomInfo, nil, http.StatusOK, "Message unpinned successfully"},
+		{"member", memberRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"not_found", moderatorRoomInfo, mongo_svc.ErrPinNotFound, http.StatusNotFound, "Pin not found"},
+		{"unpin_error", moderatorRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to unpin message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/rooms/room1/pins/message1", "", gin.Params{{Key: "id", Value: "room1"}, {Key: "message_id", Value: "message1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("UnpinMessage", "room1", "message1", mongoMockPkg).Return(tt.unpinErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.UnpinMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestPinsHandler(t *testing.T) {
+	first := model.ChatMessage{ID: primitive.NewObjectID(), RoomID: "room1", Message: "first"}
+	second := model.ChatMessage{ID: primitive.NewObjectID(), RoomID: "room1", Message: "second"}
+	other := model.ChatMessage{ID: primitive.NewObjectID(), RoomID: "room2", Message: "other"}
+	deletedID := primitive.NewObjectID().Hex()
+	room := model.Room{Members: []int{12345}, Pins: []model.RoomPin{
+		{MessageID: second.ID.Hex(), PinnedBy: 1},
+		{MessageID: deletedID, PinnedBy: 1},
+		{MessageID: other.ID.Hex(), PinnedBy: 1},
+		{MessageID: first.ID.Hex(), PinnedBy: 2},
+	}}
+	messageIDs := []string{second.ID.Hex(), deletedID, other.ID.Hex(), first.ID.Hex()}
+
+	tests := []struct {
+		name       string
+		getErr     error
+		expectCode int
+		expect     []string
+	}{
+		{"success", nil, http.StatusOK, []string{"second", "first"}},
+		{"get_error", assert.AnError, http.StatusInternalServerError, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/rooms/room1/pins", "", gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			// 取得結果の順序に関係なくピン留めした順に返す
+			mongoMockSvc.On("GetChatMessagesByIDs", messageIDs, mongoMockPkg).Return([]model.ChatMessage{first, other, second}, tt.getErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(readOnlyRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PinsHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			if tt.expectCode != http.StatusOK {
+				assert.Contains(t, w.Body.String(), "Failed to get pinned messages")
+				return
+			}
+			var resp struct {
+				Pins []PinnedMessageItem `json:"pins"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			var messages []string
+			for _, pin := range resp.Pins {
+				messages = append(messages, pin.Message)
+			}
+			assert.Equal(t, tt.expect, messages)
+			assert.Equal(t, 2, resp.Pins[1].PinnedBy)
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -9,6 +14,7 @@ import (
 type PostChatRequest struct {
 	RoomID  string `form:"room_id" json:"room_id" binding:"required"`
 	Message string `form:"message" json:"message" binding:"required"`
+	ReplyTo string `form:"reply_to" json:"reply_to"`
 }
 
 func (h *HandlerStruct) PostChatMessageHandler(c *gin.Context) {
@@ -21,19 +27,121 @@ func (h *HandlerStruct) PostChatMessageHandler(c *gin.Context) {
 	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
 	roomID := req.RoomID
 	userID := jwtinfo.UserID
-	message := req.Message
 
-	_, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to get room", "details": err.Error()})
+	room, roomInfo, ok := h.getRoomFor(c, roomID, int(userID), chat_svc.CapabilityPost)
+	if !ok {
 		return
 	}
 
-	err = h.MongoSvc.PostChatMessage(roomID, int(userID), message, h.MongoPkg)
+	// / で始まる本文はコマンドとして実行し、メッセージとしては投稿しない
+	if name, args, ok := model.ParseSlashCommand(req.Message); ok {
+		h.runSlashCommand(c, slashCommandContext{
+			Name:     name,
+			Args:     args,
+			RoomID:   roomID,
+			Room:     room,
+			RoomInfo: roomInfo,
+			UserID:   int(userID),
+		})
+		return
+	}
+
+	messageID, err := h.postUserMessage(roomID, room, roomInfo, int(userID), req.Message, req.ReplyTo)
 	if err != nil {
+		var postErr *postMessageError
+		if errors.As(err, &postErr) {
+			c.JSON(postErr.StatusCode(), gin.H{"error": postErr.Message, "details": postErr.Err.Error()})
+			return
+		}
 		c.JSON(500, gin.H{"error": "Failed to post chat", "details": err.Error()})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "Chat posted successfully"})
+	h.stopTyping(c, roomID, int(userID))
+
+	c.JSON(200, gin.H{"message": "Chat posted successfully", "message_id": messageID})
+}
+
+var errReplyTargetDeleted = errors.New("reply target has been deleted")
+
+// どの処理で投稿に失敗したかをレスポンスのメッセージとステータスとして持つ
+type postMessageError struct {
+	Status  int // 0 の場合は 500
+	Message string
+	Err     error
+}
+
+func (e *postMessageError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+func (e *postMessageError) Error() string {
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *postMessageError) Unwrap() error {
+	return e.Err
+}
+
+// ユーザーとして投稿する。HTTP からの投稿と予約投稿で共通の処理
+func (h *HandlerStruct) postUserMessage(roomID string, room model.Room, roomInfo chat_svc.Room, userID int, message string, replyTo string) (string, error) {
+	mentionedUserIDs, err := h.resolveMentions(roomID, room, roomInfo, userID, message)
+	if err != nil {
+		return "", &postMessageError{Message: "Failed to resolve mentions", Err: err}
+	}
+
+	chatMessage := model.ChatMessage{
+		RoomID:           roomID,
+		UserID:           userID,
+		Message:          message,
+		MentionedUserIds: mentionedUserIDs,
+		RetentionDays:    room.RetentionDays,
+	}
+
+	// 返信の場合は同じルームのメッセージであることを確認し、スレッドに紐づける
+	replyToUserID := 0
+	if replyTo != "" {
+		parent, err := h.getReplyTarget(roomID, replyTo)
+		if err != nil {
+			return "", err
+		}
+		chatMessage.ParentID = parent.ID.Hex()
+		chatMessage.ThreadRootID = parent.ThreadRoot()
+		replyToUserID = parent.UserID
+	}
+
+	messageID, err := h.MongoSvc.PostChatMessage(chatMessage, h.MongoPkg)
+	if err != nil {
+		return "", &postMessageError{Message: "Failed to post chat", Err: err}
+	}
+
+	h.enqueueNotifications(messageID, chatMessage, room, replyToUserID)
+	h.publishWebhookEvent(roomID, model.WebhookMessageCreated, model.WebhookMessageData{
+		MessageID:    messageID,
+		UserID:       chatMessage.UserID,
+		Message:      chatMessage.Message,
+		ThreadRootID: chatMessage.ThreadRootID,
+	})
+	return messageID, nil
+}
+
+// 返信先のメッセージを取得する。存在しない・削除済みの場合はクライアントのエラーとして返す
+func (h *HandlerStruct) getReplyTarget(roomID string, replyTo string) (model.ChatMessage, error) {
+	parent, err := h.MongoSvc.GetChatMessageByID(roomID, replyTo, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrInvalidChatMessageID) {
+		return model.ChatMessage{}, &postMessageError{Status: http.StatusBadRequest, Message: "Invalid reply_to", Err: err}
+	}
+	if errors.Is(err, mongo_svc.ErrChatMessageNotFound) {
+		return model.ChatMessage{}, &postMessageError{Status: http.StatusNotFound, Message: "Reply target not found", Err: err}
+	}
+	if err != nil {
+		return model.ChatMessage{}, &postMessageError{Message: "Failed to get reply target", Err: err}
+	}
+	if parent.DeletedAt != nil {
+		return model.ChatMessage{}, &postMessageError{Status: http.StatusGone, Message: "Reply target has been deleted", Err: errReplyTargetDeleted}
+	}
+	return parent, nil
 }
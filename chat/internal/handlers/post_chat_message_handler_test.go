@@ -4,14 +4,19 @@ import (
 	"context"
 	"microservices/chat/internal/model"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
 	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestPostChatMessageHandler(t *testing.T) {
@@ -22,7 +27,9 @@ func TestPostChatMessageHandler(t *testing.T) {
 	mongoMockPkg := &MongoPkgMock{}
 	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
 	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
-	mongoMockSvc.On("PostChatMessage", "valid_room_id", int(12345), "Hello, World!", mongoMockPkg).Return(nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+	mongoMockSvc.On("PostChatMessage", model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, Message: "Hello, World!"}, mongoMockPkg).Return("new_message_id", nil)
 
 	body := strings.NewReader(`{"room_id":"valid_room_id","message":"Hello, World!"}`)
 	req := httptest.NewRequest("POST", "/post_chat_message", body)
@@ -32,11 +39,12 @@ func TestPostChatMessageHandler(t *testing.T) {
 	req = req.WithContext(ctx)
 	c.Request = req
 
-	handler := NewHandlers(mongoMockSvc, mongoMockPkg, nil)
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
 	handler.PostChatMessageHandler(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Chat posted successfully")
+	assert.Contains(t, w.Body.String(), "new_message_id")
 }
 
 func TestPostChatMessageHandlerGetRoomByIDError(t *testing.T) {
@@ -71,7 +79,9 @@ func TestPostChatMessageHandlerPostChatMessageError(t *testing.T) {
 	mongoMockPkg := &MongoPkgMock{}
 	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
 	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
-	mongoMockSvc.On("PostChatMessage", "valid_room_id", int(12345), "Hello, World!", mongoMockPkg).Return(assert.AnError)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+	mongoMockSvc.On("PostChatMessage", model.ChatMessage{RoomID: "valid_room_id", UserID: 12345, Message: "Hello, World!"}, mongoMockPkg).Return("", assert.AnError)
 
 	body := strings.NewReader(`{"room_id":"valid_room_id","message":"Hello, World!"}`)
 	req := httptest.NewRequest("POST", "/post_chat_message", body)
@@ -81,13 +91,39 @@ func TestPostChatMessageHandlerPostChatMessageError(t *testing.T) {
 	req = req.WithContext(ctx)
 	c.Request = req
 
-	handler := NewHandlers(mongoMockSvc, mongoMockPkg, nil)
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
 	handler.PostChatMessageHandler(c)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "Failed to post chat")
 }
 
+func TestPostChatMessageHandlerReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(readOnlyRoomInfo)
+
+	body := strings.NewReader(`{"room_id":"valid_room_id","message":"Hello, World!"}`)
+	req := httptest.NewRequest("POST", "/post_chat_message", body)
+	req.Header.Set("Content-Type", "application/json")
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	c.Request = req.WithContext(ctx)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.PostChatMessageHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+	mongoMockSvc.AssertNotCalled(t, "PostChatMessage")
+}
+
 func TestPostChatMessageHandlerInvalidRequest(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -110,3 +146,98 @@ func TestPostChatMessageHandlerInvalidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "Invalid request")
 }
+
+func TestPostChatMessageHandlerReply(t *testing.T) {
+	tests := []struct {
+		name         string
+		parent       model.ChatMessage
+		expectRootID string
+	}{
+		{"reply_to_root", model.ChatMessage{ID: threadRootID}, threadRootID.Hex()},
+		{"reply_to_reply", model.ChatMessage{ID: threadReplyID, ParentID: threadRootID.Hex(), ThreadRootID: threadRootID.Hex()}, threadRootID.Hex()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+			mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "parent_id", mongoMockPkg).Return(tt.parent, nil)
+			mongoMockSvc.On("PostChatMessage", model.ChatMessage{
+				RoomID:       "valid_room_id",
+				UserID:       12345,
+				Message:      "Hello, World!",
+				ParentID:     tt.parent.ID.Hex(),
+				ThreadRootID: tt.expectRootID,
+			}, mongoMockPkg).Return("new_message_id", nil)
+
+			body := strings.NewReader(`{"room_id":"valid_room_id","message":"Hello, World!","reply_to":"parent_id"}`)
+			req := httptest.NewRequest("POST", "/post_chat_message", body)
+			req.Header.Set("Content-Type", "application/json")
+			ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+			ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+			c.Request = req.WithContext(ctx)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PostChatMessageHandler(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mongoMockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostChatMessageHandlerReplyTargetError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name       string
+		parent     model.ChatMessage
+		getErr     error
+		expectCode int
+		expect     string
+	}{
+		{"invalid_id", model.ChatMessage{}, mongo_svc.ErrInvalidChatMessageID, http.StatusBadRequest, "Invalid reply_to"},
+		{"not_found", model.ChatMessage{}, mongo_svc.ErrChatMessageNotFound, http.StatusNotFound, "Reply target not found"},
+		{"deleted", model.ChatMessage{ID: threadRootID, DeletedAt: &deletedAt}, nil, http.StatusGone, "Reply target has been deleted"},
+		{"error", model.ChatMessage{}, assert.AnError, http.StatusInternalServerError, "Failed to get reply target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+			mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "parent_id", mongoMockPkg).Return(tt.parent, tt.getErr)
+
+			body := strings.NewReader(`{"room_id":"valid_room_id","message":"Hello, World!","reply_to":"parent_id"}`)
+			req := httptest.NewRequest("POST", "/post_chat_message", body)
+			req.Header.Set("Content-Type", "application/json")
+			ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+			ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+			c.Request = req.WithContext(ctx)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PostChatMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			mongoMockSvc.AssertNotCalled(t, "PostChatMessage", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+var threadRootID, _ = primitive.ObjectIDFromHex("64a7b2f4e13e4c3f9c8b4568")
+var threadReplyID, _ = primitive.ObjectIDFromHex("64a7b2f4e13e4c3f9c8b4569")
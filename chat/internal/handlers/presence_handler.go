@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdatePresenceRequest struct {
+	Status string `json:"status"` // 省略した場合は online
+}
+
+// ハートビート。クライアントは TTL より短い間隔で送る
+func (h *HandlerStruct) UpdatePresenceHandler(c *gin.Context) {
+	var req UpdatePresenceRequest
+	// 本文が無いハートビートも受け付ける
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+			return
+		}
+	}
+	if req.Status == "" {
+		req.Status = model.PresenceOnline
+	}
+	if !model.ValidPresenceStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": "unknown status: " + req.Status})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	if err := h.PresenceSvc.SetStatus(c.Request.Context(), int(jwtinfo.UserID), req.Status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update presence", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Presence updated successfully", "status": req.Status})
+}
+
+type TypingRequest struct {
+	Typing *bool `json:"typing"` // 省略した場合は入力中
+}
+
+func (h *HandlerStruct) TypingHandler(c *gin.Context) {
+	var req TypingRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+			return
+		}
+	}
+	typing := req.Typing == nil || *req.Typing
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityPost); !ok {
+		return
+	}
+
+	if err := h.PresenceSvc.SetTyping(c.Request.Context(), roomID, userID, typing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update typing status", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Typing status updated successfully", "typing": typing})
+}
+
+// オンライン（離席中を含む）のメンバーと入力中のメンバーを返す
+func (h *HandlerStruct) RoomPresenceHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	room, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+
+	statuses, err := h.PresenceSvc.GetStatuses(c.Request.Context(), room.Members)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get presence"})
+		return
+	}
+	online := []model.MemberPresence{}
+	for memberID, status := range statuses {
+		if status != model.PresenceOffline {
+			online = append(online, model.MemberPresence{UserID: memberID, Status: status})
+		}
+	}
+	sort.Slice(online, func(i, j int) bool { return online[i].UserID < online[j].UserID })
+
+	typingUserIDs, err := h.PresenceSvc.GetTyping(c.Request.Context(), roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get typing status"})
+		return
+	}
+	// 自分自身と退出したメンバーは含めない
+	typing := []int{}
+	for _, typingUserID := range typingUserIDs {
+		if typingUserID != userID && containsInt(room.Members, typingUserID) {
+			typing = append(typing, typingUserID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"online": online, "typing": typing})
+}
+
+// 投稿したユーザーの入力中の表示を消す。失敗しても投稿は成功として扱う
+func (h *HandlerStruct) stopTyping(c *gin.Context, roomID string, userID int) {
+	if h.PresenceSvc == nil {
+		return
+	}
+	if err := h.PresenceSvc.SetTyping(c.Request.Context(), roomID, userID, false); err != nil {
+		log.Printf("failed to clear typing status for user %d in room %s: %v", userID, roomID, err)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_presence_svc"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpdatePresenceHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectStatus string
+		setErr       error
+		expectCode   int
+		expect       string
+	}{
+		{"default_online", "", model.PresenceOnline, nil, http.StatusOK, `"status":"online"`},
+		{"away", `{"status":"away"}`, model.PresenceAway, nil, http.StatusOK, `"status":"away"`},
+		{"offline", `{"status":"offline"}`, model.PresenceOffline, nil, http.StatusOK, `"status":"offline"`},
+		{"unknown_status", `{"status":"busy"}`, "", nil, http.StatusBadRequest, "unknown status: busy"},
+		{"invalid_json", `{`, "", nil, http.StatusBadRequest, "Invalid request"},
+		{"set_error", `{"status":"online"}`, model.PresenceOnline, assert.AnError, http.StatusInternalServerError, "Failed to update presence"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/presence", tt.body, nil)
+
+			presenceMockSvc := new(mock_presence_svc.PresenceSvcMock)
+			presenceMockSvc.On("SetStatus", mock.Anything, 12345, tt.expectStatus).Return(tt.setErr)
+
+			handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+			handler.PresenceSvc = presenceMockSvc
+			handler.UpdatePresenceHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectStatus == "" {
+				presenceMockSvc.AssertNotCalled(t, "SetStatus", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestTypingHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name         string
+		body         string
+		roomInfo     chat_svc.Room
+		expectTyping bool
+		setErr       error
+		expectCode   int
+		expect       string
+	}{
+		{"default_typing", "", memberRoomInfo, true, nil, http.StatusOK, `"typing":true`},
+		{"stop_typing", `{"typing":false}`, memberRoomInfo, false, nil, http.StatusOK, `"typing":false`},
+		{"read_only", "", readOnlyRoomInfo, true, nil, http.StatusForbidden, "Access denied"},
+		{"set_error", "", memberRoomInfo, true, assert.AnError, http.StatusInternalServerError, "Failed to update typing status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/typing", tt.body, gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+			presenceMockSvc := new(mock_presence_svc.PresenceSvcMock)
+			presenceMockSvc.On("SetTyping", mock.Anything, "room1", 12345, tt.expectTyping).Return(tt.setErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PresenceSvc = presenceMockSvc
+			handler.TypingHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusForbidden {
+				presenceMockSvc.AssertNotCalled(t, "SetTyping", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestRoomPresenceHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345, 2, 3, 4}}
+
+	tests := []struct {
+		name       string
+		statusErr  error
+		typingErr  error
+		expectCode int
+		expect     string
+	}{
+		// 自分自身とメンバー以外の入力中は除外する
+		{"success", nil, nil, http.StatusOK, `{"online":[{"user_id":2,"status":"away"},{"user_id":3,"status":"online"},{"user_id":12345,"status":"online"}],"typing":[3]}`},
+		{"status_error", assert.AnError, nil, http.StatusInternalServerError, "Failed to get presence"},
+		{"typing_error", nil, assert.AnError, http.StatusInternalServerError, "Failed to get typing status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/rooms/room1/presence", "", gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+			presenceMockSvc := new(mock_presence_svc.PresenceSvcMock)
+			presenceMockSvc.On("GetStatuses", mock.Anything, room.Members).Return(map[int]string{
+				12345: model.PresenceOnline,
+				2:     model.PresenceAway,
+				3:     model.PresenceOnline,
+				4:     model.PresenceOffline,
+			}, tt.statusErr)
+			presenceMockSvc.On("GetTyping", mock.Anything, "room1").Return([]int{3, 99, 12345}, tt.typingErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PresenceSvc = presenceMockSvc
+			handler.RoomPresenceHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxEmojiLength = 64
+
+type AddReactionRequest struct {
+	Emoji string `form:"emoji" json:"emoji" binding:"required"`
+}
+
+// 絵文字はフィールド名として保存するため、絵文字1つか :shortcode: の形式のみ受け付ける
+// 絵文字は肌の色・異体字セレクタ・ZWJ・キーキャップ・タグ文字による組み合わせを含む
+var (
+	emojiPattern     = regexp.MustCompile(`^(?:\p{So}|[0-9#*]\x{FE0F}?\x{20E3})(?:[\p{So}\x{1F3FB}-\x{1F3FF}\x{200D}\x{FE0E}\x{FE0F}\x{20E3}\x{E0020}-\x{E007F}])*$`)
+	shortcodePattern = regexp.MustCompile(`^:[a-z0-9_+\-]+:$`)
+)
+
+func validEmoji(emoji string) bool {
+	if emoji == "" || len(emoji) > maxEmojiLength {
+		return false
+	}
+	return emojiPattern.MatchString(emoji) || shortcodePattern.MatchString(emoji)
+}
+
+func (h *HandlerStruct) AddReactionHandler(c *gin.Context) {
+	var req AddReactionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if !validEmoji(req.Emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid emoji"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	messageID := c.Param("id")
+
+	message, _, ok := h.getMessageFor(c, messageID, int(userID), chat_svc.CapabilityPost)
+	if !ok {
+		return
+	}
+
+	if message.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return
+	}
+
+	err := h.MongoSvc.AddReaction(messageID, req.Emoji, int(userID), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrTooManyReactions) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many reactions on this message"})
+		return
+	}
+	// 確認後に削除・期限切れで消えた場合
+	if errors.Is(err, mongo_svc.ErrChatMessageDeleted) {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add reaction", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction added successfully"})
+}
+
+func (h *HandlerStruct) RemoveReactionHandler(c *gin.Context) {
+	emoji := c.Param("emoji")
+	if !validEmoji(emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid emoji"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	messageID := c.Param("id")
+
+	if _, _, ok := h.getMessageFor(c, messageID, int(userID), chat_svc.CapabilityPost); !ok {
+		return
+	}
+
+	err := h.MongoSvc.RemoveReaction(messageID, emoji, int(userID), h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reaction", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction removed successfully"})
+}
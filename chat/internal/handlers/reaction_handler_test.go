@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAddReactionContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	return newJSONRequestContext("POST", "/messages/valid_message_id/reactions", body, gin.Params{{Key: "id", Value: "valid_message_id"}})
+}
+
+func newRemoveReactionContext(emoji string) (*gin.Context, *httptest.ResponseRecorder) {
+	return newJSONRequestContext("DELETE", "/messages/valid_message_id/reactions/x", "", gin.Params{{Key: "id", Value: "valid_message_id"}, {Key: "emoji", Value: emoji}})
+}
+
+// メッセージ・ルームの取得とメンバー判定のモックを設定する
+func setupReactionMocks(message model.ChatMessage, roomInfo chat_svc.Room) (*MongoPkgMock, *mock_mongo_svc.MongoSvcMock, *mock_chat_svc.ChatSvcMock) {
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(message, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(roomInfo)
+	return mongoMockPkg, mongoMockSvc, chatMockSvc
+}
+
+func TestValidEmoji(t *testing.T) {
+	tests := []struct {
+		emoji  string
+		expect bool
+	}{
+		{"👍", true},
+		{"👍🏽", true},
+		{"❤️", true},
+		{"👨‍👩‍👧", true},
+		{"🇯🇵", true},
+		{"1️⃣", true},
+		{":thumbsup:", true},
+		{":+1:", true},
+		{"", false},
+		{"a.b", false},
+		{"$set", false},
+		{"two words", false},
+		{"hello", false},
+		{"👍abc", false},
+		{":Thumbs Up:", false},
+		{":a.b:", false},
+		{":" + strings.Repeat("a", maxEmojiLength) + ":", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.emoji, func(t *testing.T) {
+			assert.Equal(t, tt.expect, validEmoji(tt.emoji))
+		})
+	}
+}
+
+func TestAddReactionHandler(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, memberRoomInfo)
+	mongoMockSvc.On("AddReaction", "valid_message_id", "👍", 12345, mongoMockPkg).Return(nil)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Reaction added successfully")
+}
+
+func TestAddReactionHandlerInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		expect string
+	}{
+		{"missing_emoji", `{}`, "Invalid request"},
+		{"invalid_emoji", `{"emoji":"a.b"}`, "Invalid emoji"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newAddReactionContext(tt.body)
+
+			handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+			handler.AddReactionHandler(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestAddReactionHandlerFailedGetMessage(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get message")
+}
+
+func TestAddReactionHandlerFailedGetRoom(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get room")
+}
+
+func TestAddReactionHandlerForbidden(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, chat_svc.Room{})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestAddReactionHandlerDeletedMessage(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	deletedAt := time.Now()
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id", DeletedAt: &deletedAt}, memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "Message has been deleted")
+}
+
+func TestAddReactionHandlerFailedAdd(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, ownerRoomInfo)
+	mongoMockSvc.On("AddReaction", "valid_message_id", "👍", 12345, mongoMockPkg).Return(assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to add reaction")
+}
+
+func TestAddReactionHandlerTooManyReactions(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, memberRoomInfo)
+	mongoMockSvc.On("AddReaction", "valid_message_id", "👍", 12345, mongoMockPkg).Return(mongo_svc.ErrTooManyReactions)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "Too many reactions on this message")
+}
+
+// 確認後に削除・期限切れで消えたメッセージは上限とは区別する
+func TestAddReactionHandlerDeletedConcurrently(t *testing.T) {
+	c, w := newAddReactionContext(`{"emoji":"👍"}`)
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, memberRoomInfo)
+	mongoMockSvc.On("AddReaction", "valid_message_id", "👍", 12345, mongoMockPkg).Return(mongo_svc.ErrChatMessageDeleted)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AddReactionHandler(c)
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), "Message has been deleted")
+}
+
+func TestRemoveReactionHandler(t *testing.T) {
+	c, w := newRemoveReactionContext("👍")
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, memberRoomInfo)
+	mongoMockSvc.On("RemoveReaction", "valid_message_id", "👍", 12345, mongoMockPkg).Return(nil)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RemoveReactionHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Reaction removed successfully")
+}
+
+func TestRemoveReactionHandlerInvalidEmoji(t *testing.T) {
+	c, w := newRemoveReactionContext("$bad")
+
+	handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+	handler.RemoveReactionHandler(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid emoji")
+}
+
+func TestRemoveReactionHandlerForbidden(t *testing.T) {
+	c, w := newRemoveReactionContext("👍")
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, chat_svc.Room{})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RemoveReactionHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestRemoveReactionHandlerFailedRemove(t *testing.T) {
+	c, w := newRemoveReactionContext("👍")
+
+	mongoMockPkg, mongoMockSvc, chatMockSvc := setupReactionMocks(model.ChatMessage{RoomID: "valid_room_id"}, memberRoomInfo)
+	mongoMockSvc.On("RemoveReaction", "valid_message_id", "👍", 12345, mongoMockPkg).Return(assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RemoveReactionHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to remove reaction")
+}
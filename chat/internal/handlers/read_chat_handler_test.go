@@ -27,7 +27,7 @@ func TestReadChatMessages(t *testing.T) {
 	mongoMockSvc.On("ReadChatMessages", "valid_room_id", []string{"chat1", "chat2"}, int(12345), mongoMockPkg).Return(nil)
 
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
-	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(chat_svc.Room{IsMember: true, IsOwner: false})
+	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(memberRoomInfo)
 
 	body := strings.NewReader(`{
 		"room_id": "valid_room_id",
@@ -116,7 +116,7 @@ func TestReadChatMessagesZeroLen(t *testing.T) {
 	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
 
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
-	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(chat_svc.Room{IsMember: true, IsOwner: false})
+	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(memberRoomInfo)
 
 	body := strings.NewReader(`{
 		"room_id": "valid_room_id",
@@ -147,7 +147,7 @@ func TestReadChatMessagesReadChatMessagesError(t *testing.T) {
 	mongoMockSvc.On("ReadChatMessages", "valid_room_id", []string{"chat1", "chat2"}, int(12345), mongoMockPkg).Return(assert.AnError)
 
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
-	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(chat_svc.Room{IsMember: true, IsOwner: false})
+	chatMockSvc.On("GetRoomInfo", model.Room{}, int(12345)).Return(memberRoomInfo)
 
 	body := strings.NewReader(`{
 		"room_id": "valid_room_id",
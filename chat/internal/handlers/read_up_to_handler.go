@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReadUpToRequest struct {
+	MessageID string `form:"message_id" json:"message_id" binding:"required"`
+}
+
+// 指定したメッセージまでを既読にする
+func (h *HandlerStruct) ReadUpToHandler(c *gin.Context) {
+	var req ReadUpToRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	roomID := c.Param("id")
+
+	room, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room"})
+		return
+	}
+	roomInfo := h.ChatSvc.GetRoomInfo(room, int(userID))
+	if !roomInfo.Can(chat_svc.CapabilityRead) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	// 別のルームのメッセージは取得できない
+	message, err := h.MongoSvc.GetChatMessageByID(roomID, req.MessageID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message", "details": err.Error()})
+		return
+	}
+
+	err = h.MongoSvc.UpdateReadCursor(roomID, int(userID), message, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update read cursor", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Read cursor updated", "last_read_message_id": message.ID.Hex()})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReadUpToContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	return newJSONRequestContext("POST", "/rooms/valid_room_id/read_up_to", body, gin.Params{{Key: "id", Value: "valid_room_id"}})
+}
+
+func TestReadUpToHandler(t *testing.T) {
+	c, w := newReadUpToContext(`{"message_id":"valid_message_id"}`)
+
+	message := model.ChatMessage{ID: threadRootID, RoomID: "valid_room_id"}
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(message, nil)
+	mongoMockSvc.On("UpdateReadCursor", "valid_room_id", 12345, message, mongoMockPkg).Return(nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ReadUpToHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Read cursor updated")
+	assert.Contains(t, w.Body.String(), threadRootID.Hex())
+}
+
+func TestReadUpToHandlerInvalidRequest(t *testing.T) {
+	c, w := newReadUpToContext(`{}`)
+
+	handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+	handler.ReadUpToHandler(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid request")
+}
+
+func TestReadUpToHandlerForbidden(t *testing.T) {
+	c, w := newReadUpToContext(`{"message_id":"valid_message_id"}`)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(chat_svc.Room{})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ReadUpToHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestReadUpToHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		roomErr   error
+		getErr    error
+		updateErr error
+		expect    string
+	}{
+		{"get_room_error", assert.AnError, nil, nil, "Failed to get room"},
+		{"get_message_error", nil, assert.AnError, nil, "Failed to get message"},
+		{"update_error", nil, nil, assert.AnError, "Failed to update read cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newReadUpToContext(`{"message_id":"valid_message_id"}`)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, tt.roomErr)
+			mongoMockSvc.On("GetChatMessageByID", "valid_room_id", "valid_message_id", mongoMockPkg).Return(model.ChatMessage{}, tt.getErr)
+			mongoMockSvc.On("UpdateReadCursor", "valid_room_id", 12345, model.ChatMessage{}, mongoMockPkg).Return(tt.updateErr)
+
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.ReadUpToHandler(c)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 未指定の場合はルームに設定された保持日数で集計する
+type RetentionReportRequest struct {
+	RetentionDays *int `form:"retention_days"`
+}
+
+// 保持期間によって削除されるメッセージの件数を返す（実際には削除しない）
+func (h *HandlerStruct) RetentionReportHandler(c *gin.Context) {
+	var req RetentionReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if req.RetentionDays != nil && (*req.RetentionDays < 0 || *req.RetentionDays > model.MaxRetentionDays) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Retention days must be between 0 and " + strconv.Itoa(model.MaxRetentionDays)})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	room, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage)
+	if !ok {
+		return
+	}
+
+	retentionDays := room.RetentionDays
+	if req.RetentionDays != nil {
+		retentionDays = *req.RetentionDays
+	}
+	// 保持期間が無い場合は削除されるメッセージも無い
+	if retentionDays == 0 {
+		c.JSON(http.StatusOK, gin.H{"retention_days": 0, "cutoff": nil, "expired_messages": 0})
+		return
+	}
+
+	cutoff := model.RetentionCutoff(time.Now(), retentionDays)
+	count, err := h.MongoSvc.CountExpiredChatMessages(roomID, cutoff, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count expired messages", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"retention_days": retentionDays, "cutoff": cutoff, "expired_messages": count})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRetentionReportHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		room          model.Room
+		roomInfo      chat_svc.Room
+		countErr      error
+		expectCode    int
+		expectDays    int
+		expectCount   int
+		expectCounted bool
+	}{
+		{"room_setting", "", model.Room{RetentionDays: 30}, ownerRoomInfo, nil, http.StatusOK, 30, 12, true},
+		// 設定を変更する前に件数を確認できる
+		{"preview", "?retention_days=7", model.Room{RetentionDays: 30}, ownerRoomInfo, nil, http.StatusOK, 7, 12, true},
+		{"no_retention", "", model.Room{}, ownerRoomInfo, nil, http.StatusOK, 0, 0, false},
+		{"invalid_days", "?retention_days=-1", model.Room{}, ownerRoomInfo, nil, http.StatusBadRequest, 0, 0, false},
+		{"not_owner", "", model.Room{RetentionDays: 30}, moderatorRoomInfo, nil, http.StatusForbidden, 0, 0, false},
+		{"count_error", "", model.Room{RetentionDays: 30}, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, 30, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/rooms/valid_room_id/retention"+tt.query, "", roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(tt.room, nil)
+			mongoMockSvc.On("CountExpiredChatMessages", "valid_room_id", mock.MatchedBy(func(cutoff time.Time) bool {
+				expected := time.Now().Add(-time.Duration(tt.expectDays) * 24 * time.Hour)
+				return cutoff.Sub(expected).Abs() < time.Minute
+			}), mongoMockPkg).Return(int64(12), tt.countErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", tt.room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.RetentionReportHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			if tt.expectCode == http.StatusOK {
+				var response struct {
+					RetentionDays   int        `json:"retention_days"`
+					Cutoff          *time.Time `json:"cutoff"`
+					ExpiredMessages int        `json:"expired_messages"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.expectDays, response.RetentionDays)
+				assert.Equal(t, tt.expectCount, response.ExpiredMessages)
+				assert.Equal(t, tt.expectCounted, response.Cutoff != nil)
+			}
+			if !tt.expectCounted {
+				mongoMockSvc.AssertNotCalled(t, "CountExpiredChatMessages", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
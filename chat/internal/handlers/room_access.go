@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ルームを取得し、呼び出し元がそのルームで指定された操作を行えることを確認する
+func (h *HandlerStruct) getRoomFor(c *gin.Context, roomID string, userID int, capability chat_svc.Capability) (model.Room, chat_svc.Room, bool) {
+	room, err := h.MongoSvc.GetRoomByID(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room"})
+		return model.Room{}, chat_svc.Room{}, false
+	}
+
+	roomInfo := h.ChatSvc.GetRoomInfo(room, userID)
+	if !roomInfo.Can(capability) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return model.Room{}, chat_svc.Room{}, false
+	}
+
+	return room, roomInfo, true
+}
+
+// メッセージを取得し、呼び出し元がそのルームで指定された操作を行えることを確認する
+func (h *HandlerStruct) getMessageFor(c *gin.Context, messageID string, userID int, capability chat_svc.Capability) (model.ChatMessage, chat_svc.Room, bool) {
+	message, err := h.MongoSvc.GetChatMessage(messageID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message"})
+		return model.ChatMessage{}, chat_svc.Room{}, false
+	}
+
+	_, roomInfo, ok := h.getRoomFor(c, message.RoomID, userID, capability)
+	if !ok {
+		return model.ChatMessage{}, chat_svc.Room{}, false
+	}
+
+	return message, roomInfo, true
+}
+
+func containsInt(list []int, target int) bool {
+	for _, v := range list {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ownerRoomInfo     = chat_svc.Room{IsMember: true, IsOwner: true, Role: model.RoleOwner, Capabilities: chat_svc.CapabilitiesOf(model.RoleOwner)}
+	moderatorRoomInfo = chat_svc.Room{IsMember: true, Role: model.RoleModerator, Capabilities: chat_svc.CapabilitiesOf(model.RoleModerator)}
+	memberRoomInfo    = chat_svc.Room{IsMember: true, Role: model.RoleMember, Capabilities: chat_svc.CapabilitiesOf(model.RoleMember)}
+	readOnlyRoomInfo  = chat_svc.Room{IsMember: true, Role: model.RoleReadOnly, Capabilities: chat_svc.CapabilitiesOf(model.RoleReadOnly)}
+)
+
+func TestGetRoomFor(t *testing.T) {
+	tests := []struct {
+		name         string
+		roomInfo     chat_svc.Room
+		capability   chat_svc.Capability
+		expectOK     bool
+		expectStatus int
+	}{
+		{"member_can_post", memberRoomInfo, chat_svc.CapabilityPost, true, http.StatusOK},
+		{"read_only_can_read", readOnlyRoomInfo, chat_svc.CapabilityRead, true, http.StatusOK},
+		{"read_only_cannot_post", readOnlyRoomInfo, chat_svc.CapabilityPost, false, http.StatusForbidden},
+		{"member_cannot_moderate", memberRoomInfo, chat_svc.CapabilityModerate, false, http.StatusForbidden},
+		{"moderator_can_moderate", moderatorRoomInfo, chat_svc.CapabilityModerate, true, http.StatusOK},
+		{"moderator_cannot_manage", moderatorRoomInfo, chat_svc.CapabilityManage, false, http.StatusForbidden},
+		{"owner_can_manage", ownerRoomInfo, chat_svc.CapabilityManage, true, http.StatusOK},
+		{"non_member_cannot_read", chat_svc.Room{}, chat_svc.CapabilityRead, false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room_id", mongoMockPkg).Return(model.Room{}, nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			_, roomInfo, ok := handler.getRoomFor(c, "room_id", 12345, tt.capability)
+
+			assert.Equal(t, tt.expectOK, ok)
+			assert.Equal(t, tt.expectStatus, w.Code)
+			if ok {
+				assert.Equal(t, tt.roomInfo.Role, roomInfo.Role)
+			}
+		})
+	}
+}
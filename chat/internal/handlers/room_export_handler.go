@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/export_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 一覧で返すエクスポートの件数
+const roomExportListLimit = 20
+
+type CreateRoomExportRequest struct {
+	Format string `json:"format"` // 未指定の場合は jsonl
+}
+
+// 完了したエクスポートにはダウンロード先を付けて返す
+type RoomExportItem struct {
+	model.RoomExport
+	DownloadURL string `json:",omitempty"`
+}
+
+func newRoomExportItem(export model.RoomExport) RoomExportItem {
+	item := RoomExportItem{RoomExport: export}
+	if export.Status == model.ExportCompleted {
+		item.DownloadURL = "/rooms/" + export.RoomID + "/exports/" + export.ID.Hex() + "/download"
+	}
+	return item
+}
+
+// エクスポートを受け付ける（書き出しはワーカーが非同期に行う）
+func (h *HandlerStruct) CreateRoomExportHandler(c *gin.Context) {
+	var req CreateRoomExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = model.ExportFormatJSONL
+	}
+	if !model.ValidExportFormat(req.Format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be one of jsonl, csv or html"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	// 同じルームのエクスポートは同時に1件まで
+	exports, err := h.MongoSvc.GetRoomExports(roomID, roomExportListLimit, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get exports", "details": err.Error()})
+		return
+	}
+	for _, export := range exports {
+		if export.Active() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Export already in progress", "export_id": export.ID.Hex()})
+			return
+		}
+	}
+
+	exportID, err := h.MongoSvc.CreateRoomExport(model.RoomExport{
+		RoomID:      roomID,
+		RequestedBy: userID,
+		Format:      req.Format,
+		Status:      model.ExportPending,
+		CreatedAt:   time.Now(),
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Export started successfully", "export_id": exportID})
+}
+
+// ルームのエクスポートを新しい順に返す
+func (h *HandlerStruct) RoomExportsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	exports, err := h.MongoSvc.GetRoomExports(roomID, roomExportListLimit, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get exports", "details": err.Error()})
+		return
+	}
+
+	items := make([]RoomExportItem, 0, len(exports))
+	for _, export := range exports {
+		items = append(items, newRoomExportItem(export))
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": items})
+}
+
+// 権限を確認した上で対象のエクスポートを取得する
+func (h *HandlerStruct) getRoomExportFor(c *gin.Context) (model.RoomExport, bool) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return model.RoomExport{}, false
+	}
+
+	export, err := h.MongoSvc.GetRoomExport(roomID, c.Param("export_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrRoomExportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return model.RoomExport{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get export", "details": err.Error()})
+		return model.RoomExport{}, false
+	}
+	return export, true
+}
+
+func (h *HandlerStruct) RoomExportHandler(c *gin.Context) {
+	export, ok := h.getRoomExportFor(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"export": newRoomExportItem(export)})
+}
+
+// エクスポートしたファイルをストレージから読み込みながら返す
+func (h *HandlerStruct) DownloadRoomExportHandler(c *gin.Context) {
+	export, ok := h.getRoomExportFor(c)
+	if !ok {
+		return
+	}
+	if export.Status != model.ExportCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export is not ready", "status": export.Status})
+		return
+	}
+
+	body, err := h.ExportSvc.Open(c.Request.Context(), export)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open export", "details": err.Error()})
+		return
+	}
+	defer body.Close()
+
+	filename := fmt.Sprintf("room-%s-%s.%s", export.RoomID, export.ID.Hex(), export.Format)
+	c.DataFromReader(http.StatusOK, export.Size, export_svc.ContentType(export.Format), body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": filename}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, no-cache",
+	})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_export_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateRoomExportHandler(t *testing.T) {
+	active := model.RoomExport{ID: primitive.NewObjectID(), Status: model.ExportProcessing}
+	completed := model.RoomExport{ID: primitive.NewObjectID(), Status: model.ExportCompleted}
+
+	tests := []struct {
+		name         string
+		body         string
+		roomInfo     chat_svc.Room
+		exports      []model.RoomExport
+		createErr    error
+		expectCode   int
+		expectFormat string // 空の場合は作成しない
+		expect       string
+	}{
+		{"default_format", `{}`, ownerRoomInfo, nil, nil, http.StatusOK, model.ExportFormatJSONL, "Export started successfully"},
+		// 完了したエクスポートがあっても新しく作成できる
+		{"html", `{"format":"html"}`, ownerRoomInfo, []model.RoomExport{completed}, nil, http.StatusOK, model.ExportFormatHTML, "Export started successfully"},
+		{"invalid_format", `{"format":"pdf"}`, ownerRoomInfo, nil, nil, http.StatusBadRequest, "", "Format must be one of jsonl, csv or html"},
+		{"in_progress", `{"format":"csv"}`, ownerRoomInfo, []model.RoomExport{completed, active}, nil, http.StatusConflict, "", "Export already in progress"},
+		{"not_owner", `{}`, moderatorRoomInfo, nil, nil, http.StatusForbidden, "", "Access denied"},
+		{"create_error", `{}`, ownerRoomInfo, nil, assert.AnError, http.StatusInternalServerError, model.ExportFormatJSONL, "Failed to create export"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/exports", tt.body, roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("GetRoomExports", "valid_room_id", roomExportListLimit, mongoMockPkg).Return(tt.exports, nil)
+			mongoMockSvc.On("CreateRoomExport", mock.MatchedBy(func(export model.RoomExport) bool {
+				return export.RoomID == "valid_room_id" && export.RequestedBy == 12345 &&
+					export.Format == tt.expectFormat && export.Status == model.ExportPending
+			}), mongoMockPkg).Return("export1", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CreateRoomExportHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectFormat == "" {
+				mongoMockSvc.AssertNotCalled(t, "CreateRoomExport", mock.Anything, mock.Anything)
+			}
+			if tt.expectCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"export_id":"export1"`)
+			}
+		})
+	}
+}
+
+func TestRoomExportsHandler(t *testing.T) {
+	pending := model.RoomExport{ID: primitive.NewObjectID(), RoomID: "valid_room_id", Status: model.ExportPending}
+	completed := model.RoomExport{ID: primitive.NewObjectID(), RoomID: "valid_room_id", Status: model.ExportCompleted, StorageKey: "rooms/valid_room_id/exports/e.jsonl"}
+
+	c, w := newJSONRequestContext("GET", "/rooms/valid_room_id/exports", "", roomIDParams)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetRoomExports", "valid_room_id", roomExportListLimit, mongoMockPkg).Return([]model.RoomExport{pending, completed}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RoomExportsHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Exports []RoomExportItem `json:"exports"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Len(t, response.Exports, 2) {
+		// 完了したエクスポートのみダウンロードできる
+		assert.Empty(t, response.Exports[0].DownloadURL)
+		assert.Equal(t, "/rooms/valid_room_id/exports/"+completed.ID.Hex()+"/download", response.Exports[1].DownloadURL)
+	}
+	// 保存先のキーは返さない
+	assert.NotContains(t, w.Body.String(), "rooms/valid_room_id/exports/e.jsonl")
+}
+
+func TestRoomExportHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		roomInfo   chat_svc.Room
+		getErr     error
+		expectCode int
+		expect     string
+	}{
+		{"success", ownerRoomInfo, nil, http.StatusOK, `"Status":"pending"`},
+		{"not_found", ownerRoomInfo, mongo_svc.ErrRoomExportNotFound, http.StatusNotFound, "Export not found"},
+		{"get_error", ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to get export"},
+		{"not_owner", moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := gin.Params{{Key: "id", Value: "valid_room_id"}, {Key: "export_id", Value: "export1"}}
+			c, w := newJSONRequestContext("GET", "/rooms/valid_room_id/exports/export1", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("GetRoomExport", "valid_room_id", "export1", mongoMockPkg).Return(model.RoomExport{Status: model.ExportPending}, tt.getErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.RoomExportHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestDownloadRoomExportHandler(t *testing.T) {
+	exportID := primitive.NewObjectID()
+	completed := model.RoomExport{ID: exportID, RoomID: "valid_room_id", Format: model.ExportFormatCSV, Status: model.ExportCompleted, Size: 3}
+	processing := model.RoomExport{ID: exportID, RoomID: "valid_room_id", Format: model.ExportFormatCSV, Status: model.ExportProcessing}
+
+	tests := []struct {
+		name       string
+		export     model.RoomExport
+		getErr     error
+		openErr    error
+		expectCode int
+		expect     string
+	}{
+		{"success", completed, nil, nil, http.StatusOK, "id\n"},
+		{"not_ready", processing, nil, nil, http.StatusConflict, "Export is not ready"},
+		{"not_found", model.RoomExport{}, mongo_svc.ErrRoomExportNotFound, nil, http.StatusNotFound, "Export not found"},
+		{"open_error", completed, nil, assert.AnError, http.StatusInternalServerError, "Failed to open export"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := gin.Params{{Key: "id", Value: "valid_room_id"}, {Key: "export_id", Value: exportID.Hex()}}
+			c, w := newJSONRequestContext("GET", "/rooms/valid_room_id/exports/"+exportID.Hex()+"/download", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("GetRoomExport", "valid_room_id", exportID.Hex(), mongoMockPkg).Return(tt.export, tt.getErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+			exportMockSvc := new(mock_export_svc.ExportSvcMock)
+			exportMockSvc.On("Open", mock.Anything, tt.export).Return(io.NopCloser(strings.NewReader("id\n")), tt.openErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.ExportSvc = exportMockSvc
+			handler.DownloadRoomExportHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+				assert.Equal(t, "attachment; filename=room-valid_room_id-"+exportID.Hex()+".csv", w.Header().Get("Content-Disposition"))
+				assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
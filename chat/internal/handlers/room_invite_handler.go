@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultRoomInviteTTL = 7 * 24 * time.Hour
+const maxRoomInviteTTL = 30 * 24 * time.Hour
+
+type CreateRoomInviteRequest struct {
+	SingleUse bool   `form:"single_use" json:"single_use"`
+	ExpiresIn string `form:"expires_in" json:"expires_in"` // 例: "24h"。省略時は ROOM_INVITE_TTL
+}
+
+func roomInviteTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ROOM_INVITE_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultRoomInviteTTL
+	}
+	return ttl
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (h *HandlerStruct) CreateRoomInviteHandler(c *gin.Context) {
+	var req CreateRoomInviteRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	ttl := roomInviteTTL()
+	if req.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || expiresIn <= 0 || expiresIn > maxRoomInviteTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expires_in"})
+			return
+		}
+		ttl = expiresIn
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(userID), chat_svc.CapabilityModerate); !ok {
+		return
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite code", "details": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	invite := model.RoomInvite{
+		RoomID:    roomID,
+		Code:      code,
+		CreatedBy: int(userID),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if req.SingleUse {
+		invite.MaxUses = 1
+	}
+
+	_, err = h.MongoSvc.CreateRoomInvite(invite, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Invite created successfully",
+		"code":       invite.Code,
+		"invite_url": "/invites/" + invite.Code + "/accept",
+		"expires_at": invite.ExpiresAt,
+		"single_use": req.SingleUse,
+	})
+}
+
+func (h *HandlerStruct) AcceptRoomInviteHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+
+	invite, err := h.MongoSvc.AcceptRoomInvite(c.Param("code"), int(userID), time.Now(), h.MongoPkg)
+	switch {
+	case errors.Is(err, mongo_svc.ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	case errors.Is(err, mongo_svc.ErrUserBanned):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are banned from this room"})
+		return
+	case errors.Is(err, mongo_svc.ErrRoomArchived):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Room is archived"})
+		return
+	case errors.Is(err, mongo_svc.ErrInviteExpired), errors.Is(err, mongo_svc.ErrInviteUsedUp):
+		c.JSON(http.StatusGone, gin.H{"error": "Invite is no longer valid", "details": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invite", "details": err.Error()})
+		return
+	}
+
+	h.publishWebhookEvent(invite.RoomID, model.WebhookMemberJoined, model.WebhookMemberData{UserID: int(userID), ActorID: int(userID)})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Joined room successfully", "room_id": invite.RoomID})
+}
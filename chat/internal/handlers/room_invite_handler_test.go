@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGenerateInviteCode(t *testing.T) {
+	code1, err := generateInviteCode()
+	assert.NoError(t, err)
+	code2, err := generateInviteCode()
+	assert.NoError(t, err)
+
+	assert.Len(t, code1, 24)
+	assert.NotEqual(t, code1, code2)
+}
+
+func TestCreateRoomInviteHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectMaxUses int
+		expectTTL     time.Duration
+	}{
+		{"default", `{}`, 0, defaultRoomInviteTTL},
+		{"single_use", `{"single_use":true,"expires_in":"1h"}`, 1, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/invites", tt.body, gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("CreateRoomInvite", mock.MatchedBy(func(invite model.RoomInvite) bool {
+				return invite.RoomID == "valid_room_id" &&
+					invite.CreatedBy == 12345 &&
+					invite.Code != "" &&
+					invite.MaxUses == tt.expectMaxUses &&
+					invite.ExpiresAt.Sub(invite.CreatedAt) == tt.expectTTL
+			}), mongoMockPkg).Return("invite_id", nil)
+
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CreateRoomInviteHandler(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "Invite created successfully")
+			assert.Contains(t, w.Body.String(), `"invite_url":"/invites/`)
+			mongoMockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateRoomInviteHandlerInvalidExpiresIn(t *testing.T) {
+	for _, expiresIn := range []string{"abc", "-1h", "10000h"} {
+		t.Run(expiresIn, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/invites", `{"expires_in":"`+expiresIn+`"}`, gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+			handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+			handler.CreateRoomInviteHandler(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "Invalid expires_in")
+		})
+	}
+}
+
+func TestCreateRoomInviteHandlerNotOwner(t *testing.T) {
+	c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/invites", `{}`, gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.CreateRoomInviteHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestCreateRoomInviteHandlerFailedCreate(t *testing.T) {
+	c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/invites", `{}`, gin.Params{{Key: "id", Value: "valid_room_id"}})
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("CreateRoomInvite", mock.Anything, mongoMockPkg).Return("", assert.AnError)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.CreateRoomInviteHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to create invite")
+}
+
+func TestAcceptRoomInviteHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, `"room_id":"valid_room_id"`},
+		{"not_found", mongo_svc.ErrInviteNotFound, http.StatusNotFound, "Invite not found"},
+		{"expired", mongo_svc.ErrInviteExpired, http.StatusGone, "Invite is no longer valid"},
+		{"used_up", mongo_svc.ErrInviteUsedUp, http.StatusGone, "Invite is no longer valid"},
+		// 招待を作成した後にアーカイブされた
+		{"archived", mongo_svc.ErrRoomArchived, http.StatusForbidden, "Room is archived"},
+		{"error", assert.AnError, http.StatusInternalServerError, "Failed to accept invite"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/invites/code1/accept", "", gin.Params{{Key: "code", Value: "code1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("AcceptRoomInvite", "code1", 12345, mock.Anything, mongoMockPkg).Return(model.RoomInvite{RoomID: "valid_room_id"}, tt.err)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.AcceptRoomInviteHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
@@ -1,17 +1,55 @@
 package handlers
 
 import (
+	"errors"
+	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
 
 	"github.com/gin-gonic/gin"
 )
 
+type RoomListRequest struct {
+	Target string `form:"target"`
+	Query  string `form:"q"`      // ルーム名の部分一致
+	Sort   string `form:"sort"`   // activity / members / created
+	Cursor string `form:"cursor"` // 前のレスポンスの next_cursor
+	Limit  int    `form:"limit"`
+}
+
 func (h *HandlerStruct) RoomListHandler(c *gin.Context) {
-	target := c.DefaultQuery("target", "all")
+	var req RoomListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if req.Target == "" {
+		req.Target = "all"
+	}
+	if req.Sort == "" {
+		req.Sort = mongo_svc.RoomSortActivity
+	}
+	if !mongo_svc.ValidRoomSort(req.Sort) {
+		c.JSON(400, gin.H{"error": "Invalid sort"})
+		return
+	}
+	_, limit := PaginationRequest{Limit: req.Limit}.normalize()
+
 	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
 	userID := jwtinfo.UserID
 
-	rooms, err := h.MongoSvc.GetRooms(int(userID), target, h.MongoPkg)
+	rooms, nextCursor, err := h.MongoSvc.ListRooms(mongo_svc.RoomListQuery{
+		UserID: int(userID),
+		Target: req.Target,
+		Name:   req.Query,
+		Sort:   req.Sort,
+		Cursor: req.Cursor,
+		Limit:  limit,
+	}, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrInvalidRoomCursor) {
+		c.JSON(400, gin.H{"error": "Invalid cursor"})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Failed to get rooms", "details": err.Error()})
 		return
@@ -19,7 +57,26 @@ func (h *HandlerStruct) RoomListHandler(c *gin.Context) {
 
 	responseRooms := h.ChatSvc.ConvertRoomList(rooms, int(userID))
 
+	// 参加しているルームのみ未読数を返す
+	var joinedRoomIDs []string
+	for _, room := range responseRooms {
+		if room.Can(chat_svc.CapabilityRead) {
+			joinedRoomIDs = append(joinedRoomIDs, room.ID)
+		}
+	}
+	if len(joinedRoomIDs) > 0 {
+		unreadCounts, err := h.MongoSvc.GetUnreadCounts(int(userID), joinedRoomIDs, h.MongoPkg)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to get unread counts", "details": err.Error()})
+			return
+		}
+		for i := range responseRooms {
+			responseRooms[i].UnreadCount = unreadCounts[responseRooms[i].ID]
+		}
+	}
+
 	c.JSON(200, gin.H{
-		"rooms": responseRooms,
+		"rooms":       responseRooms,
+		"next_cursor": nextCursor, // 最後のページの場合は空文字
 	})
 }
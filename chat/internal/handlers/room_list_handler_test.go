@@ -5,6 +5,7 @@ import (
 	"microservices/chat/internal/model"
 	"microservices/chat/internal/svc/chat_svc"
 	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
 	"microservices/chat/tests/mocks/svc/mock_chat_svc"
 	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
 	"net/http"
@@ -15,6 +16,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func defaultRoomListQuery(target string) mongo_svc.RoomListQuery {
+	return mongo_svc.RoomListQuery{UserID: 12345, Target: target, Sort: mongo_svc.RoomSortActivity, Limit: defaultPageLimit}
+}
+
 func TestRoomListHandler(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -24,7 +29,7 @@ func TestRoomListHandler(t *testing.T) {
 	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
 
-	mongoMockSvc.On("GetRooms", int(12345), "all", mongoMockPkg).Return([]model.Room{}, nil)
+	mongoMockSvc.On("ListRooms", defaultRoomListQuery("all"), mongoMockPkg).Return([]model.Room{}, "", nil)
 	chatMockSvc.On("ConvertRoomList", []model.Room{}, int(12345)).Return([]chat_svc.Room{})
 
 	req := httptest.NewRequest("GET", "/rooms?target=all", nil)
@@ -48,7 +53,7 @@ func TestRoomListHandler_GetRoomsError(t *testing.T) {
 	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
 	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
 
-	mongoMockSvc.On("GetRooms", int(12345), "all", mongoMockPkg).Return([]model.Room{}, assert.AnError)
+	mongoMockSvc.On("ListRooms", defaultRoomListQuery("all"), mongoMockPkg).Return([]model.Room{}, "", assert.AnError)
 
 	req := httptest.NewRequest("GET", "/rooms?target=all", nil)
 	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
@@ -61,3 +66,126 @@ func TestRoomListHandler_GetRoomsError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), `Failed to get rooms`)
 }
+
+func TestRoomListHandler_UnreadCounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+
+	mongoMockSvc.On("ListRooms", defaultRoomListQuery("all"), mongoMockPkg).Return([]model.Room{}, "", nil)
+	chatMockSvc.On("ConvertRoomList", []model.Room{}, int(12345)).Return([]chat_svc.Room{
+		{ID: "joined_room", IsMember: true, Capabilities: chat_svc.CapabilitiesOf(model.RoleMember)},
+		{ID: "other_room"},
+	})
+	// 参加していないルームの未読数は取得しない
+	mongoMockSvc.On("GetUnreadCounts", int(12345), []string{"joined_room"}, mongoMockPkg).Return(map[string]int64{"joined_room": 3}, nil)
+
+	req := httptest.NewRequest("GET", "/rooms?target=all", nil)
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	req = req.WithContext(ctx)
+	c.Request = req
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RoomListHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"ID":"joined_room"`)
+	assert.Contains(t, w.Body.String(), `"UnreadCount":3`)
+	assert.Contains(t, w.Body.String(), `"UnreadCount":0`)
+}
+
+func TestRoomListHandler_GetUnreadCountsError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+
+	mongoMockSvc.On("ListRooms", defaultRoomListQuery("joined"), mongoMockPkg).Return([]model.Room{}, "", nil)
+	chatMockSvc.On("ConvertRoomList", []model.Room{}, int(12345)).Return([]chat_svc.Room{{ID: "joined_room", IsMember: true, IsOwner: true, Capabilities: chat_svc.CapabilitiesOf(model.RoleOwner)}})
+	mongoMockSvc.On("GetUnreadCounts", int(12345), []string{"joined_room"}, mongoMockPkg).Return(map[string]int64{}, assert.AnError)
+
+	req := httptest.NewRequest("GET", "/rooms?target=joined", nil)
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	req = req.WithContext(ctx)
+	c.Request = req
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RoomListHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), `Failed to get unread counts`)
+}
+
+func TestRoomListHandler_SearchAndPaginate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+
+	query := mongo_svc.RoomListQuery{UserID: 12345, Target: "all", Name: "general", Sort: mongo_svc.RoomSortMembers, Cursor: "previous", Limit: maxPageLimit}
+	mongoMockSvc.On("ListRooms", query, mongoMockPkg).Return([]model.Room{}, "next", nil)
+	chatMockSvc.On("ConvertRoomList", []model.Room{}, int(12345)).Return([]chat_svc.Room{})
+
+	req := httptest.NewRequest("GET", "/rooms?q=general&sort=members&cursor=previous&limit=1000", nil)
+	ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+	ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+	req = req.WithContext(ctx)
+	c.Request = req
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.RoomListHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"next_cursor":"next"`)
+	mongoMockSvc.AssertExpectations(t)
+}
+
+func TestRoomListHandler_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		cursorErr   bool
+		expectError string
+	}{
+		{"invalid_sort", "/rooms?sort=name", false, "Invalid sort"},
+		{"invalid_cursor", "/rooms?cursor=broken", true, "Invalid cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+
+			if tt.cursorErr {
+				query := defaultRoomListQuery("all")
+				query.Cursor = "broken"
+				mongoMockSvc.On("ListRooms", query, mongoMockPkg).Return([]model.Room{}, "", mongo_svc.ErrInvalidRoomCursor)
+			}
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			ctx := context.WithValue(req.Context(), jwtinfo_svc.UserIDKey, 12345)
+			ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, "test@example.com")
+			req = req.WithContext(ctx)
+			c.Request = req
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.RoomListHandler(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectError)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoomMemberRequest struct {
+	UserID int `form:"user_id" json:"user_id" binding:"required"`
+}
+
+func (h *HandlerStruct) LeaveRoomHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+
+	newOwnerID, err := h.MongoSvc.LeaveRoom(c.Param("id"), int(userID), h.MongoPkg)
+	switch {
+	case errors.Is(err, mongo_svc.ErrNotRoomMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You are not a member of this room"})
+		return
+	case errors.Is(err, mongo_svc.ErrDirectRoom):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot leave a direct message"})
+		return
+	case errors.Is(err, mongo_svc.ErrOwnerIsLastMember):
+		c.JSON(http.StatusConflict, gin.H{"error": "Owner cannot leave as the last member of the room"})
+		return
+	case errors.Is(err, mongo_svc.ErrMembershipConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Room membership was modified concurrently, please retry"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave room", "details": err.Error()})
+		return
+	}
+
+	h.publishWebhookEvent(c.Param("id"), model.WebhookMemberLeft, model.WebhookMemberData{UserID: int(userID), ActorID: int(userID)})
+
+	response := gin.H{"message": "Left room successfully"}
+	// オーナーが退出した場合は引き継ぎ先を返す
+	if newOwnerID != 0 {
+		response["new_owner_id"] = newOwnerID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// 他のメンバーを操作する際の共通処理
+// モデレーター同士の操作やオーナーへの操作はできず、モデレーターを対象にできるのはオーナーだけ
+func (h *HandlerStruct) bindRoomMemberRequest(c *gin.Context, capability chat_svc.Capability) (RoomMemberRequest, int, bool) {
+	var req RoomMemberRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return req, 0, false
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	if req.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot target yourself"})
+		return req, 0, false
+	}
+	room, roomInfo, ok := h.getRoomFor(c, c.Param("id"), userID, capability)
+	if !ok {
+		return req, 0, false
+	}
+	if room.RoleOf(req.UserID) == model.RoleModerator && !roomInfo.Can(chat_svc.CapabilityManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can act on moderators"})
+		return req, 0, false
+	}
+
+	return req, userID, true
+}
+
+func (h *HandlerStruct) KickMemberHandler(c *gin.Context) {
+	req, userID, ok := h.bindRoomMemberRequest(c, chat_svc.CapabilityModerate)
+	if !ok {
+		return
+	}
+
+	err := h.MongoSvc.KickMember(c.Param("id"), req.UserID, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrNotRoomMember) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this room"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to kick member", "details": err.Error()})
+		return
+	}
+
+	h.publishWebhookEvent(c.Param("id"), model.WebhookMemberRemoved, model.WebhookMemberData{UserID: req.UserID, ActorID: userID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member kicked successfully"})
+}
+
+func (h *HandlerStruct) BanMemberHandler(c *gin.Context) {
+	req, userID, ok := h.bindRoomMemberRequest(c, chat_svc.CapabilityModerate)
+	if !ok {
+		return
+	}
+
+	err := h.MongoSvc.BanMember(c.Param("id"), req.UserID, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrCannotTargetOwner) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot ban the room owner"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ban member", "details": err.Error()})
+		return
+	}
+
+	h.publishWebhookEvent(c.Param("id"), model.WebhookMemberRemoved, model.WebhookMemberData{UserID: req.UserID, ActorID: userID})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member banned successfully"})
+}
+
+func (h *HandlerStruct) UnbanMemberHandler(c *gin.Context) {
+	req, _, ok := h.bindRoomMemberRequest(c, chat_svc.CapabilityModerate)
+	if !ok {
+		return
+	}
+
+	err := h.MongoSvc.UnbanMember(c.Param("id"), req.UserID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unban member", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member unbanned successfully"})
+}
+
+func (h *HandlerStruct) TransferOwnershipHandler(c *gin.Context) {
+	req, userID, ok := h.bindRoomMemberRequest(c, chat_svc.CapabilityManage)
+	if !ok {
+		return
+	}
+
+	err := h.MongoSvc.TransferOwnership(c.Param("id"), userID, req.UserID, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrMembershipConflict) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New owner must be a member of this room"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ownership", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ownership transferred successfully", "owner_id": req.UserID})
+}
+
+type SetMemberRoleRequest struct {
+	UserID int    `form:"user_id" json:"user_id" binding:"required"`
+	Role   string `form:"role" json:"role" binding:"required"`
+}
+
+// オーナーがメンバーの役割（moderator / member / read_only）を変更する
+func (h *HandlerStruct) SetMemberRoleHandler(c *gin.Context) {
+	var req SetMemberRoleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if !model.AssignableRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if req.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot target yourself"})
+		return
+	}
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.SetMemberRole(roomID, req.UserID, req.Role, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrNotRoomMember) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this room"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set role", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "user_id": req.UserID, "role": req.Role})
+}
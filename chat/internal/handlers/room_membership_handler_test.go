@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var roomIDParams = gin.Params{{Key: "id", Value: "valid_room_id"}}
+
+func TestLeaveRoomHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		newOwnerID int
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"member", 0, nil, http.StatusOK, `{"message":"Left room successfully"}`},
+		{"owner", 99999, nil, http.StatusOK, `"new_owner_id":99999`},
+		{"not_member", 0, mongo_svc.ErrNotRoomMember, http.StatusBadRequest, "not a member"},
+		{"direct_room", 0, mongo_svc.ErrDirectRoom, http.StatusBadRequest, "Cannot leave a direct message"},
+		{"last_member", 0, mongo_svc.ErrOwnerIsLastMember, http.StatusConflict, "last member"},
+		{"conflict", 0, mongo_svc.ErrMembershipConflict, http.StatusConflict, "please retry"},
+		{"error", 0, assert.AnError, http.StatusInternalServerError, "Failed to leave room"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/leave", "", roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("LeaveRoom", "valid_room_id", 12345, mongoMockPkg).Return(tt.newOwnerID, tt.err)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.LeaveRoomHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestRoomMemberRequestValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		expectCode int
+		expect     string
+	}{
+		{"missing_user_id", `{}`, ownerRoomInfo, http.StatusBadRequest, "Invalid request"},
+		{"self", `{"user_id":12345}`, ownerRoomInfo, http.StatusBadRequest, "Cannot target yourself"},
+		{"not_owner", `{"user_id":99999}`, memberRoomInfo, http.StatusForbidden, "Access denied"},
+	}
+
+	handlers := map[string]func(h *HandlerStruct, c *gin.Context){
+		"kick":               (*HandlerStruct).KickMemberHandler,
+		"ban":                (*HandlerStruct).BanMemberHandler,
+		"unban":              (*HandlerStruct).UnbanMemberHandler,
+		"transfer_ownership": (*HandlerStruct).TransferOwnershipHandler,
+	}
+
+	for action, handle := range handlers {
+		for _, tt := range tests {
+			t.Run(action+"_"+tt.name, func(t *testing.T) {
+				c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/"+action, tt.body, roomIDParams)
+
+				mongoMockPkg := &MongoPkgMock{}
+				mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+				mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+				chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+				chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+				handle(NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc), c)
+
+				assert.Equal(t, tt.expectCode, w.Code)
+				assert.Contains(t, w.Body.String(), tt.expect)
+			})
+		}
+	}
+}
+
+func TestRoomMemberHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		args       []interface{}
+		handle     func(h *HandlerStruct, c *gin.Context)
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"kick", "KickMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).KickMemberHandler, nil, http.StatusOK, "Member kicked successfully"},
+		{"kick_not_member", "KickMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).KickMemberHandler, mongo_svc.ErrNotRoomMember, http.StatusNotFound, "not a member"},
+		{"kick_error", "KickMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).KickMemberHandler, assert.AnError, http.StatusInternalServerError, "Failed to kick member"},
+		{"ban", "BanMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).BanMemberHandler, nil, http.StatusOK, "Member banned successfully"},
+		{"ban_owner", "BanMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).BanMemberHandler, mongo_svc.ErrCannotTargetOwner, http.StatusBadRequest, "Cannot ban the room owner"},
+		{"ban_error", "BanMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).BanMemberHandler, assert.AnError, http.StatusInternalServerError, "Failed to ban member"},
+		{"unban", "UnbanMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).UnbanMemberHandler, nil, http.StatusOK, "Member unbanned successfully"},
+		{"unban_error", "UnbanMember", []interface{}{"valid_room_id", 99999}, (*HandlerStruct).UnbanMemberHandler, assert.AnError, http.StatusInternalServerError, "Failed to unban member"},
+		{"transfer", "TransferOwnership", []interface{}{"valid_room_id", 12345, 99999}, (*HandlerStruct).TransferOwnershipHandler, nil, http.StatusOK, `"owner_id":99999`},
+		{"transfer_not_member", "TransferOwnership", []interface{}{"valid_room_id", 12345, 99999}, (*HandlerStruct).TransferOwnershipHandler, mongo_svc.ErrMembershipConflict, http.StatusBadRequest, "must be a member"},
+		{"transfer_error", "TransferOwnership", []interface{}{"valid_room_id", 12345, 99999}, (*HandlerStruct).TransferOwnershipHandler, assert.AnError, http.StatusInternalServerError, "Failed to transfer ownership"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/"+tt.name, `{"user_id":99999}`, roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On(tt.method, append(tt.args, mongoMockPkg)...).Return(tt.err)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+			tt.handle(NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc), c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			mongoMockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRoomMemberRequestModeratorTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		roomInfo   chat_svc.Room
+		expectCode int
+		expect     string
+	}{
+		{"moderator_kicks_moderator", moderatorRoomInfo, http.StatusForbidden, "Only the owner can act on moderators"},
+		{"owner_kicks_moderator", ownerRoomInfo, http.StatusOK, "Member kicked successfully"},
+	}
+
+	room := model.Room{Members: []int{12345, 99999}, Roles: map[string]string{"99999": model.RoleModerator}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/valid_room_id/kick", `{"user_id":99999}`, roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("KickMember", "valid_room_id", 99999, mongoMockPkg).Return(nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.KickMemberHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestSetMemberRoleHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"moderator", `{"user_id":99999,"role":"moderator"}`, ownerRoomInfo, nil, http.StatusOK, `"role":"moderator"`},
+		{"read_only", `{"user_id":99999,"role":"read_only"}`, ownerRoomInfo, nil, http.StatusOK, `"role":"read_only"`},
+		{"invalid_role", `{"user_id":99999,"role":"owner"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Invalid role"},
+		{"missing_role", `{"user_id":99999}`, ownerRoomInfo, nil, http.StatusBadRequest, "Invalid request"},
+		{"self", `{"user_id":12345,"role":"member"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Cannot target yourself"},
+		{"by_moderator", `{"user_id":99999,"role":"moderator"}`, moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"not_member", `{"user_id":99999,"role":"member"}`, ownerRoomInfo, mongo_svc.ErrNotRoomMember, http.StatusNotFound, "not a member"},
+		{"error", `{"user_id":99999,"role":"member"}`, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to set role"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("PUT", "/rooms/valid_room_id/roles", tt.body, roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("SetMemberRole", "valid_room_id", 99999, mock.Anything, mongoMockPkg).Return(tt.err)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.SetMemberRoleHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
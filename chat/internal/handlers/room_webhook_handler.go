@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 署名用の鍵を指定する場合の最小の長さ
+const minWebhookSecretLength = 16
+
+// 配信一覧で返す最大件数
+const webhookDeliveryListLimit = 100
+
+// ルームのイベントを Webhook に送る。送信に失敗しても元の操作は成功として扱う
+func (h *HandlerStruct) publishWebhookEvent(roomID string, event string, data interface{}) {
+	if h.WebhookSvc == nil {
+		return
+	}
+	if err := h.WebhookSvc.Publish(roomID, event, data); err != nil {
+		log.Printf("failed to publish %s webhook event for room %s: %v", event, roomID, err)
+	}
+}
+
+type CreateRoomWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"` // 空の場合は全てのイベント
+	Secret string   `json:"secret"` // 空の場合は生成する
+}
+
+func (r CreateRoomWebhookRequest) validate(ctx context.Context) error {
+	if err := netguard_pkg.CheckURL(ctx, r.URL); err != nil {
+		if errors.Is(err, netguard_pkg.ErrForbiddenAddress) {
+			return fmt.Errorf("url must not point to an internal address")
+		}
+		return err
+	}
+	for _, event := range r.Events {
+		if !model.ValidWebhookEvent(event) {
+			return fmt.Errorf("unknown event: %s", event)
+		}
+	}
+	if r.Secret != "" && len(r.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minWebhookSecretLength)
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ルームの Webhook を登録する。鍵はこのレスポンスでのみ返す
+func (h *HandlerStruct) CreateRoomWebhookHandler(c *gin.Context) {
+	var req CreateRoomWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if err := req.validate(c.Request.Context()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = generateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+			return
+		}
+	}
+
+	webhookID, err := h.MongoSvc.CreateRoomWebhook(model.RoomWebhook{
+		RoomID:    roomID,
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    secret,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Webhook created successfully",
+		"webhook_id": webhookID,
+		"secret":     secret,
+	})
+}
+
+func (h *HandlerStruct) RoomWebhooksHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	webhooks, err := h.MongoSvc.GetRoomWebhooks(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+func (h *HandlerStruct) DeleteRoomWebhookHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.DeleteRoomWebhook(roomID, c.Param("webhook_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+type WebhookDeliveriesRequest struct {
+	Status string `form:"status"` // 省略した場合はデッドレター（failed）
+}
+
+// ルームの Webhook の配信を新しい順に返す
+func (h *HandlerStruct) WebhookDeliveriesHandler(c *gin.Context) {
+	var req WebhookDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	switch req.Status {
+	case "":
+		req.Status = model.DeliveryFailed
+	case model.DeliveryPending, model.DeliveryDelivered, model.DeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": "unknown status: " + req.Status})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	deliveries, err := h.MongoSvc.GetWebhookDeliveries(roomID, req.Status, webhookDeliveryListLimit, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// デッドレターになった配信をもう一度送る
+func (h *HandlerStruct) RedeliverWebhookHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.RedeliverWebhookDelivery(roomID, c.Param("delivery_id"), time.Now(), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrWebhookDeliveryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Failed delivery not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeliver webhook", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook delivery queued"})
+}
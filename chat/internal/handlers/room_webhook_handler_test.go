@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_webhook_svc"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateRoomWebhookHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		createErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"url":"https://example.com/hook","events":["message.created"]}`, ownerRoomInfo, nil, http.StatusOK, `"secret":"`},
+		{"custom_secret", `{"url":"https://example.com/hook","secret":"0123456789abcdef"}`, ownerRoomInfo, nil, http.StatusOK, `"secret":"0123456789abcdef"`},
+		{"missing_url", `{}`, ownerRoomInfo, nil, http.StatusBadRequest, "Invalid request"},
+		{"invalid_url", `{"url":"ftp://example.com"}`, ownerRoomInfo, nil, http.StatusBadRequest, "url must be an http or https URL"},
+		// 内部のネットワークには送らない
+		{"internal_url", `{"url":"http://169.254.169.254/latest/meta-data"}`, ownerRoomInfo, nil, http.StatusBadRequest, "url must not point to an internal address"},
+		{"unknown_event", `{"url":"https://example.com","events":["room.deleted"]}`, ownerRoomInfo, nil, http.StatusBadRequest, "unknown event"},
+		{"short_secret", `{"url":"https://example.com","secret":"short"}`, ownerRoomInfo, nil, http.StatusBadRequest, "secret must be at least"},
+		{"not_owner", `{"url":"https://example.com/hook"}`, moderatorRoomInfo, nil, http.StatusForbidden, ""},
+		{"create_error", `{"url":"https://example.com/hook"}`, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to create webhook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/webhooks", tt.body, gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("CreateRoomWebhook", mock.MatchedBy(func(webhook model.RoomWebhook) bool {
+				return webhook.RoomID == "room1" && webhook.CreatedBy == 12345 && len(webhook.Secret) >= minWebhookSecretLength
+			}), mongoMockPkg).Return("webhook1", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CreateRoomWebhookHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusForbidden {
+				mongoMockSvc.AssertNotCalled(t, "CreateRoomWebhook", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestRoomWebhooksHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		getErr     error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, `"URL":"https://example.com/hook"`},
+		{"get_error", assert.AnError, http.StatusInternalServerError, "Failed to get webhooks"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/rooms/room1/webhooks", "", gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetRoomWebhooks", "room1", mongoMockPkg).Return([]model.RoomWebhook{{URL: "https://example.com/hook", Secret: "secret"}}, tt.getErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.RoomWebhooksHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			// 鍵は一覧に含めない
+			assert.NotContains(t, w.Body.String(), `"secret"`)
+		})
+	}
+}
+
+func TestDeleteRoomWebhookHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	params := gin.Params{{Key: "id", Value: "room1"}, {Key: "webhook_id", Value: "webhook1"}}
+
+	tests := []struct {
+		name       string
+		deleteErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, "Webhook deleted successfully"},
+		{"not_found", mongo_svc.ErrWebhookNotFound, http.StatusNotFound, "Webhook not found"},
+		{"delete_error", assert.AnError, http.StatusInternalServerError, "Failed to delete webhook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/rooms/room1/webhooks/webhook1", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("DeleteRoomWebhook", "room1", "webhook1", mongoMockPkg).Return(tt.deleteErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.DeleteRoomWebhookHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestWebhookDeliveriesHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectStatus string
+		getErr       error
+		expectCode   int
+		expect       string
+	}{
+		{"dead_letters", "", model.DeliveryFailed, nil, http.StatusOK, `"deliveries"`},
+		{"pending", "?status=pending", model.DeliveryPending, nil, http.StatusOK, `"deliveries"`},
+		{"invalid_status", "?status=unknown", "", nil, http.StatusBadRequest, "unknown status"},
+		{"get_error", "", model.DeliveryFailed, assert.AnError, http.StatusInternalServerError, "Failed to get webhook deliveries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/rooms/room1/webhook_deliveries"+tt.query, "", gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetWebhookDeliveries", "room1", tt.expectStatus, webhookDeliveryListLimit, mongoMockPkg).Return([]model.WebhookDelivery{}, tt.getErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.WebhookDeliveriesHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestRedeliverWebhookHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	params := gin.Params{{Key: "id", Value: "room1"}, {Key: "delivery_id", Value: "delivery1"}}
+
+	tests := []struct {
+		name         string
+		redeliverErr error
+		expectCode   int
+		expect       string
+	}{
+		{"success", nil, http.StatusOK, "Webhook delivery queued"},
+		{"not_found", mongo_svc.ErrWebhookDeliveryNotFound, http.StatusNotFound, "Failed delivery not found"},
+		{"redeliver_error", assert.AnError, http.StatusInternalServerError, "Failed to redeliver webhook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/webhook_deliveries/delivery1/redeliver", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("RedeliverWebhookDelivery", "room1", "delivery1", mock.Anything, mongoMockPkg).Return(tt.redeliverErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.RedeliverWebhookHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestPostChatMessageHandlerWebhooks(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		publishErr error
+	}{
+		{"success", nil},
+		// Webhook の送信に失敗しても投稿は成功する
+		{"publish_error", assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/post_chat_message", `{"room_id":"valid_room_id","message":"hi"}`, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("PostChatMessage", mock.Anything, mongoMockPkg).Return("new_message_id", nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+			webhookMockSvc := new(mock_webhook_svc.WebhookSvcMock)
+			webhookMockSvc.On("Publish", "valid_room_id", model.WebhookMessageCreated, model.WebhookMessageData{
+				MessageID: "new_message_id",
+				UserID:    12345,
+				Message:   "hi",
+			}).Return(tt.publishErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.WebhookSvc = webhookMockSvc
+			handler.PostChatMessageHandler(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			webhookMockSvc.AssertExpectations(t)
+		})
+	}
+}
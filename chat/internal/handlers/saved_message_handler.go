@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SaveMessageRequest struct {
+	MessageID string `form:"message_id" json:"message_id" binding:"required"`
+}
+
+// 閲覧できるメッセージを自分用に保存する。保存済みの場合も成功にする
+func (h *HandlerStruct) SaveMessageHandler(c *gin.Context) {
+	var req SaveMessageRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	message, _, ok := h.getMessageFor(c, req.MessageID, userID, chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+	if message.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return
+	}
+
+	err := h.MongoSvc.SaveMessage(model.SavedMessage{
+		UserID:    userID,
+		RoomID:    message.RoomID,
+		MessageID: req.MessageID,
+		CreatedAt: time.Now(),
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message saved successfully"})
+}
+
+// 保存済みメッセージ一覧の1件
+type SavedMessageItem struct {
+	model.ChatMessage
+	RoomName string
+	SavedAt  time.Time
+}
+
+// 参加中のルームの保存済みメッセージを新しく保存した順に返す
+func (h *HandlerStruct) SavedMessagesHandler(c *gin.Context) {
+	var req PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	page, limit := req.normalize()
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	// 退出したルームのメッセージは表示しない
+	rooms, err := h.MongoSvc.GetRooms(userID, "joined", h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rooms"})
+		return
+	}
+	roomNames := map[string]string{}
+	roomIDs := []string{}
+	for _, room := range rooms {
+		roomNames[room.ID.Hex()] = room.Name
+		roomIDs = append(roomIDs, room.ID.Hex())
+	}
+
+	saved, total, err := h.MongoSvc.GetSavedMessages(userID, roomIDs, page, limit, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get saved messages"})
+		return
+	}
+	messageIDs := make([]string, 0, len(saved))
+	for _, item := range saved {
+		messageIDs = append(messageIDs, item.MessageID)
+	}
+	messages, err := h.MongoSvc.GetChatMessagesByIDs(messageIDs, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get saved messages"})
+		return
+	}
+	byID := map[string]model.ChatMessage{}
+	for _, message := range messages {
+		byID[message.ID.Hex()] = message
+	}
+
+	// 保存後に削除されたメッセージは一覧に含めない
+	items := make([]SavedMessageItem, 0, len(saved))
+	for _, item := range saved {
+		message, ok := byID[item.MessageID]
+		if !ok {
+			continue
+		}
+		items = append(items, SavedMessageItem{
+			ChatMessage: message,
+			RoomName:    roomNames[message.RoomID],
+			SavedAt:     item.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"saved": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (h *HandlerStruct) DeleteSavedMessageHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+
+	err := h.MongoSvc.DeleteSavedMessage(userID, c.Param("message_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrSavedMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved message deleted successfully"})
+}
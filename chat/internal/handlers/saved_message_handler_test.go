@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSaveMessageHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		message    model.ChatMessage
+		saveErr    error
+		expectCode int
+		expect     string
+	}{
+		// 閲覧のみのメンバーも保存できる
+		{"success", `{"message_id":"message1"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1"}, nil, http.StatusOK, "Message saved successfully"},
+		{"missing_message_id", `{}`, readOnlyRoomInfo, model.ChatMessage{}, nil, http.StatusBadRequest, "Invalid request"},
+		{"not_member", `{"message_id":"message1"}`, chat_svc.Room{}, model.ChatMessage{RoomID: "room1"}, nil, http.StatusForbidden, "Access denied"},
+		{"deleted_message", `{"message_id":"message1"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1", DeletedAt: &deletedAt}, nil, http.StatusGone, "Message has been deleted"},
+		{"save_error", `{"message_id":"message1"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1"}, assert.AnError, http.StatusInternalServerError, "Failed to save message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/me/saved", tt.body, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetChatMessage", "message1", mongoMockPkg).Return(tt.message, nil)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			var saved model.SavedMessage
+			mongoMockSvc.On("SaveMessage", mock.Anything, mongoMockPkg).Run(func(args mock.Arguments) {
+				saved = args.Get(0).(model.SavedMessage)
+			}).Return(tt.saveErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.SaveMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, 12345, saved.UserID)
+				assert.Equal(t, "room1", saved.RoomID)
+				assert.Equal(t, "message1", saved.MessageID)
+			}
+		})
+	}
+}
+
+func TestSavedMessagesHandler(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	kept := model.ChatMessage{ID: primitive.NewObjectID(), RoomID: roomID.Hex(), Message: "kept"}
+	deletedID := primitive.NewObjectID().Hex()
+	savedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	saved := []model.SavedMessage{
+		{RoomID: roomID.Hex(), MessageID: deletedID},
+		{RoomID: roomID.Hex(), MessageID: kept.ID.Hex(), CreatedAt: savedAt},
+	}
+
+	c, w := newJSONRequestContext("GET", "/me/saved?page=2&limit=5", "", nil)
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{{ID: roomID, Name: "general"}}, nil)
+	mongoMockSvc.On("GetSavedMessages", 12345, []string{roomID.Hex()}, 2, 5, mongoMockPkg).Return(saved, int64(7), nil)
+	mongoMockSvc.On("GetChatMessagesByIDs", []string{deletedID, kept.ID.Hex()}, mongoMockPkg).Return([]model.ChatMessage{kept}, nil)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.SavedMessagesHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Saved []SavedMessageItem `json:"saved"`
+		Total int64              `json:"total"`
+		Page  int                `json:"page"`
+		Limit int                `json:"limit"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	// 保存後に削除されたメッセージは含めない
+	if assert.Len(t, resp.Saved, 1) {
+		assert.Equal(t, "kept", resp.Saved[0].Message)
+		assert.Equal(t, "general", resp.Saved[0].RoomName)
+		assert.True(t, savedAt.Equal(resp.Saved[0].SavedAt))
+	}
+	assert.Equal(t, int64(7), resp.Total)
+	assert.Equal(t, 2, resp.Page)
+	assert.Equal(t, 5, resp.Limit)
+}
+
+func TestSavedMessagesHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		roomsErr error
+		savedErr error
+		getErr   error
+		expect   string
+	}{
+		{"rooms_error", assert.AnError, nil, nil, "Failed to get rooms"},
+		{"saved_error", nil, assert.AnError, nil, "Failed to get saved messages"},
+		{"messages_error", nil, nil, assert.AnError, "Failed to get saved messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/me/saved", "", nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{}, tt.roomsErr)
+			mongoMockSvc.On("GetSavedMessages", 12345, []string{}, 1, defaultPageLimit, mongoMockPkg).Return([]model.SavedMessage{}, int64(0), tt.savedErr)
+			mongoMockSvc.On("GetChatMessagesByIDs", []string{}, mongoMockPkg).Return([]model.ChatMessage{}, tt.getErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.SavedMessagesHandler(c)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestDeleteSavedMessageHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleteErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, "Saved message deleted successfully"},
+		{"not_found", mongo_svc.ErrSavedMessageNotFound, http.StatusNotFound, "Saved message not found"},
+		{"delete_error", assert.AnError, http.StatusInternalServerError, "Failed to delete saved message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/me/saved/message1", "", gin.Params{{Key: "message_id", Value: "message1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("DeleteSavedMessage", 12345, "message1", mongoMockPkg).Return(tt.deleteErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.DeleteSavedMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 予約できる最も先の日時
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+var errScheduledPostForbidden = errors.New("user can no longer post in the room")
+
+func validateSendAt(sendAt time.Time, now time.Time) error {
+	if !sendAt.After(now) {
+		return fmt.Errorf("send_at must be in the future")
+	}
+	if sendAt.After(now.Add(maxScheduleAhead)) {
+		return fmt.Errorf("send_at must be within %s", maxScheduleAhead)
+	}
+	return nil
+}
+
+type ScheduleMessageRequest struct {
+	Message string    `json:"message" binding:"required"`
+	ReplyTo string    `json:"reply_to"`
+	SendAt  time.Time `json:"send_at" binding:"required"` // RFC 3339
+}
+
+func (h *HandlerStruct) ScheduleMessageHandler(c *gin.Context) {
+	var req ScheduleMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	now := time.Now()
+	if err := validateSendAt(req.SendAt, now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	// コマンドは実行時の文脈に依存するため予約できない
+	if _, _, ok := model.ParseSlashCommand(req.Message); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slash commands cannot be scheduled"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityPost); !ok {
+		return
+	}
+	if req.ReplyTo != "" {
+		if _, err := h.getReplyTarget(roomID, req.ReplyTo); err != nil {
+			var postErr *postMessageError
+			errors.As(err, &postErr)
+			c.JSON(postErr.StatusCode(), gin.H{"error": postErr.Message, "details": postErr.Err.Error()})
+			return
+		}
+	}
+
+	scheduledID, err := h.MongoSvc.CreateScheduledMessage(model.ScheduledMessage{
+		Kind:      model.ScheduledKindMessage,
+		RoomID:    roomID,
+		UserID:    userID,
+		Message:   req.Message,
+		ReplyTo:   req.ReplyTo,
+		SendAt:    req.SendAt,
+		Status:    model.ScheduledPending,
+		CreatedAt: now,
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message scheduled successfully", "scheduled_id": scheduledID, "send_at": req.SendAt})
+}
+
+type RemindRequest struct {
+	SendAt *time.Time `json:"send_at"` // RFC 3339
+	In     string     `json:"in"`      // 現在からの時間（例: 2h, 30m）
+}
+
+// send_at と in のどちらか一方を指定する
+func (r RemindRequest) remindAt(now time.Time) (time.Time, error) {
+	if (r.SendAt == nil) == (r.In == "") {
+		return time.Time{}, fmt.Errorf("exactly one of send_at or in is required")
+	}
+	if r.SendAt != nil {
+		return *r.SendAt, nil
+	}
+	in, err := time.ParseDuration(r.In)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("in must be a duration such as 2h or 30m")
+	}
+	return now.Add(in), nil
+}
+
+func (h *HandlerStruct) RemindHandler(c *gin.Context) {
+	var req RemindRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	now := time.Now()
+	sendAt, err := req.remindAt(now)
+	if err == nil {
+		err = validateSendAt(sendAt, now)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	messageID := c.Param("id")
+
+	message, _, ok := h.getMessageFor(c, messageID, userID, chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+	if message.DeletedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Message has been deleted"})
+		return
+	}
+
+	scheduledID, err := h.MongoSvc.CreateScheduledMessage(model.ScheduledMessage{
+		Kind:      model.ScheduledKindReminder,
+		RoomID:    message.RoomID,
+		UserID:    userID,
+		MessageID: messageID,
+		SendAt:    sendAt,
+		Status:    model.ScheduledPending,
+		CreatedAt: now,
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set reminder", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reminder set successfully", "scheduled_id": scheduledID, "send_at": sendAt})
+}
+
+// 自分の未実行の予約投稿とリマインダーを実行する日時の順に返す
+func (h *HandlerStruct) ScheduledMessagesHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+
+	scheduled, err := h.MongoSvc.GetPendingScheduledMessages(int(jwtinfo.UserID), h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scheduled messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scheduled": scheduled})
+}
+
+func (h *HandlerStruct) CancelScheduledMessageHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+
+	err := h.MongoSvc.CancelScheduledMessage(int(jwtinfo.UserID), c.Param("scheduled_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrScheduledMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel scheduled message", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled message canceled successfully"})
+}
+
+// 予約投稿を実行時点の権限で投稿する（schedule_svc.PosterInterface の実装）
+func (h *HandlerStruct) PostScheduledMessage(scheduled model.ScheduledMessage) (string, error) {
+	room, err := h.MongoSvc.GetRoomByID(scheduled.RoomID, h.MongoPkg)
+	if err != nil {
+		return "", err
+	}
+	roomInfo := h.ChatSvc.GetRoomInfo(room, scheduled.UserID)
+	if !roomInfo.Can(chat_svc.CapabilityPost) {
+		return "", errScheduledPostForbidden
+	}
+	return h.postUserMessage(scheduled.RoomID, room, roomInfo, scheduled.UserID, scheduled.Message, scheduled.ReplyTo)
+}
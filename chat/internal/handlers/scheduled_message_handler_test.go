@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateSendAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, validateSendAt(now.Add(time.Minute), now))
+	assert.NoError(t, validateSendAt(now.Add(maxScheduleAhead), now))
+	assert.Error(t, validateSendAt(now, now))
+	assert.Error(t, validateSendAt(now.Add(-time.Minute), now))
+	assert.Error(t, validateSendAt(now.Add(maxScheduleAhead+time.Second), now))
+}
+
+func TestRemindRequestRemindAt(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sendAt := now.Add(3 * time.Hour)
+
+	tests := []struct {
+		name      string
+		req       RemindRequest
+		expect    time.Time
+		expectErr bool
+	}{
+		{"in", RemindRequest{In: "2h"}, now.Add(2 * time.Hour), false},
+		{"send_at", RemindRequest{SendAt: &sendAt}, sendAt, false},
+		{"both", RemindRequest{In: "2h", SendAt: &sendAt}, time.Time{}, true},
+		{"neither", RemindRequest{}, time.Time{}, true},
+		{"invalid_duration", RemindRequest{In: "two hours"}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remindAt, err := tt.req.remindAt(now)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, remindAt)
+		})
+	}
+}
+
+func TestScheduleMessageHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		replyErr   error
+		createErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"message":"standup","send_at":"` + future + `"}`, memberRoomInfo, nil, nil, http.StatusOK, `"scheduled_id":"scheduled1"`},
+		{"reply", `{"message":"standup","reply_to":"parent1","send_at":"` + future + `"}`, memberRoomInfo, nil, nil, http.StatusOK, "Message scheduled successfully"},
+		{"missing_send_at", `{"message":"standup"}`, memberRoomInfo, nil, nil, http.StatusBadRequest, "Invalid request"},
+		{"past", `{"message":"standup","send_at":"` + past + `"}`, memberRoomInfo, nil, nil, http.StatusBadRequest, "send_at must be in the future"},
+		{"slash_command", `{"message":"/topic later","send_at":"` + future + `"}`, memberRoomInfo, nil, nil, http.StatusBadRequest, "Slash commands cannot be scheduled"},
+		{"read_only", `{"message":"standup","send_at":"` + future + `"}`, readOnlyRoomInfo, nil, nil, http.StatusForbidden, "Access denied"},
+		{"reply_invalid", `{"message":"standup","reply_to":"parent1","send_at":"` + future + `"}`, memberRoomInfo, mongo_svc.ErrInvalidChatMessageID, nil, http.StatusBadRequest, "Invalid reply_to"},
+		{"reply_not_found", `{"message":"standup","reply_to":"parent1","send_at":"` + future + `"}`, memberRoomInfo, mongo_svc.ErrChatMessageNotFound, nil, http.StatusNotFound, "Reply target not found"},
+		{"reply_error", `{"message":"standup","reply_to":"parent1","send_at":"` + future + `"}`, memberRoomInfo, assert.AnError, nil, http.StatusInternalServerError, "Failed to get reply target"},
+		{"create_error", `{"message":"standup","send_at":"` + future + `"}`, memberRoomInfo, nil, assert.AnError, http.StatusInternalServerError, "Failed to schedule message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/scheduled", tt.body, gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetChatMessageByID", "room1", "parent1", mongoMockPkg).Return(model.ChatMessage{}, tt.replyErr)
+			var created model.ScheduledMessage
+			mongoMockSvc.On("CreateScheduledMessage", mock.Anything, mongoMockPkg).Run(func(args mock.Arguments) {
+				created = args.Get(0).(model.ScheduledMessage)
+			}).Return("scheduled1", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.ScheduleMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, model.ScheduledKindMessage, created.Kind)
+				assert.Equal(t, model.ScheduledPending, created.Status)
+				assert.Equal(t, "room1", created.RoomID)
+				assert.Equal(t, 12345, created.UserID)
+				assert.Equal(t, "standup", created.Message)
+			}
+		})
+	}
+}
+
+func TestRemindHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		message    model.ChatMessage
+		createErr  error
+		expectCode int
+		expect     string
+	}{
+		// 閲覧のみのメンバーもリマインダーを設定できる
+		{"success", `{"in":"2h"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1"}, nil, http.StatusOK, "Reminder set successfully"},
+		{"invalid_duration", `{"in":"soon"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1"}, nil, http.StatusBadRequest, "in must be a duration"},
+		{"past", `{"in":"-1h"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1"}, nil, http.StatusBadRequest, "send_at must be in the future"},
+		{"not_member", `{"in":"2h"}`, chat_svc.Room{}, model.ChatMessage{RoomID: "room1"}, nil, http.StatusForbidden, "Access denied"},
+		{"deleted_message", `{"in":"2h"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1", DeletedAt: &deletedAt}, nil, http.StatusGone, "Message has been deleted"},
+		{"create_error", `{"in":"2h"}`, readOnlyRoomInfo, model.ChatMessage{RoomID: "room1"}, assert.AnError, http.StatusInternalServerError, "Failed to set reminder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/messages/message1/remind", tt.body, gin.Params{{Key: "id", Value: "message1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetChatMessage", "message1", mongoMockPkg).Return(tt.message, nil)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			var created model.ScheduledMessage
+			mongoMockSvc.On("CreateScheduledMessage", mock.Anything, mongoMockPkg).Run(func(args mock.Arguments) {
+				created = args.Get(0).(model.ScheduledMessage)
+			}).Return("scheduled1", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			before := time.Now()
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.RemindHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Equal(t, model.ScheduledKindReminder, created.Kind)
+				assert.Equal(t, "message1", created.MessageID)
+				assert.Equal(t, "room1", created.RoomID)
+				assert.WithinDuration(t, before.Add(2*time.Hour), created.SendAt, time.Minute)
+			}
+		})
+	}
+}
+
+func TestScheduledMessagesHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		getErr     error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, `"Message":"standup"`},
+		{"get_error", assert.AnError, http.StatusInternalServerError, "Failed to get scheduled messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/me/scheduled", "", nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetPendingScheduledMessages", 12345, mongoMockPkg).Return([]model.ScheduledMessage{
+				{ID: primitive.NewObjectID(), Kind: model.ScheduledKindMessage, Message: "standup"},
+			}, tt.getErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.ScheduledMessagesHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestCancelScheduledMessageHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancelErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, "Scheduled message canceled successfully"},
+		{"not_found", mongo_svc.ErrScheduledMessageNotFound, http.StatusNotFound, "Scheduled message not found"},
+		{"cancel_error", assert.AnError, http.StatusInternalServerError, "Failed to cancel scheduled message"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/me/scheduled/scheduled1", "", gin.Params{{Key: "scheduled_id", Value: "scheduled1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("CancelScheduledMessage", 12345, "scheduled1", mongoMockPkg).Return(tt.cancelErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+			handler.CancelScheduledMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestPostScheduledMessage(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	scheduled := model.ScheduledMessage{Kind: model.ScheduledKindMessage, RoomID: "room1", UserID: 12345, Message: "standup"}
+
+	tests := []struct {
+		name      string
+		roomInfo  chat_svc.Room
+		expectErr error
+	}{
+		{"success", memberRoomInfo, nil},
+		// 予約後に退出・閲覧のみに変更された場合は投稿しない
+		{"forbidden", readOnlyRoomInfo, errScheduledPostForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("PostChatMessage", model.ChatMessage{RoomID: "room1", UserID: 12345, Message: "standup"}, mongoMockPkg).Return("message1", nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			messageID, err := handler.PostScheduledMessage(scheduled)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				mongoMockSvc.AssertNotCalled(t, "PostChatMessage", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "message1", messageID)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchChatMessagesRequest struct {
+	Query  string `form:"q" binding:"required"`
+	RoomID string `form:"room_id"`
+	From   string `form:"from"`
+	To     string `form:"to"`
+	UserID int    `form:"user_id"`
+	PaginationRequest
+}
+
+// RFC3339形式の日時を変換する（未指定の場合はnil）
+func parseSearchTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (h *HandlerStruct) SearchChatMessagesHandler(c *gin.Context) {
+	var req SearchChatMessagesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	from, err := parseSearchTime(req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from", "details": err.Error()})
+		return
+	}
+	to, err := parseSearchTime(req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to", "details": err.Error()})
+		return
+	}
+
+	page, limit := req.normalize()
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+
+	// 検索対象は参加済みのルームに限定する
+	rooms, err := h.MongoSvc.GetRooms(int(userID), "joined", h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rooms", "details": err.Error()})
+		return
+	}
+
+	roomIDs := []string{}
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID.Hex())
+	}
+	if req.RoomID != "" {
+		if !containsString(roomIDs, req.RoomID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		roomIDs = []string{req.RoomID}
+	}
+
+	hits, total, err := h.MongoSvc.SearchChatMessages(mongo_svc.SearchChatMessagesQuery{
+		Keyword: req.Query,
+		RoomIDs: roomIDs,
+		UserID:  req.UserID,
+		From:    from,
+		To:      to,
+		Page:    page,
+		Limit:   limit,
+	}, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search chat messages", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": h.ChatSvc.ConvertSearchResults(hits, req.Query),
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func containsString(list []string, target string) bool {
+	for _, v := range list {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var searchRoomID, _ = primitive.ObjectIDFromHex("64a7b2f4e13e4c3f9c8b4567")
+
+func newSearchContext(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	return newJSONRequestContext("GET", "/search?"+query, "", nil)
+}
+
+func TestSearchChatMessagesHandler(t *testing.T) {
+	c, w := newSearchContext("q=hello&room_id=64a7b2f4e13e4c3f9c8b4567&from=2025-01-01T00:00:00Z&user_id=99999&page=2&limit=500")
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	hits := []model.ChatMessageSearchHit{{ChatMessage: model.ChatMessage{Message: "hello world"}, Score: 1.1}}
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{{ID: searchRoomID}}, nil)
+	mongoMockSvc.On("SearchChatMessages", mongo_svc.SearchChatMessagesQuery{
+		Keyword: "hello",
+		RoomIDs: []string{"64a7b2f4e13e4c3f9c8b4567"},
+		UserID:  99999,
+		From:    &from,
+		Page:    2,
+		Limit:   100,
+	}, mongoMockPkg).Return(hits, int64(101), nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("ConvertSearchResults", hits, "hello").Return([]chat_svc.SearchResult{{Snippet: "<mark>hello</mark> world"}})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.SearchChatMessagesHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":101`)
+	assert.Contains(t, w.Body.String(), `"limit":100`)
+	mongoMockSvc.AssertExpectations(t)
+}
+
+func TestSearchChatMessagesHandlerAllJoinedRooms(t *testing.T) {
+	c, w := newSearchContext("q=hello")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{{ID: searchRoomID}}, nil)
+	mongoMockSvc.On("SearchChatMessages", mongo_svc.SearchChatMessagesQuery{
+		Keyword: "hello",
+		RoomIDs: []string{"64a7b2f4e13e4c3f9c8b4567"},
+		Page:    1,
+		Limit:   defaultPageLimit,
+	}, mongoMockPkg).Return([]model.ChatMessageSearchHit{}, int64(0), nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("ConvertSearchResults", []model.ChatMessageSearchHit{}, "hello").Return([]chat_svc.SearchResult{})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.SearchChatMessagesHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"results":[]`)
+}
+
+func TestSearchChatMessagesHandlerInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{"missing_q", "room_id=64a7b2f4e13e4c3f9c8b4567", "Invalid request"},
+		{"invalid_from", "q=hello&from=yesterday", "Invalid from"},
+		{"invalid_to", "q=hello&to=tomorrow", "Invalid to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newSearchContext(tt.query)
+
+			handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+			handler.SearchChatMessagesHandler(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestSearchChatMessagesHandlerGetRoomsError(t *testing.T) {
+	c, w := newSearchContext("q=hello")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.SearchChatMessagesHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get rooms")
+}
+
+func TestSearchChatMessagesHandlerNotJoinedRoom(t *testing.T) {
+	c, w := newSearchContext("q=hello&room_id=64a7b2f4e13e4c3f9c8b9999")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{{ID: searchRoomID}}, nil)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.SearchChatMessagesHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestSearchChatMessagesHandlerSearchError(t *testing.T) {
+	c, w := newSearchContext("q=hello")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRooms", 12345, "joined", mongoMockPkg).Return([]model.Room{{ID: searchRoomID}}, nil)
+	mongoMockSvc.On("SearchChatMessages", mongo_svc.SearchChatMessagesQuery{
+		Keyword: "hello",
+		RoomIDs: []string{"64a7b2f4e13e4c3f9c8b4567"},
+		Page:    1,
+		Limit:   defaultPageLimit,
+	}, mongoMockPkg).Return([]model.ChatMessageSearchHit{}, int64(0), assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.SearchChatMessagesHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to search chat messages")
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/command_pkg"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 組み込みのコマンドがルームに投稿する場合の BotID
+const builtinSlashCommandBotID = "builtin"
+
+// コマンドを実行したルームとユーザー
+type slashCommandContext struct {
+	Name     string
+	Args     string
+	RoomID   string
+	Room     model.Room
+	RoomInfo chat_svc.Room
+	UserID   int
+}
+
+type slashCommandResult struct {
+	ResponseType string // model.SlashResponseEphemeral / model.SlashResponseInChannel
+	Text         string
+}
+
+func ephemeral(text string) slashCommandResult {
+	return slashCommandResult{ResponseType: model.SlashResponseEphemeral, Text: text}
+}
+
+// 使い方の誤りや権限不足など、ステータスコードとメッセージをそのまま返すエラー
+type slashCommandError struct {
+	Code    int
+	Message string
+}
+
+func (e *slashCommandError) Error() string {
+	return e.Message
+}
+
+type builtinSlashCommand struct {
+	Usage       string
+	Description string
+	Run         func(h *HandlerStruct, cmd slashCommandContext) (slashCommandResult, error)
+}
+
+var builtinSlashCommands map[string]builtinSlashCommand
+
+// /help が一覧を参照するため init で登録する
+func init() {
+	builtinSlashCommands = map[string]builtinSlashCommand{
+		"help":   {"/help", "Show available commands", (*HandlerStruct).slashHelp},
+		"topic":  {"/topic [text]", "Show or change the room topic", (*HandlerStruct).slashTopic},
+		"invite": {"/invite @user", "Invite a user to the room", (*HandlerStruct).slashInvite},
+		"mute":   {"/mute", "Mute notifications for the room", (*HandlerStruct).slashMute},
+		"unmute": {"/unmute", "Unmute notifications for the room", (*HandlerStruct).slashUnmute},
+	}
+}
+
+func builtinSlashCommandNames() []string {
+	names := make([]string, 0, len(builtinSlashCommands))
+	for name := range builtinSlashCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// コマンドを実行して応答を返す。in_channel の応答はボットとしてルームに投稿する
+func (h *HandlerStruct) runSlashCommand(c *gin.Context, cmd slashCommandContext) {
+	var result slashCommandResult
+	var err error
+	botID := builtinSlashCommandBotID
+	if builtin, ok := builtinSlashCommands[cmd.Name]; ok {
+		result, err = builtin.Run(h, cmd)
+	} else {
+		result, botID, err = h.invokeExternalSlashCommand(c.Request.Context(), cmd)
+	}
+
+	var cmdErr *slashCommandError
+	if errors.As(err, &cmdErr) {
+		c.JSON(cmdErr.Code, gin.H{"error": cmdErr.Message})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run command", "details": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message":       "Command executed",
+		"command":       cmd.Name,
+		"response_type": result.ResponseType,
+		"text":          result.Text,
+	}
+	if result.ResponseType == model.SlashResponseInChannel {
+		messageID, err := h.postBotMessage(cmd.RoomID, cmd.Room, botID, "/"+cmd.Name, result.Text)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post chat", "details": err.Error()})
+			return
+		}
+		response["message_id"] = messageID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ルームに登録された外部のコマンドを HTTP で呼び出す
+func (h *HandlerStruct) invokeExternalSlashCommand(ctx context.Context, cmd slashCommandContext) (slashCommandResult, string, error) {
+	unknown := &slashCommandError{Code: http.StatusBadRequest, Message: "Unknown command: /" + cmd.Name}
+	if h.CommandClient == nil {
+		return slashCommandResult{}, "", unknown
+	}
+
+	command, err := h.MongoSvc.GetSlashCommand(cmd.RoomID, cmd.Name, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrSlashCommandNotFound) {
+		return slashCommandResult{}, "", unknown
+	}
+	if err != nil {
+		return slashCommandResult{}, "", err
+	}
+
+	resp, err := h.CommandClient.Invoke(ctx, command_pkg.Request{
+		URL:    command.URL,
+		Secret: command.Secret,
+		Payload: command_pkg.Payload{
+			CommandID: command.ID.Hex(),
+			Command:   cmd.Name,
+			Text:      cmd.Args,
+			RoomID:    cmd.RoomID,
+			UserID:    cmd.UserID,
+		},
+	}, time.Now())
+	if err != nil {
+		log.Printf("failed to invoke command /%s for room %s: %v", cmd.Name, cmd.RoomID, err)
+		return slashCommandResult{}, "", &slashCommandError{Code: http.StatusBadGateway, Message: "Command failed"}
+	}
+
+	// 不明な応答種別や空の投稿は呼び出し元にのみ返す
+	if resp.ResponseType != model.SlashResponseInChannel || strings.TrimSpace(resp.Text) == "" {
+		return ephemeral(resp.Text), command.ID.Hex(), nil
+	}
+	return slashCommandResult{ResponseType: model.SlashResponseInChannel, Text: resp.Text}, command.ID.Hex(), nil
+}
+
+func (h *HandlerStruct) slashHelp(cmd slashCommandContext) (slashCommandResult, error) {
+	lines := []string{}
+	for _, name := range builtinSlashCommandNames() {
+		builtin := builtinSlashCommands[name]
+		lines = append(lines, builtin.Usage+" - "+builtin.Description)
+	}
+
+	commands, err := h.MongoSvc.GetSlashCommands(cmd.RoomID, h.MongoPkg)
+	if err != nil {
+		return slashCommandResult{}, err
+	}
+	for _, command := range commands {
+		lines = append(lines, "/"+command.Name+" - "+command.Description)
+	}
+	return ephemeral(strings.Join(lines, "\n")), nil
+}
+
+// 引数が無い場合は現在のトピックを返し、ある場合はトピックを変更する（オーナーのみ）
+func (h *HandlerStruct) slashTopic(cmd slashCommandContext) (slashCommandResult, error) {
+	if cmd.Args == "" {
+		if cmd.Room.Topic == "" {
+			return ephemeral("This room has no topic"), nil
+		}
+		return ephemeral("Topic: " + cmd.Room.Topic), nil
+	}
+	if !cmd.RoomInfo.Can(chat_svc.CapabilityManage) {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusForbidden, Message: "Access denied"}
+	}
+	if utf8.RuneCountInString(cmd.Args) > maxRoomTopicLength {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusBadRequest, Message: "Topic is too long"}
+	}
+
+	err := h.MongoSvc.UpdateRoom(cmd.RoomID, mongo_svc.UpdateRoomFields{Topic: &cmd.Args}, time.Now(), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrRoomArchived) {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusConflict, Message: "Room is archived"}
+	}
+	if err != nil {
+		return slashCommandResult{}, err
+	}
+	return slashCommandResult{ResponseType: model.SlashResponseInChannel, Text: "Topic changed to: " + cmd.Args}, nil
+}
+
+// 指定したユーザーをルームに追加する。プライベートルームはモデレーター以上のみ
+func (h *HandlerStruct) slashInvite(cmd slashCommandContext) (slashCommandResult, error) {
+	mentions := model.ParseMentions(cmd.Args)
+	if len(mentions.Handles) != 1 {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusBadRequest, Message: "Usage: /invite @user"}
+	}
+	handle := mentions.Handles[0]
+	if cmd.RoomInfo.IsDirect {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusBadRequest, Message: "Cannot invite to a direct message"}
+	}
+	if cmd.RoomInfo.IsPrivate && !cmd.RoomInfo.Can(chat_svc.CapabilityModerate) {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusForbidden, Message: "Access denied"}
+	}
+
+	users, err := h.MongoSvc.FindUsersByHandle(handle, h.MongoPkg)
+	if err != nil {
+		return slashCommandResult{}, err
+	}
+	switch len(users) {
+	case 0:
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusNotFound, Message: "User not found: @" + handle}
+	case 1:
+	default:
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusConflict, Message: "Multiple users match @" + handle}
+	}
+	target := users[0].UserID
+
+	if containsInt(cmd.Room.Members, target) {
+		return ephemeral(fmt.Sprintf("@%s is already a member", handle)), nil
+	}
+	if containsInt(cmd.Room.Banned, target) {
+		return slashCommandResult{}, &slashCommandError{Code: http.StatusConflict, Message: "User is banned from this room"}
+	}
+
+	// 本人が承諾するまでメンバーにはしない。招待は招待されたユーザーだけが1回使える
+	code, err := generateInviteCode()
+	if err != nil {
+		return slashCommandResult{}, err
+	}
+	now := time.Now()
+	invite := model.RoomInvite{
+		RoomID:        cmd.RoomID,
+		Code:          code,
+		CreatedBy:     cmd.UserID,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(roomInviteTTL()),
+		MaxUses:       1,
+		InvitedUserID: target,
+	}
+	if _, err := h.MongoSvc.CreateRoomInvite(invite, h.MongoPkg); err != nil {
+		return slashCommandResult{}, err
+	}
+	return ephemeral(fmt.Sprintf("Invited @%s. Share this link with them: /invites/%s/accept", handle, code)), nil
+}
+
+func (h *HandlerStruct) slashMute(cmd slashCommandContext) (slashCommandResult, error) {
+	if err := h.MongoSvc.SetRoomMuted(cmd.UserID, cmd.RoomID, true, h.MongoPkg); err != nil {
+		return slashCommandResult{}, err
+	}
+	return ephemeral("Notifications for this room are muted"), nil
+}
+
+func (h *HandlerStruct) slashUnmute(cmd slashCommandContext) (slashCommandResult, error) {
+	if err := h.MongoSvc.SetRoomMuted(cmd.UserID, cmd.RoomID, false, h.MongoPkg); err != nil {
+		return slashCommandResult{}, err
+	}
+	return ephemeral("Notifications for this room are unmuted"), nil
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateSlashCommandRequest struct {
+	Name        string `json:"name" binding:"required"`
+	URL         string `json:"url" binding:"required"`
+	Description string `json:"description" binding:"max=200"`
+}
+
+func (r CreateSlashCommandRequest) validate(ctx context.Context) string {
+	if !model.ValidSlashCommandName(r.Name) {
+		return "Command name must be lowercase letters, digits, '-' or '_'"
+	}
+	if _, ok := builtinSlashCommands[r.Name]; ok {
+		return "Command name is reserved"
+	}
+	if err := netguard_pkg.CheckURL(ctx, r.URL); err != nil {
+		if errors.Is(err, netguard_pkg.ErrForbiddenAddress) {
+			return "URL must not point to an internal address"
+		}
+		return "URL must be an http(s) URL"
+	}
+	return ""
+}
+
+// 外部のコマンドをルームに登録する。署名用の鍵はこのレスポンスでのみ返す
+func (h *HandlerStruct) CreateSlashCommandHandler(c *gin.Context) {
+	var req CreateSlashCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if msg := req.validate(c.Request.Context()); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := int(jwtinfo.UserID)
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, userID, chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate command secret"})
+		return
+	}
+
+	commandID, err := h.MongoSvc.CreateSlashCommand(model.SlashCommand{
+		RoomID:      roomID,
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+		Secret:      secret,
+		CreatedBy:   userID,
+		CreatedAt:   time.Now(),
+	}, h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrSlashCommandExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Command already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create command", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Command created successfully",
+		"command_id": commandID,
+		"secret":     secret,
+	})
+}
+
+type BuiltinSlashCommandItem struct {
+	Name        string
+	Usage       string
+	Description string
+}
+
+// 組み込みのコマンドとルームに登録された外部のコマンドを返す
+func (h *HandlerStruct) SlashCommandsHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	commands, err := h.MongoSvc.GetSlashCommands(roomID, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get commands"})
+		return
+	}
+
+	builtins := []BuiltinSlashCommandItem{}
+	for _, name := range builtinSlashCommandNames() {
+		builtin := builtinSlashCommands[name]
+		builtins = append(builtins, BuiltinSlashCommandItem{Name: name, Usage: builtin.Usage, Description: builtin.Description})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"builtin": builtins, "commands": commands})
+}
+
+func (h *HandlerStruct) DeleteSlashCommandHandler(c *gin.Context) {
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	if _, _, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage); !ok {
+		return
+	}
+
+	err := h.MongoSvc.DeleteSlashCommand(roomID, c.Param("command_id"), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrSlashCommandNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Command not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete command", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Command deleted successfully"})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateSlashCommandHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		createErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"name":"deploy","url":"https://example.com/deploy","description":"Deploy the app"}`, ownerRoomInfo, nil, http.StatusOK, `"secret":"`},
+		{"missing_url", `{"name":"deploy"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Invalid request"},
+		{"invalid_name", `{"name":"Deploy!","url":"https://example.com/deploy"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Command name must be"},
+		{"reserved_name", `{"name":"topic","url":"https://example.com/deploy"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Command name is reserved"},
+		{"invalid_url", `{"name":"deploy","url":"file:///etc/passwd"}`, ownerRoomInfo, nil, http.StatusBadRequest, "URL must be an http(s) URL"},
+		{"internal_url", `{"name":"deploy","url":"http://127.0.0.1:8080/deploy"}`, ownerRoomInfo, nil, http.StatusBadRequest, "URL must not point to an internal address"},
+		{"not_owner", `{"name":"deploy","url":"https://example.com/deploy"}`, moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"exists", `{"name":"deploy","url":"https://example.com/deploy"}`, ownerRoomInfo, mongo_svc.ErrSlashCommandExists, http.StatusConflict, "Command already exists"},
+		{"create_error", `{"name":"deploy","url":"https://example.com/deploy"}`, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to create command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/rooms/room1/commands", tt.body, gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("CreateSlashCommand", mock.MatchedBy(func(command model.SlashCommand) bool {
+				return command.RoomID == "room1" && command.Name == "deploy" && command.CreatedBy == 12345 && command.Secret != ""
+			}), mongoMockPkg).Return("command1", tt.createErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CreateSlashCommandHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestSlashCommandsHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+
+	tests := []struct {
+		name       string
+		getErr     error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, `"Name":"deploy"`},
+		{"get_error", assert.AnError, http.StatusInternalServerError, "Failed to get commands"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("GET", "/rooms/room1/commands", "", gin.Params{{Key: "id", Value: "room1"}})
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetSlashCommands", "room1", mongoMockPkg).Return([]model.SlashCommand{{Name: "deploy", Secret: "secret"}}, tt.getErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.SlashCommandsHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if tt.expectCode == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"Usage":"/invite @user"`)
+				assert.NotContains(t, w.Body.String(), "secret")
+			}
+		})
+	}
+}
+
+func TestDeleteSlashCommandHandler(t *testing.T) {
+	room := model.Room{Members: []int{12345}}
+	params := gin.Params{{Key: "id", Value: "room1"}, {Key: "command_id", Value: "command1"}}
+
+	tests := []struct {
+		name       string
+		deleteErr  error
+		expectCode int
+		expect     string
+	}{
+		{"success", nil, http.StatusOK, "Command deleted successfully"},
+		{"not_found", mongo_svc.ErrSlashCommandNotFound, http.StatusNotFound, "Command not found"},
+		{"delete_error", assert.AnError, http.StatusInternalServerError, "Failed to delete command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("DELETE", "/rooms/room1/commands/command1", "", params)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "room1", mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("DeleteSlashCommand", "room1", "command1", mongoMockPkg).Return(tt.deleteErr)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(ownerRoomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.DeleteSlashCommandHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/command_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_command_pkg"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuiltinSlashCommands(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name       string
+		message    string
+		room       model.Room
+		roomInfo   chat_svc.Room
+		updateErr  error
+		users      []model.User
+		inviteErr  error
+		expectCode int
+		expect     string
+		expectPost bool
+	}{
+		{"topic_show", "/topic", model.Room{Topic: "Release day"}, memberRoomInfo, nil, nil, nil, http.StatusOK, "Topic: Release day", false},
+		{"topic_set", "/topic Ship it", model.Room{}, ownerRoomInfo, nil, nil, nil, http.StatusOK, `"response_type":"in_channel"`, true},
+		{"topic_set_by_member", "/topic Ship it", model.Room{}, memberRoomInfo, nil, nil, nil, http.StatusForbidden, "Access denied", false},
+		{"topic_archived", "/topic Ship it", model.Room{}, ownerRoomInfo, mongo_svc.ErrRoomArchived, nil, nil, http.StatusConflict, "Room is archived", false},
+		// 招待されたユーザーが承諾するまでメンバーにはしない
+		{"invite", "/invite @bob", model.Room{Members: []int{12345}}, memberRoomInfo, nil, []model.User{{UserID: 2}}, nil, http.StatusOK, "Invited @bob. Share this link with them: /invites/", false},
+		{"invite_member", "/invite @bob", model.Room{Members: []int{12345, 2}}, memberRoomInfo, nil, []model.User{{UserID: 2}}, nil, http.StatusOK, "@bob is already a member", false},
+		{"invite_usage", "/invite bob", model.Room{}, memberRoomInfo, nil, nil, nil, http.StatusBadRequest, "Usage: /invite @user", false},
+		{"invite_private_by_member", "/invite @bob", model.Room{}, chat_svc.Room{IsPrivate: true, Capabilities: memberRoomInfo.Capabilities}, nil, nil, nil, http.StatusForbidden, "Access denied", false},
+		{"invite_unknown_user", "/invite @bob", model.Room{}, memberRoomInfo, nil, []model.User{}, nil, http.StatusNotFound, "User not found: @bob", false},
+		{"invite_ambiguous", "/invite @bob", model.Room{}, memberRoomInfo, nil, []model.User{{UserID: 2}, {UserID: 3}}, nil, http.StatusConflict, "Multiple users match @bob", false},
+		{"invite_banned", "/invite @bob", model.Room{Banned: []int{2}}, memberRoomInfo, nil, []model.User{{UserID: 2}}, nil, http.StatusConflict, "User is banned from this room", false},
+		{"invite_error", "/invite @bob", model.Room{}, memberRoomInfo, nil, []model.User{{UserID: 2}}, assert.AnError, http.StatusInternalServerError, "Failed to run command", false},
+		{"mute", "/mute", model.Room{}, memberRoomInfo, nil, nil, nil, http.StatusOK, "Notifications for this room are muted", false},
+		{"unmute", "/unmute", model.Room{}, memberRoomInfo, nil, nil, nil, http.StatusOK, "Notifications for this room are unmuted", false},
+		{"help", "/help", model.Room{}, memberRoomInfo, nil, nil, nil, http.StatusOK, `/deploy - Deploy the app`, false},
+		// 外部のコマンドを実行できない場合は不明なコマンドとして扱う
+		{"unknown", "/deploy prod", model.Room{}, memberRoomInfo, nil, nil, nil, http.StatusBadRequest, "Unknown command: /deploy", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/post_chat_message", `{"room_id":"`+roomID+`","message":"`+tt.message+`"}`, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", roomID, mongoMockPkg).Return(tt.room, nil)
+			mongoMockSvc.On("UpdateRoom", roomID, mock.MatchedBy(func(fields mongo_svc.UpdateRoomFields) bool {
+				return fields.Topic != nil && *fields.Topic == "Ship it"
+			}), mock.Anything, mongoMockPkg).Return(tt.updateErr)
+			mongoMockSvc.On("FindUsersByHandle", "bob", mongoMockPkg).Return(tt.users, nil)
+			mongoMockSvc.On("CreateRoomInvite", mock.MatchedBy(func(invite model.RoomInvite) bool {
+				return invite.RoomID == roomID && invite.Code != "" && invite.CreatedBy == 12345 &&
+					invite.MaxUses == 1 && invite.InvitedUserID == 2 && invite.ExpiresAt.After(invite.CreatedAt)
+			}), mongoMockPkg).Return("invite_id", tt.inviteErr)
+			mongoMockSvc.On("SetRoomMuted", 12345, roomID, tt.name == "mute", mongoMockPkg).Return(nil)
+			mongoMockSvc.On("GetSlashCommands", roomID, mongoMockPkg).Return([]model.SlashCommand{{Name: "deploy", Description: "Deploy the app"}}, nil)
+			mongoMockSvc.On("PostChatMessage", model.ChatMessage{
+				RoomID:  roomID,
+				Message: "Topic changed to: Ship it",
+				BotID:   builtinSlashCommandBotID,
+				BotName: "/topic",
+			}, mongoMockPkg).Return("bot_message_id", nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", tt.room, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.PostChatMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			mongoMockSvc.AssertNotCalled(t, "JoinRoom", mock.Anything, mock.Anything, mock.Anything)
+			if tt.expectPost {
+				assert.Contains(t, w.Body.String(), `"message_id":"bot_message_id"`)
+			} else {
+				// コマンドの本文はメッセージとして投稿しない
+				mongoMockSvc.AssertNotCalled(t, "PostChatMessage", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestExternalSlashCommand(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	room := model.Room{Members: []int{12345}}
+	command := model.SlashCommand{ID: primitive.NewObjectID(), RoomID: roomID, Name: "deploy", URL: "https://example.com/deploy", Secret: "secret"}
+
+	tests := []struct {
+		name       string
+		findErr    error
+		response   command_pkg.Response
+		invokeErr  error
+		expectCode int
+		expect     string
+		expectPost bool
+	}{
+		{"in_channel", nil, command_pkg.Response{ResponseType: "in_channel", Text: "Deploying prod"}, nil, http.StatusOK, `"message_id":"bot_message_id"`, true},
+		{"ephemeral", nil, command_pkg.Response{Text: "Only you can see this"}, nil, http.StatusOK, `"response_type":"ephemeral"`, false},
+		{"empty_in_channel", nil, command_pkg.Response{ResponseType: "in_channel"}, nil, http.StatusOK, `"response_type":"ephemeral"`, false},
+		{"not_found", mongo_svc.ErrSlashCommandNotFound, command_pkg.Response{}, nil, http.StatusBadRequest, "Unknown command: /deploy", false},
+		{"find_error", assert.AnError, command_pkg.Response{}, nil, http.StatusInternalServerError, "Failed to run command", false},
+		{"invoke_error", nil, command_pkg.Response{}, assert.AnError, http.StatusBadGateway, "Command failed", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("POST", "/post_chat_message", `{"room_id":"`+roomID+`","message":"/deploy prod"}`, nil)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", roomID, mongoMockPkg).Return(room, nil)
+			mongoMockSvc.On("GetSlashCommand", roomID, "deploy", mongoMockPkg).Return(command, tt.findErr)
+			mongoMockSvc.On("PostChatMessage", model.ChatMessage{
+				RoomID:  roomID,
+				Message: "Deploying prod",
+				BotID:   command.ID.Hex(),
+				BotName: "/deploy",
+			}, mongoMockPkg).Return("bot_message_id", nil)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", room, 12345).Return(memberRoomInfo)
+			commandClientMock := new(mock_command_pkg.ClientMock)
+			commandClientMock.On("Invoke", mock.Anything, command_pkg.Request{
+				URL:    command.URL,
+				Secret: "secret",
+				Payload: command_pkg.Payload{
+					CommandID: command.ID.Hex(),
+					Command:   "deploy",
+					Text:      "prod",
+					RoomID:    roomID,
+					UserID:    12345,
+				},
+			}, mock.Anything).Return(tt.response, tt.invokeErr)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.CommandClient = commandClientMock
+			handler.PostChatMessageHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+			if !tt.expectPost {
+				mongoMockSvc.AssertNotCalled(t, "PostChatMessage", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HandlerStruct) ThreadHandler(c *gin.Context) {
+	var req PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	page, limit := req.normalize()
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	userID := jwtinfo.UserID
+
+	message, _, ok := h.getMessageFor(c, c.Param("id"), int(userID), chat_svc.CapabilityRead)
+	if !ok {
+		return
+	}
+
+	// 返信を指定された場合もスレッドの起点から表示する
+	var err error
+	root := message
+	if message.ThreadRootID != "" {
+		root, err = h.MongoSvc.GetChatMessage(message.ThreadRootID, h.MongoPkg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get thread root"})
+			return
+		}
+	}
+	if root.DeletedAt != nil {
+		root = root.Tombstone()
+	}
+	// 編集履歴は投稿者とモデレーターのみが履歴APIで参照できる
+	root.Revisions = nil
+	root.ReactionCounts = root.CountReactions()
+
+	replies, total, err := h.MongoSvc.GetThreadMessages(root.ID.Hex(), page, limit, h.MongoPkg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get thread messages"})
+		return
+	}
+	h.signThumbnailURLs(append([]model.ChatMessage{root}, replies...))
+
+	c.JSON(http.StatusOK, gin.H{
+		"root":    root,
+		"replies": replies,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
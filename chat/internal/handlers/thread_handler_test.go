@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_attachment_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newThreadContext(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	return newJSONRequestContext("GET", "/messages/"+threadReplyID.Hex()+"/thread?"+query, "", gin.Params{{Key: "id", Value: threadReplyID.Hex()}})
+}
+
+func TestThreadHandler(t *testing.T) {
+	c, w := newThreadContext("page=2&limit=10")
+
+	root := model.ChatMessage{
+		ID: threadRootID, RoomID: "valid_room_id", Message: "root message", ReplyCount: 11,
+		Revisions: []model.ChatMessageRevision{{Message: "original message", EditedBy: 12345}},
+	}
+	reply := model.ChatMessage{ID: threadReplyID, RoomID: "valid_room_id", ParentID: threadRootID.Hex(), ThreadRootID: threadRootID.Hex()}
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(reply, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetChatMessage", threadRootID.Hex(), mongoMockPkg).Return(root, nil)
+	mongoMockSvc.On("GetThreadMessages", threadRootID.Hex(), 2, 10, mongoMockPkg).Return([]model.ChatMessage{{Message: "last reply"}}, int64(11), nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "root message")
+	assert.Contains(t, w.Body.String(), "last reply")
+	assert.Contains(t, w.Body.String(), `"total":11`)
+	// 起点のメッセージの編集履歴は返さない
+	var resp struct {
+		Root model.ChatMessage `json:"root"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Root.Revisions)
+	assert.NotContains(t, w.Body.String(), "original message")
+}
+
+func TestThreadHandlerPostProcessing(t *testing.T) {
+	c, w := newThreadContext("")
+
+	root := model.ChatMessage{ID: threadRootID, RoomID: "valid_room_id", Reactions: map[string][]int{":+1:": {1, 2}}}
+	reply := model.ChatMessage{
+		ID: threadReplyID, RoomID: "valid_room_id", ThreadRootID: threadRootID.Hex(),
+		Attachments: []model.Attachment{
+			{ID: "a1", ContentType: "image/png", PreviewStatus: model.PreviewReady, Thumbnails: []model.Thumbnail{{Size: 160, StorageKey: "key/thumbnails/160"}}},
+		},
+	}
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(reply, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetChatMessage", threadRootID.Hex(), mongoMockPkg).Return(root, nil)
+	mongoMockSvc.On("GetThreadMessages", threadRootID.Hex(), 1, defaultPageLimit, mongoMockPkg).Return([]model.ChatMessage{reply}, int64(1), nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+	attachmentMockSvc := new(mock_attachment_svc.AttachmentSvcMock)
+	attachmentMockSvc.On("SignThumbnailURL", threadReplyID.Hex(), "a1", 160).Return("/attachments/signed")
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.AttachmentSvc = attachmentMockSvc
+	handler.ThreadHandler(c)
+
+	// 一覧と同じく、リアクション数と署名付きのサムネイルURLを返す
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"ReactionCounts":{":+1:":2}`)
+	assert.Contains(t, w.Body.String(), `"URL":"/attachments/signed"`)
+	attachmentMockSvc.AssertExpectations(t)
+}
+
+func TestThreadHandlerDeletedRoot(t *testing.T) {
+	c, w := newThreadContext("")
+
+	deletedAt := time.Now()
+	root := model.ChatMessage{ID: threadRootID, RoomID: "valid_room_id", Message: "deleted root", DeletedAt: &deletedAt}
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(root, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetThreadMessages", threadRootID.Hex(), 1, defaultPageLimit, mongoMockPkg).Return([]model.ChatMessage{}, int64(0), nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "deleted root")
+}
+
+func TestThreadHandlerInvalidRequest(t *testing.T) {
+	c, w := newThreadContext("page=abc")
+
+	handler := NewHandlers(new(mock_mongo_svc.MongoSvcMock), &MongoPkgMock{}, new(mock_chat_svc.ChatSvcMock))
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid request")
+}
+
+func TestThreadHandlerFailedGetMessage(t *testing.T) {
+	c, w := newThreadContext("")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get message")
+}
+
+func TestThreadHandlerFailedGetRoom(t *testing.T) {
+	c, w := newThreadContext("")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, assert.AnError)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, new(mock_chat_svc.ChatSvcMock))
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get room")
+}
+
+func TestThreadHandlerForbidden(t *testing.T) {
+	c, w := newThreadContext("")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(model.ChatMessage{RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(chat_svc.Room{})
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+}
+
+func TestThreadHandlerFailedGetRoot(t *testing.T) {
+	c, w := newThreadContext("")
+
+	reply := model.ChatMessage{ID: threadReplyID, RoomID: "valid_room_id", ThreadRootID: threadRootID.Hex()}
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(reply, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetChatMessage", threadRootID.Hex(), mongoMockPkg).Return(model.ChatMessage{}, assert.AnError)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get thread root")
+}
+
+func TestThreadHandlerFailedGetThreadMessages(t *testing.T) {
+	c, w := newThreadContext("")
+
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetChatMessage", threadReplyID.Hex(), mongoMockPkg).Return(model.ChatMessage{ID: threadRootID, RoomID: "valid_room_id"}, nil)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("GetThreadMessages", threadRootID.Hex(), 1, defaultPageLimit, mongoMockPkg).Return([]model.ChatMessage{}, int64(0), assert.AnError)
+
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(memberRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.ThreadHandler(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to get thread messages")
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxRoomNameLength        = 100
+	maxRoomDescriptionLength = 1000
+	maxRoomTopicLength       = 250
+)
+
+// 指定されたフィールドだけを更新する
+type UpdateRoomRequest struct {
+	Name          *string `form:"name" json:"name"`
+	Description   *string `form:"description" json:"description"`
+	Topic         *string `form:"topic" json:"topic"`
+	AvatarURL     *string `form:"avatar_url" json:"avatar_url"`
+	IsPrivate     *bool   `form:"is_private" json:"is_private"`
+	RetentionDays *int    `form:"retention_days" json:"retention_days"` // 0 を指定すると保持期間を解除する
+}
+
+func (r UpdateRoomRequest) validate() string {
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		if name == "" {
+			return "Name must not be empty"
+		}
+		if utf8.RuneCountInString(name) > maxRoomNameLength {
+			return "Name is too long"
+		}
+	}
+	if r.Description != nil && utf8.RuneCountInString(*r.Description) > maxRoomDescriptionLength {
+		return "Description is too long"
+	}
+	if r.Topic != nil && utf8.RuneCountInString(*r.Topic) > maxRoomTopicLength {
+		return "Topic is too long"
+	}
+	// 空文字はアバターの削除として扱う
+	if r.AvatarURL != nil && *r.AvatarURL != "" {
+		u, err := url.Parse(*r.AvatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "Avatar URL must be an http(s) URL"
+		}
+	}
+	if r.RetentionDays != nil && (*r.RetentionDays < 0 || *r.RetentionDays > model.MaxRetentionDays) {
+		return "Retention days must be between 0 and " + strconv.Itoa(model.MaxRetentionDays)
+	}
+	return ""
+}
+
+func (h *HandlerStruct) UpdateRoomHandler(c *gin.Context) {
+	var req UpdateRoomRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+
+	fields := mongo_svc.UpdateRoomFields{
+		Name:          req.Name,
+		Description:   req.Description,
+		Topic:         req.Topic,
+		AvatarURL:     req.AvatarURL,
+		IsPrivate:     req.IsPrivate,
+		RetentionDays: req.RetentionDays,
+	}
+	if fields.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+
+	jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+	roomID := c.Param("id")
+
+	_, roomInfo, ok := h.getRoomFor(c, roomID, int(jwtinfo.UserID), chat_svc.CapabilityManage)
+	if !ok {
+		return
+	}
+	if roomInfo.IsArchived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Room is archived"})
+		return
+	}
+
+	err := h.MongoSvc.UpdateRoom(roomID, fields, time.Now(), h.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrRoomArchived) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Room is archived"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Room updated successfully"})
+}
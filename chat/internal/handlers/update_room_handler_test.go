@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/chat_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_chat_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpdateRoomHandler(t *testing.T) {
+	archivedRoomInfo := ownerRoomInfo
+	archivedRoomInfo.IsArchived = true
+
+	tests := []struct {
+		name       string
+		body       string
+		roomInfo   chat_svc.Room
+		err        error
+		expectCode int
+		expect     string
+	}{
+		{"success", `{"name":"  Renamed  ","topic":"release"}`, ownerRoomInfo, nil, http.StatusOK, "Room updated successfully"},
+		{"privacy", `{"is_private":true}`, ownerRoomInfo, nil, http.StatusOK, "Room updated successfully"},
+		{"no_fields", `{}`, ownerRoomInfo, nil, http.StatusBadRequest, "No fields to update"},
+		{"empty_name", `{"name":"  "}`, ownerRoomInfo, nil, http.StatusBadRequest, "Name must not be empty"},
+		{"long_name", `{"name":"` + strings.Repeat("あ", maxRoomNameLength+1) + `"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Name is too long"},
+		{"long_topic", `{"topic":"` + strings.Repeat("a", maxRoomTopicLength+1) + `"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Topic is too long"},
+		{"invalid_avatar", `{"avatar_url":"javascript:alert(1)"}`, ownerRoomInfo, nil, http.StatusBadRequest, "Avatar URL must be an http(s) URL"},
+		{"clear_avatar", `{"avatar_url":""}`, ownerRoomInfo, nil, http.StatusOK, "Room updated successfully"},
+		{"retention", `{"retention_days":30}`, ownerRoomInfo, nil, http.StatusOK, "Room updated successfully"},
+		{"clear_retention", `{"retention_days":0}`, ownerRoomInfo, nil, http.StatusOK, "Room updated successfully"},
+		{"negative_retention", `{"retention_days":-1}`, ownerRoomInfo, nil, http.StatusBadRequest, "Retention days must be between 0 and 3650"},
+		{"long_retention", `{"retention_days":3651}`, ownerRoomInfo, nil, http.StatusBadRequest, "Retention days must be between 0 and 3650"},
+		{"retention_not_owner", `{"retention_days":30}`, moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"not_owner", `{"name":"Renamed"}`, moderatorRoomInfo, nil, http.StatusForbidden, "Access denied"},
+		{"archived", `{"name":"Renamed"}`, archivedRoomInfo, nil, http.StatusConflict, "Room is archived"},
+		{"archived_concurrently", `{"name":"Renamed"}`, ownerRoomInfo, mongo_svc.ErrRoomArchived, http.StatusConflict, "Room is archived"},
+		{"error", `{"name":"Renamed"}`, ownerRoomInfo, assert.AnError, http.StatusInternalServerError, "Failed to update room"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newJSONRequestContext("PATCH", "/rooms/valid_room_id", tt.body, roomIDParams)
+
+			mongoMockPkg := &MongoPkgMock{}
+			mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+			mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+			mongoMockSvc.On("UpdateRoom", "valid_room_id", mock.Anything, mock.Anything, mongoMockPkg).Return(tt.err)
+			chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+			chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(tt.roomInfo)
+
+			handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+			handler.UpdateRoomHandler(c)
+
+			assert.Equal(t, tt.expectCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expect)
+		})
+	}
+}
+
+func TestUpdateRoomHandlerTrimsName(t *testing.T) {
+	c, w := newJSONRequestContext("PATCH", "/rooms/valid_room_id", `{"name":"  Renamed  "}`, roomIDParams)
+
+	name := "Renamed"
+	mongoMockPkg := &MongoPkgMock{}
+	mongoMockSvc := new(mock_mongo_svc.MongoSvcMock)
+	mongoMockSvc.On("GetRoomByID", "valid_room_id", mongoMockPkg).Return(model.Room{}, nil)
+	mongoMockSvc.On("UpdateRoom", "valid_room_id", mongo_svc.UpdateRoomFields{Name: &name}, mock.Anything, mongoMockPkg).Return(nil)
+	chatMockSvc := new(mock_chat_svc.ChatSvcMock)
+	chatMockSvc.On("GetRoomInfo", model.Room{}, 12345).Return(ownerRoomInfo)
+
+	handler := NewHandlers(mongoMockSvc, mongoMockPkg, chatMockSvc)
+	handler.UpdateRoomHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mongoMockSvc.AssertExpectations(t)
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 認証済みのユーザーをメンションの解決用に記録する（認証ミドルウェアの後に使う）
+type UserDirectoryMiddleware struct {
+	MongoSvc mongo_svc.MongoSvcInterface
+	MongoPkg mongo_pkg.MongoPkgInterface
+	recorded sync.Map // ユーザーIDごとに記録済みのメールアドレス
+}
+
+func NewUserDirectoryMiddleware(mongoSvc mongo_svc.MongoSvcInterface, mongoPkg mongo_pkg.MongoPkgInterface) *UserDirectoryMiddleware {
+	return &UserDirectoryMiddleware{MongoSvc: mongoSvc, MongoPkg: mongoPkg}
+}
+
+func (m *UserDirectoryMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwtinfo := jwtinfo_svc.NewJwtInfo(c.Request.Context())
+		userID := int(jwtinfo.UserID)
+
+		// メールアドレスが変わらない限り、プロセスごとに1回だけ書き込む
+		if email, ok := m.recorded.Load(userID); !ok || email != jwtinfo.Email {
+			user := model.User{UserID: userID, Email: jwtinfo.Email, Handle: model.HandleFromEmail(jwtinfo.Email)}
+			if err := m.MongoSvc.UpsertUser(user, m.MongoPkg); err != nil {
+				// 記録できなくてもリクエスト自体は続ける（次のリクエストでやり直す）
+				log.Printf("failed to record user %d: %v", userID, err)
+			} else {
+				m.recorded.Store(userID, jwtinfo.Email)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/jwtinfo_svc"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserDirectoryMiddleware(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("UpsertUser", model.User{UserID: 1, Email: "Alice@example.com", Handle: "alice"}, mongoPkgMock).Return(assert.AnError).Once()
+	mongoSvcMock.On("UpsertUser", model.User{UserID: 1, Email: "Alice@example.com", Handle: "alice"}, mongoPkgMock).Return(nil).Once()
+	mongoSvcMock.On("UpsertUser", model.User{UserID: 1, Email: "alice@example.org", Handle: "alice"}, mongoPkgMock).Return(nil).Once()
+
+	m := NewUserDirectoryMiddleware(mongoSvcMock, mongoPkgMock)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), jwtinfo_svc.UserIDKey, 1)
+		ctx = context.WithValue(ctx, jwtinfo_svc.EmailKey, c.GetHeader("X-Email"))
+		c.Request = c.Request.WithContext(ctx)
+	}, m.Handler())
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(200, gin.H{"message": "success"})
+	})
+
+	request := func(email string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Email", email)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 記録に失敗してもリクエストは通し、次のリクエストでやり直す
+	assert.Equal(t, 200, request("Alice@example.com"))
+	assert.Equal(t, 200, request("Alice@example.com"))
+	// 記録済みの場合は書き込まない
+	assert.Equal(t, 200, request("Alice@example.com"))
+	// メールアドレスが変わった場合は記録し直す
+	assert.Equal(t, 200, request("alice@example.org"))
+
+	mongoSvcMock.AssertNumberOfCalls(t, "UpsertUser", 3)
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var BlobDeletionCollectionName = "blob_deletions"
+
+// 物理削除したメッセージの添付ファイル。バックグラウンドでストレージから削除する
+type BlobDeletion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	StorageKeys []string
+	QueuedAt    time.Time
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,10 +10,154 @@ import (
 var ChatMessageCollectionName = "chat_messages"
 
 type ChatMessage struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty"`
-	RoomID        string
-	UserID        int
-	Message       string
-	CreatedAt     time.Time
-	IsReadUserIds []int
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID           string
+	UserID           int
+	Message          string
+	CreatedAt        time.Time
+	IsReadUserIds    []int
+	Edited           bool
+	EditedAt         *time.Time            `bson:",omitempty"`
+	Revisions        []ChatMessageRevision `bson:",omitempty"`
+	DeletedAt        *time.Time            `bson:",omitempty"`
+	DeletedBy        int                   `bson:",omitempty"`
+	ParentID         string                `bson:",omitempty"` // 返信先のメッセージID
+	ThreadRootID     string                `bson:",omitempty"` // スレッドの起点となるメッセージID
+	ReplyCount       int                   `bson:",omitempty"` // スレッドの起点のみ保持する
+	LastReplyAt      *time.Time            `bson:",omitempty"` // スレッドの起点のみ保持する
+	Reactions        map[string][]int      `bson:",omitempty"` // 絵文字ごとのリアクションしたユーザー
+	ReactionCounts   map[string]int        `bson:"-"`          // 絵文字ごとのリアクション数（レスポンス用）
+	Attachments      []Attachment          `bson:",omitempty"`
+	MentionedUserIds []int                 `bson:",omitempty"` // メンションされたメンバー（投稿者自身は含めない）
+	BotID            string                `bson:",omitempty"` // 受信 Webhook・コマンドからの投稿の場合のみ設定する（UserID は 0）
+	BotName          string                `bson:",omitempty"`
+	ExpiresAt        *time.Time            `bson:",omitempty"`          // ルームの保持期間から計算した削除予定日時（TTL インデックスで削除する）
+	RetentionDays    int                   `bson:",omitempty" json:"-"` // ExpiresAt の計算に使った保持日数。ルームの保持日数の変更を反映したかの判定にも使う
+	ExternalID       string                `bson:",omitempty"`          // 他のシステムから取り込んだメッセージの元のID（ルーム内で一意）
+}
+
+// メッセージに添付されたファイル（本体はストレージに保存する）
+type Attachment struct {
+	ID          string
+	Name        string // アップロード時のファイル名
+	Size        int64
+	ContentType string // 本文から判定したMIMEタイプ（クライアントの申告値は使わない）
+	Checksum    string // SHA-256（16進数）
+	StorageKey  string `json:"-"`
+	// 画像のみ設定する。サムネイルはバックグラウンドで生成する
+	PreviewStatus string      `bson:",omitempty"`
+	Thumbnails    []Thumbnail `bson:",omitempty"`
+}
+
+const (
+	PreviewPending     = "pending"
+	PreviewReady       = "ready"
+	PreviewUnavailable = "unavailable" // デコードできない画像など
+)
+
+// 添付画像の縮小版（EXIF などのメタデータは含まない）
+type Thumbnail struct {
+	Size        int // 長辺の上限（元の画像の方が小さい場合は縮小しない）
+	Width       int
+	Height      int
+	ContentType string
+	StorageKey  string `json:"-"`
+	URL         string `bson:"-" json:",omitempty"` // 署名付きURL（レスポンス用）
+}
+
+// 添付ファイルの保存先のキー
+func AttachmentStorageKey(roomID string, attachmentID string) string {
+	return "rooms/" + roomID + "/attachments/" + attachmentID
+}
+
+// サムネイルの保存先のキー（元のファイルと同じ場所に置く）
+func ThumbnailStorageKey(attachmentKey string, size int) string {
+	return attachmentKey + "/thumbnails/" + strconv.Itoa(size)
+}
+
+// TTL インデックスで削除する日時。添付ファイル付きのメッセージはファイルも削除できるよう
+// TTL インデックスの対象にせず、保持期間を過ぎたメッセージの定期削除で削除する
+func (m ChatMessage) TTLExpiresAt() *time.Time {
+	if len(m.Attachments) > 0 {
+		return nil
+	}
+	return MessageExpiresAt(m.CreatedAt, m.RetentionDays)
+}
+
+// 添付ファイルと生成済みのサムネイルの保存先のキー
+func (m ChatMessage) StorageKeys() []string {
+	var keys []string
+	for _, attachment := range m.Attachments {
+		keys = append(keys, attachment.StorageKey)
+		for _, thumbnail := range attachment.Thumbnails {
+			keys = append(keys, thumbnail.StorageKey)
+		}
+	}
+	return keys
+}
+
+func (a Attachment) FindThumbnail(size int) (Thumbnail, bool) {
+	for _, thumbnail := range a.Thumbnails {
+		if thumbnail.Size == size {
+			return thumbnail, true
+		}
+	}
+	return Thumbnail{}, false
+}
+
+func (m ChatMessage) FindAttachment(attachmentID string) (Attachment, bool) {
+	for _, attachment := range m.Attachments {
+		if attachment.ID == attachmentID {
+			return attachment, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// 絵文字ごとのリアクション数を集計する（0件の絵文字は含めない）
+func (m ChatMessage) CountReactions() map[string]int {
+	counts := map[string]int{}
+	for emoji, userIDs := range m.Reactions {
+		if len(userIDs) > 0 {
+			counts[emoji] = len(userIDs)
+		}
+	}
+	return counts
+}
+
+// 削除済みメッセージは本文などを除いたトゥームストーンとして返す
+func (m ChatMessage) Tombstone() ChatMessage {
+	return ChatMessage{
+		ID:           m.ID,
+		RoomID:       m.RoomID,
+		UserID:       m.UserID,
+		CreatedAt:    m.CreatedAt,
+		DeletedAt:    m.DeletedAt,
+		DeletedBy:    m.DeletedBy,
+		ParentID:     m.ParentID,
+		ThreadRootID: m.ThreadRootID,
+		ReplyCount:   m.ReplyCount,
+		LastReplyAt:  m.LastReplyAt,
+	}
+}
+
+// スレッドの起点となるメッセージID（自身が起点の場合は自身のID）
+func (m ChatMessage) ThreadRoot() string {
+	if m.ThreadRootID != "" {
+		return m.ThreadRootID
+	}
+	return m.ID.Hex()
+}
+
+// 編集前の本文（編集されるたびに追記していく）
+type ChatMessageRevision struct {
+	Message  string
+	EditedAt time.Time
+	EditedBy int
+}
+
+// 全文検索の結果（textScoreを付与したもの）
+type ChatMessageSearchHit struct {
+	ChatMessage `bson:",inline"`
+	Score       float64 `bson:"score"`
 }
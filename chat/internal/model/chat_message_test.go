@@ -0,0 +1,77 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCountReactions(t *testing.T) {
+	message := ChatMessage{
+		Reactions: map[string][]int{
+			"👍":      {1, 2, 3},
+			":tada:": {2},
+			"😢":      {},
+		},
+	}
+
+	assert.Equal(t, map[string]int{"👍": 3, ":tada:": 1}, message.CountReactions())
+}
+
+func TestThreadRoot(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	assert.Equal(t, id.Hex(), ChatMessage{ID: id}.ThreadRoot())
+	assert.Equal(t, "root_id", ChatMessage{ID: id, ThreadRootID: "root_id"}.ThreadRoot())
+}
+
+func TestFindAttachment(t *testing.T) {
+	message := ChatMessage{Attachments: []Attachment{{ID: "a1", Name: "one.png"}, {ID: "a2", Name: "two.pdf"}}}
+
+	attachment, ok := message.FindAttachment("a2")
+	assert.True(t, ok)
+	assert.Equal(t, "two.pdf", attachment.Name)
+
+	_, ok = message.FindAttachment("missing")
+	assert.False(t, ok)
+}
+
+func TestFindThumbnail(t *testing.T) {
+	attachment := Attachment{Thumbnails: []Thumbnail{{Size: 160, Width: 160, Height: 90}, {Size: 480, Width: 300, Height: 200}}}
+
+	thumbnail, ok := attachment.FindThumbnail(480)
+	assert.True(t, ok)
+	assert.Equal(t, 300, thumbnail.Width)
+
+	_, ok = attachment.FindThumbnail(90)
+	assert.False(t, ok)
+	assert.Equal(t, "rooms/r1/attachments/a1/thumbnails/160", ThumbnailStorageKey(AttachmentStorageKey("r1", "a1"), 160))
+}
+
+func TestStorageKeys(t *testing.T) {
+	message := ChatMessage{Attachments: []Attachment{
+		{StorageKey: "rooms/r1/attachments/a1", Thumbnails: []Thumbnail{{StorageKey: "rooms/r1/attachments/a1/thumbnails/160"}}},
+		{StorageKey: "rooms/r1/attachments/a2"},
+	}}
+
+	assert.Equal(t, []string{
+		"rooms/r1/attachments/a1",
+		"rooms/r1/attachments/a1/thumbnails/160",
+		"rooms/r1/attachments/a2",
+	}, message.StorageKeys())
+	assert.Empty(t, ChatMessage{}.StorageKeys())
+}
+
+func TestTTLExpiresAt(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expiresAt := ChatMessage{CreatedAt: createdAt, RetentionDays: 30}.TTLExpiresAt()
+	if assert.NotNil(t, expiresAt) {
+		assert.Equal(t, createdAt.Add(30*24*time.Hour), *expiresAt)
+	}
+	assert.Nil(t, ChatMessage{CreatedAt: createdAt}.TTLExpiresAt())
+	// 添付ファイル付きのメッセージは定期削除で削除する
+	assert.Nil(t, ChatMessage{CreatedAt: createdAt, RetentionDays: 30, Attachments: []Attachment{{ID: "a1"}}}.TTLExpiresAt())
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var IncomingWebhookCollectionName = "incoming_webhooks"
+
+// 外部のシステムがルームに投稿するための URL
+type IncomingWebhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID    string
+	Name      string // 投稿者として表示するボットの名前
+	TokenHash string `json:"-"` // トークンはハッシュのみ保存する
+	CreatedBy int
+	CreatedAt time.Time
+}
+
+// トークンは推測できない乱数のため、ソルトなしのハッシュで照合する
+func HashIncomingWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashIncomingWebhookToken(t *testing.T) {
+	hash := HashIncomingWebhookToken("token1")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashIncomingWebhookToken("token1"))
+	assert.NotEqual(t, hash, HashIncomingWebhookToken("token2"))
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ルーム全体へのメンション
+const (
+	MentionHere = "here" // 最近ルームを見ているメンバー
+	MentionAll  = "all"  // 全メンバー
+)
+
+// 直前が英数字などの場合（メールアドレスなど）はメンションとして扱わない
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.+-])@([\w.+-]+)`)
+
+type Mentions struct {
+	Handles []string // 重複を除いた小文字の名前
+	Here    bool
+	All     bool
+}
+
+// 本文から @name / @here / @all を取り出す
+func ParseMentions(message string) Mentions {
+	var mentions Mentions
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		// 文末の句読点は名前に含めない
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		switch {
+		case handle == "":
+		case handle == MentionHere:
+			mentions.Here = true
+		case handle == MentionAll:
+			mentions.All = true
+		case !seen[handle]:
+			seen[handle] = true
+			mentions.Handles = append(mentions.Handles, handle)
+		}
+	}
+	return mentions
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		expect  Mentions
+	}{
+		{"none", "hello world", Mentions{}},
+		{"handles", "@Alice and @bob.smith, see @alice", Mentions{Handles: []string{"alice", "bob.smith"}}},
+		{"trailing_punctuation", "thanks @alice.", Mentions{Handles: []string{"alice"}}},
+		{"here_and_all", "@here @ALL deploy", Mentions{Here: true, All: true}},
+		{"email_is_not_mention", "mail me at carol@example.com", Mentions{}},
+		{"line_start", "line\n@dave", Mentions{Handles: []string{"dave"}}},
+		{"only_at", "@ alone", Mentions{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, ParseMentions(tt.message))
+		})
+	}
+}
+
+func TestHandleFromEmail(t *testing.T) {
+	assert.Equal(t, "test.user", HandleFromEmail("Test.User@example.com"))
+	assert.Equal(t, "noatmark", HandleFromEmail("NoAtMark"))
+}
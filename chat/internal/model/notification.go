@@ -0,0 +1,124 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var NotificationCollectionName = "notifications"
+var NotificationSettingsCollectionName = "notification_settings"
+var NotificationDeliveryCollectionName = "notification_deliveries"
+
+// 通知の種類
+const (
+	NotificationMention  = "mention"
+	NotificationReply    = "reply"
+	NotificationDirect   = "direct"
+	NotificationReminder = "reminder"
+)
+
+// 通知の配信チャネル
+const (
+	NotificationChannelEmail   = "email"
+	NotificationChannelWebhook = "webhook"
+)
+
+// 配信の状態
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// ユーザーごとの通知。ダイジェストにまとめるまでは DeliveryID が空
+type Notification struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	UserID     int
+	Kind       string
+	RoomID     string
+	MessageID  string
+	ActorID    int // 通知のきっかけになったメッセージの投稿者
+	Snippet    string
+	CreatedAt  time.Time
+	DeliveryID string
+}
+
+// ユーザーごとの通知設定。未設定のユーザーはメールで全ての通知を受け取る
+type NotificationSettings struct {
+	UserID     int
+	MuteAll    bool
+	MutedRooms []string `bson:",omitempty"`
+	Channel    string   // 空の場合はメール
+	WebhookURL string   `bson:",omitempty"`
+	DNDStart   string   `bson:",omitempty"` // おやすみモードの開始時刻（HH:MM）
+	DNDEnd     string   `bson:",omitempty"` // おやすみモードの終了時刻（HH:MM）
+	TimeZone   string   `bson:",omitempty"` // おやすみモードの時刻の解釈に使う（空の場合は UTC）
+	UpdatedAt  time.Time
+}
+
+func (s NotificationSettings) DeliveryChannel() string {
+	if s.Channel == "" {
+		return NotificationChannelEmail
+	}
+	return s.Channel
+}
+
+func (s NotificationSettings) Mutes(roomID string) bool {
+	if s.MuteAll {
+		return true
+	}
+	for _, id := range s.MutedRooms {
+		if id == roomID {
+			return true
+		}
+	}
+	return false
+}
+
+// HH:MM を0時からの分に変換する
+func ParseClock(value string) (int, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// now がおやすみモードの時間帯かどうか。開始が終了より遅い場合は日をまたぐ時間帯として扱う
+func (s NotificationSettings) InDoNotDisturb(now time.Time) bool {
+	start, ok := ParseClock(s.DNDStart)
+	if !ok {
+		return false
+	}
+	end, ok := ParseClock(s.DNDEnd)
+	if !ok || start == end {
+		return false
+	}
+	if loc, err := time.LoadLocation(s.TimeZone); err == nil {
+		now = now.In(loc)
+	}
+	minutes := now.Hour()*60 + now.Minute()
+	if start < end {
+		return start <= minutes && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// 配信の試行の記録
+type DeliveryAttempt struct {
+	At      time.Time
+	Channel string
+	Error   string `bson:",omitempty"`
+}
+
+// ダイジェスト1通分の配信
+type NotificationDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	UserID        int
+	Status        string
+	Attempts      []DeliveryAttempt
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time `bson:",omitempty"`
+}
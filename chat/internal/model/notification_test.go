@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationSettingsMutes(t *testing.T) {
+	assert.False(t, NotificationSettings{}.Mutes("room1"))
+	assert.True(t, NotificationSettings{MuteAll: true}.Mutes("room1"))
+	assert.True(t, NotificationSettings{MutedRooms: []string{"room2", "room1"}}.Mutes("room1"))
+	assert.False(t, NotificationSettings{MutedRooms: []string{"room2"}}.Mutes("room1"))
+}
+
+func TestNotificationSettingsDeliveryChannel(t *testing.T) {
+	assert.Equal(t, NotificationChannelEmail, NotificationSettings{}.DeliveryChannel())
+	assert.Equal(t, NotificationChannelWebhook, NotificationSettings{Channel: NotificationChannelWebhook}.DeliveryChannel())
+}
+
+func TestInDoNotDisturb(t *testing.T) {
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		settings NotificationSettings
+		now      time.Time
+		expect   bool
+	}{
+		{"not_set", NotificationSettings{}, at(12, 0), false},
+		{"inside", NotificationSettings{DNDStart: "09:00", DNDEnd: "17:00"}, at(12, 0), true},
+		{"at_start", NotificationSettings{DNDStart: "09:00", DNDEnd: "17:00"}, at(9, 0), true},
+		{"at_end", NotificationSettings{DNDStart: "09:00", DNDEnd: "17:00"}, at(17, 0), false},
+		// 日をまたぐ時間帯
+		{"overnight_late", NotificationSettings{DNDStart: "22:00", DNDEnd: "07:00"}, at(23, 30), true},
+		{"overnight_early", NotificationSettings{DNDStart: "22:00", DNDEnd: "07:00"}, at(6, 59), true},
+		{"overnight_outside", NotificationSettings{DNDStart: "22:00", DNDEnd: "07:00"}, at(12, 0), false},
+		// 12:00 UTC は東京の 21:00
+		{"time_zone", NotificationSettings{DNDStart: "20:00", DNDEnd: "23:00", TimeZone: "Asia/Tokyo"}, at(12, 0), true},
+		{"same_start_end", NotificationSettings{DNDStart: "09:00", DNDEnd: "09:00"}, at(9, 0), false},
+		{"invalid", NotificationSettings{DNDStart: "9am", DNDEnd: "17:00"}, at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, tt.settings.InDoNotDisturb(tt.now))
+		})
+	}
+}
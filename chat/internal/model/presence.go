@@ -0,0 +1,18 @@
+package model
+
+// ユーザーの在席状態（期限までにハートビートが無い場合はオフライン）
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+func ValidPresenceStatus(status string) bool {
+	return status == PresenceOnline || status == PresenceAway || status == PresenceOffline
+}
+
+// ルームのメンバーの在席状態（レスポンス用）
+type MemberPresence struct {
+	UserID int    `json:"user_id"`
+	Status string `json:"status"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidPresenceStatus(t *testing.T) {
+	assert.True(t, ValidPresenceStatus(PresenceOnline))
+	assert.True(t, ValidPresenceStatus(PresenceAway))
+	assert.True(t, ValidPresenceStatus(PresenceOffline))
+	assert.False(t, ValidPresenceStatus("busy"))
+}
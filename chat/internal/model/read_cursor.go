@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ReadCursorCollectionName = "read_cursors"
+
+// ルームごと・ユーザーごとの既読位置
+type ReadCursor struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID            string
+	UserID            int
+	LastReadMessageID string
+	LastReadAt        time.Time // 既読にしたメッセージの投稿日時
+	UpdatedAt         time.Time
+}
+
+// 既読位置より前のメッセージか、個別に既読にしたメッセージは既読として扱う
+func (m ChatMessage) IsReadBy(userID int, readCursor ReadCursor) bool {
+	for _, id := range m.IsReadUserIds {
+		if id == userID {
+			return true
+		}
+	}
+	return readCursor.ID != primitive.NilObjectID && !m.CreatedAt.After(readCursor.LastReadAt)
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestIsReadBy(t *testing.T) {
+	now := time.Now()
+	cursor := ReadCursor{ID: primitive.NewObjectID(), LastReadAt: now}
+
+	tests := []struct {
+		name    string
+		message ChatMessage
+		cursor  ReadCursor
+		expect  bool
+	}{
+		{"before_cursor", ChatMessage{CreatedAt: now.Add(-time.Minute)}, cursor, true},
+		{"at_cursor", ChatMessage{CreatedAt: now}, cursor, true},
+		{"after_cursor", ChatMessage{CreatedAt: now.Add(time.Minute)}, cursor, false},
+		{"no_cursor", ChatMessage{CreatedAt: now.Add(-time.Minute)}, ReadCursor{}, false},
+		{"marked_read", ChatMessage{CreatedAt: now.Add(time.Minute), IsReadUserIds: []int{1}}, cursor, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, tt.message.IsReadBy(1, tt.cursor))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var RoomExportCollectionName = "room_exports"
+
+// エクスポートの形式
+const (
+	ExportFormatJSONL = "jsonl"
+	ExportFormatCSV   = "csv"
+	ExportFormatHTML  = "html" // 外部のファイルを参照しない1ファイルの HTML
+)
+
+// エクスポートの状態。lease が切れた処理中のエクスポートは失敗にする
+const (
+	ExportPending    = "pending"
+	ExportProcessing = "processing"
+	ExportCompleted  = "completed"
+	ExportFailed     = "failed"
+)
+
+type RoomExport struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID      string
+	RequestedBy int
+	Format      string
+	Status      string
+	CreatedAt   time.Time
+
+	LeaseUntil   *time.Time `bson:",omitempty"` // 処理中のエクスポートは書き出しの途中で延長する
+	StorageKey   string     `bson:",omitempty" json:"-"`
+	MessageCount int64      `bson:",omitempty"`
+	Size         int64      `bson:",omitempty"`
+	Error        string     `bson:",omitempty"`
+	CompletedAt  *time.Time `bson:",omitempty"`
+}
+
+func ValidExportFormat(format string) bool {
+	switch format {
+	case ExportFormatJSONL, ExportFormatCSV, ExportFormatHTML:
+		return true
+	}
+	return false
+}
+
+// 処理が終わっていないエクスポート
+func (e RoomExport) Active() bool {
+	return e.Status == ExportPending || e.Status == ExportProcessing
+}
+
+// エクスポートしたファイルの保存先のキー（拡張子は形式と同じ）
+func RoomExportStorageKey(roomID string, exportID string, format string) string {
+	return "rooms/" + roomID + "/exports/" + exportID + "." + format
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidExportFormat(t *testing.T) {
+	assert.True(t, ValidExportFormat(ExportFormatJSONL))
+	assert.True(t, ValidExportFormat(ExportFormatCSV))
+	assert.True(t, ValidExportFormat(ExportFormatHTML))
+	assert.False(t, ValidExportFormat("pdf"))
+	assert.False(t, ValidExportFormat(""))
+}
+
+func TestRoomExportActive(t *testing.T) {
+	assert.True(t, RoomExport{Status: ExportPending}.Active())
+	assert.True(t, RoomExport{Status: ExportProcessing}.Active())
+	assert.False(t, RoomExport{Status: ExportCompleted}.Active())
+	assert.False(t, RoomExport{Status: ExportFailed}.Active())
+}
+
+func TestRoomExportStorageKey(t *testing.T) {
+	assert.Equal(t, "rooms/room1/exports/export1.csv", RoomExportStorageKey("room1", "export1", ExportFormatCSV))
+}
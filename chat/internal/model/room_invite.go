@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var RoomInviteCollectionName = "room_invites"
+var JoinRequestCollectionName = "room_join_requests"
+
+// プライベートルームへの招待コード
+type RoomInvite struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID    string
+	Code      string
+	CreatedBy int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	MaxUses   int // 0 の場合は期限内であれば何度でも使える
+	Uses      int
+	UsedBy    []int
+
+	InvitedUserID int `bson:",omitempty"` // 指定した場合はこのユーザーだけが使える
+}
+
+func (i RoomInvite) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+func (i RoomInvite) UsedUp() bool {
+	return i.MaxUses > 0 && i.Uses >= i.MaxUses
+}
+
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestDenied   = "denied"
+)
+
+// プライベートルームへの参加申請
+type JoinRequest struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID    string
+	UserID    int
+	Message   string
+	Status    string
+	CreatedAt time.Time
+	DecidedAt *time.Time `bson:",omitempty"`
+	DecidedBy int        `bson:",omitempty"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoomInviteExpired(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.False(t, RoomInvite{ExpiresAt: now.Add(time.Second)}.Expired(now))
+	assert.True(t, RoomInvite{ExpiresAt: now}.Expired(now))
+}
+
+func TestRoomInviteUsedUp(t *testing.T) {
+	assert.False(t, RoomInvite{MaxUses: 0, Uses: 10}.UsedUp())
+	assert.False(t, RoomInvite{MaxUses: 1, Uses: 0}.UsedUp())
+	assert.True(t, RoomInvite{MaxUses: 1, Uses: 1}.UsedUp())
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var RoomWebhookCollectionName = "room_webhooks"
+var WebhookDeliveryCollectionName = "webhook_deliveries"
+
+// 外部に送るルームのイベント
+const (
+	WebhookMessageCreated = "message.created"
+	WebhookMessageDeleted = "message.deleted"
+	WebhookMemberJoined   = "member.joined"
+	WebhookMemberLeft     = "member.left"
+	WebhookMemberRemoved  = "member.removed" // キック・BAN
+)
+
+var WebhookEvents = []string{
+	WebhookMessageCreated,
+	WebhookMessageDeleted,
+	WebhookMemberJoined,
+	WebhookMemberLeft,
+	WebhookMemberRemoved,
+}
+
+func ValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ルームのイベントを外部のURLに送る設定
+type RoomWebhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID    string
+	URL       string
+	Events    []string // 空の場合は全てのイベント
+	Secret    string   `json:"-"` // 署名用の鍵（作成時のみ返す）
+	CreatedBy int
+	CreatedAt time.Time
+}
+
+func (w RoomWebhook) Subscribes(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// 送信する JSON の本文
+type WebhookEvent struct {
+	Event     string      `json:"event"`
+	RoomID    string      `json:"room_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// message.created / message.deleted のデータ
+type WebhookMessageData struct {
+	MessageID    string `json:"message_id"`
+	UserID       int    `json:"user_id"`
+	Message      string `json:"message,omitempty"`
+	ThreadRootID string `json:"thread_root_id,omitempty"`
+	DeletedBy    int    `json:"deleted_by,omitempty"`
+}
+
+// member.* のデータ
+type WebhookMemberData struct {
+	UserID  int `json:"user_id"`
+	ActorID int `json:"actor_id"` // 操作したユーザー（本人が参加・退出した場合は同じ）
+}
+
+// 送信の試行の記録
+type WebhookAttempt struct {
+	At         time.Time
+	StatusCode int    `bson:",omitempty"`
+	Error      string `bson:",omitempty"`
+}
+
+// Webhook 1件へのイベントの配信。再試行しても届かなかったものは failed（デッドレター）になる
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	WebhookID     string
+	RoomID        string
+	Event         string
+	Payload       string // 署名した本文をそのまま再送できるように保存する
+	Status        string // DeliveryPending / DeliveryDelivered / DeliveryFailed
+	AttemptCount  int    // 再配信するとリセットする
+	Attempts      []WebhookAttempt
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time `bson:",omitempty"`
+}
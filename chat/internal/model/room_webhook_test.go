@@ -0,0 +1,21 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidWebhookEvent(t *testing.T) {
+	for _, event := range WebhookEvents {
+		assert.True(t, ValidWebhookEvent(event))
+	}
+	assert.False(t, ValidWebhookEvent("room.deleted"))
+	assert.False(t, ValidWebhookEvent(""))
+}
+
+func TestRoomWebhookSubscribes(t *testing.T) {
+	assert.True(t, RoomWebhook{}.Subscribes(WebhookMemberJoined))
+	assert.True(t, RoomWebhook{Events: []string{WebhookMessageCreated, WebhookMemberJoined}}.Subscribes(WebhookMemberJoined))
+	assert.False(t, RoomWebhook{Events: []string{WebhookMessageCreated}}.Subscribes(WebhookMemberJoined))
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -8,6 +11,17 @@ import (
 
 var RoomCollectionName = "rooms"
 
+// ルーム内の役割
+const (
+	RoleOwner     = "owner"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+	RoleReadOnly  = "read_only"
+)
+
+// ダイレクトメッセージ用のルーム
+const RoomKindDirect = "direct"
+
 type Room struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
 	Name      string
@@ -15,4 +29,149 @@ type Room struct {
 	CreatedAt time.Time
 	Members   []int
 	IsPrivate bool
+	Banned    []int             `bson:",omitempty"` // 参加を禁止されたユーザー
+	Roles     map[string]string `bson:",omitempty"` // ユーザーIDごとの役割。未設定のメンバーは member として扱う
+
+	Description string
+	Topic       string
+	AvatarURL   string
+	UpdatedAt   *time.Time `bson:",omitempty"`
+	ArchivedAt  *time.Time `bson:",omitempty"` // アーカイブ済みのルームは閲覧のみ可能
+
+	Kind      string `bson:",omitempty"` // 通常のルームは空文字
+	DirectKey string `bson:",omitempty"` // ダイレクトメッセージの参加者を一意に表すキー
+
+	LastActivityAt *time.Time       `bson:",omitempty"` // 最後にメッセージが投稿された日時
+	LastMessage    *RoomLastMessage `bson:",omitempty"` // ルーム一覧に表示する最新メッセージ
+
+	Pins []RoomPin `bson:",omitempty"` // ピン留めされたメッセージ（新しい順）
+
+	RetentionDays  int  `bson:",omitempty"` // メッセージの保持日数。0 の場合は無期限に保持する
+	RestampPending bool `bson:",omitempty"` // 保持日数の変更を既存のメッセージの削除予定日時に反映している途中
+
+	ExternalID string `bson:",omitempty"` // 他のシステムから取り込んだルームの元のID
+}
+
+// ルームに設定できる保持日数の上限
+const MaxRetentionDays = 3650
+
+// 保持日数が経過したメッセージの投稿日時の上限（これより前のメッセージは削除対象）
+func RetentionCutoff(now time.Time, retentionDays int) time.Time {
+	return now.Add(-time.Duration(retentionDays) * 24 * time.Hour)
+}
+
+// 保持期間が設定されていない場合は nil
+func MessageExpiresAt(createdAt time.Time, retentionDays int) *time.Time {
+	if retentionDays <= 0 {
+		return nil
+	}
+	expiresAt := createdAt.Add(time.Duration(retentionDays) * 24 * time.Hour)
+	return &expiresAt
+}
+
+// ルームにピン留めできるメッセージの上限
+const MaxRoomPins = 50
+
+type RoomPin struct {
+	MessageID string
+	PinnedBy  int
+	PinnedAt  time.Time
+}
+
+// ルーム一覧のプレビュー用に保持する最新メッセージ
+type RoomLastMessage struct {
+	MessageID string
+	UserID    int
+	Snippet   string
+	CreatedAt time.Time
+	Deleted   bool `bson:",omitempty"`
+}
+
+// プレビューに表示する本文の最大文字数
+const MessageSnippetLength = 100
+
+// 改行や連続する空白をまとめ、長い本文は末尾を省略する
+func MessageSnippet(message string) string {
+	snippet := strings.Join(strings.Fields(message), " ")
+	runes := []rune(snippet)
+	if len(runes) > MessageSnippetLength {
+		return string(runes[:MessageSnippetLength]) + "…"
+	}
+	return snippet
+}
+
+func (r Room) IsDirect() bool {
+	return r.Kind == RoomKindDirect
+}
+
+// 参加者の重複を除いて昇順に並べる
+func DirectParticipants(userIDs []int) []int {
+	unique := map[int]bool{}
+	var participants []int
+	for _, id := range userIDs {
+		if !unique[id] {
+			unique[id] = true
+			participants = append(participants, id)
+		}
+	}
+	sort.Ints(participants)
+	return participants
+}
+
+// 参加者の並び順や重複に関係なく同じキーになる
+func DirectKey(userIDs []int) string {
+	participants := DirectParticipants(userIDs)
+	keys := make([]string, len(participants))
+	for i, id := range participants {
+		keys[i] = strconv.Itoa(id)
+	}
+	return strings.Join(keys, "-")
+}
+
+func (r Room) IsPinned(messageID string) bool {
+	for _, pin := range r.Pins {
+		if pin.MessageID == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Room) Archived() bool {
+	return r.ArchivedAt != nil
+}
+
+// BSON のキーは文字列なのでユーザーIDを文字列に変換して使う
+func RoleKey(userID int) string {
+	return strconv.Itoa(userID)
+}
+
+// ユーザーの役割を返す。メンバーでない場合は空文字
+func (r Room) RoleOf(userID int) string {
+	if r.OwnerID == userID {
+		return RoleOwner
+	}
+	isMember := false
+	for _, member := range r.Members {
+		if member == userID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		return ""
+	}
+	if role, ok := r.Roles[RoleKey(userID)]; ok {
+		return role
+	}
+	return RoleMember
+}
+
+// オーナー以外に割り当てられる役割か
+func AssignableRole(role string) bool {
+	switch role {
+	case RoleModerator, RoleMember, RoleReadOnly:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoomRoleOf(t *testing.T) {
+	room := Room{
+		OwnerID: 1,
+		Members: []int{1, 2, 3, 4},
+		Roles:   map[string]string{"2": RoleModerator, "3": RoleReadOnly},
+	}
+
+	assert.Equal(t, RoleOwner, room.RoleOf(1))
+	assert.Equal(t, RoleModerator, room.RoleOf(2))
+	assert.Equal(t, RoleReadOnly, room.RoleOf(3))
+	assert.Equal(t, RoleMember, room.RoleOf(4))
+	assert.Equal(t, "", room.RoleOf(5))
+}
+
+func TestAssignableRole(t *testing.T) {
+	assert.True(t, AssignableRole(RoleModerator))
+	assert.True(t, AssignableRole(RoleMember))
+	assert.True(t, AssignableRole(RoleReadOnly))
+	assert.False(t, AssignableRole(RoleOwner))
+	assert.False(t, AssignableRole("admin"))
+}
+
+func TestDirectKey(t *testing.T) {
+	assert.Equal(t, []int{1, 5, 9}, DirectParticipants([]int{9, 1, 5, 1}))
+	assert.Equal(t, "1-5-9", DirectKey([]int{9, 1, 5}))
+	assert.Equal(t, DirectKey([]int{2, 1}), DirectKey([]int{1, 2, 2}))
+	assert.True(t, Room{Kind: RoomKindDirect}.IsDirect())
+	assert.False(t, Room{}.IsDirect())
+}
+
+func TestMessageSnippet(t *testing.T) {
+	assert.Equal(t, "hello world", MessageSnippet("  hello\n\n  world "))
+
+	long := strings.Repeat("あ", MessageSnippetLength+1)
+	assert.Equal(t, strings.Repeat("あ", MessageSnippetLength)+"…", MessageSnippet(long))
+	assert.Equal(t, strings.Repeat("あ", MessageSnippetLength), MessageSnippet(long[:len(long)-len("あ")]))
+}
+
+func TestRoomIsPinned(t *testing.T) {
+	room := Room{Pins: []RoomPin{{MessageID: "message1"}, {MessageID: "message2"}}}
+
+	assert.True(t, room.IsPinned("message2"))
+	assert.False(t, room.IsPinned("message3"))
+	assert.False(t, Room{}.IsPinned("message1"))
+}
+
+func TestRetention(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC), RetentionCutoff(now, 30))
+	assert.Equal(t, time.Date(2025, 4, 30, 12, 0, 0, 0, time.UTC), *MessageExpiresAt(now, 30))
+	assert.Nil(t, MessageExpiresAt(now, 0))
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var SavedMessageCollectionName = "saved_messages"
+
+// ユーザーごとのブックマーク。本人にのみ表示される
+type SavedMessage struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    int
+	RoomID    string
+	MessageID string
+	CreatedAt time.Time
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ScheduledMessageCollectionName = "scheduled_messages"
+
+// 予約の種類
+const (
+	ScheduledKindMessage  = "message"  // 指定した日時にルームに投稿する
+	ScheduledKindReminder = "reminder" // 指定した日時にメッセージを本人に通知する
+)
+
+// 予約の状態。処理中から待機中には戻さないため、同じ予約が2回実行されることはない
+const (
+	ScheduledPending    = "pending"
+	ScheduledProcessing = "processing"
+	ScheduledSent       = "sent"
+	ScheduledFailed     = "failed"
+)
+
+type ScheduledMessage struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Kind      string
+	RoomID    string
+	UserID    int
+	Message   string `bson:",omitempty"` // 予約投稿の本文
+	ReplyTo   string `bson:",omitempty"` // 予約投稿の返信先
+	MessageID string `bson:",omitempty"` // リマインダーの対象のメッセージ
+	SendAt    time.Time
+	Status    string
+	CreatedAt time.Time
+
+	LeaseUntil      *time.Time `bson:",omitempty"` // 処理中の予約をこの日時までに完了できなければ失敗にする
+	PostedMessageID string     `bson:",omitempty"` // 予約投稿で投稿されたメッセージ
+	Error           string     `bson:",omitempty"`
+	CompletedAt     *time.Time `bson:",omitempty"`
+}
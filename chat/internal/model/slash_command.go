@@ -0,0 +1,47 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var SlashCommandCollectionName = "slash_commands"
+
+// コマンドの応答の表示先
+const (
+	SlashResponseEphemeral = "ephemeral"  // 実行したユーザーにのみ返す
+	SlashResponseInChannel = "in_channel" // ルームに投稿する
+)
+
+// 先頭の / に続く名前の後は空白か本文の終わりであること（/usr/bin などはコマンドとして扱わない）
+var slashCommandPattern = regexp.MustCompile(`^/([a-z][a-z0-9_-]{0,31})(?:\s+|$)`)
+
+var slashCommandNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// ルームに登録された外部のコマンド（実行時に URL に HTTP で問い合わせる）
+type SlashCommand struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	RoomID      string
+	Name        string
+	Description string
+	URL         string
+	Secret      string `json:"-"` // 署名用の鍵（作成時のみ返す）
+	CreatedBy   int
+	CreatedAt   time.Time
+}
+
+// 本文がコマンドの場合は名前と引数を返す
+func ParseSlashCommand(message string) (string, string, bool) {
+	match := slashCommandPattern.FindStringSubmatch(message)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], strings.TrimSpace(message[len(match[0]):]), true
+}
+
+func ValidSlashCommandName(name string) bool {
+	return slashCommandNamePattern.MatchString(name)
+}
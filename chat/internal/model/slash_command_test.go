@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	tests := []struct {
+		message    string
+		expectName string
+		expectArgs string
+		expectOK   bool
+	}{
+		{"/topic Release day", "topic", "Release day", true},
+		{"/mute", "mute", "", true},
+		{"/invite   @bob  ", "invite", "@bob", true},
+		{"/deploy prod\nnow", "deploy", "prod\nnow", true},
+		{"hello /topic", "", "", false},
+		{"/usr/bin is a path", "", "", false},
+		{"/Topic x", "", "", false},
+		{"/", "", "", false},
+		{" /topic", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			name, args, ok := ParseSlashCommand(tt.message)
+			assert.Equal(t, tt.expectOK, ok)
+			assert.Equal(t, tt.expectName, name)
+			assert.Equal(t, tt.expectArgs, args)
+		})
+	}
+}
+
+func TestValidSlashCommandName(t *testing.T) {
+	assert.True(t, ValidSlashCommandName("deploy"))
+	assert.True(t, ValidSlashCommandName("build-status_2"))
+	assert.False(t, ValidSlashCommandName("2fa"))
+	assert.False(t, ValidSlashCommandName("Deploy"))
+	assert.False(t, ValidSlashCommandName(""))
+}
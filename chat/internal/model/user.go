@@ -0,0 +1,22 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+var UserCollectionName = "users"
+
+// 認証済みのリクエストから記録するユーザーの情報（メンションの解決に使う）
+type User struct {
+	UserID    int
+	Email     string
+	Handle    string // メンションに使う名前
+	UpdatedAt time.Time
+}
+
+// メールアドレスの @ より前を小文字にしたものをメンション用の名前にする
+func HandleFromEmail(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	return strings.ToLower(local)
+}
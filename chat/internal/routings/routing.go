@@ -11,8 +11,14 @@ type Rootings struct {
 	AuthMW gin.HandlerFunc
 }
 
-func Routing(r *gin.Engine, csrfMW gin.HandlerFunc, authMW gin.HandlerFunc, handlers handlers.HandlersInterface) {
-	r.Use(csrfMW, authMW)
+func Routing(r *gin.Engine, csrfMW gin.HandlerFunc, authMW gin.HandlerFunc, userMW gin.HandlerFunc, handlers handlers.HandlersInterface) {
+	// 署名付きURLでダウンロードするため、認証より前に登録する
+	r.GET("/attachments/:message_id/:attachment_id", handlers.DownloadAttachmentHandler)
+	r.GET("/attachments/:message_id/:attachment_id/thumbnails/:size", handlers.DownloadThumbnailHandler)
+	// 外部のシステムがトークンで投稿するため、CSRF・認証より前に登録する
+	r.POST("/hooks/:token", handlers.PostIncomingWebhookHandler)
+
+	r.Use(csrfMW, authMW, userMW)
 	r.POST("/room_create", handlers.CreateRoomHandler)
 	r.POST("/room_join", handlers.JoinRoomHandler)
 	r.GET("/room_list", handlers.RoomListHandler)
@@ -20,5 +26,66 @@ func Routing(r *gin.Engine, csrfMW gin.HandlerFunc, authMW gin.HandlerFunc, hand
 	r.GET("/load_chat/:room_id", handlers.LoadChatHandlers)
 	r.POST("/read_chat", handlers.ReadChatMessages)
 	r.DELETE("/delete_chat_message", handlers.DeleteChatMessageHandler)
+	r.GET("/search", handlers.SearchChatMessagesHandler)
+	r.PATCH("/messages/:id", handlers.EditChatMessageHandler)
+	r.GET("/messages/:id/revisions", handlers.ChatMessageRevisionsHandler)
+	r.GET("/messages/:id/deleted", handlers.DeletedChatMessageHandler)
+	r.GET("/messages/:id/thread", handlers.ThreadHandler)
+	r.POST("/messages/:id/reactions", handlers.AddReactionHandler)
+	r.DELETE("/messages/:id/reactions/:emoji", handlers.RemoveReactionHandler)
+	r.POST("/rooms/:id/read_up_to", handlers.ReadUpToHandler)
+	r.POST("/rooms/:id/invites", handlers.CreateRoomInviteHandler)
+	r.POST("/invites/:code/accept", handlers.AcceptRoomInviteHandler)
+	r.POST("/rooms/:id/join_requests", handlers.CreateJoinRequestHandler)
+	r.GET("/rooms/:id/join_requests", handlers.JoinRequestsHandler)
+	r.POST("/rooms/:id/join_requests/:request_id/approve", handlers.ApproveJoinRequestHandler)
+	r.POST("/rooms/:id/join_requests/:request_id/deny", handlers.DenyJoinRequestHandler)
+	r.POST("/rooms/:id/leave", handlers.LeaveRoomHandler)
+	r.POST("/rooms/:id/kick", handlers.KickMemberHandler)
+	r.POST("/rooms/:id/ban", handlers.BanMemberHandler)
+	r.POST("/rooms/:id/unban", handlers.UnbanMemberHandler)
+	r.POST("/rooms/:id/transfer_ownership", handlers.TransferOwnershipHandler)
+	r.PUT("/rooms/:id/roles", handlers.SetMemberRoleHandler)
+	r.PATCH("/rooms/:id", handlers.UpdateRoomHandler)
+	r.DELETE("/rooms/:id", handlers.DeleteRoomHandler)
+	r.POST("/rooms/:id/archive", handlers.ArchiveRoomHandler)
+	r.POST("/rooms/:id/unarchive", handlers.UnarchiveRoomHandler)
+	r.POST("/dms", handlers.CreateDirectRoomHandler)
+	r.POST("/rooms/:id/attachments", handlers.UploadAttachmentsHandler)
+	r.GET("/messages/:id/attachments/:attachment_id", handlers.AttachmentURLHandler)
+	r.GET("/mentions", handlers.MentionsHandler)
+	r.GET("/me/notification_settings", handlers.NotificationSettingsHandler)
+	r.PUT("/me/notification_settings", handlers.UpdateNotificationSettingsHandler)
+	r.POST("/rooms/:id/mute", handlers.MuteRoomHandler)
+	r.DELETE("/rooms/:id/mute", handlers.UnmuteRoomHandler)
+	r.POST("/rooms/:id/webhooks", handlers.CreateRoomWebhookHandler)
+	r.GET("/rooms/:id/webhooks", handlers.RoomWebhooksHandler)
+	r.DELETE("/rooms/:id/webhooks/:webhook_id", handlers.DeleteRoomWebhookHandler)
+	r.GET("/rooms/:id/webhook_deliveries", handlers.WebhookDeliveriesHandler)
+	r.POST("/rooms/:id/webhook_deliveries/:delivery_id/redeliver", handlers.RedeliverWebhookHandler)
+	r.POST("/rooms/:id/incoming_webhooks", handlers.CreateIncomingWebhookHandler)
+	r.GET("/rooms/:id/incoming_webhooks", handlers.IncomingWebhooksHandler)
+	r.DELETE("/rooms/:id/incoming_webhooks/:webhook_id", handlers.DeleteIncomingWebhookHandler)
+	r.POST("/rooms/:id/commands", handlers.CreateSlashCommandHandler)
+	r.GET("/rooms/:id/commands", handlers.SlashCommandsHandler)
+	r.DELETE("/rooms/:id/commands/:command_id", handlers.DeleteSlashCommandHandler)
+	r.POST("/presence", handlers.UpdatePresenceHandler)
+	r.POST("/rooms/:id/typing", handlers.TypingHandler)
+	r.GET("/rooms/:id/presence", handlers.RoomPresenceHandler)
+	r.POST("/rooms/:id/pins", handlers.PinMessageHandler)
+	r.DELETE("/rooms/:id/pins/:message_id", handlers.UnpinMessageHandler)
+	r.GET("/rooms/:id/pins", handlers.PinsHandler)
+	r.POST("/me/saved", handlers.SaveMessageHandler)
+	r.GET("/me/saved", handlers.SavedMessagesHandler)
+	r.DELETE("/me/saved/:message_id", handlers.DeleteSavedMessageHandler)
+	r.POST("/rooms/:id/scheduled", handlers.ScheduleMessageHandler)
+	r.POST("/messages/:id/remind", handlers.RemindHandler)
+	r.GET("/me/scheduled", handlers.ScheduledMessagesHandler)
+	r.DELETE("/me/scheduled/:scheduled_id", handlers.CancelScheduledMessageHandler)
+	r.GET("/rooms/:id/retention", handlers.RetentionReportHandler)
+	r.POST("/rooms/:id/exports", handlers.CreateRoomExportHandler)
+	r.GET("/rooms/:id/exports", handlers.RoomExportsHandler)
+	r.GET("/rooms/:id/exports/:export_id", handlers.RoomExportHandler)
+	r.GET("/rooms/:id/exports/:export_id/download", handlers.DownloadRoomExportHandler)
 	r.GET("/health", handlers.HealthCheckHandler)
 }
@@ -35,6 +35,198 @@ func (m *MockHandlers) ReadChatMessages(c *gin.Context) {
 func (m *MockHandlers) DeleteChatMessageHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"status": "success"})
 }
+func (m *MockHandlers) SearchChatMessagesHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) EditChatMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ChatMessageRevisionsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DeletedChatMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ThreadHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) AddReactionHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RemoveReactionHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ReadUpToHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateRoomInviteHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) AcceptRoomInviteHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateJoinRequestHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) JoinRequestsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ApproveJoinRequestHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DenyJoinRequestHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) LeaveRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) KickMemberHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) BanMemberHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UnbanMemberHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) TransferOwnershipHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) SetMemberRoleHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UpdateRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ArchiveRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UnarchiveRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DeleteRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateDirectRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UploadAttachmentsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) AttachmentURLHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DownloadAttachmentHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DownloadThumbnailHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) MentionsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) NotificationSettingsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UpdateNotificationSettingsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) MuteRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UnmuteRoomHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateRoomWebhookHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RoomWebhooksHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DeleteRoomWebhookHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) WebhookDeliveriesHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RedeliverWebhookHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateIncomingWebhookHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) IncomingWebhooksHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DeleteIncomingWebhookHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) PostIncomingWebhookHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateSlashCommandHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) SlashCommandsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DeleteSlashCommandHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UpdatePresenceHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) TypingHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RoomPresenceHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) PinMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) UnpinMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) PinsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) SaveMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) SavedMessagesHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DeleteSavedMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ScheduleMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RemindHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) ScheduledMessagesHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CancelScheduledMessageHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RetentionReportHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) CreateRoomExportHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RoomExportsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) RoomExportHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
+func (m *MockHandlers) DownloadRoomExportHandler(c *gin.Context) {
+	c.JSON(200, gin.H{"status": "success"})
+}
 
 type MockMiddleware struct{}
 
@@ -48,6 +240,10 @@ func (m *MockMiddleware) AuthMW(c *gin.Context) {
 	c.Next()
 }
 
+func (m *MockMiddleware) UserMW(c *gin.Context) {
+	c.Next()
+}
+
 func TestRouting(t *testing.T) {
 	expected := map[string]string{
 		"/room_create": "POST",
@@ -56,7 +252,7 @@ func TestRouting(t *testing.T) {
 	r := gin.Default()
 	mwMock := &MockMiddleware{}
 	handlersMock := &MockHandlers{}
-	Routing(r, mwMock.CSRFMW, mwMock.AuthMW, handlersMock)
+	Routing(r, mwMock.CSRFMW, mwMock.AuthMW, mwMock.UserMW, handlersMock)
 
 	for path, method := range expected {
 		t.Run(path, func(t *testing.T) {
@@ -69,3 +265,47 @@ func TestRouting(t *testing.T) {
 		})
 	}
 }
+
+func TestRoutingSignedDownloadSkipsAuth(t *testing.T) {
+	r := gin.Default()
+	rejectMW := func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "not set jwt token"})
+	}
+	Routing(r, rejectMW, rejectMW, rejectMW, &MockHandlers{})
+
+	// 署名付きURLのダウンロードは認証を通さない
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/attachments/message_id/attachment_id", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/attachments/message_id/attachment_id/thumbnails/160", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/messages/message_id/attachments/attachment_id", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRoutingIncomingWebhookSkipsCSRF(t *testing.T) {
+	r := gin.Default()
+	rejectMW := func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid csrf token"})
+	}
+	Routing(r, rejectMW, rejectMW, rejectMW, &MockHandlers{})
+
+	// 受信 Webhook はトークンで認証するため CSRF・ログインを通さない
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/hooks/token", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 管理用の API は通常どおり CSRF・ログインが必要
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/rooms/room_id/incoming_webhooks", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,263 @@
+package attachment_svc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/pkg/storage_pkg"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAttachmentEmpty          = errors.New("attachment is empty")
+	ErrAttachmentTooLarge       = errors.New("attachment is too large")
+	ErrAttachmentTypeNotAllowed = errors.New("attachment type is not allowed")
+	ErrInvalidSignature         = errors.New("invalid attachment url signature")
+	ErrAttachmentURLExpired     = errors.New("attachment url has expired")
+	errAttachmentSizeMismatch   = errors.New("attachment size does not match")
+)
+
+// ATTACHMENT_ALLOWED_TYPES 未設定時に許可するMIMEタイプ
+var DefaultAllowedTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"application/zip",
+	"text/plain",
+}
+
+// MIMEタイプの判定に使う先頭のバイト数（http.DetectContentType が参照する長さ）
+const sniffLength = 512
+
+// ファイル名の最大文字数
+const maxAttachmentNameLength = 255
+
+type AttachmentSvcInterface interface {
+	Store(ctx context.Context, roomID string, name string, size int64, body io.Reader) (model.Attachment, error)
+	Open(ctx context.Context, attachment model.Attachment) (io.ReadCloser, error)
+	Remove(ctx context.Context, attachment model.Attachment) error
+	SignURL(messageID string, attachmentID string) (string, time.Time)
+	VerifyURL(messageID string, attachmentID string, expires string, signature string) error
+	OpenThumbnail(ctx context.Context, thumbnail model.Thumbnail) (io.ReadCloser, error)
+	SignThumbnailURL(messageID string, attachmentID string, size int) string
+	VerifyThumbnailURL(messageID string, attachmentID string, size int, expires string, signature string) error
+	MaxSize() int64
+}
+
+type AttachmentSvcStruct struct {
+	Storage      storage_pkg.BlobStorageInterface
+	Clock        clock_svc.ClockInterface
+	Secret       []byte // ダウンロードURLの署名に使う鍵
+	MaxBytes     int64  // 1ファイルあたりの上限
+	AllowedTypes []string
+	URLTTL       time.Duration // ダウンロードURLの有効期間
+}
+
+func NewAttachmentSvc(
+	storage storage_pkg.BlobStorageInterface,
+	clock clock_svc.ClockInterface,
+	secret string,
+	maxSize int64,
+	allowedTypes []string,
+	urlTTL time.Duration,
+) *AttachmentSvcStruct {
+	return &AttachmentSvcStruct{
+		Storage:      storage,
+		Clock:        clock,
+		Secret:       []byte(secret),
+		MaxBytes:     maxSize,
+		AllowedTypes: allowedTypes,
+		URLTTL:       urlTTL,
+	}
+}
+
+// 本文の先頭からMIMEタイプを判定する（パラメータは除く）
+func SniffContentType(head []byte) string {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return contentType
+}
+
+// パスを含むファイル名や長すぎるファイル名を保存用に整える
+func SanitizeName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	runes := []rune(name)
+	if len(runes) > maxAttachmentNameLength {
+		name = string(runes[:maxAttachmentNameLength])
+	}
+	return name
+}
+
+func (s *AttachmentSvcStruct) MaxSize() int64 {
+	return s.MaxBytes
+}
+
+func (s *AttachmentSvcStruct) allowed(contentType string) bool {
+	for _, allowed := range s.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// 読み込んだバイト数を数えながらチェックサムを計算する
+type hashingReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	read   int64
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	r.hash.Write(p[:n])
+	return n, err
+}
+
+// サイズと種類を確認してストレージに保存し、メッセージに記録するメタデータを返す
+func (s *AttachmentSvcStruct) Store(ctx context.Context, roomID string, name string, size int64, body io.Reader) (model.Attachment, error) {
+	if size > s.MaxBytes {
+		return model.Attachment{}, ErrAttachmentTooLarge
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return model.Attachment{}, err
+	}
+	if n == 0 {
+		return model.Attachment{}, ErrAttachmentEmpty
+	}
+	head = head[:n]
+
+	// クライアントが申告した Content-Type は信用せず、本文から判定する
+	contentType := SniffContentType(head)
+	if !s.allowed(contentType) {
+		return model.Attachment{}, fmt.Errorf("%w: %s", ErrAttachmentTypeNotAllowed, contentType)
+	}
+
+	id := primitive.NewObjectID().Hex()
+	attachment := model.Attachment{
+		ID:          id,
+		Name:        SanitizeName(name),
+		Size:        size,
+		ContentType: contentType,
+		StorageKey:  model.AttachmentStorageKey(roomID, id),
+	}
+	// 画像はサムネイルの生成待ちにする（生成はワーカーが行う）
+	if strings.HasPrefix(contentType, "image/") {
+		attachment.PreviewStatus = model.PreviewPending
+	}
+
+	// 申告より大きい本文は上限の1バイト先までしか読まない
+	reader := &hashingReader{
+		reader: io.MultiReader(bytes.NewReader(head), io.LimitReader(body, s.MaxBytes-int64(n)+1)),
+		hash:   sha256.New(),
+	}
+	if err := s.Storage.Put(ctx, attachment.StorageKey, reader, size, contentType); err != nil {
+		return model.Attachment{}, err
+	}
+	if reader.read != size {
+		s.Storage.Delete(ctx, attachment.StorageKey)
+		return model.Attachment{}, errAttachmentSizeMismatch
+	}
+	attachment.Checksum = hex.EncodeToString(reader.hash.Sum(nil))
+
+	return attachment, nil
+}
+
+func (s *AttachmentSvcStruct) Open(ctx context.Context, attachment model.Attachment) (io.ReadCloser, error) {
+	return s.Storage.Get(ctx, attachment.StorageKey)
+}
+
+func (s *AttachmentSvcStruct) OpenThumbnail(ctx context.Context, thumbnail model.Thumbnail) (io.ReadCloser, error) {
+	return s.Storage.Get(ctx, thumbnail.StorageKey)
+}
+
+// 元のファイルと生成済みのサムネイルを削除する
+func (s *AttachmentSvcStruct) Remove(ctx context.Context, attachment model.Attachment) error {
+	for _, thumbnail := range attachment.Thumbnails {
+		if err := s.Storage.Delete(ctx, thumbnail.StorageKey); err != nil {
+			return err
+		}
+	}
+	return s.Storage.Delete(ctx, attachment.StorageKey)
+}
+
+// 署名対象のパス（/attachments/ 以降）
+func attachmentPath(messageID string, attachmentID string) string {
+	return messageID + "/" + attachmentID
+}
+
+func thumbnailPath(messageID string, attachmentID string, size int) string {
+	return attachmentPath(messageID, attachmentID) + "/thumbnails/" + strconv.Itoa(size)
+}
+
+func (s *AttachmentSvcStruct) signature(path string, expires string) string {
+	h := hmac.New(sha256.New, s.Secret)
+	h.Write([]byte(path + ":" + expires))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *AttachmentSvcStruct) sign(path string) (string, time.Time) {
+	expiresAt := s.Clock.Now().Add(s.URLTTL).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	url := fmt.Sprintf("/attachments/%s?expires=%s&signature=%s", path, expires, s.signature(path, expires))
+	return url, expiresAt
+}
+
+// 有効期限付きのダウンロードURLを発行する
+func (s *AttachmentSvcStruct) SignURL(messageID string, attachmentID string) (string, time.Time) {
+	return s.sign(attachmentPath(messageID, attachmentID))
+}
+
+func (s *AttachmentSvcStruct) SignThumbnailURL(messageID string, attachmentID string, size int) string {
+	url, _ := s.sign(thumbnailPath(messageID, attachmentID, size))
+	return url
+}
+
+func (s *AttachmentSvcStruct) VerifyURL(messageID string, attachmentID string, expires string, signature string) error {
+	return s.verify(attachmentPath(messageID, attachmentID), expires, signature)
+}
+
+func (s *AttachmentSvcStruct) VerifyThumbnailURL(messageID string, attachmentID string, size int, expires string, signature string) error {
+	return s.verify(thumbnailPath(messageID, attachmentID, size), expires, signature)
+}
+
+func (s *AttachmentSvcStruct) verify(path string, expires string, signature string) error {
+	expected := s.signature(path, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if s.Clock.Now().Unix() > expiresAt {
+		return ErrAttachmentURLExpired
+	}
+	return nil
+}
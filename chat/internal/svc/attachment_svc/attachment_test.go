@@ -0,0 +1,172 @@
+package attachment_svc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_storage_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000IHDR")
+
+func TestSniffContentType(t *testing.T) {
+	assert.Equal(t, "image/png", SniffContentType(pngHeader))
+	assert.Equal(t, "text/plain", SniffContentType([]byte("hello")))
+	// 拡張子や申告値ではなく中身で判定する
+	assert.Equal(t, "text/html", SniffContentType([]byte("<html><script>alert(1)</script>")))
+}
+
+func TestSanitizeName(t *testing.T) {
+	assert.Equal(t, "photo.png", SanitizeName("../../photo.png"))
+	assert.Equal(t, "photo.png", SanitizeName(`C:\Users\me\photo.png`))
+	assert.Equal(t, "attachment", SanitizeName(" "))
+	assert.Len(t, []rune(SanitizeName(strings.Repeat("あ", 300))), maxAttachmentNameLength)
+}
+
+func TestStore(t *testing.T) {
+	png := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0}, 600)...)
+	checksum := sha256.Sum256(png)
+
+	tests := []struct {
+		name         string
+		body         []byte
+		size         int64
+		putErr       error
+		expectPut    bool
+		expectDelete bool
+		expectErr    error
+		returnErr    bool
+	}{
+		{"success", png, int64(len(png)), nil, true, false, nil, false},
+		{"too_large", png, 2048, nil, false, false, ErrAttachmentTooLarge, true},
+		{"empty", []byte{}, 0, nil, false, false, ErrAttachmentEmpty, true},
+		{"type_not_allowed", []byte("<html></html>"), 13, nil, false, false, ErrAttachmentTypeNotAllowed, true},
+		{"put_error", png, int64(len(png)), assert.AnError, true, false, assert.AnError, true},
+		// 申告されたサイズと実際のサイズが異なる場合は保存したものを消す
+		{"size_mismatch", png, int64(len(png)) - 1, nil, true, true, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(mock_storage_pkg.BlobStorageMock)
+			var stored []byte
+			storage.On("Put", mock.Anything, mock.MatchedBy(func(key string) bool {
+				return strings.HasPrefix(key, "rooms/room1/attachments/")
+			}), mock.Anything, tt.size, "image/png").Run(func(args mock.Arguments) {
+				stored, _ = io.ReadAll(args.Get(2).(io.Reader))
+			}).Return(tt.putErr)
+			storage.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+			svc := NewAttachmentSvc(storage, mock_clock_svc.FixedClock{}, "secret", 1024, DefaultAllowedTypes, time.Minute)
+			attachment, err := svc.Store(context.Background(), "room1", "dir/photo.png", tt.size, bytes.NewReader(tt.body))
+
+			if tt.returnErr {
+				assert.Error(t, err)
+				if tt.expectErr != nil {
+					assert.ErrorIs(t, err, tt.expectErr)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "photo.png", attachment.Name)
+				assert.Equal(t, "image/png", attachment.ContentType)
+				assert.Equal(t, model.PreviewPending, attachment.PreviewStatus)
+				assert.Equal(t, int64(len(png)), attachment.Size)
+				assert.Equal(t, hex.EncodeToString(checksum[:]), attachment.Checksum)
+				assert.Equal(t, "rooms/room1/attachments/"+attachment.ID, attachment.StorageKey)
+				assert.Equal(t, png, stored)
+			}
+			if tt.expectPut {
+				storage.AssertCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything, tt.size, "image/png")
+			} else {
+				storage.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.expectDelete {
+				storage.AssertCalled(t, "Delete", mock.Anything, mock.Anything)
+			} else {
+				storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestSignURL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewAttachmentSvc(nil, mock_clock_svc.FixedClock{FixedTime: now}, "secret", 1024, DefaultAllowedTypes, 5*time.Minute)
+
+	signed, expiresAt := svc.SignURL("message1", "attachment1")
+	assert.Equal(t, now.Add(5*time.Minute), expiresAt)
+
+	parsed, err := url.Parse(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, "/attachments/message1/attachment1", parsed.Path)
+	expires := parsed.Query().Get("expires")
+	signature := parsed.Query().Get("signature")
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		attachmentID string
+		expires      string
+		signature    string
+		expectErr    error
+	}{
+		{"valid", now, "attachment1", expires, signature, nil},
+		{"expired", now.Add(6 * time.Minute), "attachment1", expires, signature, ErrAttachmentURLExpired},
+		{"other_attachment", now, "attachment2", expires, signature, ErrInvalidSignature},
+		{"extended_expiry", now, "attachment1", "9999999999", signature, ErrInvalidSignature},
+		{"invalid_signature", now, "attachment1", expires, "invalid", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.Clock = mock_clock_svc.FixedClock{FixedTime: tt.now}
+			err := svc.VerifyURL("message1", tt.attachmentID, tt.expires, tt.signature)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSignThumbnailURL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := NewAttachmentSvc(nil, mock_clock_svc.FixedClock{FixedTime: now}, "secret", 1024, DefaultAllowedTypes, 5*time.Minute)
+
+	parsed, err := url.Parse(svc.SignThumbnailURL("message1", "attachment1", 160))
+	assert.NoError(t, err)
+	assert.Equal(t, "/attachments/message1/attachment1/thumbnails/160", parsed.Path)
+	expires := parsed.Query().Get("expires")
+	signature := parsed.Query().Get("signature")
+
+	assert.NoError(t, svc.VerifyThumbnailURL("message1", "attachment1", 160, expires, signature))
+	assert.ErrorIs(t, svc.VerifyThumbnailURL("message1", "attachment1", 480, expires, signature), ErrInvalidSignature)
+	// サムネイルの署名で元のファイルはダウンロードできない
+	assert.ErrorIs(t, svc.VerifyURL("message1", "attachment1", expires, signature), ErrInvalidSignature)
+}
+
+func TestRemove(t *testing.T) {
+	storage := new(mock_storage_pkg.BlobStorageMock)
+	storage.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	svc := NewAttachmentSvc(storage, mock_clock_svc.FixedClock{}, "secret", 1024, DefaultAllowedTypes, time.Minute)
+	err := svc.Remove(context.Background(), model.Attachment{
+		StorageKey: "original",
+		Thumbnails: []model.Thumbnail{{StorageKey: "original/thumbnails/160"}},
+	})
+	assert.NoError(t, err)
+	storage.AssertCalled(t, "Delete", mock.Anything, "original")
+	storage.AssertCalled(t, "Delete", mock.Anything, "original/thumbnails/160")
+}
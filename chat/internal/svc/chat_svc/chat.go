@@ -5,6 +5,7 @@ import "microservices/chat/internal/model"
 type ChatSvcInterface interface {
 	ConvertRoomList(rooms []model.Room, userId int) []Room
 	GetRoomInfo(room model.Room, userId int) Room
+	ConvertSearchResults(hits []model.ChatMessageSearchHit, keyword string) []SearchResult
 }
 
 type ChatSvcStruct struct{}
@@ -14,14 +15,45 @@ func NewChatSvc() *ChatSvcStruct {
 }
 
 type Room struct {
-	ID          string
-	Name        string
-	OwnerID     int
-	IsPrivate   bool
-	IsMember    bool
-	IsOwner     bool
-	MemberCount int
-	CreatedAt   string
+	ID           string
+	Name         string
+	OwnerID      int
+	IsPrivate    bool
+	IsMember     bool
+	IsOwner      bool
+	MemberCount  int
+	CreatedAt    string
+	Description  string
+	Topic        string
+	AvatarURL    string
+	IsArchived   bool
+	IsDirect     bool
+	Participants []int // ダイレクトメッセージの場合のみ参加者を返す
+	UnreadCount  int64
+	Role         string // 呼び出し元の役割。メンバーでない場合は空文字
+	Capabilities Capabilities
+
+	LastActivityAt string
+	LastMessage    *LastMessage // メッセージが無い場合は nil
+
+	Pins []Pin // 閲覧できない場合は nil
+
+	RetentionDays int // メッセージの保持日数。0 の場合は無期限
+}
+
+type Pin struct {
+	MessageID string
+	PinnedBy  int
+	PinnedAt  string
+}
+
+// ルーム一覧に表示する最新メッセージのプレビュー
+type LastMessage struct {
+	MessageID string
+	UserID    int
+	Snippet   string
+	CreatedAt string
+	IsDeleted bool
 }
 
 func contains(members []int, target int) bool {
@@ -34,15 +66,63 @@ func contains(members []int, target int) bool {
 }
 
 func (s *ChatSvcStruct) GetRoomInfo(room model.Room, userId int) Room {
+	role := room.RoleOf(userId)
+	capabilities := CapabilitiesOf(role)
+	// アーカイブ済みのルームは閲覧のみ。オーナーはアーカイブの解除や削除のために管理権限を残す
+	if room.Archived() {
+		capabilities = Capabilities{CanRead: capabilities.CanRead, CanManage: capabilities.CanManage}
+	}
+	var participants []int
+	if room.IsDirect() {
+		participants = room.Members
+	}
+	// メッセージが無いルームは作成日時を最終アクティビティとする
+	lastActivityAt := room.CreatedAt
+	if room.LastActivityAt != nil {
+		lastActivityAt = *room.LastActivityAt
+	}
+	// 最新メッセージのプレビューはピン留めと同じく閲覧できるユーザーにのみ返す
+	var lastMessage *LastMessage
+	if room.LastMessage != nil && capabilities.CanRead {
+		lastMessage = &LastMessage{
+			MessageID: room.LastMessage.MessageID,
+			UserID:    room.LastMessage.UserID,
+			Snippet:   room.LastMessage.Snippet,
+			CreatedAt: room.LastMessage.CreatedAt.String(),
+			IsDeleted: room.LastMessage.Deleted,
+		}
+	}
+	var pins []Pin
+	if capabilities.CanRead {
+		pins = make([]Pin, 0, len(room.Pins))
+		for _, pin := range room.Pins {
+			pins = append(pins, Pin{MessageID: pin.MessageID, PinnedBy: pin.PinnedBy, PinnedAt: pin.PinnedAt.String()})
+		}
+	}
 	return Room{
-		ID:          room.ID.Hex(),
-		Name:        room.Name,
-		OwnerID:     room.OwnerID,
-		IsPrivate:   room.IsPrivate,
-		IsMember:    contains(room.Members, userId),
-		IsOwner:     room.OwnerID == userId,
-		MemberCount: len(room.Members),
-		CreatedAt:   room.CreatedAt.String(),
+		ID:           room.ID.Hex(),
+		Name:         room.Name,
+		OwnerID:      room.OwnerID,
+		IsPrivate:    room.IsPrivate,
+		IsMember:     contains(room.Members, userId),
+		IsOwner:      room.OwnerID == userId,
+		MemberCount:  len(room.Members),
+		CreatedAt:    room.CreatedAt.String(),
+		Description:  room.Description,
+		Topic:        room.Topic,
+		AvatarURL:    room.AvatarURL,
+		IsArchived:   room.Archived(),
+		IsDirect:     room.IsDirect(),
+		Participants: participants,
+		Role:         role,
+		Capabilities: capabilities,
+
+		LastActivityAt: lastActivityAt.String(),
+		LastMessage:    lastMessage,
+
+		Pins: pins,
+
+		RetentionDays: room.RetentionDays,
 	}
 }
 
@@ -70,3 +70,58 @@ func TestConvertRoomList(t *testing.T) {
 		}
 	}
 }
+
+func TestGetRoomInfoLastMessage(t *testing.T) {
+	svc := NewChatSvc()
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	postedAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	// メッセージが無いルームは作成日時が最終アクティビティになる
+	empty := svc.GetRoomInfo(model.Room{CreatedAt: createdAt}, 1)
+	if empty.LastActivityAt != createdAt.String() || empty.LastMessage != nil {
+		t.Errorf("unexpected activity for empty room: %+v", empty)
+	}
+
+	publicRoom := model.Room{
+		CreatedAt:      createdAt,
+		Members:        []int{1, 2},
+		LastActivityAt: &postedAt,
+		LastMessage:    &model.RoomLastMessage{MessageID: "message1", UserID: 2, Snippet: "hello", CreatedAt: postedAt},
+	}
+	room := svc.GetRoomInfo(publicRoom, 1)
+	if room.LastActivityAt != postedAt.String() {
+		t.Errorf("expected last activity %s, got %s", postedAt, room.LastActivityAt)
+	}
+	expected := LastMessage{MessageID: "message1", UserID: 2, Snippet: "hello", CreatedAt: postedAt.String()}
+	if room.LastMessage == nil || *room.LastMessage != expected {
+		t.Errorf("expected last message %+v, got %+v", expected, room.LastMessage)
+	}
+
+	// 公開ルームでもメンバー以外には最新メッセージを返さない
+	outsider := svc.GetRoomInfo(publicRoom, 3)
+	if outsider.LastMessage != nil {
+		t.Errorf("expected no last message for outsider, got %+v", outsider.LastMessage)
+	}
+}
+
+func TestGetRoomInfoPins(t *testing.T) {
+	svc := NewChatSvc()
+	pinnedAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	room := model.Room{
+		OwnerID: 1,
+		Members: []int{1, 2},
+		Pins:    []model.RoomPin{{MessageID: "message1", PinnedBy: 1, PinnedAt: pinnedAt}},
+	}
+
+	member := svc.GetRoomInfo(room, 2)
+	expected := []Pin{{MessageID: "message1", PinnedBy: 1, PinnedAt: pinnedAt.String()}}
+	if len(member.Pins) != 1 || member.Pins[0] != expected[0] {
+		t.Errorf("expected pins %+v, got %+v", expected, member.Pins)
+	}
+
+	// メンバー以外にはピン留めを返さない
+	outsider := svc.GetRoomInfo(room, 3)
+	if outsider.Pins != nil {
+		t.Errorf("expected no pins for outsider, got %+v", outsider.Pins)
+	}
+}
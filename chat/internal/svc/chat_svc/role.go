@@ -0,0 +1,47 @@
+package chat_svc
+
+import "microservices/chat/internal/model"
+
+type Capability string
+
+const (
+	CapabilityRead     Capability = "read"     // メッセージの閲覧・既読
+	CapabilityPost     Capability = "post"     // 投稿・返信・リアクション
+	CapabilityModerate Capability = "moderate" // 他人のメッセージ削除・キック・BAN・招待・参加申請の承認
+	CapabilityManage   Capability = "manage"   // 役割の変更・オーナーの譲渡
+)
+
+type Capabilities struct {
+	CanRead     bool
+	CanPost     bool
+	CanModerate bool
+	CanManage   bool
+}
+
+func CapabilitiesOf(role string) Capabilities {
+	switch role {
+	case model.RoleOwner:
+		return Capabilities{CanRead: true, CanPost: true, CanModerate: true, CanManage: true}
+	case model.RoleModerator:
+		return Capabilities{CanRead: true, CanPost: true, CanModerate: true}
+	case model.RoleMember:
+		return Capabilities{CanRead: true, CanPost: true}
+	case model.RoleReadOnly:
+		return Capabilities{CanRead: true}
+	}
+	return Capabilities{}
+}
+
+func (r Room) Can(capability Capability) bool {
+	switch capability {
+	case CapabilityRead:
+		return r.Capabilities.CanRead
+	case CapabilityPost:
+		return r.Capabilities.CanPost
+	case CapabilityModerate:
+		return r.Capabilities.CanModerate
+	case CapabilityManage:
+		return r.Capabilities.CanManage
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package chat_svc
+
+import (
+	"microservices/chat/internal/model"
+	"testing"
+	"time"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	tests := []struct {
+		role   string
+		expect Capabilities
+	}{
+		{model.RoleOwner, Capabilities{CanRead: true, CanPost: true, CanModerate: true, CanManage: true}},
+		{model.RoleModerator, Capabilities{CanRead: true, CanPost: true, CanModerate: true}},
+		{model.RoleMember, Capabilities{CanRead: true, CanPost: true}},
+		{model.RoleReadOnly, Capabilities{CanRead: true}},
+		{"", Capabilities{}},
+	}
+
+	for _, tt := range tests {
+		if got := CapabilitiesOf(tt.role); got != tt.expect {
+			t.Errorf("role %q: expected %+v, got %+v", tt.role, tt.expect, got)
+		}
+	}
+}
+
+func TestGetRoomInfoRole(t *testing.T) {
+	room := model.Room{
+		OwnerID: 1,
+		Members: []int{1, 2, 3},
+		Roles:   map[string]string{"3": model.RoleReadOnly},
+	}
+
+	svc := NewChatSvc()
+
+	expect := []struct {
+		userID  int
+		role    string
+		canRead bool
+		canPost bool
+	}{
+		{1, model.RoleOwner, true, true},
+		{2, model.RoleMember, true, true},
+		{3, model.RoleReadOnly, true, false},
+		{4, "", false, false},
+	}
+
+	for _, e := range expect {
+		info := svc.GetRoomInfo(room, e.userID)
+		if info.Role != e.role {
+			t.Errorf("user %d: expected Role %q, got %q", e.userID, e.role, info.Role)
+		}
+		if info.Can(CapabilityRead) != e.canRead {
+			t.Errorf("user %d: expected CanRead %v", e.userID, e.canRead)
+		}
+		if info.Can(CapabilityPost) != e.canPost {
+			t.Errorf("user %d: expected CanPost %v", e.userID, e.canPost)
+		}
+	}
+}
+
+func TestGetRoomInfoArchived(t *testing.T) {
+	archivedAt := time.Now()
+	room := model.Room{
+		OwnerID:    1,
+		Members:    []int{1, 2},
+		Roles:      map[string]string{"2": model.RoleModerator},
+		ArchivedAt: &archivedAt,
+	}
+
+	svc := NewChatSvc()
+
+	owner := svc.GetRoomInfo(room, 1)
+	if !owner.IsArchived {
+		t.Errorf("expected IsArchived true")
+	}
+	if owner.Capabilities != (Capabilities{CanRead: true, CanManage: true}) {
+		t.Errorf("owner: unexpected capabilities %+v", owner.Capabilities)
+	}
+
+	moderator := svc.GetRoomInfo(room, 2)
+	if moderator.Capabilities != (Capabilities{CanRead: true}) {
+		t.Errorf("moderator: unexpected capabilities %+v", moderator.Capabilities)
+	}
+}
+
+func TestGetRoomInfoDirect(t *testing.T) {
+	svc := NewChatSvc()
+
+	direct := svc.GetRoomInfo(model.Room{Kind: model.RoomKindDirect, IsPrivate: true, Members: []int{1, 2}}, 1)
+	if !direct.IsDirect || len(direct.Participants) != 2 {
+		t.Errorf("expected direct room with participants, got %+v", direct)
+	}
+	// ダイレクトメッセージにはオーナーがいないので管理・モデレーション権限は無い
+	if direct.Capabilities != (Capabilities{CanRead: true, CanPost: true}) {
+		t.Errorf("unexpected capabilities %+v", direct.Capabilities)
+	}
+
+	room := svc.GetRoomInfo(model.Room{Members: []int{1, 2}}, 1)
+	if room.IsDirect || room.Participants != nil {
+		t.Errorf("expected normal room without participants, got %+v", room)
+	}
+}
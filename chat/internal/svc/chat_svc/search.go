@@ -0,0 +1,129 @@
+package chat_svc
+
+import (
+	"html"
+	"microservices/chat/internal/model"
+	"strings"
+	"unicode"
+)
+
+// ヒット箇所の前後に残す文字数
+const snippetRadius = 30
+
+type SearchResult struct {
+	ID        string
+	RoomID    string
+	UserID    int
+	Message   string
+	Snippet   string // ヒット箇所を<mark>で囲ったHTMLエスケープ済みの抜粋
+	Score     float64
+	CreatedAt string
+}
+
+// 検索キーワードからハイライト対象の単語を取り出す（除外指定の "-word" は対象外）
+func searchTerms(keyword string) [][]rune {
+	var terms [][]rune
+	for _, field := range strings.Fields(keyword) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		field = strings.Trim(field, `"`)
+		if field == "" {
+			continue
+		}
+		terms = append(terms, toLowerRunes([]rune(field)))
+	}
+	return terms
+}
+
+func toLowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func hasPrefixRunes(s []rune, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// message 中でいずれかの単語にマッチする範囲を [start, end) のrune位置で返す
+func matchRanges(message []rune, terms [][]rune) [][2]int {
+	lower := toLowerRunes(message)
+	var ranges [][2]int
+	for i := 0; i < len(lower); {
+		matched := 0
+		for _, term := range terms {
+			if len(term) > matched && hasPrefixRunes(lower[i:], term) {
+				matched = len(term)
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		ranges = append(ranges, [2]int{i, i + matched})
+		i += matched
+	}
+	return ranges
+}
+
+func BuildSnippet(message string, keyword string) string {
+	runes := []rune(message)
+	ranges := matchRanges(runes, searchTerms(keyword))
+
+	// 最初のヒット箇所を中心に切り出す（ヒットしない場合は先頭から）
+	start, end := 0, len(runes)
+	if len(ranges) > 0 {
+		start = max(ranges[0][0]-snippetRadius, 0)
+		end = min(ranges[0][1]+snippetRadius, len(runes))
+	} else {
+		end = min(snippetRadius*2, len(runes))
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, r := range ranges {
+		if r[0] < start || r[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:r[0]])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[r[0]:r[1]])))
+		b.WriteString("</mark>")
+		pos = r[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func (s *ChatSvcStruct) ConvertSearchResults(hits []model.ChatMessageSearchHit, keyword string) []SearchResult {
+	results := []SearchResult{}
+	for _, hit := range hits {
+		results = append(results, SearchResult{
+			ID:        hit.ID.Hex(),
+			RoomID:    hit.RoomID,
+			UserID:    hit.UserID,
+			Message:   hit.Message,
+			Snippet:   BuildSnippet(hit.Message, keyword),
+			Score:     hit.Score,
+			CreatedAt: hit.CreatedAt.String(),
+		})
+	}
+	return results
+}
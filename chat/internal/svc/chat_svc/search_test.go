@@ -0,0 +1,69 @@
+package chat_svc
+
+import (
+	"microservices/chat/internal/model"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildSnippet(t *testing.T) {
+	long := strings.Repeat("a", 40) + " Hello " + strings.Repeat("b", 40)
+
+	tests := []struct {
+		name    string
+		message string
+		keyword string
+		expect  string
+	}{
+		{"highlight", "hello world", "hello", "<mark>hello</mark> world"},
+		{"case_insensitive", "Hello HELLO", "hello", "<mark>Hello</mark> <mark>HELLO</mark>"},
+		{"multiple_terms", "go is fun", `go "fun" -is`, "<mark>go</mark> is <mark>fun</mark>"},
+		{"escape_html", "<b>hello</b>", "hello", "&lt;b&gt;<mark>hello</mark>&lt;/b&gt;"},
+		{"multibyte", "今日はGoの勉強", "go", "今日は<mark>Go</mark>の勉強"},
+		{"no_match", "hello world", "xyz", "hello world"},
+		{"trim", long, "hello", "…" + strings.Repeat("a", 29) + " <mark>Hello</mark> " + strings.Repeat("b", 29) + "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildSnippet(tt.message, tt.keyword); got != tt.expect {
+				t.Errorf("expected %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestConvertSearchResults(t *testing.T) {
+	id := primitive.NewObjectID()
+	hits := []model.ChatMessageSearchHit{
+		{
+			ChatMessage: model.ChatMessage{
+				ID:        id,
+				RoomID:    "room1",
+				UserID:    1,
+				Message:   "hello world",
+				CreatedAt: time.Now(),
+			},
+			Score: 1.5,
+		},
+	}
+
+	svc := NewChatSvc()
+	results := svc.ConvertSearchResults(hits, "world")
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].ID != id.Hex() {
+		t.Errorf("expected ID %s, got %s", id.Hex(), results[0].ID)
+	}
+	if results[0].Snippet != "hello <mark>world</mark>" {
+		t.Errorf("unexpected snippet %s", results[0].Snippet)
+	}
+	if results[0].Score != 1.5 {
+		t.Errorf("expected Score 1.5, got %f", results[0].Score)
+	}
+}
@@ -0,0 +1,245 @@
+package export_svc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/storage_pkg"
+	"os"
+	"strconv"
+	"time"
+)
+
+type ExportSvcInterface interface {
+	RunRoomExports() error
+	Open(ctx context.Context, export model.RoomExport) (io.ReadCloser, error)
+}
+
+type ExportSvcStruct struct {
+	MongoSvc mongo_svc.MongoSvcInterface
+	MongoPkg mongo_pkg.MongoPkgInterface
+	Clock    clock_svc.ClockInterface
+	Storage  storage_pkg.BlobStorageInterface
+
+	Lease     time.Duration // 1ページ書き出すごとに延長する
+	BatchSize int           // 1回の実行で処理するエクスポートの件数
+	PageSize  int           // 一度に読み込むメッセージの件数
+	TempDir   string        // 書き出し中のファイルを置くディレクトリ（空の場合は OS の既定）
+}
+
+func NewExportSvc(
+	mongoSvc mongo_svc.MongoSvcInterface,
+	mongoPkg mongo_pkg.MongoPkgInterface,
+	clock clock_svc.ClockInterface,
+	storage storage_pkg.BlobStorageInterface,
+) *ExportSvcStruct {
+	return &ExportSvcStruct{
+		MongoSvc:  mongoSvc,
+		MongoPkg:  mongoPkg,
+		Clock:     clock,
+		Storage:   storage,
+		Lease:     5 * time.Minute,
+		BatchSize: 2,
+		PageSize:  500,
+	}
+}
+
+func (s *ExportSvcStruct) Open(ctx context.Context, export model.RoomExport) (io.ReadCloser, error) {
+	return s.Storage.Get(ctx, export.StorageKey)
+}
+
+// 待機中のエクスポートを書き出してストレージに保存する
+func (s *ExportSvcStruct) RunRoomExports() error {
+	expired, err := s.MongoSvc.ExpireRoomExportLeases(s.Clock.Now(), s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		log.Printf("marked %d room exports as failed after their lease expired", expired)
+	}
+
+	claimed, err := s.MongoSvc.ClaimPendingRoomExports(s.Clock.Now(), s.Lease, s.BatchSize, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, export := range claimed {
+		if err := s.run(export); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ExportSvcStruct) run(export model.RoomExport) error {
+	err := s.export(&export)
+	if errors.Is(err, mongo_svc.ErrExportLeaseLost) {
+		// 既に失敗にされているため状態は変更しない
+		log.Printf("room export %s lost its lease", export.ID.Hex())
+		return nil
+	}
+	if err != nil {
+		log.Printf("room export %s for room %s failed: %v", export.ID.Hex(), export.RoomID, err)
+		export.Status = model.ExportFailed
+		export.StorageKey = ""
+		export.Error = err.Error()
+	} else {
+		export.Status = model.ExportCompleted
+	}
+
+	err = s.MongoSvc.CompleteRoomExport(export, s.Clock.Now(), s.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrExportLeaseLost) {
+		log.Printf("room export %s lost its lease", export.ID.Hex())
+		// 書き出している間にルームが削除された場合などはファイルを参照するエクスポートが残らない
+		if export.StorageKey != "" {
+			return s.Storage.Delete(context.Background(), export.StorageKey)
+		}
+		return nil
+	}
+	return err
+}
+
+// 一時ファイルに書き出してからストレージに保存する。メッセージはページごとに読み込むため、ルーム全体をメモリに載せない
+func (s *ExportSvcStruct) export(export *model.RoomExport) error {
+	room, err := s.MongoSvc.GetRoomByID(export.RoomID, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(s.TempDir, "room-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	count, err := s.write(*export, room, file)
+	if err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := model.RoomExportStorageKey(export.RoomID, export.ID.Hex(), export.Format)
+	if err := s.Storage.Put(context.Background(), key, file, size, ContentType(export.Format)); err != nil {
+		return err
+	}
+
+	export.StorageKey = key
+	export.MessageCount = count
+	export.Size = size
+	return nil
+}
+
+func (s *ExportSvcStruct) write(export model.RoomExport, room model.Room, w io.Writer) (int64, error) {
+	buffered := bufio.NewWriter(w)
+	writer, err := newExportWriter(export.Format, buffered)
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.Begin(room); err != nil {
+		return 0, err
+	}
+
+	names := map[int]string{}
+	var count int64
+	afterID := ""
+	for {
+		messages, err := s.MongoSvc.GetChatMessagesAfter(export.RoomID, afterID, s.PageSize, s.MongoPkg)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.resolveNames(messages, names); err != nil {
+			return 0, err
+		}
+		for _, message := range messages {
+			if err := writer.Write(newRecord(message, names)); err != nil {
+				return 0, err
+			}
+			count++
+		}
+		if len(messages) < s.PageSize {
+			break
+		}
+		afterID = messages[len(messages)-1].ID.Hex()
+
+		// 他のワーカーに失敗にされないように lease を延長する
+		err = s.MongoSvc.ExtendRoomExportLease(export.ID.Hex(), s.Clock.Now().Add(s.Lease), s.MongoPkg)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := writer.End(); err != nil {
+		return 0, err
+	}
+	return count, buffered.Flush()
+}
+
+// まだ名前を取得していない投稿者だけを問い合わせる
+func (s *ExportSvcStruct) resolveNames(messages []model.ChatMessage, names map[int]string) error {
+	var userIDs []int
+	seen := map[int]bool{}
+	for _, message := range messages {
+		if message.BotID != "" || seen[message.UserID] {
+			continue
+		}
+		if _, ok := names[message.UserID]; ok {
+			continue
+		}
+		seen[message.UserID] = true
+		userIDs = append(userIDs, message.UserID)
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	users, err := s.MongoSvc.GetUsersByIDs(userIDs, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		names[user.UserID] = user.Handle
+	}
+	// ユーザー情報が無い投稿者は ID で表示する
+	for _, userID := range userIDs {
+		if names[userID] == "" {
+			names[userID] = "user-" + strconv.Itoa(userID)
+		}
+	}
+	return nil
+}
+
+func newRecord(message model.ChatMessage, names map[int]string) Record {
+	record := Record{
+		ID:           message.ID.Hex(),
+		CreatedAt:    message.CreatedAt,
+		UserID:       message.UserID,
+		UserName:     names[message.UserID],
+		Message:      message.Message,
+		ParentID:     message.ParentID,
+		ThreadRootID: message.ThreadRootID,
+		EditedAt:     message.EditedAt,
+	}
+	if message.BotID != "" {
+		record.UserID = 0
+		record.UserName = message.BotName
+	}
+	for _, attachment := range message.Attachments {
+		record.Attachments = append(record.Attachments, attachment.Name)
+	}
+	return record
+}
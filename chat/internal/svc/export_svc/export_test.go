@@ -0,0 +1,118 @@
+package export_svc
+
+import (
+	"context"
+	"io"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_storage_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRunRoomExports(t *testing.T) {
+	first := model.ChatMessage{ID: primitive.NewObjectID(), UserID: 1, Message: "hello", CreatedAt: now}
+	second := model.ChatMessage{ID: primitive.NewObjectID(), UserID: 2, Message: "hi", CreatedAt: now}
+	third := model.ChatMessage{ID: primitive.NewObjectID(), BotID: "hook1", BotName: "deploy-bot", Message: "deployed", CreatedAt: now}
+
+	tests := []struct {
+		name         string
+		pageErr      error
+		extendErr    error
+		putErr       error
+		completeErr  error
+		expectStatus string // 空の場合は状態を変更しない
+		expectError  string
+	}{
+		{"completed", nil, nil, nil, nil, model.ExportCompleted, ""},
+		{"read_error", assert.AnError, nil, nil, nil, model.ExportFailed, assert.AnError.Error()},
+		{"put_error", nil, nil, assert.AnError, nil, model.ExportFailed, assert.AnError.Error()},
+		// lease が切れたエクスポートは既に失敗になっている
+		{"lease_lost", nil, mongo_svc.ErrExportLeaseLost, nil, nil, "", ""},
+		// 保存した後に失敗にされた（ルームが削除された）場合はファイルを削除する
+		{"lease_lost_after_put", nil, nil, nil, mongo_svc.ErrExportLeaseLost, model.ExportCompleted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := model.RoomExport{ID: primitive.NewObjectID(), RoomID: "room1", Format: model.ExportFormatJSONL, Status: model.ExportProcessing}
+			key := model.RoomExportStorageKey("room1", export.ID.Hex(), model.ExportFormatJSONL)
+
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("ExpireRoomExportLeases", now, mongoPkgMock).Return(int64(0), nil)
+			mongoSvcMock.On("ClaimPendingRoomExports", now, 5*time.Minute, 2, mongoPkgMock).Return([]model.RoomExport{export}, nil)
+			mongoSvcMock.On("GetRoomByID", "room1", mongoPkgMock).Return(model.Room{Name: "Team"}, nil)
+			// 1ページ目は PageSize 件あるので次のページも読み込む
+			mongoSvcMock.On("GetChatMessagesAfter", "room1", "", 2, mongoPkgMock).Return([]model.ChatMessage{first, second}, tt.pageErr)
+			mongoSvcMock.On("GetChatMessagesAfter", "room1", second.ID.Hex(), 2, mongoPkgMock).Return([]model.ChatMessage{third}, nil)
+			// ユーザー情報が無い投稿者は ID で表示する
+			mongoSvcMock.On("GetUsersByIDs", []int{1, 2}, mongoPkgMock).Return([]model.User{{UserID: 1, Handle: "alice"}}, nil)
+			mongoSvcMock.On("ExtendRoomExportLease", export.ID.Hex(), now.Add(5*time.Minute), mongoPkgMock).Return(tt.extendErr)
+			mongoSvcMock.On("CompleteRoomExport", mock.Anything, now, mongoPkgMock).Return(tt.completeErr)
+
+			var stored string
+			storageMock := new(mock_storage_pkg.BlobStorageMock)
+			storageMock.On("Put", mock.Anything, key, mock.Anything, mock.Anything, "application/x-ndjson").Run(func(args mock.Arguments) {
+				body, _ := io.ReadAll(args.Get(2).(io.Reader))
+				stored = string(body)
+				assert.Equal(t, int64(len(body)), args.Get(3).(int64))
+			}).Return(tt.putErr)
+			storageMock.On("Delete", mock.Anything, key).Return(nil)
+
+			svc := NewExportSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, storageMock)
+			svc.PageSize = 2
+			svc.TempDir = t.TempDir()
+			assert.NoError(t, svc.RunRoomExports())
+
+			if tt.expectStatus == "" {
+				mongoSvcMock.AssertNotCalled(t, "CompleteRoomExport", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			mongoSvcMock.AssertCalled(t, "CompleteRoomExport", mock.MatchedBy(func(completed model.RoomExport) bool {
+				if completed.Status != tt.expectStatus || completed.Error != tt.expectError {
+					return false
+				}
+				if tt.expectStatus == model.ExportFailed {
+					return completed.StorageKey == ""
+				}
+				return completed.StorageKey == key && completed.MessageCount == 3 && completed.Size == int64(len(stored))
+			}), now, mongoPkgMock)
+			if tt.completeErr != nil {
+				storageMock.AssertCalled(t, "Delete", mock.Anything, key)
+			} else {
+				storageMock.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+			}
+
+			if tt.expectStatus == model.ExportCompleted {
+				lines := strings.Split(strings.TrimSpace(stored), "\n")
+				if assert.Len(t, lines, 3) {
+					assert.Contains(t, lines[0], `"user_name":"alice"`)
+					assert.Contains(t, lines[1], `"user_name":"user-2"`)
+					assert.Contains(t, lines[2], `"user_name":"deploy-bot"`)
+				}
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	storageMock := new(mock_storage_pkg.BlobStorageMock)
+	storageMock.On("Get", mock.Anything, "rooms/room1/exports/e.csv").Return(io.NopCloser(strings.NewReader("id\n")), nil)
+
+	svc := NewExportSvc(nil, nil, mock_clock_svc.FixedClock{FixedTime: now}, storageMock)
+	body, err := svc.Open(context.Background(), model.RoomExport{StorageKey: "rooms/room1/exports/e.csv"})
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(body)
+	assert.Equal(t, "id\n", string(content))
+}
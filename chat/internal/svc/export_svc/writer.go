@@ -0,0 +1,179 @@
+package export_svc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"microservices/chat/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// エクスポートする1件のメッセージ。投稿者はユーザー名に解決しておく
+type Record struct {
+	ID           string     `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UserID       int        `json:"user_id,omitempty"` // ボットの投稿の場合は 0
+	UserName     string     `json:"user_name"`
+	Message      string     `json:"message"`
+	ParentID     string     `json:"parent_id,omitempty"`
+	ThreadRootID string     `json:"thread_root_id,omitempty"`
+	EditedAt     *time.Time `json:"edited_at,omitempty"`
+	Attachments  []string   `json:"attachments,omitempty"` // ファイル名のみ（本体は含めない）
+}
+
+// 形式ごとの書き出し。Begin・Write・End の順に呼ぶ
+type exportWriter interface {
+	Begin(room model.Room) error
+	Write(record Record) error
+	End() error
+}
+
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case model.ExportFormatJSONL:
+		return newJSONLWriter(w), nil
+	case model.ExportFormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case model.ExportFormatHTML:
+		return &htmlWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+func ContentType(format string) string {
+	switch format {
+	case model.ExportFormatJSONL:
+		return "application/x-ndjson"
+	case model.ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case model.ExportFormatHTML:
+		return "text/html; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// 1行に1件の JSON
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &jsonlWriter{encoder: encoder}
+}
+
+func (w *jsonlWriter) Begin(room model.Room) error {
+	return nil
+}
+
+func (w *jsonlWriter) Write(record Record) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonlWriter) End() error {
+	return nil
+}
+
+var csvHeader = []string{"id", "created_at", "user_id", "user_name", "message", "parent_id", "thread_root_id", "edited_at", "attachments"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// 表計算ソフトで数式として解釈されないように、先頭が数式の記号の値は ' を付ける
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (w *csvWriter) Begin(room model.Room) error {
+	return w.w.Write(csvHeader)
+}
+
+func (w *csvWriter) Write(record Record) error {
+	userID := ""
+	if record.UserID != 0 {
+		userID = strconv.Itoa(record.UserID)
+	}
+	return w.w.Write([]string{
+		record.ID,
+		formatTime(&record.CreatedAt),
+		userID,
+		csvCell(record.UserName),
+		csvCell(record.Message),
+		record.ParentID,
+		record.ThreadRootID,
+		formatTime(record.EditedAt),
+		csvCell(strings.Join(record.Attachments, "; ")),
+	})
+}
+
+func (w *csvWriter) End() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// スタイルを埋め込んだ1ファイルの HTML。スクリプトや外部のファイルは参照しない
+type htmlWriter struct {
+	w io.Writer
+}
+
+const htmlStyle = `body{font-family:sans-serif;max-width:48rem;margin:2rem auto;padding:0 1rem;color:#222}` +
+	`ol{list-style:none;padding:0}li{padding:.5rem 0;border-bottom:1px solid #eee}` +
+	`li.reply{margin-left:2rem}.meta{color:#666;font-size:.85rem}.body{white-space:pre-wrap;margin-top:.25rem}` +
+	`.attachments{color:#666;font-size:.85rem}`
+
+func (w *htmlWriter) Begin(room model.Room) error {
+	name := html.EscapeString(room.Name)
+	_, err := fmt.Fprintf(w.w,
+		"<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n<h1>%s</h1>\n<ol>\n",
+		name, htmlStyle, name,
+	)
+	return err
+}
+
+func (w *htmlWriter) Write(record Record) error {
+	class := ""
+	if record.ParentID != "" {
+		class = ` class="reply"`
+	}
+	edited := ""
+	if record.EditedAt != nil {
+		edited = " (edited)"
+	}
+	attachments := ""
+	if len(record.Attachments) > 0 {
+		attachments = `<div class="attachments">Attachments: ` + html.EscapeString(strings.Join(record.Attachments, ", ")) + "</div>"
+	}
+	_, err := fmt.Fprintf(w.w,
+		"<li id=\"m-%s\"%s><div class=\"meta\"><strong>%s</strong> <time datetime=\"%s\">%s</time>%s</div><div class=\"body\">%s</div>%s</li>\n",
+		html.EscapeString(record.ID),
+		class,
+		html.EscapeString(record.UserName),
+		formatTime(&record.CreatedAt),
+		record.CreatedAt.UTC().Format("2006-01-02 15:04"),
+		edited,
+		html.EscapeString(record.Message),
+		attachments,
+	)
+	return err
+}
+
+func (w *htmlWriter) End() error {
+	_, err := io.WriteString(w.w, "</ol>\n</body>\n</html>\n")
+	return err
+}
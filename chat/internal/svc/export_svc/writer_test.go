@@ -0,0 +1,74 @@
+package export_svc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"microservices/chat/internal/model"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	writerCreatedAt = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	writerRecords   = []Record{
+		{ID: "m1", CreatedAt: writerCreatedAt, UserID: 1, UserName: "alice", Message: "<b>hello</b>\nworld", Attachments: []string{"a.png"}},
+		{ID: "m2", CreatedAt: writerCreatedAt, UserName: "deploy-bot", Message: "=SUM(A1)", ParentID: "m1", ThreadRootID: "m1"},
+	}
+)
+
+func writeAll(t *testing.T, format string) string {
+	var buf bytes.Buffer
+	writer, err := newExportWriter(format, &buf)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Begin(model.Room{Name: "Team <A>"}))
+	for _, record := range writerRecords {
+		assert.NoError(t, writer.Write(record))
+	}
+	assert.NoError(t, writer.End())
+	return buf.String()
+}
+
+func TestJSONLWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(writeAll(t, model.ExportFormatJSONL)), "\n")
+	if assert.Len(t, lines, 2) {
+		var record Record
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+		assert.Equal(t, writerRecords[0], record)
+		// HTML をエスケープせずにそのまま書き出す
+		assert.Contains(t, lines[0], `"message":"<b>hello</b>\nworld"`)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(writeAll(t, model.ExportFormatCSV))).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, csvHeader, rows[0])
+		assert.Equal(t, []string{"m1", "2025-01-02T03:04:05Z", "1", "alice", "<b>hello</b>\nworld", "", "", "", "a.png"}, rows[1])
+		// 数式として解釈される値は ' を付ける
+		assert.Equal(t, "'=SUM(A1)", rows[2][4])
+		assert.Equal(t, "", rows[2][2])
+	}
+}
+
+func TestHTMLWriter(t *testing.T) {
+	out := writeAll(t, model.ExportFormatHTML)
+
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.Contains(t, out, "<title>Team &lt;A&gt;</title>")
+	assert.Contains(t, out, "&lt;b&gt;hello&lt;/b&gt;\nworld")
+	assert.NotContains(t, out, "<b>hello</b>")
+	assert.Contains(t, out, `<li id="m-m2" class="reply">`)
+	assert.Contains(t, out, "Attachments: a.png")
+	assert.NotContains(t, out, "<script")
+	assert.True(t, strings.HasSuffix(out, "</html>\n"))
+}
+
+func TestNewExportWriterUnknownFormat(t *testing.T) {
+	_, err := newExportWriter("pdf", &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,221 @@
+package import_svc
+
+import (
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ImportSvcInterface interface {
+	Import(source Source, mapping UserMapping, ownerID int) (Result, error)
+}
+
+type ImportSvcStruct struct {
+	MongoSvc mongo_svc.MongoSvcInterface
+	MongoPkg mongo_pkg.MongoPkgInterface
+
+	BatchSize int // 一度に書き込むメッセージの件数
+}
+
+func NewImportSvc(mongoSvc mongo_svc.MongoSvcInterface, mongoPkg mongo_pkg.MongoPkgInterface) *ImportSvcStruct {
+	return &ImportSvcStruct{
+		MongoSvc:  mongoSvc,
+		MongoPkg:  mongoPkg,
+		BatchSize: 200,
+	}
+}
+
+type Result struct {
+	Rooms    int   // 取り込み先のルームの数
+	Imported int64 // 追加したメッセージの件数
+	Skipped  int64 // 取り込み済みだったメッセージの件数
+	Orphans  int64 // 返信先が見つからず、返信ではないメッセージとして取り込んだ件数
+}
+
+// 取り込み元のルームとメッセージを追加する。ルームは ownerID をオーナーとして作成する
+// 元のIDで取り込み済みかを判定するので、同じ取り込み元を何度取り込んでもメッセージは重複しない
+func (s *ImportSvcStruct) Import(source Source, mapping UserMapping, ownerID int) (Result, error) {
+	// 途中まで書き込んでから失敗しないよう、先に全ての投稿者が対応付けられているか確認する
+	if err := checkUserMapping(source, mapping); err != nil {
+		return Result{}, err
+	}
+
+	run := &importRun{ImportSvcStruct: s, mapping: mapping, ownerID: ownerID, rooms: map[string]bool{}}
+	err := source.Each(run.add)
+	if err == nil {
+		err = run.flush()
+	}
+	return run.result, err
+}
+
+func checkUserMapping(source Source, mapping UserMapping) error {
+	unmapped := map[string]bool{}
+	err := source.Each(func(record Record) error {
+		if user := record.Message.User; user != "" && mapping[user] == 0 {
+			unmapped[user] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(unmapped) == 0 {
+		return nil
+	}
+
+	users := make([]string, 0, len(unmapped))
+	for user := range unmapped {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return fmt.Errorf("users not found in mapping: %s", strings.Join(users, ", "))
+}
+
+// 1回の取り込みの状態。同じルームのメッセージを BatchSize 件ずつまとめて書き込む
+type importRun struct {
+	*ImportSvcStruct
+	mapping UserMapping
+	ownerID int
+
+	room   Room
+	batch  []Message
+	rooms  map[string]bool // 取り込んだルームの ExternalID
+	result Result
+}
+
+func (r *importRun) add(record Record) error {
+	if len(r.batch) > 0 && record.Room.ExternalID != r.room.ExternalID {
+		if err := r.flush(); err != nil {
+			return err
+		}
+	}
+	r.room = record.Room
+	r.batch = append(r.batch, record.Message)
+	if len(r.batch) >= r.BatchSize {
+		return r.flush()
+	}
+	return nil
+}
+
+func (r *importRun) flush() error {
+	if len(r.batch) == 0 {
+		return nil
+	}
+	batch := r.batch
+	r.batch = nil
+
+	room, err := r.MongoSvc.UpsertImportedRoom(r.newRoom(batch), r.MongoPkg)
+	if err != nil {
+		return err
+	}
+	roomID := room.ID.Hex()
+	if !r.rooms[r.room.ExternalID] {
+		r.rooms[r.room.ExternalID] = true
+		r.result.Rooms++
+	}
+
+	// 取り込み済みのメッセージと、前のバッチや前回の取り込みで追加した返信先をまとめて取得する
+	var externalIDs []string
+	for _, message := range batch {
+		externalIDs = append(externalIDs, message.ExternalID)
+		if message.ParentExternalID != "" {
+			externalIDs = append(externalIDs, message.ParentExternalID)
+		}
+	}
+	existing, err := r.MongoSvc.GetImportedChatMessages(roomID, externalIDs, r.MongoPkg)
+	if err != nil {
+		return err
+	}
+	known := map[string]model.ChatMessage{}
+	for _, message := range existing {
+		known[message.ExternalID] = message
+	}
+
+	var messages []model.ChatMessage
+	for _, message := range batch {
+		if _, ok := known[message.ExternalID]; ok {
+			r.result.Skipped++
+			continue
+		}
+		chatMessage := model.ChatMessage{
+			// _id の順が元の投稿順になるよう、元の投稿日時から ID を作る
+			ID:            primitive.NewObjectIDFromTimestamp(message.CreatedAt),
+			RoomID:        roomID,
+			UserID:        r.mapping[message.User],
+			Message:       message.Text,
+			CreatedAt:     message.CreatedAt,
+			BotID:         message.BotID,
+			BotName:       message.BotName,
+			ExternalID:    message.ExternalID,
+			RetentionDays: room.RetentionDays,
+		}
+		if message.ParentExternalID != "" {
+			if parent, ok := known[message.ParentExternalID]; ok {
+				chatMessage.ParentID = parent.ID.Hex()
+				chatMessage.ThreadRootID = parent.ThreadRootID
+				if chatMessage.ThreadRootID == "" {
+					chatMessage.ThreadRootID = parent.ID.Hex()
+				}
+			} else {
+				r.result.Orphans++
+			}
+		}
+		known[message.ExternalID] = chatMessage
+		messages = append(messages, chatMessage)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	inserted, err := r.MongoSvc.ImportChatMessages(roomID, messages, r.MongoPkg)
+	r.result.Imported += inserted
+	if err != nil {
+		return err
+	}
+	// 同時に取り込まれたメッセージは追加されない
+	r.result.Skipped += int64(len(messages)) - inserted
+	return nil
+}
+
+// バッチの取り込み先のルーム。作成済みの場合はメンバーの追加にのみ使われる
+func (r *importRun) newRoom(batch []Message) model.Room {
+	createdAt := r.room.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = batch[0].CreatedAt
+	}
+
+	members := []int{r.ownerID}
+	seen := map[int]bool{r.ownerID: true}
+	addMember := func(user string) {
+		// 対応付けられていないメンバーは取り込まない
+		if userID := r.mapping[user]; userID != 0 && !seen[userID] {
+			seen[userID] = true
+			members = append(members, userID)
+		}
+	}
+	if r.room.Members != nil {
+		for _, user := range r.room.Members {
+			addMember(user)
+		}
+	} else {
+		for _, message := range batch {
+			addMember(message.User)
+		}
+	}
+
+	return model.Room{
+		ExternalID:  r.room.ExternalID,
+		Name:        r.room.Name,
+		OwnerID:     r.ownerID,
+		CreatedAt:   createdAt,
+		Members:     members,
+		IsPrivate:   r.room.IsPrivate,
+		Description: r.room.Description,
+		Topic:       r.room.Topic,
+	}
+}
@@ -0,0 +1,142 @@
+package import_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type sliceSource []Record
+
+func (s sliceSource) Each(fn func(Record) error) error {
+	for _, record := range s {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var base = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func record(externalID string, user string, parent string, minutes int) Record {
+	return Record{
+		Room: Room{ExternalID: "general", Name: "general"},
+		Message: Message{
+			ExternalID:       externalID,
+			User:             user,
+			Text:             "message " + externalID,
+			CreatedAt:        base.Add(time.Duration(minutes) * time.Minute),
+			ParentExternalID: parent,
+		},
+	}
+}
+
+func TestImport(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	room := model.Room{ID: roomID, ExternalID: "general", RetentionDays: 7}
+	m0 := model.ChatMessage{ID: primitive.NewObjectID(), ExternalID: "m0"}
+	m1 := model.ChatMessage{ID: primitive.NewObjectID(), ExternalID: "m1"}
+
+	source := sliceSource{
+		// 前回取り込んだメッセージは追加しない
+		record("m0", "alice", "", 0),
+		record("m1", "alice", "", 1),
+		// 返信先は前のバッチで追加したメッセージでも解決する
+		record("m2", "bob", "m1", 2),
+		record("m3", "alice", "missing", 3),
+	}
+	mapping := UserMapping{"alice": 1, "bob": 2}
+
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("UpsertImportedRoom", model.Room{
+		ExternalID: "general", Name: "general", OwnerID: 99, CreatedAt: base, Members: []int{99, 1},
+	}, mongoPkgMock).Return(room, nil).Once()
+	mongoSvcMock.On("UpsertImportedRoom", model.Room{
+		ExternalID: "general", Name: "general", OwnerID: 99, CreatedAt: base.Add(2 * time.Minute), Members: []int{99, 2, 1},
+	}, mongoPkgMock).Return(room, nil).Once()
+	mongoSvcMock.On("GetImportedChatMessages", roomID.Hex(), []string{"m0", "m1"}, mongoPkgMock).Return([]model.ChatMessage{m0}, nil)
+	mongoSvcMock.On("GetImportedChatMessages", roomID.Hex(), []string{"m2", "m1", "m3", "missing"}, mongoPkgMock).Return([]model.ChatMessage{m1}, nil)
+	mongoSvcMock.On("ImportChatMessages", roomID.Hex(), mock.MatchedBy(func(messages []model.ChatMessage) bool {
+		return len(messages) == 1 && messages[0].ExternalID == "m1"
+	}), mongoPkgMock).Return(int64(1), nil)
+	mongoSvcMock.On("ImportChatMessages", roomID.Hex(), mock.MatchedBy(func(messages []model.ChatMessage) bool {
+		return len(messages) == 2 && messages[0].ExternalID == "m2"
+	}), mongoPkgMock).Return(int64(2), nil)
+
+	svc := NewImportSvc(mongoSvcMock, mongoPkgMock)
+	svc.BatchSize = 2
+	result, err := svc.Import(source, mapping, 99)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Rooms: 1, Imported: 3, Skipped: 1, Orphans: 1}, result)
+
+	mongoSvcMock.AssertCalled(t, "ImportChatMessages", roomID.Hex(), mock.MatchedBy(func(messages []model.ChatMessage) bool {
+		if len(messages) != 2 {
+			return false
+		}
+		reply, orphan := messages[0], messages[1]
+		return reply.UserID == 2 && reply.ParentID == m1.ID.Hex() && reply.ThreadRootID == m1.ID.Hex() &&
+			reply.CreatedAt.Equal(base.Add(2*time.Minute)) && reply.RetentionDays == 7 &&
+			// _id は元の投稿日時から作る
+			reply.ID.Timestamp().Equal(base.Add(2*time.Minute)) &&
+			orphan.UserID == 1 && orphan.ParentID == "" && orphan.ThreadRootID == ""
+	}), mongoPkgMock)
+}
+
+func TestImportMemberList(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	source := sliceSource{{
+		Room:    Room{ExternalID: "C01", Name: "general", CreatedAt: base, Members: []string{"U01", "U99"}, IsPrivate: true},
+		Message: Message{ExternalID: "1.0", BotID: "slack:B01", BotName: "deploy", Text: "deployed", CreatedAt: base.Add(time.Hour)},
+	}}
+
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	// メンバーの一覧がある場合は一覧を使い、対応付けられていないメンバーは追加しない
+	mongoSvcMock.On("UpsertImportedRoom", model.Room{
+		ExternalID: "C01", Name: "general", OwnerID: 99, CreatedAt: base, Members: []int{99, 1}, IsPrivate: true,
+	}, mongoPkgMock).Return(model.Room{ID: roomID}, nil)
+	mongoSvcMock.On("GetImportedChatMessages", roomID.Hex(), []string{"1.0"}, mongoPkgMock).Return([]model.ChatMessage{}, nil)
+	mongoSvcMock.On("ImportChatMessages", roomID.Hex(), mock.MatchedBy(func(messages []model.ChatMessage) bool {
+		return len(messages) == 1 && messages[0].UserID == 0 && messages[0].BotID == "slack:B01" && messages[0].BotName == "deploy"
+	}), mongoPkgMock).Return(int64(1), nil)
+
+	result, err := NewImportSvc(mongoSvcMock, mongoPkgMock).Import(source, UserMapping{"U01": 1}, 99)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Rooms: 1, Imported: 1}, result)
+}
+
+func TestImportErrors(t *testing.T) {
+	source := sliceSource{record("m1", "alice", "", 0), record("m2", "carol", "", 1), record("m3", "bob", "", 2)}
+
+	t.Run("unmapped_users", func(t *testing.T) {
+		mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+
+		_, err := NewImportSvc(mongoSvcMock, new(mock_mongo_pkg.MongoPkgMock)).Import(source, UserMapping{"alice": 1}, 99)
+		assert.EqualError(t, err, "users not found in mapping: bob, carol")
+		// 対応付けが足りない場合は何も書き込まない
+		mongoSvcMock.AssertNotCalled(t, "UpsertImportedRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("import_error", func(t *testing.T) {
+		roomID := primitive.NewObjectID()
+		mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+		mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+		mongoSvcMock.On("UpsertImportedRoom", mock.Anything, mongoPkgMock).Return(model.Room{ID: roomID}, nil)
+		mongoSvcMock.On("GetImportedChatMessages", roomID.Hex(), mock.Anything, mongoPkgMock).Return([]model.ChatMessage{}, nil)
+		mongoSvcMock.On("ImportChatMessages", roomID.Hex(), mock.Anything, mongoPkgMock).Return(int64(1), assert.AnError)
+
+		result, err := NewImportSvc(mongoSvcMock, mongoPkgMock).Import(source, UserMapping{"alice": 1, "bob": 2, "carol": 3}, 99)
+		assert.ErrorIs(t, err, assert.AnError)
+		// 失敗するまでに追加した件数は返す
+		assert.Equal(t, int64(1), result.Imported)
+		mongoSvcMock.AssertNumberOfCalls(t, "ImportChatMessages", 1)
+	})
+}
@@ -0,0 +1,248 @@
+package import_svc
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slack のエクスポートのチャンネル（channels.json・groups.json の要素）
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Created int64    `json:"created"`
+	Members []string `json:"members"`
+	Topic   struct {
+		Value string `json:"value"`
+	} `json:"topic"`
+	Purpose struct {
+		Value string `json:"value"`
+	} `json:"purpose"`
+	private bool
+}
+
+// チャンネルのディレクトリにある日付ごとの JSON の要素
+type slackMessage struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+}
+
+// 取り込むメッセージの subtype。参加・退出やトピックの変更などの通知は取り込まない
+var slackMessageSubtypes = map[string]bool{
+	"":                 true,
+	"thread_broadcast": true,
+	"file_share":       true,
+	"bot_message":      true,
+	"me_message":       true,
+}
+
+// Slack の本文は <>& のみエスケープされている
+var slackTextUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// users.json の要素。ワークスペースのIDを調べるのに使う
+type slackUser struct {
+	TeamID string `json:"team_id"`
+}
+
+type slackSource struct {
+	zip      *zip.Reader
+	team     string
+	channels []slackChannel
+}
+
+// Slack のエクスポートの zip。公開チャンネル（channels.json）と非公開チャンネル（groups.json）を取り込む
+// ダイレクトメッセージと添付ファイルは取り込まない
+// チャンネルIDはワークスペースごとに振られるので、team（空の場合は users.json のワークスペース）で区別する
+func NewSlackSource(r io.ReaderAt, size int64, team string) (Source, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &slackSource{zip: archive, team: team}
+	if source.team == "" {
+		var users []slackUser
+		if _, err := source.readJSON("users.json", &users); err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if user.TeamID != "" {
+				source.team = user.TeamID
+				break
+			}
+		}
+	}
+	if source.team == "" {
+		return nil, fmt.Errorf("slack team id not found in export; specify the team")
+	}
+
+	found := false
+	for _, name := range []string{"channels.json", "groups.json"} {
+		var channels []slackChannel
+		ok, err := source.readJSON(name, &channels)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		found = true
+		for _, channel := range channels {
+			channel.private = name == "groups.json"
+			source.channels = append(source.channels, channel)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("channels.json not found in slack export")
+	}
+	return source, nil
+}
+
+// zip 内の JSON を読み込む。ファイルが無い場合は false
+func (s *slackSource) readJSON(name string, v interface{}) (bool, error) {
+	file, err := s.zip.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	if err := json.NewDecoder(file).Decode(v); err != nil {
+		return true, fmt.Errorf("%s: %w", name, err)
+	}
+	return true, nil
+}
+
+func (s *slackSource) Each(fn func(Record) error) error {
+	for _, channel := range s.channels {
+		room := Room{
+			ExternalID:  "slack:" + s.team + ":" + channel.ID,
+			Name:        channel.Name,
+			Description: channel.Purpose.Value,
+			Topic:       channel.Topic.Value,
+			IsPrivate:   channel.private,
+			Members:     channel.Members,
+		}
+		if channel.Created > 0 {
+			room.CreatedAt = time.Unix(channel.Created, 0).UTC()
+		}
+		if room.Members == nil {
+			room.Members = []string{}
+		}
+
+		for _, name := range s.dayFiles(channel.Name) {
+			var messages []slackMessage
+			if _, err := s.readJSON(name, &messages); err != nil {
+				return err
+			}
+			// 1日分のファイルの中も投稿順に並べ直す
+			sort.SliceStable(messages, func(i, j int) bool {
+				return slackTSLess(messages[i].TS, messages[j].TS)
+			})
+
+			for _, message := range messages {
+				record, ok, err := newSlackRecord(room, message)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				if !ok {
+					continue
+				}
+				if err := fn(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// チャンネルのディレクトリにある日付ごとのファイル（ファイル名の順が日付の順になる）
+func (s *slackSource) dayFiles(channelName string) []string {
+	var names []string
+	for _, file := range s.zip.File {
+		dir, base := path.Split(file.Name)
+		if dir == channelName+"/" && strings.HasSuffix(base, ".json") {
+			names = append(names, file.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newSlackRecord(room Room, message slackMessage) (Record, bool, error) {
+	if message.Type != "message" || !slackMessageSubtypes[message.Subtype] {
+		return Record{}, false, nil
+	}
+	createdAt, err := parseSlackTS(message.TS)
+	if err != nil {
+		return Record{}, false, err
+	}
+
+	imported := Message{
+		ExternalID: message.TS,
+		User:       message.User,
+		Text:       slackTextUnescaper.Replace(message.Text),
+		CreatedAt:  createdAt,
+	}
+	// スレッドの起点は thread_ts が自身の ts と同じ
+	if message.ThreadTS != "" && message.ThreadTS != message.TS {
+		imported.ParentExternalID = message.ThreadTS
+	}
+	// ボットの投稿はユーザーを対応付けずにボットとして取り込む
+	if message.Subtype == "bot_message" || message.User == "" {
+		if message.BotID == "" {
+			return Record{}, false, nil
+		}
+		imported.User = ""
+		imported.BotID = "slack:" + message.BotID
+		imported.BotName = message.Username
+		if imported.BotName == "" {
+			imported.BotName = "bot"
+		}
+	}
+	return Record{Room: room, Message: imported}, true, nil
+}
+
+// "1700000000.000100" の形式（秒.マイクロ秒）
+func parseSlackTS(ts string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(ts, ".")
+	seconds, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ts %q", ts)
+	}
+	var micros int64
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		micros, err = strconv.ParseInt(frac, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid ts %q", ts)
+		}
+	}
+	return time.Unix(seconds, micros*int64(time.Microsecond)).UTC(), nil
+}
+
+func slackTSLess(a string, b string) bool {
+	at, errA := parseSlackTS(a)
+	bt, errB := parseSlackTS(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return at.Before(bt)
+}
@@ -0,0 +1,147 @@
+package import_svc
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSlackArchive(t *testing.T, files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestSlackSource(t *testing.T) {
+	archive := newSlackArchive(t, map[string]string{
+		"users.json":    `[{"id":"U01","name":"alice","team_id":"T01"}]`,
+		"channels.json": `[{"id":"C01","name":"general","created":1577836800,"members":["U01","U02"],"topic":{"value":"Chat"},"purpose":{"value":"Everything"}}]`,
+		"groups.json":   `[{"id":"G01","name":"secret","created":1577836800}]`,
+		"general/2020-01-02.json": `[
+			{"type":"message","user":"U02","text":"next day","ts":"1577923200.000100"}
+		]`,
+		// 1日分のファイルの中が投稿順でなくても並べ直す
+		"general/2020-01-01.json": `[
+			{"type":"message","user":"U02","text":"reply","ts":"1577836900.000200","thread_ts":"1577836800.000100"},
+			{"type":"message","user":"U01","text":"a &lt; b &amp;&amp; c","ts":"1577836800.000100","thread_ts":"1577836800.000100"},
+			{"type":"message","subtype":"channel_join","user":"U02","text":"joined","ts":"1577836850.000000"},
+			{"type":"message","subtype":"bot_message","bot_id":"B01","username":"deploy","text":"deployed","ts":"1577836950.000000"}
+		]`,
+		"secret/2020-01-01.json": `[{"type":"message","user":"U01","text":"private","ts":"1577836800.000000"}]`,
+	})
+
+	source, err := NewSlackSource(archive, archive.Size(), "")
+	assert.NoError(t, err)
+
+	records := collect(t, source)
+	if !assert.Len(t, records, 5) {
+		return
+	}
+
+	general := records[0].Room
+	assert.Equal(t, Room{
+		ExternalID:  "slack:T01:C01",
+		Name:        "general",
+		Description: "Everything",
+		Topic:       "Chat",
+		CreatedAt:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Members:     []string{"U01", "U02"},
+	}, general)
+
+	assert.Equal(t, Message{
+		ExternalID: "1577836800.000100",
+		User:       "U01",
+		Text:       "a < b && c",
+		CreatedAt:  time.Date(2020, 1, 1, 0, 0, 0, 100000, time.UTC),
+	}, records[0].Message)
+	assert.Equal(t, "1577836800.000100", records[1].Message.ParentExternalID)
+	assert.Equal(t, Message{
+		ExternalID: "1577836950.000000",
+		BotID:      "slack:B01",
+		BotName:    "deploy",
+		Text:       "deployed",
+		CreatedAt:  time.Date(2020, 1, 1, 0, 2, 30, 0, time.UTC),
+	}, records[2].Message)
+	assert.Equal(t, "next day", records[3].Message.Text)
+
+	// 非公開チャンネルは非公開のルームにする
+	assert.Equal(t, "slack:T01:G01", records[4].Room.ExternalID)
+	assert.True(t, records[4].Room.IsPrivate)
+	assert.Equal(t, []string{}, records[4].Room.Members)
+}
+
+func TestSlackSourceTeam(t *testing.T) {
+	archive := newSlackArchive(t, map[string]string{
+		"users.json":              `[{"id":"U01","name":"alice","team_id":"T01"}]`,
+		"channels.json":           `[{"id":"C01","name":"general"}]`,
+		"general/2020-01-01.json": `[{"type":"message","user":"U01","text":"hello","ts":"1577836800.000000"}]`,
+	})
+
+	// 指定したワークスペースを users.json より優先する
+	source, err := NewSlackSource(archive, archive.Size(), "T99")
+	assert.NoError(t, err)
+	records := collect(t, source)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "slack:T99:C01", records[0].Room.ExternalID)
+	}
+}
+
+func TestNewSlackSourceInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"no_channels", map[string]string{"users.json": `[{"id":"U01","team_id":"T01"}]`}},
+		{"invalid_channels", map[string]string{"users.json": `[{"id":"U01","team_id":"T01"}]`, "channels.json": `{`}},
+		// ワークスペースが分からない場合はチャンネルIDが他のワークスペースと重なり得るので取り込まない
+		{"no_team", map[string]string{"users.json": `[{"id":"U01"}]`, "channels.json": `[]`}},
+		{"invalid_users", map[string]string{"users.json": `{`, "channels.json": `[]`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := newSlackArchive(t, tt.files)
+			_, err := NewSlackSource(archive, archive.Size(), "")
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := NewSlackSource(bytes.NewReader([]byte("not a zip")), 9, "T01")
+	assert.Error(t, err)
+}
+
+func TestParseSlackTS(t *testing.T) {
+	tests := []struct {
+		ts        string
+		expect    time.Time
+		expectErr bool
+	}{
+		{"1577836800.000100", time.Date(2020, 1, 1, 0, 0, 0, 100000, time.UTC), false},
+		{"1577836800", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"1577836800.5", time.Date(2020, 1, 1, 0, 0, 0, 500000000, time.UTC), false},
+		{"abc", time.Time{}, true},
+		{"1577836800.x", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ts, func(t *testing.T) {
+			parsed, err := parseSlackTS(tt.ts)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, parsed)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package import_svc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 取り込むルーム。ExternalID が同じルームは何度取り込んでも同じルームになる
+// 別の取り込み元のルームと重ならないよう、ExternalID には取り込み元ごとの名前空間を付ける
+type Room struct {
+	ExternalID  string
+	Name        string
+	Description string
+	Topic       string
+	IsPrivate   bool
+	CreatedAt   time.Time // ゼロ値の場合は最初のメッセージの投稿日時にする
+	Members     []string  // 外部のユーザーID。nil の場合はメッセージの投稿者をメンバーにする
+}
+
+// 取り込むメッセージ。ExternalID はルーム内で一意
+type Message struct {
+	ExternalID       string
+	User             string // 外部のユーザーID（ボットの場合は空）
+	BotID            string
+	BotName          string
+	Text             string
+	CreatedAt        time.Time
+	ParentExternalID string // 返信先のメッセージの ExternalID
+}
+
+type Record struct {
+	Room    Room
+	Message Message
+}
+
+// 取り込み元の形式ごとの読み込み。同じルームのメッセージは続けて古い順に渡す
+// 書き込む前の確認のために2回読むことがあるので、Each は何度でも呼べるようにする
+type Source interface {
+	Each(fn func(Record) error) error
+}
+
+// 外部のユーザーIDからこのシステムのユーザーID（model.User の UserID）への対応
+type UserMapping map[string]int
+
+// {"外部のユーザーID": ユーザーID, ...} の形式の JSON を読み込む
+func ReadUserMapping(r io.Reader) (UserMapping, error) {
+	var mapping UserMapping
+	if err := json.NewDecoder(r).Decode(&mapping); err != nil {
+		return nil, fmt.Errorf("invalid user mapping: %w", err)
+	}
+	for external, userID := range mapping {
+		if userID <= 0 {
+			return nil, fmt.Errorf("invalid user mapping: user id for %q must be positive", external)
+		}
+	}
+	return mapping, nil
+}
+
+// JSONL の1行（1件のメッセージ）
+type jsonlRecord struct {
+	ID        string    `json:"id"`
+	Room      string    `json:"room"`
+	User      string    `json:"user"`
+	CreatedAt time.Time `json:"created_at"` // RFC 3339
+	Message   string    `json:"message"`
+	ParentID  string    `json:"parent_id"`
+}
+
+// 1行の上限（これより長い行はエラーにする）
+const maxJSONLLine = 1 << 20
+
+type jsonlSource struct {
+	r           io.ReadSeeker
+	defaultRoom string
+}
+
+// 1行に1件のメッセージを書いた JSONL。room を省略した行は defaultRoom に取り込む
+// 行の順に取り込むので、返信は返信先より後の行に書く
+func NewJSONLSource(r io.ReadSeeker, defaultRoom string) Source {
+	return &jsonlSource{r: r, defaultRoom: defaultRoom}
+}
+
+func (s *jsonlSource) Each(fn func(Record) error) error {
+	if _, err := s.r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(s.r)
+	scanner.Buffer(make([]byte, 64<<10), maxJSONLLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var record jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if record.Room == "" {
+			record.Room = s.defaultRoom
+		}
+		if err := record.validate(); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		err := fn(Record{
+			Room: Room{ExternalID: "jsonl:" + record.Room, Name: record.Room},
+			Message: Message{
+				ExternalID:       record.ID,
+				User:             record.User,
+				Text:             record.Message,
+				CreatedAt:        record.CreatedAt,
+				ParentExternalID: record.ParentID,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (r jsonlRecord) validate() error {
+	switch {
+	case r.ID == "":
+		return fmt.Errorf("id is required")
+	case r.Room == "":
+		return fmt.Errorf("room is required")
+	case r.User == "":
+		return fmt.Errorf("user is required")
+	case r.CreatedAt.IsZero():
+		return fmt.Errorf("created_at is required")
+	}
+	return nil
+}
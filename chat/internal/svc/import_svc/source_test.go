@@ -0,0 +1,86 @@
+package import_svc
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collect(t *testing.T, source Source) []Record {
+	var records []Record
+	assert.NoError(t, source.Each(func(record Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	return records
+}
+
+func TestReadUserMapping(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expect    UserMapping
+		expectErr bool
+	}{
+		{"success", `{"alice": 1, "U02": 2}`, UserMapping{"alice": 1, "U02": 2}, false},
+		{"invalid_json", `["alice"]`, nil, true},
+		{"invalid_user_id", `{"alice": 0}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := ReadUserMapping(strings.NewReader(tt.input))
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, mapping)
+		})
+	}
+}
+
+func TestJSONLSource(t *testing.T) {
+	input := `{"id":"m1","room":"general","user":"alice","created_at":"2020-01-01T00:00:00Z","message":"hello"}
+
+{"id":"m2","user":"bob","created_at":"2020-01-01T00:01:00Z","message":"hi","parent_id":"m1"}
+`
+	source := NewJSONLSource(strings.NewReader(input), "default")
+
+	records := collect(t, source)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, Record{
+			Room:    Room{ExternalID: "jsonl:general", Name: "general"},
+			Message: Message{ExternalID: "m1", User: "alice", Text: "hello", CreatedAt: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}, records[0])
+		// room を省略した行は既定のルームに取り込む
+		assert.Equal(t, "jsonl:default", records[1].Room.ExternalID)
+		assert.Equal(t, "m1", records[1].Message.ParentExternalID)
+	}
+
+	// 確認と書き込みで2回読めるように先頭から読み直す
+	assert.Len(t, collect(t, source), 2)
+}
+
+func TestJSONLSourceInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"invalid_json", `{"id":`, "line 1"},
+		{"missing_id", `{"room":"general","user":"alice","created_at":"2020-01-01T00:00:00Z"}`, "id is required"},
+		{"missing_room", `{"id":"m1","user":"alice","created_at":"2020-01-01T00:00:00Z"}`, "room is required"},
+		{"missing_user", `{"id":"m1","room":"general","created_at":"2020-01-01T00:00:00Z"}`, "user is required"},
+		{"missing_created_at", "\n" + `{"id":"m1","room":"general","user":"alice"}`, "line 2: created_at is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewJSONLSource(strings.NewReader(tt.input), "").Each(func(Record) error { return nil })
+			assert.ErrorContains(t, err, tt.expect)
+		})
+	}
+}
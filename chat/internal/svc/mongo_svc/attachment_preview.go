@@ -0,0 +1,96 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// サムネイルの生成待ちの検索用（生成待ちのメッセージだけを対象にする）
+var pendingPreviewIndex = mongo.IndexModel{
+	Keys: bson.D{{Key: "attachments.previewstatus", Value: 1}},
+	Options: options.Index().
+		SetName("attachments_previewstatus_pending").
+		SetPartialFilterExpression(bson.M{"attachments.previewstatus": model.PreviewPending}),
+}
+
+// サムネイルの生成待ちの添付ファイルを持つメッセージを古い順に取得する
+func (m *MongoSvcStruct) GetPendingPreviewMessages(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, pendingPreviewIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.FindWithOptions(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"attachments.previewstatus": model.PreviewPending, "deletedat": nil},
+		options.Find().
+			SetProjection(bson.M{"revisions": 0}).
+			SetSort(bson.D{{Key: "_id", Value: 1}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	messages := []model.ChatMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// 添付ファイルのサムネイルの生成結果を記録する
+func (m *MongoSvcStruct) SetAttachmentPreview(messageID string, attachmentID string, status string, thumbnails []model.Thumbnail, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": id, "attachments.id": attachmentID}
+	update := bson.M{
+		"$set": bson.M{
+			"attachments.$.previewstatus": status,
+			"attachments.$.thumbnails":    thumbnails,
+		},
+	}
+	result, err := collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	// 生成中にメッセージが物理削除された場合は、保存したサムネイルを削除待ちにする
+	if result.MatchedCount == 0 {
+		var keys []string
+		for _, thumbnail := range thumbnails {
+			keys = append(keys, thumbnail.StorageKey)
+		}
+		return queueBlobDeletions(mongo.MongoPkgStruct.Ctx, mongo.MongoPkgStruct.Db, keys)
+	}
+	return nil
+}
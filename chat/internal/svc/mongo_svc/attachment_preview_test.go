@@ -0,0 +1,127 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestGetPendingPreviewMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		indexErr  error
+		findErr   error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, false},
+		{"error", true, nil, nil, true},
+		{"index_error", false, assert.AnError, nil, true},
+		{"find_error", false, nil, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				message := args.Get(0).(*model.ChatMessage)
+				*message = model.ChatMessage{RoomID: "room1", Attachments: []model.Attachment{{ID: "a1", PreviewStatus: model.PreviewPending}}}
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, pendingPreviewIndex).Return("", tt.indexErr)
+			mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"attachments.previewstatus": model.PreviewPending, "deletedat": nil}, mock.Anything).Return(mongoCursorMock, tt.findErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			messages, err := mockSvcStruct.GetPendingPreviewMessages(10, mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, messages, 1)
+			assert.Equal(t, "a1", messages[0].Attachments[0].ID)
+		})
+	}
+}
+
+func TestSetAttachmentPreview(t *testing.T) {
+	messageID := "64a7b2f4e13e4c3f9c8b4567"
+	id, _ := primitive.ObjectIDFromHex(messageID)
+	thumbnails := []model.Thumbnail{{Size: 160, Width: 160, Height: 120, ContentType: "image/jpeg", StorageKey: "key/thumbnails/160"}}
+
+	tests := []struct {
+		name      string
+		messageID string
+		initErr   bool
+		updateErr error
+		matched   int64
+		returnErr bool
+	}{
+		{"success", messageID, false, nil, 1, false},
+		// 生成中に物理削除されたメッセージのサムネイルは削除待ちにする
+		{"message_purged", messageID, false, nil, 0, false},
+		{"error", messageID, true, nil, 1, true},
+		{"invalid_id", "invalid_object_id", false, nil, 1, true},
+		{"update_error", messageID, false, assert.AnError, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": id, "attachments.id": "a1"}, bson.M{
+				"$set": bson.M{
+					"attachments.$.previewstatus": model.PreviewReady,
+					"attachments.$.thumbnails":    thumbnails,
+				},
+			}).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, tt.updateErr)
+			blobCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			blobCollectionMock.On("InsertOne", mock.Anything, mock.MatchedBy(func(deletion model.BlobDeletion) bool {
+				return assert.ObjectsAreEqual([]string{"key/thumbnails/160"}, deletion.StorageKeys)
+			})).Return("", nil)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+			mongoDatabaseMock.On("Collection", model.BlobDeletionCollectionName).Return(blobCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.SetAttachmentPreview(tt.messageID, "a1", model.PreviewReady, thumbnails, mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.matched == 0 {
+				blobCollectionMock.AssertExpectations(t)
+			} else {
+				blobCollectionMock.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 1件の削除待ちに記録するキーの数（ドキュメントのサイズ上限に収まるようにする）
+const blobDeletionChunkSize = 1000
+
+// filter に一致するメッセージの添付ファイルと生成済みのサムネイルの保存先のキーを返す
+func findStorageKeys(ctx context.Context, collection mongo_pkg.MongoCollectionInterface, filter bson.M) ([]string, error) {
+	withAttachments := bson.M{"attachments.0": bson.M{"$exists": true}}
+	for key, value := range filter {
+		withAttachments[key] = value
+	}
+
+	cursor, err := collection.FindWithOptions(ctx, withAttachments, options.Find().SetProjection(bson.M{"attachments": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []string
+	for cursor.Next(ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		keys = append(keys, message.StorageKeys()...)
+	}
+	return keys, nil
+}
+
+// 物理削除したメッセージの添付ファイルを削除待ちとして記録する
+// メッセージより先にファイルを消さないよう、メッセージを削除した後に呼び出す
+func queueBlobDeletions(ctx context.Context, db mongo_pkg.MongoDatabaseInterface, keys []string) error {
+	collection := db.Collection(model.BlobDeletionCollectionName)
+	now := time.Now()
+	for start := 0; start < len(keys); start += blobDeletionChunkSize {
+		end := min(start+blobDeletionChunkSize, len(keys))
+		if _, err := collection.InsertOne(ctx, model.BlobDeletion{StorageKeys: keys[start:end], QueuedAt: now}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter に一致するメッセージを sort の順に最大 limit 件物理削除し、添付ファイルを削除待ちにする
+func purgeChatMessageBatch(ctx context.Context, db mongo_pkg.MongoDatabaseInterface, filter bson.M, sort bson.D, limit int) (int64, error) {
+	collection := db.Collection(model.ChatMessageCollectionName)
+
+	cursor, err := collection.FindWithOptions(
+		ctx,
+		filter,
+		options.Find().SetProjection(bson.M{"_id": 1, "attachments": 1}).SetSort(sort).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	var keys []string
+	for cursor.Next(ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return 0, err
+		}
+		ids = append(ids, message.ID)
+		keys = append(keys, message.StorageKeys()...)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := queueBlobDeletions(ctx, db, keys); err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// 記録された順に削除待ちの添付ファイルを最大 limit 件返す
+func (m *MongoSvcStruct) GetBlobDeletions(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.BlobDeletion, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.BlobDeletionCollectionName)
+
+	cursor, err := collection.FindWithOptions(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	deletions := []model.BlobDeletion{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var deletion model.BlobDeletion
+		if err := cursor.Decode(&deletion); err != nil {
+			return nil, err
+		}
+		deletions = append(deletions, deletion)
+	}
+
+	return deletions, nil
+}
+
+// ストレージから削除し終えた削除待ちを取り除く
+func (m *MongoSvcStruct) DeleteBlobDeletion(deletionID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.BlobDeletionCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(deletionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,101 @@
+package mongo_svc
+
+import (
+	"context"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestQueueBlobDeletions(t *testing.T) {
+	keys := make([]string, blobDeletionChunkSize+1)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("rooms/r1/attachments/a%d", i)
+	}
+
+	blobCollection := new(mock_mongo_pkg.MongoCollectionMock)
+	blobCollection.On("InsertOne", mock.Anything, mock.Anything).Return("", nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.BlobDeletionCollectionName).Return(blobCollection)
+
+	// ドキュメントのサイズ上限に収まるよう分けて記録する
+	assert.NoError(t, queueBlobDeletions(context.TODO(), mongoDatabaseMock, keys))
+	blobCollection.AssertNumberOfCalls(t, "InsertOne", 2)
+	blobCollection.AssertCalled(t, "InsertOne", mock.Anything, mock.MatchedBy(func(deletion model.BlobDeletion) bool {
+		return len(deletion.StorageKeys) == 1 && deletion.StorageKeys[0] == keys[blobDeletionChunkSize]
+	}))
+
+	// キーが無い場合は何も記録しない
+	emptyCollection := new(mock_mongo_pkg.MongoCollectionMock)
+	emptyDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	emptyDatabaseMock.On("Collection", model.BlobDeletionCollectionName).Return(emptyCollection)
+	assert.NoError(t, queueBlobDeletions(context.TODO(), emptyDatabaseMock, nil))
+	emptyCollection.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+}
+
+func TestGetBlobDeletions(t *testing.T) {
+	deletion := model.BlobDeletion{ID: primitive.NewObjectID(), StorageKeys: []string{"rooms/r1/attachments/a1"}}
+
+	tests := []struct {
+		name      string
+		findErr   error
+		decodeErr error
+		returnErr bool
+	}{
+		{"success", nil, nil, false},
+		{"find_error", assert.AnError, nil, true},
+		{"decode_error", nil, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.BlobDeletion) = deletion
+			}).Return(tt.decodeErr)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			blobCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			blobCollection.On("FindWithOptions", mock.Anything, bson.M{}, mock.MatchedBy(func(opts *options.FindOptions) bool {
+				return *opts.Limit == 10
+			})).Return(mongoCursorMock, tt.findErr)
+
+			svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+				model.BlobDeletionCollectionName: blobCollection,
+			})
+
+			deletions, err := svc.GetBlobDeletions(10, pkg)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("GetBlobDeletions() [%s] error = %v", tt.name, err)
+			}
+			if !tt.returnErr {
+				assert.Equal(t, []model.BlobDeletion{deletion}, deletions)
+			}
+		})
+	}
+}
+
+func TestDeleteBlobDeletion(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	blobCollection := new(mock_mongo_pkg.MongoCollectionMock)
+	blobCollection.On("DeleteOne", mock.Anything, bson.M{"_id": id}).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+
+	svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+		model.BlobDeletionCollectionName: blobCollection,
+	})
+
+	assert.NoError(t, svc.DeleteBlobDeletion(id.Hex(), pkg))
+	assert.Error(t, svc.DeleteBlobDeletion("invalid_id", pkg))
+	blobCollection.AssertExpectations(t)
+}
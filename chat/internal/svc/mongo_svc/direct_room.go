@@ -0,0 +1,67 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrDirectRoom = errors.New("operation is not allowed on direct message rooms")
+
+// 同じ参加者のダイレクトメッセージは1つだけ
+var directKeyIndex = mongo.IndexModel{
+	Keys: bson.D{{Key: "directkey", Value: 1}},
+	Options: options.Index().
+		SetName("directkey").
+		SetUnique(true).
+		SetPartialFilterExpression(bson.M{"kind": model.RoomKindDirect}),
+}
+
+// 参加者が同じダイレクトメッセージがあればそれを返し、無ければ作成する
+// 戻り値の bool は新しく作成した場合に true
+func (m *MongoSvcStruct) GetOrCreateDirectRoom(userIDs []int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, bool, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.Room{}, false, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, directKeyIndex)
+	if err != nil {
+		return model.Room{}, false, err
+	}
+
+	key := model.DirectKey(userIDs)
+	filter := bson.M{"kind": model.RoomKindDirect, "directkey": key}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"name":      "",
+			"ownerid":   0, // ダイレクトメッセージにオーナーはいない
+			"createdat": now,
+			"members":   model.DirectParticipants(userIDs),
+			"isprivate": true,
+		},
+	}
+
+	// 同時に作成された場合は一意制約に引っかかるので、作成済みのものを返す
+	result, err := collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil && !isDuplicateKeyError(err) {
+		return model.Room{}, false, err
+	}
+	created := err == nil && result.UpsertedCount > 0
+
+	var room model.Room
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, filter, &room)
+	if err != nil {
+		return model.Room{}, false, err
+	}
+
+	return room, created, nil
+}
@@ -0,0 +1,67 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestGetOrCreateDirectRoom(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := bson.M{"kind": model.RoomKindDirect, "directkey": "1-2"}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"name":      "",
+			"ownerid":   0,
+			"createdat": now,
+			"members":   []int{1, 2},
+			"isprivate": true,
+		},
+	}
+	duplicateErr := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+
+	tests := []struct {
+		name          string
+		upserted      int64
+		upsertErr     error
+		findErr       error
+		expectCreated bool
+		expectErr     bool
+	}{
+		{"created", 1, nil, nil, true, false},
+		{"existing", 0, nil, nil, false, false},
+		{"created_concurrently", 0, duplicateErr, nil, false, false},
+		{"upsert_error", 0, assert.AnError, nil, false, true},
+		{"find_error", 1, nil, assert.AnError, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("CreateIndex", mock.Anything, directKeyIndex).Return("directkey", nil)
+			collection.On("UpdateOneWithOptions", mock.Anything, filter, update, mock.Anything).Return(&mongo.UpdateResult{UpsertedCount: tt.upserted}, tt.upsertErr)
+			collection.On("FindOne", mock.Anything, filter, mock.Anything).Return(tt.findErr).Run(func(args mock.Arguments) {
+				room := args.Get(2).(*model.Room)
+				room.Kind = model.RoomKindDirect
+				room.Members = []int{1, 2}
+			})
+			svc, pkg := setupRoomCollection(collection)
+
+			// 順番や重複が違っても同じ参加者として扱う
+			room, created, err := svc.GetOrCreateDirectRoom([]int{2, 1, 2}, now, pkg)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectCreated, created)
+			assert.Equal(t, []int{1, 2}, room.Members)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 同じ元のIDのルームは1つだけ
+var importedRoomIndex = mongo.IndexModel{
+	Keys: bson.D{{Key: "externalid", Value: 1}},
+	Options: options.Index().
+		SetName("externalid").
+		SetUnique(true).
+		SetPartialFilterExpression(bson.M{"externalid": bson.M{"$exists": true}}),
+}
+
+// 取り込みをやり直してもメッセージが重複しないよう、元のIDをルーム内で一意にする
+var importedChatMessageIndex = mongo.IndexModel{
+	Keys: bson.D{{Key: "roomid", Value: 1}, {Key: "externalid", Value: 1}},
+	Options: options.Index().
+		SetName("roomid_externalid").
+		SetUnique(true).
+		SetPartialFilterExpression(bson.M{"externalid": bson.M{"$exists": true}}),
+}
+
+// 元のIDのルームがあればそれを返し、無ければ作成する
+// 作成済みのルームの設定は変更せず、メンバーのみ追加する
+func (m *MongoSvcStruct) UpsertImportedRoom(room model.Room, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.Room{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	if err := m.createIndexOnce(mongo, model.RoomCollectionName, importedRoomIndex); err != nil {
+		return model.Room{}, err
+	}
+
+	members := room.Members
+	if members == nil {
+		members = []int{}
+	}
+	filter := bson.M{"externalid": room.ExternalID}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"name":        room.Name,
+			"ownerid":     room.OwnerID,
+			"createdat":   room.CreatedAt,
+			"isprivate":   room.IsPrivate,
+			"description": room.Description,
+			"topic":       room.Topic,
+			"avatarurl":   "",
+		},
+		"$addToSet": bson.M{"members": bson.M{"$each": members}},
+	}
+
+	// 同時に作成された場合は一意制約に引っかかるので、作成済みのものに追加し直す
+	_, err = collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, filter, update, options.Update().SetUpsert(true))
+	if isDuplicateKeyError(err) {
+		_, err = collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, bson.M{"$addToSet": update["$addToSet"]})
+	}
+	if err != nil {
+		return model.Room{}, err
+	}
+
+	var upserted model.Room
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, filter, &upserted)
+	if err != nil {
+		return model.Room{}, err
+	}
+
+	return upserted, nil
+}
+
+// 元のIDで取り込み済みのメッセージを取得する（返信先の解決に使うため ID とスレッドのみ）
+func (m *MongoSvcStruct) GetImportedChatMessages(roomID string, externalIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	if len(externalIDs) == 0 {
+		return []model.ChatMessage{}, nil
+	}
+
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "externalid": 1, "threadrootid": 1})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID, "externalid": bson.M{"$in": externalIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	messages := []model.ChatMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+type importedThread struct {
+	replies     int
+	lastReplyAt time.Time
+}
+
+// 取り込み済みでないメッセージのみ追加し、追加した件数を返す
+// ルームの最終アクティビティとスレッドの返信数は追加したメッセージの分だけ反映する
+func (m *MongoSvcStruct) ImportChatMessages(roomID string, messages []model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return 0, err
+	}
+
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, importedChatMessageIndex); err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	var latest *model.ChatMessage
+	threads := map[string]*importedThread{}
+	for _, message := range messages {
+		message.RoomID = roomID
+		// _id の順に読み込まれるよう、元の投稿日時から ID を作る
+		if message.ID.IsZero() {
+			message.ID = primitive.NewObjectIDFromTimestamp(message.CreatedAt)
+		}
+		if message.IsReadUserIds == nil {
+			message.IsReadUserIds = []int{}
+		}
+		message.ExpiresAt = message.TTLExpiresAt()
+		if message.ExpiresAt != nil {
+			if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageExpiryIndex); err != nil {
+				return inserted, err
+			}
+		}
+
+		result, err := collection.UpdateOneWithOptions(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"roomid": roomID, "externalid": message.ExternalID},
+			bson.M{"$setOnInsert": message},
+			options.Update().SetUpsert(true),
+		)
+		// 同時に取り込まれた場合は取り込み済みとして扱う
+		if isDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return inserted, err
+		}
+		if result.UpsertedCount == 0 {
+			continue
+		}
+		inserted++
+
+		if latest == nil || !message.CreatedAt.Before(latest.CreatedAt) {
+			latest = &message
+		}
+		if message.ThreadRootID != "" {
+			thread, ok := threads[message.ThreadRootID]
+			if !ok {
+				thread = &importedThread{}
+				threads[message.ThreadRootID] = thread
+			}
+			thread.replies++
+			if message.CreatedAt.After(thread.lastReplyAt) {
+				thread.lastReplyAt = message.CreatedAt
+			}
+		}
+	}
+
+	if latest != nil {
+		err = updateRoomActivity(mongo, id, latest.ID.Hex(), *latest)
+		if err != nil {
+			return inserted, err
+		}
+	}
+
+	for threadRootID, thread := range threads {
+		rootID, err := primitive.ObjectIDFromHex(threadRootID)
+		if err != nil {
+			return inserted, err
+		}
+		_, err = collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": rootID},
+			bson.M{
+				"$inc": bson.M{"replycount": thread.replies},
+				"$max": bson.M{"lastreplyat": thread.lastReplyAt},
+			},
+		)
+		if err != nil {
+			return inserted, err
+		}
+	}
+
+	return inserted, nil
+}
@@ -0,0 +1,150 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpsertImportedRoom(t *testing.T) {
+	createdAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	room := model.Room{ExternalID: "C1", Name: "general", OwnerID: 1, CreatedAt: createdAt, Members: []int{1, 2}, Topic: "chat"}
+	existing := model.Room{ID: primitive.NewObjectID(), ExternalID: "C1", Name: "renamed", OwnerID: 1, Members: []int{1, 2, 3}}
+
+	tests := []struct {
+		name      string
+		upsertErr error
+	}{
+		{"success", nil},
+		// 同時に作成された場合はメンバーの追加だけやり直す
+		{"duplicate", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := bson.M{"externalid": "C1"}
+			addMembers := bson.M{"members": bson.M{"$each": []int{1, 2}}}
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, importedRoomIndex).Return("", nil)
+			mongoCollectionMock.On("UpdateOneWithOptions", mock.Anything, filter, bson.M{
+				"$setOnInsert": bson.M{
+					"name":        "general",
+					"ownerid":     1,
+					"createdat":   createdAt,
+					"isprivate":   false,
+					"description": "",
+					"topic":       "chat",
+					"avatarurl":   "",
+				},
+				"$addToSet": addMembers,
+			}, mock.Anything).Return(&mongo.UpdateResult{UpsertedCount: 1}, tt.upsertErr)
+			mongoCollectionMock.On("UpdateOne", mock.Anything, filter, bson.M{"$addToSet": addMembers}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			mongoCollectionMock.On("FindOne", mock.Anything, filter, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(2).(*model.Room) = existing
+			}).Return(nil)
+			svc, pkg := newWebhookTestSvc(model.RoomCollectionName, mongoCollectionMock)
+
+			upserted, err := svc.UpsertImportedRoom(room, pkg)
+			assert.NoError(t, err)
+			// 作成済みのルームは保存されている設定を返す
+			assert.Equal(t, existing, upserted)
+			if tt.upsertErr == nil {
+				mongoCollectionMock.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetImportedChatMessages(t *testing.T) {
+	message := model.ChatMessage{ID: primitive.NewObjectID(), ExternalID: "1700000000.000100"}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.ChatMessage) = message
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	externalIDs := []string{"1700000000.000100", "1700000000.000200"}
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"roomid": "room1", "externalid": bson.M{"$in": externalIDs}}, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.ChatMessageCollectionName, mongoCollectionMock)
+
+	messages, err := svc.GetImportedChatMessages("room1", externalIDs, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ChatMessage{message}, messages)
+
+	// 問い合わせる ID が無い場合は接続しない
+	messages, err = svc.GetImportedChatMessages("room1", nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestImportChatMessages(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := model.ChatMessage{ID: primitive.NewObjectIDFromTimestamp(base), UserID: 1, Message: "root", CreatedAt: base, ExternalID: "m1", RetentionDays: 30}
+	reply := model.ChatMessage{ID: primitive.NewObjectIDFromTimestamp(base.Add(time.Minute)), UserID: 2, Message: "reply", CreatedAt: base.Add(time.Minute), ExternalID: "m2", ParentID: root.ID.Hex(), ThreadRootID: root.ID.Hex()}
+	imported := model.ChatMessage{ID: primitive.NewObjectIDFromTimestamp(base.Add(2 * time.Minute)), UserID: 1, Message: "again", CreatedAt: base.Add(2 * time.Minute), ExternalID: "m3", RetentionDays: 30}
+
+	chatMessageCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	// 保持期間のあるメッセージが複数あってもインデックスの作成は1回だけ行う
+	chatMessageCollectionMock.On("CreateIndex", mock.Anything, importedChatMessageIndex).Return("", nil).Once()
+	chatMessageCollectionMock.On("CreateIndex", mock.Anything, chatMessageExpiryIndex).Return("", nil).Once()
+	for _, tt := range []struct {
+		message  model.ChatMessage
+		upserted int64
+	}{
+		{root, 1},
+		{reply, 1},
+		// 取り込み済みのメッセージは数えず、最終アクティビティも更新しない
+		{imported, 0},
+	} {
+		chatMessageCollectionMock.On("UpdateOneWithOptions", mock.Anything,
+			bson.M{"roomid": roomID.Hex(), "externalid": tt.message.ExternalID},
+			mock.MatchedBy(func(update bson.M) bool {
+				message := update["$setOnInsert"].(model.ChatMessage)
+				return message.ID == tt.message.ID && message.RoomID == roomID.Hex() && message.IsReadUserIds != nil
+			}),
+			mock.Anything,
+		).Return(&mongo.UpdateResult{UpsertedCount: tt.upserted}, nil)
+	}
+	chatMessageCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": root.ID}, bson.M{
+		"$inc": bson.M{"replycount": 1},
+		"$max": bson.M{"lastreplyat": reply.CreatedAt},
+	}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+
+	roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	roomCollectionMock.On("UpdateOne", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["_id"] == roomID
+	}), mock.MatchedBy(func(update bson.M) bool {
+		set := update["$set"].(bson.M)
+		return set["lastactivityat"] == reply.CreatedAt && set["lastmessage"].(model.RoomLastMessage).MessageID == reply.ID.Hex()
+	})).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+
+	svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+		model.ChatMessageCollectionName: chatMessageCollectionMock,
+		model.RoomCollectionName:        roomCollectionMock,
+	})
+
+	inserted, err := svc.ImportChatMessages(roomID.Hex(), []model.ChatMessage{root, reply, imported}, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), inserted)
+	chatMessageCollectionMock.AssertNumberOfCalls(t, "CreateIndex", 2)
+
+	// 保持期間が設定されたルームは元の投稿日時から削除予定日時を計算する
+	chatMessageCollectionMock.AssertCalled(t, "UpdateOneWithOptions", mock.Anything, bson.M{"roomid": roomID.Hex(), "externalid": "m1"}, mock.MatchedBy(func(update bson.M) bool {
+		message := update["$setOnInsert"].(model.ChatMessage)
+		return message.ExpiresAt != nil && message.ExpiresAt.Equal(base.Add(30*24*time.Hour))
+	}), mock.Anything)
+	roomCollectionMock.AssertNumberOfCalls(t, "UpdateOne", 1)
+}
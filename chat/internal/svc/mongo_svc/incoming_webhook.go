@@ -0,0 +1,120 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrIncomingWebhookNotFound = errors.New("incoming webhook not found")
+
+var incomingWebhookTokenIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "tokenhash", Value: 1}},
+	Options: options.Index().SetName("tokenhash").SetUnique(true),
+}
+
+var incomingWebhookRoomIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}},
+	Options: options.Index().SetName("roomid"),
+}
+
+func (m *MongoSvcStruct) CreateIncomingWebhook(webhook model.IncomingWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.IncomingWebhookCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, incomingWebhookTokenIndex)
+	if err != nil {
+		return "", err
+	}
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, incomingWebhookRoomIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return collection.InsertOne(mongo.MongoPkgStruct.Ctx, webhook)
+}
+
+func (m *MongoSvcStruct) GetIncomingWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.IncomingWebhook, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.IncomingWebhookCollectionName)
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	webhooks := []model.IncomingWebhook{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var webhook model.IncomingWebhook
+		if err := cursor.Decode(&webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+// トークンのハッシュで受信 Webhook を取得する
+func (m *MongoSvcStruct) GetIncomingWebhookByToken(tokenHash string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.IncomingWebhook, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.IncomingWebhook{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.IncomingWebhookCollectionName)
+
+	var webhook model.IncomingWebhook
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"tokenhash": tokenHash}, &webhook)
+	if errors.Is(err, errNoDocuments) {
+		return model.IncomingWebhook{}, ErrIncomingWebhookNotFound
+	}
+	if err != nil {
+		return model.IncomingWebhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// 受信 Webhook を削除する。削除後はそのトークンでは投稿できない
+func (m *MongoSvcStruct) DeleteIncomingWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.IncomingWebhookCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return ErrIncomingWebhookNotFound
+	}
+
+	result, err := collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrIncomingWebhookNotFound
+	}
+
+	return nil
+}
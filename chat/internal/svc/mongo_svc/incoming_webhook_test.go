@@ -0,0 +1,110 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateIncomingWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		indexErr  error
+		insertErr error
+		returnErr bool
+	}{
+		{"success", nil, nil, false},
+		{"index_error", assert.AnError, nil, true},
+		{"insert_error", nil, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := model.IncomingWebhook{RoomID: "room1", Name: "CI", TokenHash: "hash"}
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, incomingWebhookTokenIndex).Return("", tt.indexErr)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, incomingWebhookRoomIndex).Return("", nil)
+			mongoCollectionMock.On("InsertOne", mock.Anything, webhook).Return("webhook1", tt.insertErr)
+			svc, pkg := newWebhookTestSvc(model.IncomingWebhookCollectionName, mongoCollectionMock)
+
+			id, err := svc.CreateIncomingWebhook(webhook, pkg)
+			if tt.returnErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "webhook1", id)
+		})
+	}
+}
+
+func TestGetIncomingWebhookByToken(t *testing.T) {
+	found := model.IncomingWebhook{ID: primitive.NewObjectID(), RoomID: "room1", Name: "CI"}
+
+	tests := []struct {
+		name      string
+		findErr   error
+		expectErr error
+	}{
+		{"success", nil, nil},
+		{"not_found", mongo.ErrNoDocuments, ErrIncomingWebhookNotFound},
+		{"find_error", assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("FindOne", mock.Anything, bson.M{"tokenhash": "hash"}, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(2).(*model.IncomingWebhook) = found
+			}).Return(tt.findErr)
+			svc, pkg := newWebhookTestSvc(model.IncomingWebhookCollectionName, mongoCollectionMock)
+
+			webhook, err := svc.GetIncomingWebhookByToken("hash", pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, found, webhook)
+		})
+	}
+}
+
+func TestDeleteIncomingWebhook(t *testing.T) {
+	webhookID := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		webhookID string
+		deleted   int64
+		expectErr error
+	}{
+		{"success", webhookID.Hex(), 1, nil},
+		{"not_found", webhookID.Hex(), 0, ErrIncomingWebhookNotFound},
+		{"invalid_id", "invalid", 0, ErrIncomingWebhookNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			// 別のルームの受信 Webhook は削除できない
+			mongoCollectionMock.On("DeleteOne", mock.Anything, bson.M{"_id": webhookID, "roomid": "room1"}).
+				Return(&mongo.DeleteResult{DeletedCount: tt.deleted}, nil)
+			svc, pkg := newWebhookTestSvc(model.IncomingWebhookCollectionName, mongoCollectionMock)
+
+			err := svc.DeleteIncomingWebhook("room1", tt.webhookID, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// メンション一覧の取得用
+var mentionIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "mentioneduserids", Value: 1}, {Key: "createdat", Value: -1}},
+	Options: options.Index().SetName("mentioneduserids_createdat"),
+}
+
+// 指定されたルームのうち、ユーザーがメンションされたメッセージを新しい順に取得する
+func (m *MongoSvcStruct) GetMentions(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, mentionIndex)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := bson.M{
+		"mentioneduserids": userID,
+		"roomid":           bson.M{"$in": roomIDs},
+		"deletedat":        nil,
+	}
+	total, err := collection.CountDocuments(mongo.MongoPkgStruct.Ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"revisions": 0}).
+		SetSort(bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	messages := []model.ChatMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, total, nil
+}
+
+// since 以降にルームのメッセージを既読にしたユーザー（@here の対象）
+func (m *MongoSvcStruct) GetActiveReaders(roomID string, since time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ReadCursorCollectionName)
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID, "updatedat": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	userIDs := []int{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var readCursor model.ReadCursor
+		if err := cursor.Decode(&readCursor); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, readCursor.UserID)
+	}
+
+	return userIDs, nil
+}
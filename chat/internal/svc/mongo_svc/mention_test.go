@@ -0,0 +1,104 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGetMentions(t *testing.T) {
+	filter := bson.M{
+		"mentioneduserids": 12345,
+		"roomid":           bson.M{"$in": []string{"room1"}},
+		"deletedat":        nil,
+	}
+
+	tests := []struct {
+		name      string
+		initErr   bool
+		indexErr  error
+		countErr  error
+		findErr   error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, nil, false},
+		{"error", true, nil, nil, nil, true},
+		{"index_error", false, assert.AnError, nil, nil, true},
+		{"count_error", false, nil, assert.AnError, nil, true},
+		{"find_error", false, nil, nil, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				message := args.Get(0).(*model.ChatMessage)
+				*message = model.ChatMessage{RoomID: "room1", Message: "@test hi", MentionedUserIds: []int{12345}}
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, mentionIndex).Return("", tt.indexErr)
+			mongoCollectionMock.On("CountDocuments", mock.Anything, filter).Return(int64(1), tt.countErr)
+			mongoCollectionMock.On("FindWithOptions", mock.Anything, filter, mock.Anything).Return(mongoCursorMock, tt.findErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			messages, total, err := mockSvcStruct.GetMentions(12345, []string{"room1"}, 1, 20, mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			assert.Len(t, messages, 1)
+		})
+	}
+}
+
+func TestGetActiveReaders(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		readCursor := args.Get(0).(*model.ReadCursor)
+		*readCursor = model.ReadCursor{RoomID: "room1", UserID: 2}
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("Find", mock.Anything, bson.M{"roomid": "room1", "updatedat": bson.M{"$gte": since}}).Return(mongoCursorMock, nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.ReadCursorCollectionName).Return(mongoCollectionMock)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+	userIDs, err := mockSvcStruct.GetActiveReaders("room1", since, mongoPkgMock)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, userIDs)
+}
@@ -1,13 +1,16 @@
 package mongo_svc
 
 import (
-	"fmt"
+	"errors"
 	"microservices/chat/internal/model"
 	"microservices/chat/pkg/mongo_pkg"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoSvcInterface interface {
@@ -15,15 +18,118 @@ type MongoSvcInterface interface {
 	GetRoomByID(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, error)
 	JoinRoom(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
 	GetRooms(userID int, target string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error)
-	PostChatMessage(roomID string, userID int, message string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	PostChatMessage(chatMessage model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
 	GetChatMessages(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error)
 	ReadChatMessages(roomID string, chatID []string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
 	GetChatMessageByID(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ChatMessage, error)
-	DeleteChatMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	DeleteChatMessage(roomID string, messageID string, deletedBy int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	PurgeDeletedChatMessages(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	GetChatMessage(messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ChatMessage, error)
+	GetThreadMessages(rootID string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error)
+	EditChatMessage(original model.ChatMessage, message string, editorID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	AddReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	RemoveReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	SearchChatMessages(query SearchChatMessagesQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessageSearchHit, int64, error)
+	UpdateReadCursor(roomID string, userID int, message model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetReadCursors(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]model.ReadCursor, error)
+	GetUnreadCounts(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]int64, error)
+	CreateRoomInvite(invite model.RoomInvite, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	AcceptRoomInvite(code string, userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomInvite, error)
+	CreateJoinRequest(request model.JoinRequest, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	GetJoinRequests(roomID string, status string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.JoinRequest, error)
+	DecideJoinRequest(roomID string, requestID string, status string, deciderID int, mongo_pkg mongo_pkg.MongoPkgInterface) (model.JoinRequest, error)
+	LeaveRoom(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) (int, error)
+	KickMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	BanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	UnbanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	TransferOwnership(roomID string, fromUserID int, toUserID int, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	SetMemberRole(roomID string, userID int, role string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	UpdateRoom(roomID string, fields UpdateRoomFields, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	ArchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	UnarchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	DeleteRoom(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetOrCreateDirectRoom(userIDs []int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, bool, error)
+	ListRooms(query RoomListQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, string, error)
+	GetPendingPreviewMessages(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error)
+	SetAttachmentPreview(messageID string, attachmentID string, status string, thumbnails []model.Thumbnail, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	UpsertUser(user model.User, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetUsersByHandles(handles []string, userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error)
+	GetMentions(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error)
+	GetActiveReaders(roomID string, since time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error)
+	GetUsersByIDs(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error)
+	FindUsersByHandle(handle string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error)
+	GetNotificationSettings(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) (map[int]model.NotificationSettings, error)
+	UpdateNotificationSettings(settings model.NotificationSettings, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	SetRoomMuted(userID int, roomID string, muted bool, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	InsertNotifications(notifications []model.Notification, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetPendingNotificationUsers(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error)
+	CreateNotificationDelivery(userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.NotificationDelivery, bool, error)
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.NotificationDelivery, error)
+	GetDeliveryNotifications(deliveryID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Notification, error)
+	RecordDeliveryAttempt(deliveryID string, attempt model.DeliveryAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	CreateRoomWebhook(webhook model.RoomWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	GetRoomWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error)
+	GetRoomWebhooksByIDs(webhookIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error)
+	DeleteRoomWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	InsertWebhookDeliveries(deliveries []model.WebhookDelivery, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error)
+	RecordWebhookAttempt(deliveryID string, attempt model.WebhookAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetWebhookDeliveries(roomID string, status string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error)
+	RedeliverWebhookDelivery(roomID string, deliveryID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	CreateIncomingWebhook(webhook model.IncomingWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	GetIncomingWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.IncomingWebhook, error)
+	GetIncomingWebhookByToken(tokenHash string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.IncomingWebhook, error)
+	DeleteIncomingWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	CreateSlashCommand(command model.SlashCommand, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	GetSlashCommands(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SlashCommand, error)
+	GetSlashCommand(roomID string, name string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.SlashCommand, error)
+	DeleteSlashCommand(roomID string, commandID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	PinMessage(roomID string, pin model.RoomPin, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	UnpinMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetChatMessagesByIDs(messageIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error)
+	SaveMessage(saved model.SavedMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	GetSavedMessages(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SavedMessage, int64, error)
+	DeleteSavedMessage(userID int, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	CreateScheduledMessage(scheduled model.ScheduledMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	GetPendingScheduledMessages(userID int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ScheduledMessage, error)
+	CancelScheduledMessage(userID int, scheduledID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	ClaimDueScheduledMessage(now time.Time, lease time.Duration, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ScheduledMessage, bool, error)
+	CompleteScheduledMessage(scheduledID string, status string, postedMessageID string, errMessage string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	ExpireScheduledMessageLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	GetRetentionRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error)
+	GetRestampPendingRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error)
+	RestampChatMessageExpiry(roomID string, retentionDays int, now time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	CountExpiredChatMessages(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	PurgeExpiredChatMessages(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	PurgeExpiredChatMessageReferences(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	CreateRoomExport(export model.RoomExport, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error)
+	GetRoomExports(roomID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error)
+	GetRoomExport(roomID string, exportID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomExport, error)
+	ClaimPendingRoomExports(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error)
+	ExtendRoomExportLease(exportID string, leaseUntil time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	CompleteRoomExport(export model.RoomExport, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error
+	ExpireRoomExportLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	PurgeExpiredRoomExports(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	GetChatMessagesAfter(roomID string, afterID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error)
+	UpsertImportedRoom(room model.Room, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, error)
+	GetImportedChatMessages(roomID string, externalIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error)
+	ImportChatMessages(roomID string, messages []model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error)
+	GetBlobDeletions(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.BlobDeletion, error)
+	DeleteBlobDeletion(deletionID string, mongo_pkg mongo_pkg.MongoPkgInterface) error
 }
 
+var ErrEditConflict = errors.New("chat message was modified concurrently")
+var ErrChatMessageDeleted = errors.New("chat message not found or already deleted")
+
+var (
+	ErrChatMessageNotFound  = errors.New("chat message not found")
+	ErrInvalidChatMessageID = errors.New("invalid chat message id")
+)
+
 type MongoSvcStruct struct {
 	Db mongo_pkg.MongoDatabaseInterface
+
+	indexes sync.Map // 作成済みのインデックス（コレクション名/インデックス名）
 }
 
 func NewMongoSvc(db mongo_pkg.MongoDatabaseInterface) *MongoSvcStruct {
@@ -47,6 +153,20 @@ func Init(mongo_pkg mongo_pkg.MongoPkgInterface) (mongo *Mongo, err error) {
 	return mongo, nil
 }
 
+// 呼び出しの多い処理用に、インデックスの作成をプロセスごとに1回だけ行う
+func (m *MongoSvcStruct) createIndexOnce(mongo *Mongo, collectionName string, index mongo.IndexModel) error {
+	key := collectionName + "/" + *index.Options.Name
+	if _, ok := m.indexes.Load(key); ok {
+		return nil
+	}
+	collection := mongo.MongoPkgStruct.Db.Collection(collectionName)
+	if _, err := collection.CreateIndex(mongo.MongoPkgStruct.Ctx, index); err != nil {
+		return err
+	}
+	m.indexes.Store(key, true)
+	return nil
+}
+
 func (m *MongoSvcStruct) CreateRoom(room model.Room, mongo_pkg mongo_pkg.MongoPkgInterface) (interface{}, error) {
 	mongo, err := Init(mongo_pkg)
 	if err != nil {
@@ -104,32 +224,37 @@ func (m *MongoSvcStruct) JoinRoom(roomID string, userID int, mongo_pkg mongo_pkg
 		return err
 	}
 
-	_, err = collection.UpdateOne(
+	// BAN されたユーザーとアーカイブされたルームには追加しない（BAN・アーカイブと同時に参加されても整合性が崩れないようにフィルタで判定する）
+	result, err := collection.UpdateOne(
 		mongo.MongoPkgStruct.Ctx,
-		bson.M{"_id": id},
+		bson.M{"_id": id, "banned": bson.M{"$ne": userID}, "archivedat": nil},
 		bson.M{"$addToSet": bson.M{"members": userID}}, // 重複追加防止してくれる
 	)
 	if err != nil {
 		return err
 	}
+	if result.MatchedCount == 0 {
+		// 一致しなかった理由を返す
+		var room model.Room
+		err := collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id}, &room)
+		switch {
+		case errors.Is(err, errNoDocuments):
+			return ErrRoomNotFound
+		case err != nil:
+			return err
+		case room.Archived():
+			return ErrRoomArchived
+		}
+		return ErrUserBanned
+	}
 
 	return nil
 }
 
 func (m *MongoSvcStruct) GetRooms(userID int, target string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
-	var filter bson.M
-	switch target {
-	case "all":
-		filter = bson.M{
-			"$or": []bson.M{
-				{"isprivate": false},
-				{"members": userID}, // 参加済みの場合はプライベートでも表示
-			},
-		}
-	case "joined":
-		filter = bson.M{"members": userID} // 参加済みのものだけ
-	default:
-		return nil, fmt.Errorf("invalid target: %s", target)
+	filter, err := roomTargetFilter(userID, target)
+	if err != nil {
+		return nil, err
 	}
 
 	mongo, err := Init(mongo_pkg)
@@ -158,10 +283,10 @@ func (m *MongoSvcStruct) GetRooms(userID int, target string, mongo_pkg mongo_pkg
 	return rooms, nil
 }
 
-func (m *MongoSvcStruct) PostChatMessage(roomID string, userID int, message string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+func (m *MongoSvcStruct) PostChatMessage(chatMessage model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
 	mongo, err := Init(mongo_pkg)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer mongo.MongoPkgStruct.Cancel()
@@ -169,25 +294,81 @@ func (m *MongoSvcStruct) PostChatMessage(roomID string, userID int, message stri
 	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
 
 	// roomIDをObjectIDに変換
-	id, err := primitive.ObjectIDFromHex(roomID)
+	id, err := primitive.ObjectIDFromHex(chatMessage.RoomID)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	chatMessage.RoomID = id.Hex()
+	if chatMessage.CreatedAt.IsZero() {
+		chatMessage.CreatedAt = time.Now()
+	}
+	if chatMessage.IsReadUserIds == nil {
+		chatMessage.IsReadUserIds = []int{}
+	}
+	// 保持期間が設定されたルームのメッセージは TTL インデックスでも削除されるようにする
+	chatMessage.ExpiresAt = chatMessage.TTLExpiresAt()
+	if chatMessage.ExpiresAt != nil {
+		if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageExpiryIndex); err != nil {
+			return "", err
+		}
 	}
 
-	chatMessage := model.ChatMessage{
-		RoomID:        id.Hex(),
-		UserID:        userID,
-		Message:       message,
-		CreatedAt:     time.Now(),
-		IsReadUserIds: []int{},
+	insertedID, err := collection.InsertOne(mongo.MongoPkgStruct.Ctx, chatMessage)
+	if err != nil {
+		return "", err
 	}
 
-	_, err = collection.InsertOne(mongo.MongoPkgStruct.Ctx, chatMessage)
+	err = updateRoomActivity(mongo, id, insertedID, chatMessage)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	// 返信の場合はスレッドの起点に返信数と最終返信日時を反映する
+	if chatMessage.ThreadRootID != "" {
+		rootID, err := primitive.ObjectIDFromHex(chatMessage.ThreadRootID)
+		if err != nil {
+			return "", err
+		}
+		_, err = collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": rootID},
+			bson.M{
+				"$inc": bson.M{"replycount": 1},
+				"$max": bson.M{"lastreplyat": chatMessage.CreatedAt},
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return insertedID, nil
+}
+
+// ルーム一覧の並び替えとプレビューのために最終アクティビティを更新する
+// 投稿順が前後した場合に古いメッセージで上書きしないよう日時を条件にする
+func updateRoomActivity(m *Mongo, roomID primitive.ObjectID, messageID string, chatMessage model.ChatMessage) error {
+	_, err := m.MongoPkgStruct.Db.Collection(model.RoomCollectionName).UpdateOne(
+		m.MongoPkgStruct.Ctx,
+		bson.M{
+			"_id": roomID,
+			"$or": []bson.M{
+				{"lastactivityat": nil},
+				{"lastactivityat": bson.M{"$lte": chatMessage.CreatedAt}},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"lastactivityat": chatMessage.CreatedAt,
+			"lastmessage": model.RoomLastMessage{
+				MessageID: messageID,
+				UserID:    chatMessage.UserID,
+				Snippet:   model.MessageSnippet(chatMessage.Message),
+				CreatedAt: chatMessage.CreatedAt,
+			},
+		}},
+	)
+	return err
 }
 
 func (m *MongoSvcStruct) GetChatMessages(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
@@ -198,7 +379,9 @@ func (m *MongoSvcStruct) GetChatMessages(roomID string, mongo_pkg mongo_pkg.Mong
 
 	defer mongo.MongoPkgStruct.Cancel()
 	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
-	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID})
+	// 編集履歴はモデレーション用のため、履歴一覧には含めない
+	opts := options.Find().SetProjection(bson.M{"revisions": 0})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID}, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -210,6 +393,10 @@ func (m *MongoSvcStruct) GetChatMessages(roomID string, mongo_pkg mongo_pkg.Mong
 		if err := cursor.Decode(&message); err != nil {
 			return nil, err
 		}
+		if message.DeletedAt != nil {
+			message = message.Tombstone()
+		}
+		message.ReactionCounts = message.CountReactions()
 		messages = append(messages, message)
 	}
 
@@ -262,11 +449,37 @@ func (m *MongoSvcStruct) GetChatMessageByID(roomID string, messageID string, mon
 
 	id, err := primitive.ObjectIDFromHex(messageID)
 	if err != nil {
-		return model.ChatMessage{}, err
+		return model.ChatMessage{}, ErrInvalidChatMessageID
 	}
 
 	var message model.ChatMessage
 	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID}, &message)
+	if errors.Is(err, errNoDocuments) {
+		return model.ChatMessage{}, ErrChatMessageNotFound
+	}
+	if err != nil {
+		return model.ChatMessage{}, err
+	}
+
+	return message, nil
+}
+
+func (m *MongoSvcStruct) GetChatMessage(messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ChatMessage, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.ChatMessage{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return model.ChatMessage{}, err
+	}
+
+	var message model.ChatMessage
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id}, &message)
 	if err != nil {
 		return model.ChatMessage{}, err
 	}
@@ -274,7 +487,95 @@ func (m *MongoSvcStruct) GetChatMessageByID(roomID string, messageID string, mon
 	return message, nil
 }
 
-func (m *MongoSvcStruct) DeleteChatMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+func (m *MongoSvcStruct) GetThreadMessages(rootID string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	filter := bson.M{"threadrootid": rootID}
+	total, err := collection.CountDocuments(mongo.MongoPkgStruct.Ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"revisions": 0}).
+		SetSort(bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	messages := []model.ChatMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, 0, err
+		}
+		if message.DeletedAt != nil {
+			message = message.Tombstone()
+		}
+		message.ReactionCounts = message.CountReactions()
+		messages = append(messages, message)
+	}
+
+	return messages, total, nil
+}
+
+func (m *MongoSvcStruct) EditChatMessage(original model.ChatMessage, message string, editorID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	now := time.Now()
+	// 取得時点の本文を条件に含めて、同時編集で履歴が欠けないようにする
+	filter := bson.M{
+		"_id":     original.ID,
+		"roomid":  original.RoomID,
+		"message": original.Message,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"message":  message,
+			"edited":   true,
+			"editedat": now,
+		},
+		"$push": bson.M{
+			"revisions": model.ChatMessageRevision{
+				Message:  original.Message,
+				EditedAt: now,
+				EditedBy: editorID,
+			},
+		},
+	}
+
+	result, err := collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrEditConflict
+	}
+
+	return updateLastMessagePreview(mongo, original.RoomID, original.ID.Hex(), bson.M{
+		"lastmessage.snippet": model.MessageSnippet(message),
+	})
+}
+
+// 物理削除はせず、削除日時と削除者を記録する（本文はパージされるまで残す）
+func (m *MongoSvcStruct) DeleteChatMessage(roomID string, messageID string, deletedBy int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
 	mongo, err := Init(mongo_pkg)
 	if err != nil {
 		return err
@@ -288,10 +589,197 @@ func (m *MongoSvcStruct) DeleteChatMessage(roomID string, messageID string, mong
 		return err
 	}
 
-	_, err = collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID})
+	// 削除済みの場合は最初の削除者を残す
+	filter := bson.M{"_id": id, "roomid": roomID, "deletedat": nil}
+	update := bson.M{"$set": bson.M{"deletedat": time.Now(), "deletedby": deletedBy}}
+
+	result, err := collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, update)
 	if err != nil {
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return ErrChatMessageDeleted
+	}
 
-	return nil
+	// 削除されたメッセージの本文はプレビューにも残さない
+	return updateLastMessagePreview(mongo, roomID, messageID, bson.M{
+		"lastmessage.snippet": "",
+		"lastmessage.deleted": true,
+	})
+}
+
+// 論理削除から一定期間経過したメッセージを _id の順に最大 limit 件物理削除し、添付ファイルを削除待ちにする
+func (m *MongoSvcStruct) PurgeDeletedChatMessages(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+
+	return purgeChatMessageBatch(
+		mongo.MongoPkgStruct.Ctx,
+		mongo.MongoPkgStruct.Db,
+		bson.M{"deletedat": bson.M{"$lte": before}},
+		bson.D{{Key: "_id", Value: 1}},
+		limit,
+	)
+}
+
+// 1つのメッセージに付けられる絵文字の種類の上限
+const MaxReactionsPerMessage = 20
+
+var ErrTooManyReactions = errors.New("too many distinct reactions on the chat message")
+
+func (m *MongoSvcStruct) AddReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	// 既に付いている絵文字か、付いている絵文字の種類が上限未満の場合のみ追加する
+	filter := bson.M{"$or": bson.A{
+		bson.M{"reactions." + emoji + ".0": bson.M{"$exists": true}},
+		bson.M{"$expr": bson.M{"$lt": bson.A{
+			bson.M{"$size": bson.M{"$filter": bson.M{
+				"input": bson.M{"$objectToArray": bson.M{"$ifNull": bson.A{"$reactions", bson.M{}}}},
+				"cond":  bson.M{"$gt": bson.A{bson.M{"$size": "$$this.v"}, 0}},
+			}}},
+			MaxReactionsPerMessage,
+		}}},
+	}}
+	matched, err := m.updateReaction(messageID, filter, bson.M{"$addToSet": bson.M{"reactions." + emoji: userID}}, mongo_pkg) // 重複追加防止してくれる
+	if err != nil {
+		return err
+	}
+	if matched > 0 {
+		return nil
+	}
+
+	// 一致しない場合は、確認後に削除されたメッセージか上限に達しているかを読み直して判断する
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	id, _ := primitive.ObjectIDFromHex(messageID)
+	var message model.ChatMessage
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "deletedat": nil}, &message)
+	if errors.Is(err, errNoDocuments) {
+		return ErrChatMessageDeleted
+	}
+	if err != nil {
+		return err
+	}
+	return ErrTooManyReactions
+}
+
+func (m *MongoSvcStruct) RemoveReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	_, err := m.updateReaction(messageID, bson.M{}, bson.M{"$pull": bson.M{"reactions." + emoji: userID}}, mongo_pkg)
+	return err
+}
+
+func (m *MongoSvcStruct) updateReaction(messageID string, filter bson.M, update bson.M, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return 0, err
+	}
+
+	filter["_id"] = id
+	filter["deletedat"] = nil
+	result, err := collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.MatchedCount, nil
+}
+
+type SearchChatMessagesQuery struct {
+	Keyword string
+	RoomIDs []string // 検索対象のルーム（参加済みのルームに絞り込んだもの）
+	UserID  int      // 0 の場合は投稿者で絞り込まない
+	From    *time.Time
+	To      *time.Time
+	Page    int
+	Limit   int
+}
+
+func (q SearchChatMessagesQuery) filter() bson.M {
+	filter := bson.M{
+		"$text":     bson.M{"$search": q.Keyword},
+		"roomid":    bson.M{"$in": q.RoomIDs},
+		"deletedat": nil,
+	}
+	if q.UserID != 0 {
+		filter["userid"] = q.UserID
+	}
+	createdAt := bson.M{}
+	if q.From != nil {
+		createdAt["$gte"] = *q.From
+	}
+	if q.To != nil {
+		createdAt["$lte"] = *q.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdat"] = createdAt
+	}
+	return filter
+}
+
+var chatMessageTextIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "message", Value: "text"}},
+	Options: options.Index().SetName("message_text"),
+}
+
+func (m *MongoSvcStruct) SearchChatMessages(query SearchChatMessagesQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessageSearchHit, int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	// $text はテキストインデックスが無いと使えないので、検索前に作成しておく（既にあれば何もしない）
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, chatMessageTextIndex)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := query.filter()
+	total, err := collection.CountDocuments(mongo.MongoPkgStruct.Ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	opts := options.Find().
+		SetProjection(score).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "createdat", Value: -1}}).
+		SetSkip(int64((query.Page - 1) * query.Limit)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var hits []model.ChatMessageSearchHit
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var hit model.ChatMessageSearchHit
+		if err := cursor.Decode(&hit); err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, total, nil
 }
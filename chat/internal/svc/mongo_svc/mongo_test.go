@@ -6,11 +6,14 @@ import (
 	"microservices/chat/pkg/mongo_pkg"
 	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func setupInitMock(wantErr bool, collection string, returnVal interface{}) mongo_pkg.MongoPkgInterface {
@@ -135,27 +138,44 @@ func TestGetRoomByID(t *testing.T) {
 }
 
 func TestJoinRoom(t *testing.T) {
+	archivedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
 	tests := []struct {
 		name         string
 		initErr      bool
 		request      string
 		updateOneErr bool
+		matched      int64
+		room         model.Room // 一致しなかった場合に読み直すルーム
+		findErr      error
+		expectErr    error
 		returnErr    bool
 	}{
-		{"success", false, "64a7b2f4e13e4c3f9c8b4567", false, false},
-		{"error", true, "64a7b2f4e13e4c3f9c8b4567", false, true},
-		{"invalid_id", false, "invalid_object_id", false, true},
-		{"updateone_error", false, "64a7b2f4e13e4c3f9c8b4567", true, true},
+		{"success", false, "64a7b2f4e13e4c3f9c8b4567", false, 1, model.Room{}, nil, nil, false},
+		{"error", true, "64a7b2f4e13e4c3f9c8b4567", false, 1, model.Room{}, nil, nil, true},
+		{"invalid_id", false, "invalid_object_id", false, 1, model.Room{}, nil, nil, true},
+		{"updateone_error", false, "64a7b2f4e13e4c3f9c8b4567", true, 1, model.Room{}, nil, nil, true},
+		// BAN されている場合・アーカイブされている場合はフィルタに一致しない
+		{"banned", false, "64a7b2f4e13e4c3f9c8b4567", false, 0, model.Room{Banned: []int{1}}, nil, ErrUserBanned, true},
+		{"archived", false, "64a7b2f4e13e4c3f9c8b4567", false, 0, model.Room{ArchivedAt: &archivedAt}, nil, ErrRoomArchived, true},
+		{"not_found", false, "64a7b2f4e13e4c3f9c8b4567", false, 0, model.Room{}, mongo.ErrNoDocuments, ErrRoomNotFound, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			id, _ := primitive.ObjectIDFromHex(tt.request)
 			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
 			if tt.updateOneErr {
 				mongoCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{}, assert.AnError)
 			} else {
-				mongoCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+				mongoCollectionMock.On("UpdateOne", mock.Anything,
+					bson.M{"_id": id, "banned": bson.M{"$ne": 1}, "archivedat": nil},
+					bson.M{"$addToSet": bson.M{"members": 1}},
+				).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
 			}
+			mongoCollectionMock.On("FindOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(2).(*model.Room) = tt.room
+			}).Return(tt.findErr)
 			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
 			mongoDatabaseMock.On("Collection", "rooms").Return(mongoCollectionMock)
 
@@ -172,6 +192,9 @@ func TestJoinRoom(t *testing.T) {
 			if (err != nil) != tt.returnErr {
 				t.Errorf("JoinRoom() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
 			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
 			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
 				m.AssertExpectations(t)
 			}
@@ -217,6 +240,8 @@ func TestGetRoomsForAll(t *testing.T) {
 						{"isprivate": false},
 						{"members": 1},
 					},
+					"archivedat": nil,
+					"kind":       bson.M{"$ne": model.RoomKindDirect},
 				}
 			} else {
 				filter = bson.M{"members": 1} // 参加済みのものだけ
@@ -258,25 +283,63 @@ func TestPostChatMessage(t *testing.T) {
 		name          string
 		initErr       bool
 		requestRoomId string
+		threadRootId  string
 		insertOneErr  bool
+		updateOneErr  bool
+		roomUpdateErr bool
 		returnErr     bool
 	}{
-		{"success", false, "64a7b2f4e13e4c3f9c8b4567", false, false},
-		{"error", true, "64a7b2f4e13e4c3f9c8b4567", false, true},
-		{"invalid_id", false, "invalid_object_id", false, true},
-		{"insertone_error", false, "64a7b2f4e13e4c3f9c8b4567", true, true},
+		{"success", false, "64a7b2f4e13e4c3f9c8b4567", "", false, false, false, false},
+		{"success_reply", false, "64a7b2f4e13e4c3f9c8b4567", "64a7b2f4e13e4c3f9c8b4568", false, false, false, false},
+		{"error", true, "64a7b2f4e13e4c3f9c8b4567", "", false, false, false, true},
+		{"invalid_id", false, "invalid_object_id", "", false, false, false, true},
+		{"insertone_error", false, "64a7b2f4e13e4c3f9c8b4567", "", true, false, false, true},
+		{"invalid_thread_root_id", false, "64a7b2f4e13e4c3f9c8b4567", "invalid_object_id", false, false, false, true},
+		{"updateone_error", false, "64a7b2f4e13e4c3f9c8b4567", "64a7b2f4e13e4c3f9c8b4568", false, true, false, true},
+		{"room_update_error", false, "64a7b2f4e13e4c3f9c8b4567", "", false, false, true, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			inserted := mock.MatchedBy(func(message model.ChatMessage) bool {
+				return !message.CreatedAt.IsZero() && message.IsReadUserIds != nil
+			})
 			if tt.insertOneErr {
-				mongoCollectionMock.On("InsertOne", mock.Anything, mock.Anything).Return("", assert.AnError)
+				mongoCollectionMock.On("InsertOne", mock.Anything, inserted).Return("", assert.AnError)
 			} else {
-				mongoCollectionMock.On("InsertOne", mock.Anything, mock.Anything).Return("mocked_id", nil)
+				mongoCollectionMock.On("InsertOne", mock.Anything, inserted).Return("mocked_id", nil)
 			}
+			rootID, _ := primitive.ObjectIDFromHex(tt.threadRootId)
+			update := mock.MatchedBy(func(update bson.M) bool {
+				return assert.ObjectsAreEqual(bson.M{"replycount": 1}, update["$inc"])
+			})
+			if tt.updateOneErr {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": rootID}, update).Return(&mongo.UpdateResult{}, assert.AnError)
+			} else {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": rootID}, update).Return(&mongo.UpdateResult{}, nil)
+			}
+			// ルームの最終アクティビティと最新メッセージを更新する
+			roomID, _ := primitive.ObjectIDFromHex(tt.requestRoomId)
+			roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			roomFilter := mock.MatchedBy(func(filter bson.M) bool {
+				return filter["_id"] == roomID && filter["$or"] != nil
+			})
+			roomUpdate := mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				lastMessage := set["lastmessage"].(model.RoomLastMessage)
+				return lastMessage.MessageID == "mocked_id" && lastMessage.UserID == 1 && lastMessage.Snippet == "Hello, World!" &&
+					set["lastactivityat"] == lastMessage.CreatedAt
+			})
+			var roomUpdateErr error
+			if tt.roomUpdateErr {
+				roomUpdateErr = assert.AnError
+			}
+			roomCollectionMock.On("UpdateOne", mock.Anything, roomFilter, roomUpdate).Return(&mongo.UpdateResult{MatchedCount: 1}, roomUpdateErr)
+
 			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
 			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+			mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollectionMock)
 
 			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
 				Ctx:    context.TODO(),
@@ -287,16 +350,25 @@ func TestPostChatMessage(t *testing.T) {
 
 			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
 
-			err := mockSvcStruct.PostChatMessage(
-				tt.requestRoomId,
-				1,
-				"Hello, World!",
+			id, err := mockSvcStruct.PostChatMessage(
+				model.ChatMessage{
+					RoomID:       tt.requestRoomId,
+					UserID:       1,
+					Message:      "Hello, World!",
+					ThreadRootID: tt.threadRootId,
+				},
 				mongoPkgMock,
 			)
 
 			if (err != nil) != tt.returnErr {
 				t.Errorf("PostChatMessage() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
 			}
+			if !tt.returnErr {
+				assert.Equal(t, "mocked_id", id)
+			}
+			if tt.name == "success" {
+				mongoCollectionMock.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
 
 			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
 				m.AssertExpectations(t)
@@ -305,6 +377,42 @@ func TestPostChatMessage(t *testing.T) {
 	}
 }
 
+// 保持期間が設定されたルームでは投稿日時から削除予定日時を計算する
+func TestPostChatMessageExpiresAt(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(30 * 24 * time.Hour)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	// インデックスの作成は最初の投稿でだけ行う
+	mongoCollectionMock.On("CreateIndex", mock.Anything, chatMessageExpiryIndex).Return("", nil).Once()
+	mongoCollectionMock.On("InsertOne", mock.Anything, mock.MatchedBy(func(message model.ChatMessage) bool {
+		return message.ExpiresAt != nil && message.ExpiresAt.Equal(expiresAt)
+	})).Return("mocked_id", nil)
+	roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	roomCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+	mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollectionMock)
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+
+	svc := NewMongoSvc(mongoDatabaseMock)
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	for i := 0; i < 2; i++ {
+		_, err := svc.PostChatMessage(
+			model.ChatMessage{RoomID: "64a7b2f4e13e4c3f9c8b4567", UserID: 1, Message: "Hello", CreatedAt: createdAt, RetentionDays: 30},
+			mongoPkgMock,
+		)
+		assert.NoError(t, err)
+	}
+	mongoCollectionMock.AssertExpectations(t)
+	mongoCollectionMock.AssertNumberOfCalls(t, "CreateIndex", 1)
+}
+
 func TestGetChatMessages(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -334,11 +442,14 @@ func TestGetChatMessages(t *testing.T) {
 			mongoCursorMock.On("Close", mock.Anything).Return(nil)
 
 			filter := bson.M{"roomid": "64a7b2f4e13e4c3f9c8b4567"}
+			opts := mock.MatchedBy(func(opts *options.FindOptions) bool {
+				return assert.ObjectsAreEqual(bson.M{"revisions": 0}, opts.Projection)
+			})
 
 			if tt.findErr {
-				mongoCollectionMock.On("Find", mock.Anything, filter).Return(mongoCursorMock, assert.AnError)
+				mongoCollectionMock.On("FindWithOptions", mock.Anything, filter, opts).Return(mongoCursorMock, assert.AnError)
 			} else {
-				mongoCollectionMock.On("Find", mock.Anything, filter).Return(mongoCursorMock, nil)
+				mongoCollectionMock.On("FindWithOptions", mock.Anything, filter, opts).Return(mongoCursorMock, nil)
 			}
 			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
 			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
@@ -422,24 +533,22 @@ func TestGetChatMessageByID(t *testing.T) {
 		name             string
 		initErr          bool
 		requestMessageId string
-		findOneErr       bool
+		findOneErr       error
+		expectErr        error
 		returnErr        bool
 	}{
-		{"success", false, "64a7b2f4e13e4c3f9c8b4568", false, false},
-		{"error", true, "64a7b2f4e13e4c3f9c8b4568", false, true},
-		{"invalid_id", false, "invalid_object_id", false, true},
-		{"findone_error", false, "64a7b2f4e13e4c3f9c8b4568", true, true},
+		{"success", false, "64a7b2f4e13e4c3f9c8b4568", nil, nil, false},
+		{"error", true, "64a7b2f4e13e4c3f9c8b4568", nil, nil, true},
+		{"invalid_id", false, "invalid_object_id", nil, ErrInvalidChatMessageID, true},
+		{"not_found", false, "64a7b2f4e13e4c3f9c8b4568", mongo.ErrNoDocuments, ErrChatMessageNotFound, true},
+		{"findone_error", false, "64a7b2f4e13e4c3f9c8b4568", assert.AnError, nil, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
 			var chatMessage model.ChatMessage
-			if tt.findOneErr {
-				mongoCollectionMock.On("FindOne", mock.Anything, mock.Anything, &chatMessage).Return(assert.AnError)
-			} else {
-				mongoCollectionMock.On("FindOne", mock.Anything, mock.Anything, &chatMessage).Return(nil)
-			}
+			mongoCollectionMock.On("FindOne", mock.Anything, mock.Anything, &chatMessage).Return(tt.findOneErr)
 			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
 			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
 
@@ -457,6 +566,9 @@ func TestGetChatMessageByID(t *testing.T) {
 			if (err != nil) != tt.returnErr {
 				t.Errorf("GetChatMessageByID() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
 			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
 
 			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
 				m.AssertExpectations(t)
@@ -470,25 +582,374 @@ func TestDeleteChatMessageByID(t *testing.T) {
 		name             string
 		initErr          bool
 		requestMessageId string
+		matched          int64
 		deleteErr        bool
 		returnErr        bool
+	}{
+		{"success", false, "64a7b2f4e13e4c3f9c8b4568", 1, false, false},
+		{"error", true, "64a7b2f4e13e4c3f9c8b4568", 1, false, true},
+		{"invalid_id", false, "invalid_object_id", 1, false, true},
+		{"delete_error", false, "64a7b2f4e13e4c3f9c8b4568", 1, true, true},
+		// 同時に削除された場合は2回目の削除を失敗にする
+		{"already_deleted", false, "64a7b2f4e13e4c3f9c8b4568", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			update := mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["deletedby"] == 1 && set["deletedat"] != nil
+			})
+			if tt.deleteErr {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, update).Return(&mongo.UpdateResult{}, assert.AnError)
+			} else {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, update).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			}
+			// 最新メッセージだった場合はプレビューの本文も消す
+			roomID, _ := primitive.ObjectIDFromHex("64a7b2f4e13e4c3f9c8b4567")
+			roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			roomCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": roomID, "lastmessage.messageid": tt.requestMessageId},
+				bson.M{"$set": bson.M{"lastmessage.snippet": "", "lastmessage.deleted": true}},
+			).Return(&mongo.UpdateResult{}, nil)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+			mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.DeleteChatMessage("64a7b2f4e13e4c3f9c8b4567", tt.requestMessageId, 1, mongoPkgMock)
+
+			if (err != nil) != tt.returnErr {
+				t.Errorf("DeleteChatMessageByID() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+
+			if tt.matched == 0 {
+				assert.ErrorIs(t, err, ErrChatMessageDeleted)
+				roomCollectionMock.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestSearchChatMessagesQueryFilter(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	query := SearchChatMessagesQuery{Keyword: "hello", RoomIDs: []string{"room1"}}
+	assert.Equal(t, bson.M{
+		"$text":     bson.M{"$search": "hello"},
+		"roomid":    bson.M{"$in": []string{"room1"}},
+		"deletedat": nil,
+	}, query.filter())
+
+	query = SearchChatMessagesQuery{Keyword: "hello", RoomIDs: []string{"room1"}, UserID: 2, From: &from, To: &to}
+	assert.Equal(t, bson.M{
+		"$text":     bson.M{"$search": "hello"},
+		"roomid":    bson.M{"$in": []string{"room1"}},
+		"deletedat": nil,
+		"userid":    2,
+		"createdat": bson.M{"$gte": from, "$lte": to},
+	}, query.filter())
+}
+
+func TestSearchChatMessages(t *testing.T) {
+	tests := []struct {
+		name           string
+		initErr        bool
+		createIndexErr bool
+		countErr       bool
+		findErr        bool
+		decodeErr      bool
+		returnErr      bool
+	}{
+		{"success", false, false, false, false, false, false},
+		{"error", true, false, false, false, false, true},
+		{"create_index_error", false, true, false, false, false, true},
+		{"count_error", false, false, true, false, false, true},
+		{"find_error", false, false, false, true, false, true},
+		{"decode_error", false, false, false, false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			var hit model.ChatMessageSearchHit
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			if tt.decodeErr {
+				mongoCursorMock.On("Decode", &hit).Return(assert.AnError)
+			} else {
+				mongoCursorMock.On("Decode", &hit).Return(nil)
+			}
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			query := SearchChatMessagesQuery{Keyword: "hello", RoomIDs: []string{"room1"}, Page: 2, Limit: 10}
+
+			if tt.createIndexErr {
+				mongoCollectionMock.On("CreateIndex", mock.Anything, chatMessageTextIndex).Return("", assert.AnError)
+			} else {
+				mongoCollectionMock.On("CreateIndex", mock.Anything, chatMessageTextIndex).Return("message_text", nil)
+			}
+			if tt.countErr {
+				mongoCollectionMock.On("CountDocuments", mock.Anything, query.filter()).Return(int64(0), assert.AnError)
+			} else {
+				mongoCollectionMock.On("CountDocuments", mock.Anything, query.filter()).Return(int64(11), nil)
+			}
+			findMatcher := mock.MatchedBy(func(opts *options.FindOptions) bool {
+				return *opts.Skip == 10 && *opts.Limit == 10
+			})
+			if tt.findErr {
+				mongoCollectionMock.On("FindWithOptions", mock.Anything, query.filter(), findMatcher).Return(mongoCursorMock, assert.AnError)
+			} else {
+				mongoCollectionMock.On("FindWithOptions", mock.Anything, query.filter(), findMatcher).Return(mongoCursorMock, nil)
+			}
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+			hits, total, err := mockSvcStruct.SearchChatMessages(query, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("SearchChatMessages() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			if !tt.returnErr {
+				assert.Len(t, hits, 1)
+				assert.Equal(t, int64(11), total)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestGetChatMessage(t *testing.T) {
+	tests := []struct {
+		name             string
+		initErr          bool
+		requestMessageId string
+		findOneErr       bool
+		returnErr        bool
 	}{
 		{"success", false, "64a7b2f4e13e4c3f9c8b4568", false, false},
 		{"error", true, "64a7b2f4e13e4c3f9c8b4568", false, true},
 		{"invalid_id", false, "invalid_object_id", false, true},
-		{"delete_error", false, "64a7b2f4e13e4c3f9c8b4568", true, true},
+		{"findone_error", false, "64a7b2f4e13e4c3f9c8b4568", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			var chatMessage model.ChatMessage
+			if tt.findOneErr {
+				mongoCollectionMock.On("FindOne", mock.Anything, mock.Anything, &chatMessage).Return(assert.AnError)
+			} else {
+				mongoCollectionMock.On("FindOne", mock.Anything, mock.Anything, &chatMessage).Return(nil)
+			}
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			_, err := mockSvcStruct.GetChatMessage(tt.requestMessageId, mongoPkgMock)
+
+			if (err != nil) != tt.returnErr {
+				t.Errorf("GetChatMessage() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestEditChatMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		updateErr bool
+		matched   int64
+		expectErr error
+		returnErr bool
+	}{
+		{"success", false, false, 1, nil, false},
+		{"error", true, false, 1, nil, true},
+		{"update_error", false, true, 1, nil, true},
+		{"conflict", false, false, 0, ErrEditConflict, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := model.ChatMessage{
+				ID:      primitive.NewObjectID(),
+				RoomID:  "64a7b2f4e13e4c3f9c8b4567",
+				UserID:  1,
+				Message: "before",
+			}
+			filter := bson.M{"_id": original.ID, "roomid": original.RoomID, "message": "before"}
+			update := mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				revision := update["$push"].(bson.M)["revisions"].(model.ChatMessageRevision)
+				return set["message"] == "after" && set["edited"] == true &&
+					revision.Message == "before" && revision.EditedBy == 2
+			})
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			if tt.updateErr {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, filter, update).Return(&mongo.UpdateResult{}, assert.AnError)
+			} else {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, filter, update).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			}
+			roomID, _ := primitive.ObjectIDFromHex(original.RoomID)
+			roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			roomCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": roomID, "lastmessage.messageid": original.ID.Hex()},
+				bson.M{"$set": bson.M{"lastmessage.snippet": "after"}},
+			).Return(&mongo.UpdateResult{}, nil)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+			mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.EditChatMessage(original, "after", 2, mongoPkgMock)
+
+			if (err != nil) != tt.returnErr {
+				t.Errorf("EditChatMessage() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestGetChatMessagesTombstone(t *testing.T) {
+	deletedAt := time.Now()
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		message := args.Get(0).(*model.ChatMessage)
+		*message = model.ChatMessage{RoomID: "room1", UserID: 1, Message: "secret", DeletedAt: &deletedAt, DeletedBy: 2, Reactions: map[string][]int{"👍": {1, 3}}}
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, mock.Anything, mock.Anything).Return(mongoCursorMock, nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+	messages, err := mockSvcStruct.GetChatMessages("room1", mongoPkgMock)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "", messages[0].Message)
+	assert.Equal(t, &deletedAt, messages[0].DeletedAt)
+	assert.Equal(t, 2, messages[0].DeletedBy)
+	// トゥームストーンにはリアクションも含めない
+	assert.Equal(t, map[string]int{}, messages[0].ReactionCounts)
+}
+
+func TestPurgeDeletedChatMessages(t *testing.T) {
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	messageID := primitive.NewObjectID()
+	attachment := model.Attachment{StorageKey: "rooms/r1/attachments/a1", Thumbnails: []model.Thumbnail{{StorageKey: "rooms/r1/attachments/a1/thumbnails/160"}}}
+
+	tests := []struct {
+		name      string
+		initErr   bool
+		found     bool
+		findErr   error
+		deleteErr bool
+		queueErr  error
+		returnErr bool
+	}{
+		{"success", false, true, nil, false, nil, false},
+		{"nothing_to_purge", false, false, nil, false, nil, false},
+		{"error", true, true, nil, false, nil, true},
+		{"find_error", false, true, assert.AnError, false, nil, true},
+		{"delete_error", false, true, nil, true, nil, true},
+		{"queue_error", false, true, nil, false, assert.AnError, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			if tt.found {
+				mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+				mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*model.ChatMessage) = model.ChatMessage{ID: messageID, Attachments: []model.Attachment{attachment}}
+				}).Return(nil).Once()
+			}
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
 			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			// 削除から保持期間を過ぎたメッセージを _id の順に limit 件ずつ取得する
+			mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"deletedat": bson.M{"$lte": before}}, mock.MatchedBy(func(opts *options.FindOptions) bool {
+				return *opts.Limit == 100 && assert.ObjectsAreEqual(bson.D{{Key: "_id", Value: 1}}, opts.Sort)
+			})).Return(mongoCursorMock, tt.findErr)
+			filter := bson.M{"_id": bson.M{"$in": []primitive.ObjectID{messageID}}}
 			if tt.deleteErr {
-				mongoCollectionMock.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{}, assert.AnError)
+				mongoCollectionMock.On("DeleteMany", mock.Anything, filter).Return(&mongo.DeleteResult{}, assert.AnError)
 			} else {
-				mongoCollectionMock.On("DeleteOne", mock.Anything, mock.Anything).Return(&mongo.DeleteResult{}, nil)
+				mongoCollectionMock.On("DeleteMany", mock.Anything, filter).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
 			}
+			blobCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			blobCollectionMock.On("InsertOne", mock.Anything, mock.MatchedBy(func(deletion model.BlobDeletion) bool {
+				return assert.ObjectsAreEqual([]string{attachment.StorageKey, attachment.Thumbnails[0].StorageKey}, deletion.StorageKeys)
+			})).Return("", tt.queueErr)
 			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
 			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+			mongoDatabaseMock.On("Collection", model.BlobDeletionCollectionName).Return(blobCollectionMock)
 
 			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
 				Ctx:    context.TODO(),
@@ -499,10 +960,186 @@ func TestDeleteChatMessageByID(t *testing.T) {
 
 			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
 
-			err := mockSvcStruct.DeleteChatMessage("64a7b2f4e13e4c3f9c8b4567", tt.requestMessageId, mongoPkgMock)
+			count, err := mockSvcStruct.PurgeDeletedChatMessages(before, 100, mongoPkgMock)
 
 			if (err != nil) != tt.returnErr {
-				t.Errorf("DeleteChatMessageByID() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+				t.Errorf("PurgeDeletedChatMessages() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			switch {
+			case !tt.found:
+				assert.Equal(t, int64(0), count)
+				mongoCollectionMock.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+			case !tt.returnErr:
+				assert.Equal(t, int64(1), count)
+				blobCollectionMock.AssertExpectations(t)
+			}
+			// メッセージを削除できなかった場合は添付ファイルを削除待ちにしない
+			if tt.deleteErr {
+				blobCollectionMock.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestGetThreadMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		countErr  bool
+		findErr   bool
+		decodeErr bool
+		returnErr bool
+	}{
+		{"success", false, false, false, false, false},
+		{"error", true, false, false, false, true},
+		{"count_error", false, true, false, false, true},
+		{"find_error", false, false, true, false, true},
+		{"decode_error", false, false, false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletedAt := time.Now()
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Times(2)
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			decode := mongoCursorMock.On("Decode", mock.AnythingOfType("*model.ChatMessage"))
+			if tt.decodeErr {
+				decode.Return(assert.AnError)
+			} else {
+				replies := []model.ChatMessage{
+					{Message: "reply", ThreadRootID: "root", Reactions: map[string][]int{":+1:": {1, 2}}},
+					{Message: "deleted reply", ThreadRootID: "root", DeletedAt: &deletedAt},
+				}
+				i := 0
+				decode.Run(func(args mock.Arguments) {
+					*args.Get(0).(*model.ChatMessage) = replies[i]
+					i++
+				}).Return(nil)
+			}
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			filter := bson.M{"threadrootid": "root"}
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			if tt.countErr {
+				mongoCollectionMock.On("CountDocuments", mock.Anything, filter).Return(int64(0), assert.AnError)
+			} else {
+				mongoCollectionMock.On("CountDocuments", mock.Anything, filter).Return(int64(2), nil)
+			}
+			findMatcher := mock.MatchedBy(func(opts *options.FindOptions) bool {
+				return *opts.Skip == 20 && *opts.Limit == 20
+			})
+			if tt.findErr {
+				mongoCollectionMock.On("FindWithOptions", mock.Anything, filter, findMatcher).Return(mongoCursorMock, assert.AnError)
+			} else {
+				mongoCollectionMock.On("FindWithOptions", mock.Anything, filter, findMatcher).Return(mongoCursorMock, nil)
+			}
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			messages, total, err := mockSvcStruct.GetThreadMessages("root", 2, 20, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("GetThreadMessages() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			if !tt.returnErr {
+				assert.Equal(t, int64(2), total)
+				assert.Len(t, messages, 2)
+				assert.Equal(t, "reply", messages[0].Message)
+				assert.Equal(t, map[string]int{":+1:": 2}, messages[0].ReactionCounts)
+				assert.Equal(t, "", messages[1].Message)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestUpdateReaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		messageId string
+		remove    bool
+		updateErr bool
+		matched   int64
+		findErr   error // 一致しなかった場合の読み直しの結果
+		expectErr error
+		returnErr bool
+	}{
+		{"add_success", false, "64a7b2f4e13e4c3f9c8b4568", false, false, 1, nil, nil, false},
+		{"add_too_many", false, "64a7b2f4e13e4c3f9c8b4568", false, false, 0, nil, ErrTooManyReactions, true},
+		// 確認後に削除・期限切れで消えたメッセージは上限とは区別する
+		{"add_deleted", false, "64a7b2f4e13e4c3f9c8b4568", false, false, 0, mongo.ErrNoDocuments, ErrChatMessageDeleted, true},
+		{"add_find_error", false, "64a7b2f4e13e4c3f9c8b4568", false, false, 0, assert.AnError, assert.AnError, true},
+		{"remove_success", false, "64a7b2f4e13e4c3f9c8b4568", true, false, 0, nil, nil, false},
+		{"error", true, "64a7b2f4e13e4c3f9c8b4568", false, false, 0, nil, nil, true},
+		{"invalid_id", false, "invalid_object_id", false, false, 0, nil, nil, true},
+		{"update_error", false, "64a7b2f4e13e4c3f9c8b4568", true, true, 0, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, _ := primitive.ObjectIDFromHex(tt.messageId)
+			filter := mock.MatchedBy(func(filter bson.M) bool {
+				if filter["_id"] != id || filter["deletedat"] != nil {
+					return false
+				}
+				// 追加する場合は絵文字の種類の上限を条件に含める
+				_, capped := filter["$or"]
+				return capped != tt.remove
+			})
+			update := bson.M{"$addToSet": bson.M{"reactions.👍": 1}}
+			if tt.remove {
+				update = bson.M{"$pull": bson.M{"reactions.👍": 1}}
+			}
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			if tt.updateErr {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, filter, update).Return(&mongo.UpdateResult{}, assert.AnError)
+			} else {
+				mongoCollectionMock.On("UpdateOne", mock.Anything, filter, update).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			}
+			var message model.ChatMessage
+			mongoCollectionMock.On("FindOne", mock.Anything, bson.M{"_id": id, "deletedat": nil}, &message).Return(tt.findErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			var err error
+			if tt.remove {
+				err = mockSvcStruct.RemoveReaction(tt.messageId, "👍", 1, mongoPkgMock)
+			} else {
+				err = mockSvcStruct.AddReaction(tt.messageId, "👍", 1, mongoPkgMock)
+			}
+			if (err != nil) != tt.returnErr {
+				t.Errorf("updateReaction() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
+			if tt.remove || tt.matched > 0 {
+				mongoCollectionMock.AssertNotCalled(t, "FindOne", mock.Anything, mock.Anything, mock.Anything)
 			}
 
 			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
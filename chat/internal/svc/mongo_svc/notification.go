@@ -0,0 +1,352 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var notificationSettingsIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "userid", Value: 1}},
+	Options: options.Index().SetName("userid").SetUnique(true),
+}
+
+// ダイジェストにまとめる前の通知の取得用
+var pendingNotificationIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "deliveryid", Value: 1}, {Key: "userid", Value: 1}, {Key: "createdat", Value: 1}},
+	Options: options.Index().SetName("deliveryid_userid_createdat"),
+}
+
+// 配信待ちの取得用
+var dueDeliveryIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextattemptat", Value: 1}},
+	Options: options.Index().SetName("status_nextattemptat"),
+}
+
+// 指定されたユーザーの通知設定を取得する（設定していないユーザーは含まれない）
+func (m *MongoSvcStruct) GetNotificationSettings(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) (map[int]model.NotificationSettings, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationSettingsCollectionName)
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"userid": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	settings := map[int]model.NotificationSettings{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var s model.NotificationSettings
+		if err := cursor.Decode(&s); err != nil {
+			return nil, err
+		}
+		settings[s.UserID] = s
+	}
+
+	return settings, nil
+}
+
+// 通知設定を保存する（ミュートしたルームは SetRoomMuted で個別に変更する）
+func (m *MongoSvcStruct) UpdateNotificationSettings(settings model.NotificationSettings, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationSettingsCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, notificationSettingsIndex)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"muteall":    settings.MuteAll,
+			"channel":    settings.Channel,
+			"webhookurl": settings.WebhookURL,
+			"dndstart":   settings.DNDStart,
+			"dndend":     settings.DNDEnd,
+			"timezone":   settings.TimeZone,
+			"updatedat":  time.Now(),
+		},
+	}
+	_, err = collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"userid": settings.UserID}, update, options.Update().SetUpsert(true))
+	if err != nil && !isDuplicateKeyError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ルームの通知をミュートする・ミュートを解除する
+func (m *MongoSvcStruct) SetRoomMuted(userID int, roomID string, muted bool, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationSettingsCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, notificationSettingsIndex)
+	if err != nil {
+		return err
+	}
+
+	operator := "$pull"
+	if muted {
+		operator = "$addToSet"
+	}
+	update := bson.M{
+		operator: bson.M{"mutedrooms": roomID},
+		"$set":   bson.M{"updatedat": time.Now()},
+	}
+	_, err = collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"userid": userID}, update, options.Update().SetUpsert(true))
+	if err != nil && !isDuplicateKeyError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) InsertNotifications(notifications []model.Notification, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationCollectionName)
+
+	for _, notification := range notifications {
+		// ダイジェストにまとめるまでは未割り当て
+		notification.DeliveryID = ""
+		if _, err := collection.InsertOne(mongo.MongoPkgStruct.Ctx, notification); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// before より前の通知がまとめられずに残っているユーザーを取得する
+func (m *MongoSvcStruct) GetPendingNotificationUsers(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, pendingNotificationIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"deliveryid": ""}},
+		{"$group": bson.M{"_id": "$userid", "oldest": bson.M{"$min": "$createdat"}}},
+		{"$match": bson.M{"oldest": bson.M{"$lte": before}}},
+		{"$sort": bson.M{"oldest": 1}},
+		{"$limit": limit},
+	}
+	cursor, err := collection.Aggregate(mongo.MongoPkgStruct.Ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	userIDs := []int{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var group struct {
+			UserID int `bson:"_id"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, group.UserID)
+	}
+
+	return userIDs, nil
+}
+
+// ユーザーのまとめられていない通知を1通の配信にまとめる。
+// 対象の通知が無い場合（他のワーカーが先にまとめた場合など）は false を返す
+func (m *MongoSvcStruct) CreateNotificationDelivery(userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.NotificationDelivery, bool, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.NotificationDelivery{}, false, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	notifications := mongo.MongoPkgStruct.Db.Collection(model.NotificationCollectionName)
+	deliveries := mongo.MongoPkgStruct.Db.Collection(model.NotificationDeliveryCollectionName)
+
+	delivery := model.NotificationDelivery{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		Status:        model.DeliveryPending,
+		Attempts:      []model.DeliveryAttempt{},
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+
+	// 先に通知を割り当てることで、同じ通知が複数の配信に含まれないようにする
+	result, err := notifications.UpdateMany(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"userid": userID, "deliveryid": "", "createdat": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"deliveryid": delivery.ID.Hex()}},
+	)
+	if err != nil {
+		return model.NotificationDelivery{}, false, err
+	}
+	if result.ModifiedCount == 0 {
+		return model.NotificationDelivery{}, false, nil
+	}
+
+	_, err = deliveries.CreateIndex(mongo.MongoPkgStruct.Ctx, dueDeliveryIndex)
+	if err == nil {
+		_, err = deliveries.InsertOne(mongo.MongoPkgStruct.Ctx, delivery)
+	}
+	if err != nil {
+		// 次回まとめ直せるように割り当てを戻す
+		notifications.UpdateMany(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"deliveryid": delivery.ID.Hex()},
+			bson.M{"$set": bson.M{"deliveryid": ""}},
+		)
+		return model.NotificationDelivery{}, false, err
+	}
+
+	return delivery, true, nil
+}
+
+// 配信時刻を過ぎた配信を取得し、lease の間は他のワーカーが取得しないようにする
+func (m *MongoSvcStruct) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.NotificationDelivery, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationDeliveryCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, dueDeliveryIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "nextattemptat", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{
+		"status":        model.DeliveryPending,
+		"nextattemptat": bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var due []model.NotificationDelivery
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var delivery model.NotificationDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return nil, err
+		}
+		due = append(due, delivery)
+	}
+
+	claimed := []model.NotificationDelivery{}
+	for _, delivery := range due {
+		// 読み取った後に他のワーカーが取得していないことを配信時刻で確認する
+		result, err := collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": delivery.ID, "status": model.DeliveryPending, "nextattemptat": delivery.NextAttemptAt},
+			bson.M{"$set": bson.M{"nextattemptat": now.Add(lease)}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, delivery)
+	}
+
+	return claimed, nil
+}
+
+// 配信にまとめた通知を古い順に取得する
+func (m *MongoSvcStruct) GetDeliveryNotifications(deliveryID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Notification, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationCollectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"deliveryid": deliveryID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	notifications := []model.Notification{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var notification model.Notification
+		if err := cursor.Decode(&notification); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+// 配信の試行を記録する。status が pending の場合は nextAttemptAt に再試行する
+func (m *MongoSvcStruct) RecordDeliveryAttempt(deliveryID string, attempt model.DeliveryAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.NotificationDeliveryCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"status": status, "nextattemptat": nextAttemptAt}
+	if status == model.DeliveryDelivered {
+		set["deliveredat"] = attempt.At
+	}
+	_, err = collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id},
+		bson.M{"$push": bson.M{"attempts": attempt}, "$set": set},
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
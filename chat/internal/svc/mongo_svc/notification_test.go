@@ -0,0 +1,268 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpdateNotificationSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		indexErr  error
+		updateErr error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, false},
+		{"error", true, nil, nil, true},
+		{"index_error", false, assert.AnError, nil, true},
+		{"update_error", false, nil, assert.AnError, true},
+		{"concurrent_upsert", false, nil, mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, notificationSettingsIndex).Return("", tt.indexErr)
+			mongoCollectionMock.On("UpdateOneWithOptions", mock.Anything, bson.M{"userid": 12345}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				// ミュートしたルームは上書きしない
+				_, hasMutedRooms := set["mutedrooms"]
+				return set["channel"] == model.NotificationChannelWebhook && set["dndstart"] == "22:00" && !hasMutedRooms
+			}), mock.Anything).Return(&mongo.UpdateResult{}, tt.updateErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.NotificationSettingsCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.UpdateNotificationSettings(model.NotificationSettings{
+				UserID:     12345,
+				Channel:    model.NotificationChannelWebhook,
+				WebhookURL: "https://example.com/hook",
+				DNDStart:   "22:00",
+				DNDEnd:     "07:00",
+				MutedRooms: []string{"room1"},
+			}, mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetRoomMuted(t *testing.T) {
+	tests := []struct {
+		name           string
+		muted          bool
+		expectOperator string
+	}{
+		{"mute", true, "$addToSet"},
+		{"unmute", false, "$pull"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, notificationSettingsIndex).Return("", nil)
+			mongoCollectionMock.On("UpdateOneWithOptions", mock.Anything, bson.M{"userid": 12345}, mock.MatchedBy(func(update bson.M) bool {
+				operation, ok := update[tt.expectOperator].(bson.M)
+				return ok && operation["mutedrooms"] == "room1"
+			}), mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.NotificationSettingsCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			assert.NoError(t, mockSvcStruct.SetRoomMuted(12345, "room1", tt.muted, mongoPkgMock))
+			mongoCollectionMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateNotificationDelivery(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		initErr      bool
+		claimErr     error
+		claimed      int64
+		insertErr    error
+		expectCreate bool
+		expectRevert bool
+		returnErr    bool
+	}{
+		{"success", false, nil, 2, nil, true, false, false},
+		{"error", true, nil, 0, nil, false, false, true},
+		{"claim_error", false, assert.AnError, 0, nil, false, false, true},
+		// 他のワーカーが先にまとめた場合
+		{"nothing_to_claim", false, nil, 0, nil, false, false, false},
+		{"insert_error", false, nil, 2, assert.AnError, false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifications := new(mock_mongo_pkg.MongoCollectionMock)
+			notifications.On("UpdateMany", mock.Anything, bson.M{"userid": 12345, "deliveryid": "", "createdat": bson.M{"$lte": now}}, mock.Anything).
+				Return(&mongo.UpdateResult{ModifiedCount: tt.claimed}, tt.claimErr)
+			notifications.On("UpdateMany", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+				id, ok := filter["deliveryid"].(string)
+				return ok && id != ""
+			}), bson.M{"$set": bson.M{"deliveryid": ""}}).Return(&mongo.UpdateResult{}, nil)
+			deliveries := new(mock_mongo_pkg.MongoCollectionMock)
+			deliveries.On("CreateIndex", mock.Anything, dueDeliveryIndex).Return("", nil)
+			deliveries.On("InsertOne", mock.Anything, mock.Anything).Return("", tt.insertErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.NotificationCollectionName).Return(notifications)
+			mongoDatabaseMock.On("Collection", model.NotificationDeliveryCollectionName).Return(deliveries)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			delivery, created, err := mockSvcStruct.CreateNotificationDelivery(12345, now, mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectCreate, created)
+			if tt.expectCreate {
+				assert.Equal(t, 12345, delivery.UserID)
+				assert.Equal(t, model.DeliveryPending, delivery.Status)
+				assert.Equal(t, now, delivery.NextAttemptAt)
+			}
+			if tt.expectRevert {
+				notifications.AssertCalled(t, "UpdateMany", mock.Anything, mock.Anything, bson.M{"$set": bson.M{"deliveryid": ""}})
+			} else {
+				notifications.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, bson.M{"$set": bson.M{"deliveryid": ""}})
+			}
+		})
+	}
+}
+
+func TestClaimDueDeliveries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := []model.NotificationDelivery{
+		{ID: primitive.NewObjectID(), UserID: 1, Status: model.DeliveryPending, NextAttemptAt: now.Add(-time.Minute)},
+		{ID: primitive.NewObjectID(), UserID: 2, Status: model.DeliveryPending, NextAttemptAt: now.Add(-time.Second)},
+	}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Twice()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	decoded := 0
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.NotificationDelivery) = due[decoded]
+		decoded++
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, dueDeliveryIndex).Return("", nil)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{
+		"status":        model.DeliveryPending,
+		"nextattemptat": bson.M{"$lte": now},
+	}, mock.Anything).Return(mongoCursorMock, nil)
+	update := bson.M{"$set": bson.M{"nextattemptat": now.Add(time.Minute)}}
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": due[0].ID, "status": model.DeliveryPending, "nextattemptat": due[0].NextAttemptAt}, update).
+		Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	// 他のワーカーが先に取得した配信は含めない
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": due[1].ID, "status": model.DeliveryPending, "nextattemptat": due[1].NextAttemptAt}, update).
+		Return(&mongo.UpdateResult{MatchedCount: 0}, nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.NotificationDeliveryCollectionName).Return(mongoCollectionMock)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+	claimed, err := mockSvcStruct.ClaimDueDeliveries(now, time.Minute, 10, mongoPkgMock)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, due[0].ID, claimed[0].ID)
+		assert.Equal(t, now.Add(time.Minute), claimed[0].NextAttemptAt)
+	}
+}
+
+func TestRecordDeliveryAttempt(t *testing.T) {
+	deliveryID := primitive.NewObjectID()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		deliveryID        string
+		status            string
+		updateErr         error
+		expectDeliveredAt bool
+		returnErr         bool
+	}{
+		{"delivered", deliveryID.Hex(), model.DeliveryDelivered, nil, true, false},
+		{"retry", deliveryID.Hex(), model.DeliveryPending, nil, false, false},
+		{"invalid_id", "invalid", model.DeliveryPending, nil, false, true},
+		{"update_error", deliveryID.Hex(), model.DeliveryFailed, assert.AnError, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := model.DeliveryAttempt{At: now, Channel: model.NotificationChannelEmail}
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": deliveryID}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				_, hasDeliveredAt := set["deliveredat"]
+				return set["status"] == tt.status && hasDeliveredAt == tt.expectDeliveredAt &&
+					update["$push"].(bson.M)["attempts"] == attempt
+			})).Return(&mongo.UpdateResult{MatchedCount: 1}, tt.updateErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.NotificationDeliveryCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.RecordDeliveryAttempt(tt.deliveryID, attempt, tt.status, now.Add(time.Minute), mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				mongoCollectionMock.AssertExpectations(t)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ルームとユーザーの組み合わせごとに既読位置は1件だけ持つ
+var readCursorIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}, {Key: "userid", Value: 1}},
+	Options: options.Index().SetName("roomid_userid").SetUnique(true),
+}
+
+// 未読数のカウント用
+var chatMessageRoomCreatedAtIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}, {Key: "createdat", Value: 1}},
+	Options: options.Index().SetName("roomid_createdat"),
+}
+
+var isDuplicateKeyError = mongo.IsDuplicateKeyError
+
+// 既読位置を指定されたメッセージまで進める。既に新しい位置まで既読の場合は何もしない
+func (m *MongoSvcStruct) UpdateReadCursor(roomID string, userID int, message model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ReadCursorCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, readCursorIndex)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"roomid":     roomID,
+		"userid":     userID,
+		"lastreadat": bson.M{"$lt": message.CreatedAt},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"lastreadmessageid": message.ID.Hex(),
+			"lastreadat":        message.CreatedAt,
+			"updatedat":         time.Now(),
+		},
+	}
+
+	// 既読位置の方が新しい場合はフィルタに一致せず upsert が一意制約に引っかかる
+	_, err = collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil && !isDuplicateKeyError(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) GetReadCursors(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]model.ReadCursor, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ReadCursorCollectionName)
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"userid": userID, "roomid": bson.M{"$in": roomIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	readCursors := map[string]model.ReadCursor{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var readCursor model.ReadCursor
+		if err := cursor.Decode(&readCursor); err != nil {
+			return nil, err
+		}
+		readCursors[readCursor.RoomID] = readCursor
+	}
+
+	return readCursors, nil
+}
+
+// ルームごとに既読位置より後の他ユーザーのメッセージ数を返す
+func (m *MongoSvcStruct) GetUnreadCounts(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]int64, error) {
+	counts := map[string]int64{}
+	if len(roomIDs) == 0 {
+		return counts, nil
+	}
+
+	readCursors, err := m.GetReadCursors(userID, roomIDs, mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+
+	if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageRoomCreatedAtIndex); err != nil {
+		return nil, err
+	}
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	// 全ルーム分をまとめて数える
+	pipeline := []bson.M{
+		{"$match": unreadFilter(userID, roomIDs, readCursors)},
+		{"$group": bson.M{"_id": "$roomid", "count": bson.M{"$sum": 1}}},
+	}
+	cursor, err := collection.Aggregate(mongo.MongoPkgStruct.Ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	for _, roomID := range roomIDs {
+		counts[roomID] = 0
+	}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var group struct {
+			RoomID string `bson:"_id"`
+			Count  int64  `bson:"count"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return nil, err
+		}
+		counts[group.RoomID] = group.Count
+	}
+
+	return counts, nil
+}
+
+func unreadFilter(userID int, roomIDs []string, readCursors map[string]model.ReadCursor) bson.M {
+	var conditions bson.A
+	// 既読位置が無いルームは全メッセージが未読
+	unread := []string{}
+	for _, roomID := range roomIDs {
+		readCursor, ok := readCursors[roomID]
+		if !ok || readCursor.ID == primitive.NilObjectID {
+			unread = append(unread, roomID)
+			continue
+		}
+		conditions = append(conditions, bson.M{"roomid": roomID, "createdat": bson.M{"$gt": readCursor.LastReadAt}})
+	}
+	if len(unread) > 0 {
+		conditions = append(conditions, bson.M{"roomid": bson.M{"$in": unread}})
+	}
+
+	return bson.M{
+		"$or":       conditions,
+		"userid":    bson.M{"$ne": userID},
+		"deletedat": nil,
+	}
+}
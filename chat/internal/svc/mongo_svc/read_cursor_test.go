@@ -0,0 +1,162 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpdateReadCursor(t *testing.T) {
+	messageID, _ := primitive.ObjectIDFromHex("64a7b2f4e13e4c3f9c8b4568")
+	message := model.ChatMessage{ID: messageID, RoomID: "room1", CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	duplicateErr := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "duplicate key"}}}
+
+	tests := []struct {
+		name      string
+		initErr   bool
+		indexErr  error
+		updateErr error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, false},
+		{"already_read_newer", false, nil, duplicateErr, false},
+		{"error", true, nil, nil, true},
+		{"index_error", false, assert.AnError, nil, true},
+		{"update_error", false, nil, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, readCursorIndex).Return("roomid_userid", tt.indexErr)
+			filter := bson.M{"roomid": "room1", "userid": 1, "lastreadat": bson.M{"$lt": message.CreatedAt}}
+			mongoCollectionMock.On("UpdateOneWithOptions", mock.Anything, filter, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["lastreadmessageid"] == messageID.Hex() && set["lastreadat"] == message.CreatedAt
+			}), mock.Anything).Return(&mongo.UpdateResult{}, tt.updateErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ReadCursorCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.UpdateReadCursor("room1", 1, message, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("UpdateReadCursor() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+
+			if m, ok := mongoPkgMock.(interface{ AssertExpectations(*testing.T) }); ok {
+				m.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestGetUnreadCounts(t *testing.T) {
+	lastReadAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		initErr      bool
+		findErr      error
+		aggregateErr error
+		decodeErr    error
+		expect       map[string]int64
+		returnErr    bool
+	}{
+		{"success", false, nil, nil, nil, map[string]int64{"room1": 2, "room2": 5, "room3": 0}, false},
+		{"error", true, nil, nil, nil, nil, true},
+		{"find_error", false, assert.AnError, nil, nil, nil, true},
+		{"aggregate_error", false, nil, assert.AnError, nil, nil, true},
+		{"decode_error", false, nil, nil, assert.AnError, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomIDs := []string{"room1", "room2", "room3"}
+
+			// room1 のみ既読位置がある
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				readCursor := args.Get(0).(*model.ReadCursor)
+				*readCursor = model.ReadCursor{ID: primitive.NewObjectID(), RoomID: "room1", UserID: 1, LastReadAt: lastReadAt}
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			readCursorCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			readCursorCollectionMock.On("Find", mock.Anything, bson.M{"userid": 1, "roomid": bson.M{"$in": roomIDs}}).Return(mongoCursorMock, tt.findErr)
+
+			// 未読のメッセージがあるルームのみ集計結果に含まれる
+			groups := []bson.M{{"_id": "room1", "count": int64(2)}, {"_id": "room2", "count": int64(5)}}
+			aggregateCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			aggregateCursorMock.On("Next", mock.Anything).Return(true).Times(len(groups))
+			aggregateCursorMock.On("Next", mock.Anything).Return(false).Once()
+			i := 0
+			aggregateCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				data, _ := bson.Marshal(groups[i])
+				_ = bson.Unmarshal(data, args.Get(0))
+				i++
+			}).Return(tt.decodeErr)
+			aggregateCursorMock.On("Close", mock.Anything).Return(nil)
+
+			pipeline := []bson.M{
+				{"$match": bson.M{
+					"$or": bson.A{
+						bson.M{"roomid": "room1", "createdat": bson.M{"$gt": lastReadAt}},
+						bson.M{"roomid": bson.M{"$in": []string{"room2", "room3"}}},
+					},
+					"userid":    bson.M{"$ne": 1},
+					"deletedat": nil,
+				}},
+				{"$group": bson.M{"_id": "$roomid", "count": bson.M{"$sum": 1}}},
+			}
+			chatMessageCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			chatMessageCollectionMock.On("CreateIndex", mock.Anything, chatMessageRoomCreatedAtIndex).Return("roomid_createdat", nil).Once()
+			chatMessageCollectionMock.On("Aggregate", mock.Anything, pipeline).Return(aggregateCursorMock, tt.aggregateErr)
+
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.ReadCursorCollectionName).Return(readCursorCollectionMock)
+			mongoDatabaseMock.On("Collection", model.ChatMessageCollectionName).Return(chatMessageCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			counts, err := mockSvcStruct.GetUnreadCounts(1, roomIDs, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("GetUnreadCounts() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			if !tt.returnErr {
+				assert.Equal(t, tt.expect, counts)
+				// インデックスは2回目以降は作成しない
+				mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+				mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+				aggregateCursorMock.On("Next", mock.Anything).Return(false).Once()
+				_, err = mockSvcStruct.GetUnreadCounts(1, roomIDs, mongoPkgMock)
+				assert.NoError(t, err)
+				chatMessageCollectionMock.AssertNumberOfCalls(t, "CreateIndex", 1)
+				chatMessageCollectionMock.AssertNumberOfCalls(t, "Aggregate", 2)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// expiresat を過ぎたメッセージは MongoDB が自動で削除する
+var chatMessageExpiryIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "expiresat", Value: 1}},
+	Options: options.Index().SetName("expiresat_ttl").SetExpireAfterSeconds(0),
+}
+
+// 保持日数の変更を反映していないメッセージ。保持期間を解除した場合は削除予定日時が残っているメッセージ
+func staleExpiryFilter(roomID string, retentionDays int) bson.M {
+	if retentionDays <= 0 {
+		return bson.M{"roomid": roomID, "$or": bson.A{
+			bson.M{"retentiondays": bson.M{"$gt": 0}},
+			bson.M{"expiresat": bson.M{"$exists": true}},
+		}}
+	}
+	return bson.M{"roomid": roomID, "retentiondays": bson.M{"$ne": retentionDays}}
+}
+
+// 保持日数の変更を反映していないメッセージの削除予定日時を、古い順に最大 limit 件計算し直す
+// 新しい保持期間を既に過ぎたメッセージは TTL インデックスで一度に削除されないよう削除予定日時を設定せず、
+// 定期削除で BatchSize 件ずつ削除する。残りが無くなったらルームの restamppending を取り除く
+func (m *MongoSvcStruct) RestampChatMessageExpiry(roomID string, retentionDays int, now time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	db := mongo.MongoPkgStruct.Db
+	collection := db.Collection(model.ChatMessageCollectionName)
+
+	if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageRoomCreatedAtIndex); err != nil {
+		return 0, err
+	}
+
+	filter := staleExpiryFilter(roomID, retentionDays)
+	cursor, err := collection.FindWithOptions(
+		mongo.MongoPkgStruct.Ctx,
+		filter,
+		options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.D{{Key: "createdat", Value: 1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return 0, err
+		}
+		ids = append(ids, message.ID)
+	}
+
+	if len(ids) > 0 {
+		var update interface{}
+		if retentionDays <= 0 {
+			update = bson.M{"$unset": bson.M{"retentiondays": "", "expiresat": ""}}
+		} else {
+			if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageExpiryIndex); err != nil {
+				return 0, err
+			}
+			expiresAt := bson.M{"$add": bson.A{"$createdat", (time.Duration(retentionDays) * 24 * time.Hour).Milliseconds()}}
+			update = bson.A{bson.M{"$set": bson.M{
+				"retentiondays": retentionDays,
+				// 添付ファイル付きのメッセージはファイルも削除できるよう TTL インデックスの対象にしない
+				"expiresat": bson.M{"$cond": bson.A{
+					bson.M{"$and": bson.A{
+						bson.M{"$gt": bson.A{expiresAt, now}},
+						bson.M{"$eq": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$attachments", bson.A{}}}}, 0}},
+					}},
+					expiresAt,
+					"$$REMOVE",
+				}},
+			}}}
+		}
+		// 途中で保持日数が変更された場合は次の実行で計算し直す
+		filter["_id"] = bson.M{"$in": ids}
+		if _, err := collection.UpdateMany(mongo.MongoPkgStruct.Ctx, filter, update); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(ids) < limit {
+		id, err := primitive.ObjectIDFromHex(roomID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = db.Collection(model.RoomCollectionName).UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": id, "retentiondays": retentionDays, "restamppending": true},
+			bson.M{"$unset": bson.M{"restamppending": ""}},
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(ids)), nil
+}
+
+// 保持日数の変更を既存のメッセージに反映している途中のルームの ID と保持日数だけを返す
+func (m *MongoSvcStruct) GetRestampPendingRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	cursor, err := collection.FindWithOptions(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"restamppending": true},
+		options.Find().SetProjection(bson.M{"_id": 1, "retentiondays": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var rooms []model.Room
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var room model.Room
+		if err := cursor.Decode(&room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+
+	return rooms, nil
+}
+
+// 保持期間が設定されたルームの ID と保持日数だけを返す
+func (m *MongoSvcStruct) GetRetentionRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	cursor, err := collection.FindWithOptions(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"retentiondays": bson.M{"$gt": 0}},
+		options.Find().SetProjection(bson.M{"_id": 1, "retentiondays": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var rooms []model.Room
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var room model.Room
+		if err := cursor.Decode(&room); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, room)
+	}
+
+	return rooms, nil
+}
+
+// 指定日時より前に投稿されたメッセージの件数（論理削除済みも含む）
+func (m *MongoSvcStruct) CountExpiredChatMessages(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageRoomCreatedAtIndex); err != nil {
+		return 0, err
+	}
+
+	return collection.CountDocuments(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID, "createdat": bson.M{"$lt": before}})
+}
+
+// 指定日時より前に投稿されたメッセージを古い順に最大 limit 件削除し、添付ファイルを削除待ちにする
+// 削除したメッセージへの参照は PurgeExpiredChatMessageReferences で取り除く
+func (m *MongoSvcStruct) PurgeExpiredChatMessages(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+
+	if err := m.createIndexOnce(mongo, model.ChatMessageCollectionName, chatMessageRoomCreatedAtIndex); err != nil {
+		return 0, err
+	}
+
+	return purgeChatMessageBatch(
+		mongo.MongoPkgStruct.Ctx,
+		mongo.MongoPkgStruct.Db,
+		bson.M{"roomid": roomID, "createdat": bson.M{"$lt": before}},
+		bson.D{{Key: "createdat", Value: 1}},
+		limit,
+	)
+}
+
+// ルームごとに保存済みメッセージ・通知を探すためのインデックス
+var savedMessageRoomIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}},
+	Options: options.Index().SetName("roomid"),
+}
+
+var notificationRoomIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}},
+	Options: options.Index().SetName("roomid"),
+}
+
+// 保持期間を過ぎたメッセージへの参照を取り除く
+// TTL インデックスで削除されたメッセージは定期削除で見つからないため、削除したメッセージではなく
+// 指定日時とメッセージが残っているかどうかで判断する。本文を含む通知・Webhook の配信も削除する
+// 保存済みメッセージ・通知・Webhook の配信はそれぞれ最大 limit 件ずつ削除し、削除した件数の合計を返す
+func (m *MongoSvcStruct) PurgeExpiredChatMessageReferences(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	ctx := mongo.MongoPkgStruct.Ctx
+	db := mongo.MongoPkgStruct.Db
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return 0, err
+	}
+	rooms := db.Collection(model.RoomCollectionName)
+	_, err = rooms.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "lastmessage.createdat": bson.M{"$lt": before}},
+		bson.M{"$unset": bson.M{"lastmessage": ""}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var room model.Room
+	if err := rooms.FindOne(ctx, bson.M{"_id": id}, &room); err != nil {
+		return 0, err
+	}
+	var pinned []string
+	for _, pin := range room.Pins {
+		pinned = append(pinned, pin.MessageID)
+	}
+	missing, err := missingChatMessageIDs(ctx, db, pinned)
+	if err != nil {
+		return 0, err
+	}
+	if len(missing) > 0 {
+		_, err = rooms.UpdateOne(
+			ctx,
+			bson.M{"_id": id},
+			bson.M{"$pull": bson.M{"pins": bson.M{"messageid": bson.M{"$in": missing}}}},
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := m.createIndexOnce(mongo, model.SavedMessageCollectionName, savedMessageRoomIndex); err != nil {
+		return 0, err
+	}
+	if err := m.createIndexOnce(mongo, model.NotificationCollectionName, notificationRoomIndex); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, name := range []string{model.SavedMessageCollectionName, model.NotificationCollectionName} {
+		collection := db.Collection(name)
+		ids, err := findOrphanedReferences(ctx, collection, roomID, limit)
+		if err != nil {
+			return total, err
+		}
+		count, err := deleteByIDs(ctx, collection, ids)
+		total += count
+		if err != nil {
+			return total, err
+		}
+	}
+
+	// 配信の本文にはメッセージがそのまま含まれる
+	deliveries := db.Collection(model.WebhookDeliveryCollectionName)
+	cursor, err := deliveries.FindWithOptions(
+		ctx,
+		bson.M{"roomid": roomID, "event": model.WebhookMessageCreated, "createdat": bson.M{"$lt": before}},
+		options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return total, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var delivery model.WebhookDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return total, err
+		}
+		ids = append(ids, delivery.ID)
+	}
+	count, err := deleteByIDs(ctx, deliveries, ids)
+	total += count
+	if err != nil {
+		return total, err
+	}
+
+	return total, nil
+}
+
+// messageIDs のうちメッセージが存在しないもの
+func missingChatMessageIDs(ctx context.Context, db mongo_pkg.MongoDatabaseInterface, messageIDs []string) ([]string, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+	var ids []primitive.ObjectID
+	for _, messageID := range messageIDs {
+		if id, err := primitive.ObjectIDFromHex(messageID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	cursor, err := db.Collection(model.ChatMessageCollectionName).FindWithOptions(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	existing := map[string]bool{}
+	for cursor.Next(ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		existing[message.ID.Hex()] = true
+	}
+
+	var missing []string
+	for _, messageID := range messageIDs {
+		if !existing[messageID] {
+			missing = append(missing, messageID)
+		}
+	}
+	return missing, nil
+}
+
+// ルームのドキュメントのうち、messageid のメッセージが存在しないものの ID を最大 limit 件返す
+func findOrphanedReferences(ctx context.Context, collection mongo_pkg.MongoCollectionInterface, roomID string, limit int) ([]primitive.ObjectID, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"roomid": roomID}},
+		bson.M{"$addFields": bson.M{"messageoid": bson.M{"$convert": bson.M{"input": "$messageid", "to": "objectId", "onError": nil}}}},
+		bson.M{"$lookup": bson.M{
+			"from":         model.ChatMessageCollectionName,
+			"localField":   "messageoid",
+			"foreignField": "_id",
+			"pipeline":     bson.A{bson.M{"$project": bson.M{"_id": 1}}},
+			"as":           "message",
+		}},
+		bson.M{"$match": bson.M{"message": bson.M{"$size": 0}}},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{"_id": 1}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var reference struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&reference); err != nil {
+			return nil, err
+		}
+		ids = append(ids, reference.ID)
+	}
+	return ids, nil
+}
+
+func deleteByIDs(ctx context.Context, collection mongo_pkg.MongoCollectionInterface, ids []primitive.ObjectID) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,378 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// コレクション名ごとにモックを返すテスト用のサービス
+func newRetentionTestSvc(collections map[string]*mock_mongo_pkg.MongoCollectionMock) (*MongoSvcStruct, mongo_pkg.MongoPkgInterface) {
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	for name, collection := range collections {
+		mongoDatabaseMock.On("Collection", name).Return(collection)
+	}
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	return NewMongoSvc(mongoDatabaseMock), setupInitMock(false, "chatapp", mongoPkgStruct)
+}
+
+func TestUpdateRoomRetention(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	retentionDays := 30
+
+	roomCollection := new(mock_mongo_pkg.MongoCollectionMock)
+	roomCollection.On("UpdateOne", mock.Anything,
+		bson.M{"_id": id, "archivedat": nil},
+		bson.M{"$set": bson.M{"retentiondays": 30, "restamppending": true, "updatedat": now}},
+	).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	messageCollection := new(mock_mongo_pkg.MongoCollectionMock)
+	svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+		model.RoomCollectionName:        roomCollection,
+		model.ChatMessageCollectionName: messageCollection,
+	})
+
+	err := svc.UpdateRoom(membershipRoomID, UpdateRoomFields{RetentionDays: &retentionDays}, now, pkg)
+	assert.NoError(t, err)
+	// 既存のメッセージは定期削除で少しずつ計算し直す
+	messageCollection.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRestampChatMessageExpiry(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	messageIDs := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	expiresAt := bson.M{"$add": bson.A{"$createdat", int64(30 * 24 * 60 * 60 * 1000)}}
+
+	tests := []struct {
+		name          string
+		retentionDays int
+		found         []primitive.ObjectID
+		filter        bson.M
+		update        interface{}
+		expectDone    bool
+	}{
+		{
+			"set", 30, messageIDs[:1],
+			bson.M{"roomid": membershipRoomID, "retentiondays": bson.M{"$ne": 30}},
+			bson.A{bson.M{"$set": bson.M{
+				"retentiondays": 30,
+				"expiresat": bson.M{"$cond": bson.A{
+					bson.M{"$and": bson.A{
+						bson.M{"$gt": bson.A{expiresAt, now}},
+						bson.M{"$eq": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$attachments", bson.A{}}}}, 0}},
+					}},
+					expiresAt,
+					"$$REMOVE",
+				}},
+			}}},
+			true,
+		},
+		{
+			"unset", 0, messageIDs[:1],
+			bson.M{"roomid": membershipRoomID, "$or": bson.A{
+				bson.M{"retentiondays": bson.M{"$gt": 0}},
+				bson.M{"expiresat": bson.M{"$exists": true}},
+			}},
+			bson.M{"$unset": bson.M{"retentiondays": "", "expiresat": ""}},
+			true,
+		},
+		// limit 件見つかった場合は残りがあるかもしれないので反映中のままにする
+		{
+			"full_batch", 30, messageIDs,
+			bson.M{"roomid": membershipRoomID, "retentiondays": bson.M{"$ne": 30}},
+			mock.Anything,
+			false,
+		},
+		{
+			"nothing_to_restamp", 30, nil,
+			bson.M{"roomid": membershipRoomID, "retentiondays": bson.M{"$ne": 30}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			for _, messageID := range tt.found {
+				message := model.ChatMessage{ID: messageID}
+				mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+				mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*model.ChatMessage) = message
+				}).Return(nil).Once()
+			}
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			messageCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			messageCollection.On("CreateIndex", mock.Anything, mock.Anything).Return("", nil)
+			messageCollection.On("FindWithOptions", mock.Anything, tt.filter, mock.Anything).Return(mongoCursorMock, nil)
+			messageCollection.On("UpdateMany", mock.Anything, mock.Anything, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			roomCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			roomCollection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": id, "retentiondays": tt.retentionDays, "restamppending": true},
+				bson.M{"$unset": bson.M{"restamppending": ""}},
+			).Return(&mongo.UpdateResult{}, nil)
+			svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+				model.RoomCollectionName:        roomCollection,
+				model.ChatMessageCollectionName: messageCollection,
+			})
+
+			count, err := svc.RestampChatMessageExpiry(membershipRoomID, tt.retentionDays, now, 2, pkg)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(tt.found)), count)
+
+			if tt.update == nil {
+				messageCollection.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				filter := bson.M{"_id": bson.M{"$in": tt.found}}
+				for key, value := range tt.filter {
+					filter[key] = value
+				}
+				messageCollection.AssertCalled(t, "UpdateMany", mock.Anything, filter, tt.update)
+			}
+			if tt.expectDone {
+				roomCollection.AssertNumberOfCalls(t, "UpdateOne", 1)
+			} else {
+				roomCollection.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetRestampPendingRooms(t *testing.T) {
+	room := model.Room{ID: primitive.NewObjectID(), RetentionDays: 30}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.Room) = room
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"restamppending": true}, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.RoomCollectionName, mongoCollectionMock)
+
+	rooms, err := svc.GetRestampPendingRooms(pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Room{room}, rooms)
+}
+
+func TestGetRetentionRooms(t *testing.T) {
+	room := model.Room{ID: primitive.NewObjectID(), RetentionDays: 30}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.Room) = room
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"retentiondays": bson.M{"$gt": 0}}, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.RoomCollectionName, mongoCollectionMock)
+
+	rooms, err := svc.GetRetentionRooms(pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Room{room}, rooms)
+}
+
+func TestCountExpiredChatMessages(t *testing.T) {
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, chatMessageRoomCreatedAtIndex).Return("", nil).Once()
+	mongoCollectionMock.On("CountDocuments", mock.Anything, bson.M{"roomid": "room1", "createdat": bson.M{"$lt": before}}).Return(int64(12), nil)
+	svc, pkg := newWebhookTestSvc(model.ChatMessageCollectionName, mongoCollectionMock)
+
+	// インデックスの作成は最初の呼び出しでだけ行う
+	for i := 0; i < 2; i++ {
+		count, err := svc.CountExpiredChatMessages("room1", before, pkg)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(12), count)
+	}
+	mongoCollectionMock.AssertNumberOfCalls(t, "CreateIndex", 1)
+}
+
+func TestPurgeExpiredChatMessages(t *testing.T) {
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	messageIDs := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	hexIDs := []string{messageIDs[0].Hex(), messageIDs[1].Hex()}
+
+	tests := []struct {
+		name        string
+		found       []primitive.ObjectID
+		deleteErr   error
+		expectCount int64
+		returnErr   bool
+	}{
+		{"success", messageIDs, nil, 2, false},
+		{"nothing_to_purge", nil, nil, 0, false},
+		{"delete_error", messageIDs, assert.AnError, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			for _, id := range tt.found {
+				messageID := id
+				mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+				mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*model.ChatMessage) = model.ChatMessage{ID: messageID, Attachments: []model.Attachment{{StorageKey: "key/" + messageID.Hex()}}}
+				}).Return(nil).Once()
+			}
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			messageCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			messageCollection.On("CreateIndex", mock.Anything, chatMessageRoomCreatedAtIndex).Return("", nil)
+			messageCollection.On("FindWithOptions", mock.Anything,
+				bson.M{"roomid": membershipRoomID, "createdat": bson.M{"$lt": before}}, mock.Anything,
+			).Return(mongoCursorMock, nil)
+			messageCollection.On("DeleteMany", mock.Anything, bson.M{"_id": bson.M{"$in": messageIDs}}).
+				Return(&mongo.DeleteResult{DeletedCount: int64(len(tt.found))}, tt.deleteErr)
+
+			blobCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			blobCollection.On("InsertOne", mock.Anything, mock.MatchedBy(func(deletion model.BlobDeletion) bool {
+				return assert.ObjectsAreEqual([]string{"key/" + hexIDs[0], "key/" + hexIDs[1]}, deletion.StorageKeys)
+			})).Return("", nil)
+
+			svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+				model.ChatMessageCollectionName:  messageCollection,
+				model.BlobDeletionCollectionName: blobCollection,
+			})
+
+			count, err := svc.PurgeExpiredChatMessages(membershipRoomID, before, 100, pkg)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("PurgeExpiredChatMessages() [%s] error = %v", tt.name, err)
+			}
+			assert.Equal(t, tt.expectCount, count)
+
+			switch {
+			case tt.found == nil:
+				messageCollection.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+			case tt.returnErr:
+				blobCollection.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+			default:
+				blobCollection.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func TestPurgeExpiredChatMessageReferences(t *testing.T) {
+	roomID, _ := primitive.ObjectIDFromHex(membershipRoomID)
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	remaining := primitive.NewObjectID()
+	expired := primitive.NewObjectID()
+	savedIDs := []primitive.ObjectID{primitive.NewObjectID()}
+	notificationIDs := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}
+	deliveryIDs := []primitive.ObjectID{primitive.NewObjectID()}
+
+	// _id だけを返すカーソル
+	idCursor := func(ids []primitive.ObjectID) *mock_mongo_pkg.MongoCursorMock {
+		cursor := new(mock_mongo_pkg.MongoCursorMock)
+		for _, id := range ids {
+			id := id
+			cursor.On("Next", mock.Anything).Return(true).Once()
+			cursor.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				data, _ := bson.Marshal(bson.M{"_id": id})
+				_ = bson.Unmarshal(data, args.Get(0))
+			}).Return(nil).Once()
+		}
+		cursor.On("Next", mock.Anything).Return(false).Once()
+		cursor.On("Close", mock.Anything).Return(nil)
+		return cursor
+	}
+
+	tests := []struct {
+		name         string
+		aggregateErr error
+		expectCount  int64
+		returnErr    bool
+	}{
+		{"success", nil, 4, false},
+		{"aggregate_error", assert.AnError, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roomCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			// TTL インデックスで削除されたメッセージもプレビューに残さない
+			roomCollection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": roomID, "lastmessage.createdat": bson.M{"$lt": before}},
+				bson.M{"$unset": bson.M{"lastmessage": ""}},
+			).Return(&mongo.UpdateResult{}, nil)
+			roomCollection.On("FindOne", mock.Anything, bson.M{"_id": roomID}, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(2).(*model.Room) = model.Room{ID: roomID, Pins: []model.RoomPin{{MessageID: remaining.Hex()}, {MessageID: expired.Hex()}}}
+			}).Return(nil)
+			roomCollection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": roomID},
+				bson.M{"$pull": bson.M{"pins": bson.M{"messageid": bson.M{"$in": []string{expired.Hex()}}}}},
+			).Return(&mongo.UpdateResult{}, nil)
+
+			messageCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			messageCollection.On("FindWithOptions", mock.Anything,
+				bson.M{"_id": bson.M{"$in": []primitive.ObjectID{remaining, expired}}}, mock.Anything,
+			).Return(idCursor([]primitive.ObjectID{remaining}), nil)
+
+			savedCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			savedCollection.On("CreateIndex", mock.Anything, savedMessageRoomIndex).Return("", nil)
+			savedCollection.On("Aggregate", mock.Anything, mock.Anything).Return(idCursor(savedIDs), tt.aggregateErr)
+			savedCollection.On("DeleteMany", mock.Anything, bson.M{"_id": bson.M{"$in": savedIDs}}).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+
+			notificationCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			notificationCollection.On("CreateIndex", mock.Anything, notificationRoomIndex).Return("", nil)
+			notificationCollection.On("Aggregate", mock.Anything, mock.Anything).Return(idCursor(notificationIDs), nil)
+			notificationCollection.On("DeleteMany", mock.Anything, bson.M{"_id": bson.M{"$in": notificationIDs}}).Return(&mongo.DeleteResult{DeletedCount: 2}, nil)
+
+			deliveryCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			deliveryCollection.On("FindWithOptions", mock.Anything,
+				bson.M{"roomid": membershipRoomID, "event": model.WebhookMessageCreated, "createdat": bson.M{"$lt": before}}, mock.Anything,
+			).Return(idCursor(deliveryIDs), nil)
+			deliveryCollection.On("DeleteMany", mock.Anything, bson.M{"_id": bson.M{"$in": deliveryIDs}}).Return(&mongo.DeleteResult{DeletedCount: 1}, nil)
+
+			svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+				model.RoomCollectionName:            roomCollection,
+				model.ChatMessageCollectionName:     messageCollection,
+				model.SavedMessageCollectionName:    savedCollection,
+				model.NotificationCollectionName:    notificationCollection,
+				model.WebhookDeliveryCollectionName: deliveryCollection,
+			})
+
+			count, err := svc.PurgeExpiredChatMessageReferences(membershipRoomID, before, 100, pkg)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("PurgeExpiredChatMessageReferences() [%s] error = %v", tt.name, err)
+			}
+			assert.Equal(t, tt.expectCount, count)
+
+			roomCollection.AssertExpectations(t)
+			if tt.returnErr {
+				savedCollection.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+				deliveryCollection.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+				return
+			}
+			savedCollection.AssertExpectations(t)
+			notificationCollection.AssertExpectations(t)
+			deliveryCollection.AssertExpectations(t)
+		})
+	}
+}
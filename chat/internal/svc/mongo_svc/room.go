@@ -0,0 +1,207 @@
+package mongo_svc
+
+import (
+	"context"
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrRoomNotFound    = errors.New("room not found")
+	ErrRoomArchived    = errors.New("room is archived")
+	ErrRoomNotArchived = errors.New("room is not archived")
+)
+
+// ルーム削除時にあわせて削除する、ルームに紐づくコレクション
+var roomScopedCollectionNames = []string{
+	model.ChatMessageCollectionName,
+	model.ReadCursorCollectionName,
+	model.RoomInviteCollectionName,
+	model.JoinRequestCollectionName,
+	model.RoomWebhookCollectionName,
+	model.WebhookDeliveryCollectionName,
+	model.IncomingWebhookCollectionName,
+	model.SlashCommandCollectionName,
+	model.SavedMessageCollectionName,
+	model.ScheduledMessageCollectionName,
+	model.RoomExportCollectionName,
+	// 配信はユーザーごとに複数のルームの通知をまとめるので、通知だけを削除する。
+	// 通知が残っていない配信は送らずに配信済みになる
+	model.NotificationCollectionName,
+}
+
+// 更新するフィールドだけを指定する
+type UpdateRoomFields struct {
+	Name          *string
+	Description   *string
+	Topic         *string
+	AvatarURL     *string
+	IsPrivate     *bool
+	RetentionDays *int // 変更すると既存のメッセージの削除予定日時を定期削除で計算し直す
+}
+
+func (f UpdateRoomFields) Empty() bool {
+	return f.Name == nil && f.Description == nil && f.Topic == nil && f.AvatarURL == nil && f.IsPrivate == nil && f.RetentionDays == nil
+}
+
+func (f UpdateRoomFields) set() bson.M {
+	set := bson.M{}
+	if f.Name != nil {
+		set["name"] = *f.Name
+	}
+	if f.Description != nil {
+		set["description"] = *f.Description
+	}
+	if f.Topic != nil {
+		set["topic"] = *f.Topic
+	}
+	if f.AvatarURL != nil {
+		set["avatarurl"] = *f.AvatarURL
+	}
+	if f.IsPrivate != nil {
+		set["isprivate"] = *f.IsPrivate
+	}
+	if f.RetentionDays != nil {
+		set["retentiondays"] = *f.RetentionDays
+		set["restamppending"] = true
+	}
+	return set
+}
+
+// アーカイブ済みのルームは更新しない
+func (m *MongoSvcStruct) UpdateRoom(roomID string, fields UpdateRoomFields, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	set := fields.set()
+	set["updatedat"] = now
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "archivedat": nil},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrRoomArchived
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) ArchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "archivedat": nil},
+		bson.M{"$set": bson.M{"archivedat": now, "updatedat": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrRoomArchived
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) UnarchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "archivedat": bson.M{"$ne": nil}},
+		bson.M{"$set": bson.M{"updatedat": now}, "$unset": bson.M{"archivedat": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrRoomNotArchived
+	}
+
+	return nil
+}
+
+// ルームとルームに紐づくメッセージ・既読位置・招待・参加申請・Webhook・コマンドを1つのトランザクションで削除する
+// メッセージの添付ファイルとエクスポートしたファイルは削除待ちにする
+func (m *MongoSvcStruct) DeleteRoom(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	db := mongo.MongoPkgStruct.Db
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	return db.WithTransaction(mongo.MongoPkgStruct.Ctx, func(ctx context.Context) error {
+		result, err := db.Collection(model.RoomCollectionName).DeleteOne(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return ErrRoomNotFound
+		}
+
+		keys, err := findStorageKeys(ctx, db.Collection(model.ChatMessageCollectionName), bson.M{"roomid": roomID})
+		if err != nil {
+			return err
+		}
+		_, exportKeys, err := findRoomExports(ctx, db.Collection(model.RoomExportCollectionName), bson.M{"roomid": roomID})
+		if err != nil {
+			return err
+		}
+		keys = append(keys, exportKeys...)
+		for _, name := range roomScopedCollectionNames {
+			if _, err := db.Collection(name).DeleteMany(ctx, bson.M{"roomid": roomID}); err != nil {
+				return err
+			}
+		}
+		return queueBlobDeletions(ctx, db, keys)
+	})
+}
@@ -0,0 +1,353 @@
+package mongo_svc
+
+import (
+	"context"
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrRoomExportNotFound = errors.New("room export not found")
+	ErrExportLeaseLost    = errors.New("room export is no longer processing")
+)
+
+var roomExportIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}, {Key: "createdat", Value: -1}},
+	Options: options.Index().SetName("roomid_createdat"),
+}
+
+var pendingRoomExportIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "status", Value: 1}, {Key: "createdat", Value: 1}},
+	Options: options.Index().SetName("status_createdat"),
+}
+
+func (m *MongoSvcStruct) CreateRoomExport(export model.RoomExport, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, roomExportIndex)
+	if err != nil {
+		return "", err
+	}
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, pendingRoomExportIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return collection.InsertOne(mongo.MongoPkgStruct.Ctx, export)
+}
+
+// ルームのエクスポートを新しい順に最大 limit 件取得する
+func (m *MongoSvcStruct) GetRoomExports(roomID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	exports := []model.RoomExport{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var export model.RoomExport
+		if err := cursor.Decode(&export); err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, nil
+}
+
+func (m *MongoSvcStruct) GetRoomExport(roomID string, exportID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomExport, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.RoomExport{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return model.RoomExport{}, ErrRoomExportNotFound
+	}
+
+	var export model.RoomExport
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID}, &export)
+	if errors.Is(err, errNoDocuments) {
+		return model.RoomExport{}, ErrRoomExportNotFound
+	}
+	if err != nil {
+		return model.RoomExport{}, err
+	}
+
+	return export, nil
+}
+
+// 待機中のエクスポートを古い順に処理中にして取得する。待機中から処理中への変更は1つのワーカーしか成功しない
+func (m *MongoSvcStruct) ClaimPendingRoomExports(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, pendingRoomExportIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"status": model.ExportPending}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var pending []model.RoomExport
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var export model.RoomExport
+		if err := cursor.Decode(&export); err != nil {
+			return nil, err
+		}
+		pending = append(pending, export)
+	}
+
+	leaseUntil := now.Add(lease)
+	claimed := []model.RoomExport{}
+	for _, export := range pending {
+		result, err := collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": export.ID, "status": model.ExportPending},
+			bson.M{"$set": bson.M{"status": model.ExportProcessing, "leaseuntil": leaseUntil}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+		export.Status = model.ExportProcessing
+		export.LeaseUntil = &leaseUntil
+		claimed = append(claimed, export)
+	}
+
+	return claimed, nil
+}
+
+// 書き出し中のエクスポートの lease を延長する。既に失敗にされていた場合は ErrExportLeaseLost
+func (m *MongoSvcStruct) ExtendRoomExportLease(exportID string, leaseUntil time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(exportID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "status": model.ExportProcessing},
+		bson.M{"$set": bson.M{"leaseuntil": leaseUntil}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrExportLeaseLost
+	}
+
+	return nil
+}
+
+// 処理中のエクスポートを完了か失敗にする。lease が切れて失敗にされたエクスポートは変更しない
+func (m *MongoSvcStruct) CompleteRoomExport(export model.RoomExport, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	set := bson.M{"status": export.Status, "completedat": now}
+	if export.StorageKey != "" {
+		set["storagekey"] = export.StorageKey
+		set["messagecount"] = export.MessageCount
+		set["size"] = export.Size
+	}
+	if export.Error != "" {
+		set["error"] = export.Error
+	}
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": export.ID, "status": model.ExportProcessing},
+		bson.M{"$set": set, "$unset": bson.M{"leaseuntil": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrExportLeaseLost
+	}
+
+	return nil
+}
+
+// filter に一致するエクスポートの ID と保存先のキーを返す
+func findRoomExports(ctx context.Context, collection mongo_pkg.MongoCollectionInterface, filter bson.M) ([]primitive.ObjectID, []string, error) {
+	cursor, err := collection.FindWithOptions(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "storagekey": 1}))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	var keys []string
+	for cursor.Next(ctx) {
+		var export model.RoomExport
+		if err := cursor.Decode(&export); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, export.ID)
+		if export.StorageKey != "" {
+			keys = append(keys, export.StorageKey)
+		}
+	}
+	return ids, keys, nil
+}
+
+// 保持期間が設定されたルームで、指定日時より前に作成されたエクスポートを削除し、ファイルを削除待ちにする
+// エクスポートには作成時点のメッセージが含まれるため、作成時に投稿されたメッセージと同じ期間だけ残す
+func (m *MongoSvcStruct) PurgeExpiredRoomExports(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	db := mongo.MongoPkgStruct.Db
+	collection := db.Collection(model.RoomExportCollectionName)
+
+	// 処理中のエクスポートは完了か失敗になった後に削除する
+	ids, keys, err := findRoomExports(mongo.MongoPkgStruct.Ctx, collection, bson.M{
+		"roomid":    roomID,
+		"status":    bson.M{"$in": bson.A{model.ExportCompleted, model.ExportFailed}},
+		"createdat": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result, err := collection.DeleteMany(mongo.MongoPkgStruct.Ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := queueBlobDeletions(mongo.MongoPkgStruct.Ctx, db, keys); err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// lease が切れた処理中のエクスポートを失敗にする（ワーカーが停止した場合など）
+func (m *MongoSvcStruct) ExpireRoomExportLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomExportCollectionName)
+
+	result, err := collection.UpdateMany(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"status": model.ExportProcessing, "leaseuntil": bson.M{"$lt": now}},
+		bson.M{
+			"$set":   bson.M{"status": model.ExportFailed, "error": "lease expired before the export completed", "completedat": now},
+			"$unset": bson.M{"leaseuntil": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// afterID より後のメッセージを _id の順に最大 limit 件取得する（afterID が空の場合は先頭から）
+// ルーム全体を一度に読み込まずに、1ページずつ接続し直して読み進めるために使う
+func (m *MongoSvcStruct) GetChatMessagesAfter(roomID string, afterID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	filter := bson.M{"roomid": roomID, "deletedat": nil}
+	if afterID != "" {
+		id, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, err
+		}
+		filter["_id"] = bson.M{"$gt": id}
+	}
+
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"revisions": 0, "isreaduserids": 0})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	messages := []model.ChatMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
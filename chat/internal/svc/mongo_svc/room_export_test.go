@@ -0,0 +1,326 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateRoomExport(t *testing.T) {
+	export := model.RoomExport{RoomID: "room1", RequestedBy: 1, Format: model.ExportFormatCSV, Status: model.ExportPending}
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, roomExportIndex).Return("", nil)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, pendingRoomExportIndex).Return("", nil)
+	mongoCollectionMock.On("InsertOne", mock.Anything, export).Return("export1", nil)
+	svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+	id, err := svc.CreateRoomExport(export, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, "export1", id)
+}
+
+func TestGetRoomExports(t *testing.T) {
+	export := model.RoomExport{ID: primitive.NewObjectID(), RoomID: "room1", Status: model.ExportCompleted}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.RoomExport) = export
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"roomid": "room1"}, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+	exports, err := svc.GetRoomExports("room1", 20, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.RoomExport{export}, exports)
+}
+
+func TestGetRoomExport(t *testing.T) {
+	exportID := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		exportID  string
+		findErr   error
+		expectErr error
+	}{
+		{"success", exportID.Hex(), nil, nil},
+		// 他のルームのエクスポートは見つからない扱いにする
+		{"not_found", exportID.Hex(), mongo.ErrNoDocuments, ErrRoomExportNotFound},
+		{"invalid_id", "invalid", nil, ErrRoomExportNotFound},
+		{"find_error", exportID.Hex(), assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("FindOne", mock.Anything, bson.M{"_id": exportID, "roomid": "room1"}, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*args.Get(2).(*model.RoomExport) = model.RoomExport{ID: exportID, RoomID: "room1"}
+				}).Return(tt.findErr)
+			svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+			export, err := svc.GetRoomExport("room1", tt.exportID, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, exportID, export.ID)
+			}
+		})
+	}
+}
+
+func TestClaimPendingRoomExports(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	first := model.RoomExport{ID: primitive.NewObjectID(), Status: model.ExportPending}
+	second := model.RoomExport{ID: primitive.NewObjectID(), Status: model.ExportPending}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Twice()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.RoomExport) = first
+	}).Return(nil).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.RoomExport) = second
+	}).Return(nil).Once()
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	leaseUntil := now.Add(5 * time.Minute)
+	claim := bson.M{"$set": bson.M{"status": model.ExportProcessing, "leaseuntil": leaseUntil}}
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, pendingRoomExportIndex).Return("", nil)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"status": model.ExportPending}, mock.Anything).Return(mongoCursorMock, nil)
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": first.ID, "status": model.ExportPending}, claim).
+		Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	// 他のワーカーが先に取得したエクスポートは返さない
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": second.ID, "status": model.ExportPending}, claim).
+		Return(&mongo.UpdateResult{MatchedCount: 0}, nil)
+	svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+	claimed, err := svc.ClaimPendingRoomExports(now, 5*time.Minute, 10, pkg)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, first.ID, claimed[0].ID)
+		assert.Equal(t, model.ExportProcessing, claimed[0].Status)
+		assert.Equal(t, leaseUntil, *claimed[0].LeaseUntil)
+	}
+}
+
+func TestExtendRoomExportLease(t *testing.T) {
+	exportID := primitive.NewObjectID()
+	leaseUntil := time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		matched   int64
+		expectErr error
+	}{
+		{"success", 1, nil},
+		// lease が切れて失敗にされたエクスポートは延長しない
+		{"lease_lost", 0, ErrExportLeaseLost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": exportID, "status": model.ExportProcessing},
+				bson.M{"$set": bson.M{"leaseuntil": leaseUntil}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+			err := svc.ExtendRoomExportLease(exportID.Hex(), leaseUntil, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCompleteRoomExport(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	exportID := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		export    model.RoomExport
+		matched   int64
+		expectSet bson.M
+		expectErr error
+	}{
+		{
+			"completed",
+			model.RoomExport{ID: exportID, Status: model.ExportCompleted, StorageKey: "rooms/room1/exports/e.csv", MessageCount: 3, Size: 120},
+			1,
+			bson.M{"status": model.ExportCompleted, "completedat": now, "storagekey": "rooms/room1/exports/e.csv", "messagecount": int64(3), "size": int64(120)},
+			nil,
+		},
+		{
+			"failed",
+			model.RoomExport{ID: exportID, Status: model.ExportFailed, Error: "boom"},
+			1,
+			bson.M{"status": model.ExportFailed, "completedat": now, "error": "boom"},
+			nil,
+		},
+		{
+			"lease_lost",
+			model.RoomExport{ID: exportID, Status: model.ExportFailed, Error: "boom"},
+			0,
+			bson.M{"status": model.ExportFailed, "completedat": now, "error": "boom"},
+			ErrExportLeaseLost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": exportID, "status": model.ExportProcessing},
+				bson.M{"$set": tt.expectSet, "$unset": bson.M{"leaseuntil": ""}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+			err := svc.CompleteRoomExport(tt.export, now, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExpireRoomExportLeases(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("UpdateMany", mock.Anything,
+		bson.M{"status": model.ExportProcessing, "leaseuntil": bson.M{"$lt": now}},
+		mock.MatchedBy(func(update bson.M) bool {
+			return update["$set"].(bson.M)["status"] == model.ExportFailed
+		}),
+	).Return(&mongo.UpdateResult{ModifiedCount: 1}, nil)
+	svc, pkg := newWebhookTestSvc(model.RoomExportCollectionName, mongoCollectionMock)
+
+	expired, err := svc.ExpireRoomExportLeases(now, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+}
+
+func TestPurgeExpiredRoomExports(t *testing.T) {
+	before := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	completed := model.RoomExport{ID: primitive.NewObjectID(), Status: model.ExportCompleted, StorageKey: "rooms/room1/exports/e1.csv"}
+	failed := model.RoomExport{ID: primitive.NewObjectID(), Status: model.ExportFailed}
+
+	tests := []struct {
+		name        string
+		found       []model.RoomExport
+		expectCount int64
+	}{
+		// 失敗したエクスポートはファイルが無いので削除待ちにしない
+		{"success", []model.RoomExport{completed, failed}, 2},
+		{"nothing_to_purge", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			for _, export := range tt.found {
+				export := export
+				mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+				mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*model.RoomExport) = export
+				}).Return(nil).Once()
+			}
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			exportCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			// 処理中のエクスポートは削除しない
+			exportCollection.On("FindWithOptions", mock.Anything, bson.M{
+				"roomid":    "room1",
+				"status":    bson.M{"$in": bson.A{model.ExportCompleted, model.ExportFailed}},
+				"createdat": bson.M{"$lt": before},
+			}, mock.Anything).Return(mongoCursorMock, nil)
+			exportCollection.On("DeleteMany", mock.Anything, bson.M{"_id": bson.M{"$in": []primitive.ObjectID{completed.ID, failed.ID}}}).
+				Return(&mongo.DeleteResult{DeletedCount: 2}, nil)
+			blobCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			blobCollection.On("InsertOne", mock.Anything, mock.MatchedBy(func(deletion model.BlobDeletion) bool {
+				return assert.ObjectsAreEqual([]string{completed.StorageKey}, deletion.StorageKeys)
+			})).Return("", nil)
+			svc, pkg := newRetentionTestSvc(map[string]*mock_mongo_pkg.MongoCollectionMock{
+				model.RoomExportCollectionName:   exportCollection,
+				model.BlobDeletionCollectionName: blobCollection,
+			})
+
+			count, err := svc.PurgeExpiredRoomExports("room1", before, pkg)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectCount, count)
+			if tt.found == nil {
+				exportCollection.AssertNotCalled(t, "DeleteMany", mock.Anything, mock.Anything)
+				blobCollection.AssertNotCalled(t, "InsertOne", mock.Anything, mock.Anything)
+				return
+			}
+			exportCollection.AssertExpectations(t)
+			blobCollection.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetChatMessagesAfter(t *testing.T) {
+	afterID := primitive.NewObjectID()
+	message := model.ChatMessage{ID: primitive.NewObjectID(), RoomID: "room1", Message: "hello"}
+
+	tests := []struct {
+		name      string
+		afterID   string
+		filter    bson.M
+		returnErr bool
+	}{
+		{"first_page", "", bson.M{"roomid": "room1", "deletedat": nil}, false},
+		{"next_page", afterID.Hex(), bson.M{"roomid": "room1", "deletedat": nil, "_id": bson.M{"$gt": afterID}}, false},
+		{"invalid_cursor", "invalid", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.ChatMessage) = message
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("FindWithOptions", mock.Anything, tt.filter, mock.Anything).Return(mongoCursorMock, nil)
+			svc, pkg := newWebhookTestSvc(model.ChatMessageCollectionName, mongoCollectionMock)
+
+			messages, err := svc.GetChatMessagesAfter("room1", tt.afterID, 100, pkg)
+			if tt.returnErr {
+				assert.Error(t, err)
+				mongoCollectionMock.AssertNotCalled(t, "FindWithOptions", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []model.ChatMessage{message}, messages)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInviteNotFound      = errors.New("invite not found")
+	ErrInviteExpired       = errors.New("invite has expired")
+	ErrInviteUsedUp        = errors.New("invite has already been used")
+	ErrJoinRequestExists   = errors.New("join request is already pending")
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestDecided  = errors.New("join request has already been decided")
+)
+
+var errNoDocuments = mongo.ErrNoDocuments
+
+var roomInviteCodeIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "code", Value: 1}},
+	Options: options.Index().SetName("code").SetUnique(true),
+}
+
+// 保留中の参加申請はルームとユーザーの組み合わせごとに1件まで
+var pendingJoinRequestIndex = mongo.IndexModel{
+	Keys: bson.D{{Key: "roomid", Value: 1}, {Key: "userid", Value: 1}},
+	Options: options.Index().
+		SetName("roomid_userid_pending").
+		SetUnique(true).
+		SetPartialFilterExpression(bson.M{"status": model.JoinRequestPending}),
+}
+
+func (m *MongoSvcStruct) CreateRoomInvite(invite model.RoomInvite, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomInviteCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, roomInviteCodeIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return collection.InsertOne(mongo.MongoPkgStruct.Ctx, invite)
+}
+
+// 招待コードを使ってルームに参加する。使用回数の消費は条件付き更新で行い、同時に使われても上限を超えない
+func (m *MongoSvcStruct) AcceptRoomInvite(code string, userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomInvite, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.RoomInvite{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomInviteCollectionName)
+
+	var invite model.RoomInvite
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"code": code}, &invite)
+	if errors.Is(err, errNoDocuments) {
+		return model.RoomInvite{}, ErrInviteNotFound
+	}
+	if err != nil {
+		return model.RoomInvite{}, err
+	}
+	// 他のユーザー宛ての招待は存在しないものとして扱う
+	if invite.InvitedUserID != 0 && invite.InvitedUserID != userID {
+		return model.RoomInvite{}, ErrInviteNotFound
+	}
+	if invite.Expired(now) {
+		return model.RoomInvite{}, ErrInviteExpired
+	}
+
+	// アーカイブされたルームの招待は使用回数を消費せずに失敗にする
+	roomID, err := primitive.ObjectIDFromHex(invite.RoomID)
+	if err != nil {
+		return model.RoomInvite{}, err
+	}
+	var room model.Room
+	err = mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName).FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": roomID}, &room)
+	if errors.Is(err, errNoDocuments) {
+		return model.RoomInvite{}, ErrInviteNotFound
+	}
+	if err != nil {
+		return model.RoomInvite{}, err
+	}
+	if room.Archived() {
+		return model.RoomInvite{}, ErrRoomArchived
+	}
+
+	// 同じユーザーが再度使った場合は回数を消費しない
+	if !containsInt(invite.UsedBy, userID) {
+		if invite.UsedUp() {
+			return model.RoomInvite{}, ErrInviteUsedUp
+		}
+
+		filter := bson.M{"_id": invite.ID, "expiresat": bson.M{"$gt": now}}
+		if invite.MaxUses > 0 {
+			filter["uses"] = bson.M{"$lt": invite.MaxUses}
+		}
+		result, err := collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, bson.M{
+			"$inc":      bson.M{"uses": 1},
+			"$addToSet": bson.M{"usedby": userID},
+		})
+		if err != nil {
+			return model.RoomInvite{}, err
+		}
+		if result.MatchedCount == 0 {
+			return model.RoomInvite{}, ErrInviteUsedUp
+		}
+	}
+
+	err = m.JoinRoom(invite.RoomID, userID, mongo_pkg)
+	if err != nil {
+		return model.RoomInvite{}, err
+	}
+
+	return invite, nil
+}
+
+func (m *MongoSvcStruct) CreateJoinRequest(request model.JoinRequest, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.JoinRequestCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, pendingJoinRequestIndex)
+	if err != nil {
+		return "", err
+	}
+
+	request.Status = model.JoinRequestPending
+	id, err := collection.InsertOne(mongo.MongoPkgStruct.Ctx, request)
+	if isDuplicateKeyError(err) {
+		return "", ErrJoinRequestExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (m *MongoSvcStruct) GetJoinRequests(roomID string, status string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.JoinRequest, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.JoinRequestCollectionName)
+
+	cursor, err := collection.FindWithOptions(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"roomid": roomID, "status": status},
+		options.Find().SetSort(bson.D{{Key: "createdat", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	requests := []model.JoinRequest{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var request model.JoinRequest
+		if err := cursor.Decode(&request); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// 保留中の参加申請を承認または却下する。承認した場合は申請者をメンバーに追加する
+func (m *MongoSvcStruct) DecideJoinRequest(roomID string, requestID string, status string, deciderID int, mongo_pkg mongo_pkg.MongoPkgInterface) (model.JoinRequest, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.JoinRequest{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.JoinRequestCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return model.JoinRequest{}, err
+	}
+
+	var request model.JoinRequest
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID}, &request)
+	if errors.Is(err, errNoDocuments) {
+		return model.JoinRequest{}, ErrJoinRequestNotFound
+	}
+	if err != nil {
+		return model.JoinRequest{}, err
+	}
+	if request.Status != model.JoinRequestPending {
+		return model.JoinRequest{}, ErrJoinRequestDecided
+	}
+
+	decidedAt := time.Now()
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "status": model.JoinRequestPending},
+		bson.M{"$set": bson.M{"status": status, "decidedat": decidedAt, "decidedby": deciderID}},
+	)
+	if err != nil {
+		return model.JoinRequest{}, err
+	}
+	if result.MatchedCount == 0 {
+		return model.JoinRequest{}, ErrJoinRequestDecided
+	}
+
+	if status == model.JoinRequestApproved {
+		err = m.JoinRoom(roomID, request.UserID, mongo_pkg)
+		if err != nil {
+			return model.JoinRequest{}, err
+		}
+	}
+
+	request.Status = status
+	request.DecidedAt = &decidedAt
+	request.DecidedBy = deciderID
+	return request, nil
+}
+
+func containsInt(values []int, target int) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
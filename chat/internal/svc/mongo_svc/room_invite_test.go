@@ -0,0 +1,291 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateRoomInvite(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		indexErr  error
+		insertErr error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, false},
+		{"error", true, nil, nil, true},
+		{"index_error", false, assert.AnError, nil, true},
+		{"insert_error", false, nil, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invite := model.RoomInvite{RoomID: "room1", Code: "code1", MaxUses: 1}
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, roomInviteCodeIndex).Return("code", tt.indexErr)
+			mongoCollectionMock.On("InsertOne", mock.Anything, invite).Return("invite_id", tt.insertErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.RoomInviteCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			_, err := mockSvcStruct.CreateRoomInvite(invite, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("CreateRoomInvite() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+		})
+	}
+}
+
+func TestAcceptRoomInvite(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	inviteID := primitive.NewObjectID()
+	roomID := "64a7b2f4e13e4c3f9c8b4567"
+
+	tests := []struct {
+		name         string
+		invite       model.RoomInvite
+		findErr      error
+		expectUpdate bool
+		matched      int64
+		expectErr    error
+		returnErr    bool
+		archived     bool
+	}{
+		{"success_single_use", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1}, nil, true, 1, nil, false, false},
+		{"success_unlimited", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), Uses: 5}, nil, true, 1, nil, false, false},
+		{"reuse_by_same_user", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1, Uses: 1, UsedBy: []int{1}}, nil, false, 0, nil, false, false},
+		{"not_found", model.RoomInvite{}, mongo.ErrNoDocuments, false, 0, ErrInviteNotFound, true, false},
+		{"find_error", model.RoomInvite{}, assert.AnError, false, 0, nil, true, false},
+		{"expired", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now}, nil, false, 0, ErrInviteExpired, true, false},
+		{"used_up", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1, Uses: 1, UsedBy: []int{2}}, nil, false, 0, ErrInviteUsedUp, true, false},
+		{"used_up_concurrently", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1}, nil, true, 0, ErrInviteUsedUp, true, false},
+		{"invited_user", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1, InvitedUserID: 1}, nil, true, 1, nil, false, false},
+		// 他のユーザー宛ての招待は使えない
+		{"other_user", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1, InvitedUserID: 2}, nil, false, 0, ErrInviteNotFound, true, false},
+		// アーカイブされたルームには参加できず、使用回数も消費しない
+		{"archived", model.RoomInvite{ID: inviteID, RoomID: roomID, ExpiresAt: now.Add(time.Hour), MaxUses: 1}, nil, false, 0, ErrRoomArchived, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inviteCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			inviteCollectionMock.On("FindOne", mock.Anything, bson.M{"code": "code1"}, mock.Anything).Run(func(args mock.Arguments) {
+				invite := args.Get(2).(*model.RoomInvite)
+				*invite = tt.invite
+			}).Return(tt.findErr)
+			if tt.expectUpdate {
+				filter := bson.M{"_id": inviteID, "expiresat": bson.M{"$gt": now}}
+				if tt.invite.MaxUses > 0 {
+					filter["uses"] = bson.M{"$lt": tt.invite.MaxUses}
+				}
+				inviteCollectionMock.On("UpdateOne", mock.Anything, filter, bson.M{
+					"$inc":      bson.M{"uses": 1},
+					"$addToSet": bson.M{"usedby": 1},
+				}).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			}
+
+			roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			roomObjectID, _ := primitive.ObjectIDFromHex(roomID)
+			roomCollectionMock.On("FindOne", mock.Anything, bson.M{"_id": roomObjectID}, mock.Anything).Run(func(args mock.Arguments) {
+				if tt.archived {
+					archivedAt := now
+					args.Get(2).(*model.Room).ArchivedAt = &archivedAt
+				}
+			}).Return(nil)
+			roomCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, bson.M{"$addToSet": bson.M{"members": 1}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.RoomInviteCollectionName).Return(inviteCollectionMock)
+			mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			invite, err := mockSvcStruct.AcceptRoomInvite("code1", 1, now, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("AcceptRoomInvite() [%s] error = %v", tt.name, err)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
+			if !tt.returnErr {
+				assert.Equal(t, roomID, invite.RoomID)
+				roomCollectionMock.AssertCalled(t, "UpdateOne", mock.Anything, mock.Anything, bson.M{"$addToSet": bson.M{"members": 1}})
+			} else {
+				roomCollectionMock.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.archived {
+				inviteCollectionMock.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreateJoinRequest(t *testing.T) {
+	duplicateErr := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "duplicate key"}}}
+
+	tests := []struct {
+		name      string
+		initErr   bool
+		insertErr error
+		expectErr error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, false},
+		{"error", true, nil, nil, true},
+		{"already_pending", false, duplicateErr, ErrJoinRequestExists, true},
+		{"insert_error", false, assert.AnError, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, pendingJoinRequestIndex).Return("roomid_userid_pending", nil)
+			mongoCollectionMock.On("InsertOne", mock.Anything, model.JoinRequest{RoomID: "room1", UserID: 1, Status: model.JoinRequestPending}).Return("request_id", tt.insertErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.JoinRequestCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			id, err := mockSvcStruct.CreateJoinRequest(model.JoinRequest{RoomID: "room1", UserID: 1}, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("CreateJoinRequest() [%s] error = %v, initErr %v", tt.name, err, tt.initErr)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
+			if !tt.returnErr {
+				assert.Equal(t, "request_id", id)
+			}
+		})
+	}
+}
+
+func TestGetJoinRequests(t *testing.T) {
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		request := args.Get(0).(*model.JoinRequest)
+		*request = model.JoinRequest{RoomID: "room1", UserID: 2, Status: model.JoinRequestPending}
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"roomid": "room1", "status": model.JoinRequestPending}, mock.Anything).Return(mongoCursorMock, nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.JoinRequestCollectionName).Return(mongoCollectionMock)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+	requests, err := mockSvcStruct.GetJoinRequests("room1", model.JoinRequestPending, mongoPkgMock)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.JoinRequest{{RoomID: "room1", UserID: 2, Status: model.JoinRequestPending}}, requests)
+}
+
+func TestDecideJoinRequest(t *testing.T) {
+	requestID := "64a7b2f4e13e4c3f9c8b4570"
+	id, _ := primitive.ObjectIDFromHex(requestID)
+	roomID := "64a7b2f4e13e4c3f9c8b4567"
+
+	tests := []struct {
+		name       string
+		requestID  string
+		status     string
+		found      model.JoinRequest
+		findErr    error
+		matched    int64
+		expectJoin bool
+		expectErr  error
+		returnErr  bool
+	}{
+		{"approve", requestID, model.JoinRequestApproved, model.JoinRequest{ID: id, RoomID: roomID, UserID: 2, Status: model.JoinRequestPending}, nil, 1, true, nil, false},
+		{"deny", requestID, model.JoinRequestDenied, model.JoinRequest{ID: id, RoomID: roomID, UserID: 2, Status: model.JoinRequestPending}, nil, 1, false, nil, false},
+		{"invalid_id", "invalid_object_id", model.JoinRequestApproved, model.JoinRequest{}, nil, 0, false, nil, true},
+		{"not_found", requestID, model.JoinRequestApproved, model.JoinRequest{}, mongo.ErrNoDocuments, 0, false, ErrJoinRequestNotFound, true},
+		{"already_decided", requestID, model.JoinRequestApproved, model.JoinRequest{ID: id, RoomID: roomID, UserID: 2, Status: model.JoinRequestDenied}, nil, 0, false, ErrJoinRequestDecided, true},
+		{"decided_concurrently", requestID, model.JoinRequestApproved, model.JoinRequest{ID: id, RoomID: roomID, UserID: 2, Status: model.JoinRequestPending}, nil, 0, false, ErrJoinRequestDecided, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			requestCollectionMock.On("FindOne", mock.Anything, bson.M{"_id": id, "roomid": roomID}, mock.Anything).Run(func(args mock.Arguments) {
+				request := args.Get(2).(*model.JoinRequest)
+				*request = tt.found
+			}).Return(tt.findErr)
+			requestCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": id, "status": model.JoinRequestPending}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["status"] == tt.status && set["decidedby"] == 1
+			})).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+
+			roomCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			roomCollectionMock.On("UpdateOne", mock.Anything, mock.Anything, bson.M{"$addToSet": bson.M{"members": 2}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.JoinRequestCollectionName).Return(requestCollectionMock)
+			mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			request, err := mockSvcStruct.DecideJoinRequest(roomID, tt.requestID, tt.status, 1, mongoPkgMock)
+			if (err != nil) != tt.returnErr {
+				t.Errorf("DecideJoinRequest() [%s] error = %v", tt.name, err)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			}
+			if !tt.returnErr {
+				assert.Equal(t, tt.status, request.Status)
+				assert.Equal(t, 1, request.DecidedBy)
+			}
+			if tt.expectJoin {
+				roomCollectionMock.AssertNumberOfCalls(t, "UpdateOne", 1)
+			} else {
+				roomCollectionMock.AssertNotCalled(t, "UpdateOne", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
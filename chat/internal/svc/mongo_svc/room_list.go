@@ -0,0 +1,208 @@
+package mongo_svc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ルーム一覧の並び順（いずれも降順）
+const (
+	RoomSortActivity = "activity" // 最後にメッセージが投稿された日時
+	RoomSortMembers  = "members"  // メンバー数
+	RoomSortCreated  = "created"  // 作成日時
+)
+
+var ErrInvalidRoomCursor = errors.New("invalid room list cursor")
+
+// 並び順ごとのソートキー
+var roomSortFields = map[string]string{
+	RoomSortActivity: "lastactivityat",
+	RoomSortMembers:  "membercount",
+	RoomSortCreated:  "createdat",
+}
+
+func ValidRoomSort(sort string) bool {
+	_, ok := roomSortFields[sort]
+	return ok
+}
+
+type RoomListQuery struct {
+	UserID int
+	Target string
+	Name   string // ルーム名の部分一致（大文字小文字は区別しない）
+	Sort   string
+	Cursor string // 前のページの next_cursor。空の場合は先頭から
+	Limit  int
+}
+
+// 前のページの最後のルームのソートキーとID
+// 日時は Mongo に合わせてミリ秒で保持する
+type roomListCursor struct {
+	Value int64  `json:"v"`
+	ID    string `json:"id"`
+}
+
+func roomTargetFilter(userID int, target string) (bson.M, error) {
+	switch target {
+	case "all":
+		return bson.M{
+			"$or": []bson.M{
+				{"isprivate": false},
+				{"members": userID}, // 参加済みの場合はプライベートでも表示
+			},
+			"archivedat": nil, // アーカイブ済みのルームは参加済み一覧にだけ表示
+			"kind":       bson.M{"$ne": model.RoomKindDirect},
+		}, nil
+	case "joined":
+		return bson.M{"members": userID}, nil // 参加済みのものだけ
+	case "direct":
+		return bson.M{"members": userID, "kind": model.RoomKindDirect}, nil
+	default:
+		return nil, fmt.Errorf("invalid target: %s", target)
+	}
+}
+
+// 一度もメッセージが投稿されていないルームは作成日時を最終アクティビティとして扱う
+func roomLastActivity(room model.Room) time.Time {
+	if room.LastActivityAt != nil {
+		return *room.LastActivityAt
+	}
+	return room.CreatedAt
+}
+
+func encodeRoomListCursor(room model.Room, sort string) string {
+	cursor := roomListCursor{ID: room.ID.Hex()}
+	switch sort {
+	case RoomSortActivity:
+		cursor.Value = roomLastActivity(room).UnixMilli()
+	case RoomSortMembers:
+		cursor.Value = int64(len(room.Members))
+	case RoomSortCreated:
+		cursor.Value = room.CreatedAt.UnixMilli()
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// カーソルより後ろ（ソートキーが小さいか、同じ値でIDが小さい）のルームに絞り込む条件
+func decodeRoomListCursor(value string, sort string) (bson.M, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidRoomCursor
+	}
+	var cursor roomListCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidRoomCursor
+	}
+	id, err := primitive.ObjectIDFromHex(cursor.ID)
+	if err != nil {
+		return nil, ErrInvalidRoomCursor
+	}
+
+	var sortValue interface{} = time.UnixMilli(cursor.Value)
+	if sort == RoomSortMembers {
+		sortValue = cursor.Value
+	}
+	field := roomSortFields[sort]
+	return bson.M{
+		"$or": []bson.M{
+			{field: bson.M{"$lt": sortValue}},
+			{field: sortValue, "_id": bson.M{"$lt": id}},
+		},
+	}, nil
+}
+
+func (q RoomListQuery) pipeline() ([]bson.M, error) {
+	filter, err := roomTargetFilter(q.UserID, q.Target)
+	if err != nil {
+		return nil, err
+	}
+	if q.Name != "" {
+		filter["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(q.Name), Options: "i"}
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$addFields": bson.M{
+			"membercount":    bson.M{"$size": bson.M{"$ifNull": bson.A{"$members", bson.A{}}}},
+			"lastactivityat": bson.M{"$ifNull": bson.A{"$lastactivityat", "$createdat"}},
+		}},
+	}
+	if q.Cursor != "" {
+		after, err := decodeRoomListCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, bson.M{"$match": after})
+	}
+	// 次のページの有無を判定するために1件多く取得する
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{Key: roomSortFields[q.Sort], Value: -1}, {Key: "_id", Value: -1}}},
+		bson.M{"$limit": q.Limit + 1},
+	)
+	return pipeline, nil
+}
+
+// 条件に合うルームを並び順に従って取得する
+// 続きがある場合は次のページのカーソルを返す（無い場合は空文字）
+func (m *MongoSvcStruct) ListRooms(query RoomListQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, string, error) {
+	if !ValidRoomSort(query.Sort) {
+		return nil, "", fmt.Errorf("invalid sort: %s", query.Sort)
+	}
+	pipeline, err := query.pipeline()
+	if err != nil {
+		return nil, "", err
+	}
+
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+	cursor, err := collection.Aggregate(mongo.MongoPkgStruct.Ctx, pipeline)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	rooms := []model.Room{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var room model.Room
+		if err := cursor.Decode(&room); err != nil {
+			return nil, "", err
+		}
+		rooms = append(rooms, room)
+	}
+
+	if len(rooms) <= query.Limit {
+		return rooms, "", nil
+	}
+	rooms = rooms[:query.Limit]
+	return rooms, encodeRoomListCursor(rooms[len(rooms)-1], query.Sort), nil
+}
+
+// 最新メッセージが編集・削除された場合はルーム一覧のプレビューにも反映する
+func updateLastMessagePreview(mongo *Mongo, roomID string, messageID string, set bson.M) error {
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+	_, err = mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName).UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "lastmessage.messageid": messageID},
+		bson.M{"$set": set},
+	)
+	return err
+}
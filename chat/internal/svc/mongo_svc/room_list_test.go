@@ -0,0 +1,127 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRoomListCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastActivityAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		room      model.Room
+		sort      string
+		field     string
+		sortValue interface{}
+	}{
+		{"activity", model.Room{ID: id, CreatedAt: createdAt, LastActivityAt: &lastActivityAt}, RoomSortActivity, "lastactivityat", lastActivityAt.Local()},
+		// メッセージが無いルームは作成日時で並ぶ
+		{"activity_without_messages", model.Room{ID: id, CreatedAt: createdAt}, RoomSortActivity, "lastactivityat", createdAt.Local()},
+		{"members", model.Room{ID: id, CreatedAt: createdAt, Members: []int{1, 2, 3}}, RoomSortMembers, "membercount", int64(3)},
+		{"created", model.Room{ID: id, CreatedAt: createdAt, LastActivityAt: &lastActivityAt}, RoomSortCreated, "createdat", createdAt.Local()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, err := decodeRoomListCursor(encodeRoomListCursor(tt.room, tt.sort), tt.sort)
+			assert.NoError(t, err)
+			assert.Equal(t, bson.M{
+				"$or": []bson.M{
+					{tt.field: bson.M{"$lt": tt.sortValue}},
+					{tt.field: tt.sortValue, "_id": bson.M{"$lt": id}},
+				},
+			}, after)
+		})
+	}
+
+	_, err := decodeRoomListCursor("not a cursor", RoomSortActivity)
+	assert.ErrorIs(t, err, ErrInvalidRoomCursor)
+}
+
+func TestRoomListQueryPipeline(t *testing.T) {
+	query := RoomListQuery{UserID: 1, Target: "joined", Name: "go.dev", Sort: RoomSortMembers, Limit: 10}
+
+	pipeline, err := query.pipeline()
+	assert.NoError(t, err)
+	// 名前の記号はエスケープして部分一致にする
+	assert.Equal(t, bson.M{"$match": bson.M{"members": 1, "name": primitive.Regex{Pattern: `go\.dev`, Options: "i"}}}, pipeline[0])
+	assert.Equal(t, bson.M{"$sort": bson.D{{Key: "membercount", Value: -1}, {Key: "_id", Value: -1}}}, pipeline[2])
+	assert.Equal(t, bson.M{"$limit": 11}, pipeline[3])
+
+	query.Cursor = encodeRoomListCursor(model.Room{ID: primitive.NewObjectID(), Members: []int{1}}, RoomSortMembers)
+	pipeline, err = query.pipeline()
+	assert.NoError(t, err)
+	assert.Len(t, pipeline, 5)
+
+	_, err = RoomListQuery{UserID: 1, Target: "unknown", Sort: RoomSortMembers}.pipeline()
+	assert.Error(t, err)
+}
+
+func TestListRooms(t *testing.T) {
+	rooms := []model.Room{
+		{ID: primitive.NewObjectID(), Name: "first", Members: []int{1, 2}},
+		{ID: primitive.NewObjectID(), Name: "second", Members: []int{1}},
+	}
+
+	tests := []struct {
+		name       string
+		sort       string
+		cursor     string
+		limit      int
+		expectLen  int
+		expectNext bool
+		expectErr  error
+		returnErr  bool
+	}{
+		{"last_page", RoomSortMembers, "", 2, 2, false, nil, false},
+		{"has_next_page", RoomSortMembers, "", 1, 1, true, nil, false},
+		{"invalid_sort", "name", "", 1, 0, false, nil, true},
+		{"invalid_cursor", RoomSortMembers, "%%%", 1, 0, false, ErrInvalidRoomCursor, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Times(len(rooms))
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			i := 0
+			mongoCursorMock.On("Decode", mock.AnythingOfType("*model.Room")).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.Room) = rooms[i]
+				i++
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("Aggregate", mock.Anything, mock.Anything).Return(mongoCursorMock, nil)
+			svc, pkg := setupRoomCollection(collection)
+
+			got, next, err := svc.ListRooms(RoomListQuery{UserID: 1, Target: "joined", Sort: tt.sort, Cursor: tt.cursor, Limit: tt.limit}, pkg)
+			if tt.returnErr {
+				assert.Error(t, err)
+				if tt.expectErr != nil {
+					assert.ErrorIs(t, err, tt.expectErr)
+				}
+				collection.AssertNotCalled(t, "Aggregate", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, got, tt.expectLen)
+			if tt.expectNext {
+				// 次のページは最後に返したルームの後ろから始まる
+				assert.Equal(t, encodeRoomListCursor(rooms[0], tt.sort), next)
+			} else {
+				assert.Empty(t, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNotRoomMember      = errors.New("user is not a member of the room")
+	ErrUserBanned         = errors.New("user is banned from the room")
+	ErrOwnerIsLastMember  = errors.New("owner is the last member of the room")
+	ErrCannotTargetOwner  = errors.New("operation cannot target the room owner")
+	ErrMembershipConflict = errors.New("room membership was modified concurrently")
+)
+
+// 各操作は条件付きの UpdateOne 1回で行い、同時に参加・退出されてもメンバー一覧が壊れないようにする
+
+// ルームから退出する。オーナーが退出する場合はモデレーター、メンバー、閲覧のみの順で最も古くから参加しているユーザーにオーナーを引き継ぐ
+// 戻り値は新しいオーナーのユーザーID（引き継ぎが無い場合は 0）
+func (m *MongoSvcStruct) LeaveRoom(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) (int, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return 0, err
+	}
+
+	var room model.Room
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id}, &room)
+	if err != nil {
+		return 0, err
+	}
+	// ダイレクトメッセージは参加者の組み合わせで識別するため退出できない
+	if room.IsDirect() {
+		return 0, ErrDirectRoom
+	}
+
+	if room.OwnerID != userID {
+		result, err := collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": id, "ownerid": bson.M{"$ne": userID}, "members": userID},
+			bson.M{"$pull": bson.M{"members": userID}, "$unset": bson.M{roleField(userID): ""}},
+		)
+		if err != nil {
+			return 0, err
+		}
+		if result.MatchedCount == 0 {
+			return 0, ErrNotRoomMember
+		}
+		return 0, nil
+	}
+
+	successor := successorOf(room)
+	if successor == 0 {
+		return 0, ErrOwnerIsLastMember
+	}
+
+	// 後継者が同時に退出した場合やオーナーが変わった場合は一致しない
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "ownerid": userID, "members": successor},
+		bson.M{
+			"$set":   bson.M{"ownerid": successor},
+			"$pull":  bson.M{"members": userID},
+			"$unset": bson.M{roleField(successor): ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount == 0 {
+		return 0, ErrMembershipConflict
+	}
+
+	return successor, nil
+}
+
+// メンバーをルームから外す。再参加は可能
+func (m *MongoSvcStruct) KickMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "ownerid": bson.M{"$ne": userID}, "members": userID},
+		bson.M{"$pull": bson.M{"members": userID}, "$unset": bson.M{roleField(userID): ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotRoomMember
+	}
+
+	return nil
+}
+
+// メンバーから外した上で BAN リストに追加する。メンバーでないユーザーも BAN できる
+func (m *MongoSvcStruct) BanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "ownerid": bson.M{"$ne": userID}},
+		bson.M{
+			"$pull":     bson.M{"members": userID},
+			"$addToSet": bson.M{"banned": userID},
+			"$unset":    bson.M{roleField(userID): ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrCannotTargetOwner
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) UnbanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"banned": userID}},
+	)
+	return err
+}
+
+// オーナーを他のメンバーに譲る。譲り先がメンバーでなくなっていた場合は失敗する
+func (m *MongoSvcStruct) TransferOwnership(roomID string, fromUserID int, toUserID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "ownerid": fromUserID, "members": toUserID},
+		bson.M{"$set": bson.M{"ownerid": toUserID}, "$unset": bson.M{roleField(toUserID): ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMembershipConflict
+	}
+
+	return nil
+}
+
+// 役割を変更する。member に戻す場合は役割の設定を削除する
+func (m *MongoSvcStruct) SetMemberRole(roomID string, userID int, role string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{roleField(userID): role}}
+	if role == model.RoleMember {
+		update = bson.M{"$unset": bson.M{roleField(userID): ""}}
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "ownerid": bson.M{"$ne": userID}, "members": userID},
+		update,
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotRoomMember
+	}
+
+	return nil
+}
+
+func roleField(userID int) string {
+	return "roles." + model.RoleKey(userID)
+}
+
+// オーナーの引き継ぎ先を選ぶ。該当者がいない場合は 0
+func successorOf(room model.Room) int {
+	for _, role := range []string{model.RoleModerator, model.RoleMember, model.RoleReadOnly} {
+		for _, member := range room.Members {
+			if member != room.OwnerID && room.RoleOf(member) == role {
+				return member
+			}
+		}
+	}
+	return 0
+}
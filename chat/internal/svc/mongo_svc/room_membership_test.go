@@ -0,0 +1,269 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const membershipRoomID = "64a7b2f4e13e4c3f9c8b4567"
+
+func setupRoomCollection(collection *mock_mongo_pkg.MongoCollectionMock) (*MongoSvcStruct, mongo_pkg.MongoPkgInterface) {
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(collection)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	return NewMongoSvc(mongoDatabaseMock), setupInitMock(false, "chatapp", mongoPkgStruct)
+}
+
+func TestLeaveRoom(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	tests := []struct {
+		name         string
+		room         model.Room
+		filter       bson.M
+		update       bson.M
+		matched      int64
+		expectOwner  int
+		expectErr    error
+		expectUpdate bool
+	}{
+		{
+			"member_leaves",
+			model.Room{OwnerID: 2, Members: []int{2, 1}},
+			bson.M{"_id": id, "ownerid": bson.M{"$ne": 1}, "members": 1},
+			bson.M{"$pull": bson.M{"members": 1}, "$unset": bson.M{"roles.1": ""}},
+			1, 0, nil, true,
+		},
+		{
+			"not_member",
+			model.Room{OwnerID: 2, Members: []int{2}},
+			bson.M{"_id": id, "ownerid": bson.M{"$ne": 1}, "members": 1},
+			bson.M{"$pull": bson.M{"members": 1}, "$unset": bson.M{"roles.1": ""}},
+			0, 0, ErrNotRoomMember, true,
+		},
+		{
+			"owner_leaves_and_transfers",
+			model.Room{OwnerID: 1, Members: []int{1, 3, 2}},
+			bson.M{"_id": id, "ownerid": 1, "members": 3},
+			bson.M{"$set": bson.M{"ownerid": 3}, "$pull": bson.M{"members": 1}, "$unset": bson.M{"roles.3": ""}},
+			1, 3, nil, true,
+		},
+		{
+			"owner_leaves_and_transfers_to_moderator",
+			model.Room{OwnerID: 1, Members: []int{1, 3, 2}, Roles: map[string]string{"3": model.RoleReadOnly, "2": model.RoleModerator}},
+			bson.M{"_id": id, "ownerid": 1, "members": 2},
+			bson.M{"$set": bson.M{"ownerid": 2}, "$pull": bson.M{"members": 1}, "$unset": bson.M{"roles.2": ""}},
+			1, 2, nil, true,
+		},
+		{
+			"direct_room",
+			model.Room{Kind: model.RoomKindDirect, Members: []int{1, 2}},
+			nil, nil,
+			0, 0, ErrDirectRoom, false,
+		},
+		{
+			"owner_leaves_concurrently",
+			model.Room{OwnerID: 1, Members: []int{1, 3}},
+			bson.M{"_id": id, "ownerid": 1, "members": 3},
+			bson.M{"$set": bson.M{"ownerid": 3}, "$pull": bson.M{"members": 1}, "$unset": bson.M{"roles.3": ""}},
+			0, 0, ErrMembershipConflict, true,
+		},
+		{
+			"owner_is_last_member",
+			model.Room{OwnerID: 1, Members: []int{1}},
+			nil, nil, 0, 0, ErrOwnerIsLastMember, false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("FindOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Run(func(args mock.Arguments) {
+				room := args.Get(2).(*model.Room)
+				*room = tt.room
+			}).Return(nil)
+			if tt.expectUpdate {
+				collection.On("UpdateOne", mock.Anything, tt.filter, tt.update).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			}
+			svc, pkg := setupRoomCollection(collection)
+
+			newOwner, err := svc.LeaveRoom(membershipRoomID, 1, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectOwner, newOwner)
+			collection.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLeaveRoomErrors(t *testing.T) {
+	_, err := NewMongoSvc(nil).LeaveRoom(membershipRoomID, 1, setupInitMock(true, "chatapp", nil))
+	assert.Error(t, err)
+
+	collection := new(mock_mongo_pkg.MongoCollectionMock)
+	svc, pkg := setupRoomCollection(collection)
+	_, err = svc.LeaveRoom("invalid_object_id", 1, pkg)
+	assert.Error(t, err)
+
+	collection.On("FindOne", mock.Anything, mock.Anything, mock.Anything).Return(assert.AnError)
+	_, err = svc.LeaveRoom(membershipRoomID, 1, pkg)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestKickMember(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	tests := []struct {
+		name      string
+		matched   int64
+		updateErr error
+		expectErr error
+	}{
+		{"success", 1, nil, nil},
+		{"not_member", 0, nil, ErrNotRoomMember},
+		{"update_error", 0, assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": id, "ownerid": bson.M{"$ne": 2}, "members": 2},
+				bson.M{"$pull": bson.M{"members": 2}, "$unset": bson.M{"roles.2": ""}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, tt.updateErr)
+			svc, pkg := setupRoomCollection(collection)
+
+			err := svc.KickMember(membershipRoomID, 2, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBanMember(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	tests := []struct {
+		name      string
+		matched   int64
+		expectErr error
+	}{
+		{"success", 1, nil},
+		{"owner", 0, ErrCannotTargetOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": id, "ownerid": bson.M{"$ne": 2}},
+				bson.M{"$pull": bson.M{"members": 2}, "$addToSet": bson.M{"banned": 2}, "$unset": bson.M{"roles.2": ""}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := setupRoomCollection(collection)
+
+			err := svc.BanMember(membershipRoomID, 2, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUnbanMember(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	collection := new(mock_mongo_pkg.MongoCollectionMock)
+	collection.On("UpdateOne", mock.Anything, bson.M{"_id": id}, bson.M{"$pull": bson.M{"banned": 2}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	svc, pkg := setupRoomCollection(collection)
+
+	assert.NoError(t, svc.UnbanMember(membershipRoomID, 2, pkg))
+	assert.Error(t, svc.UnbanMember("invalid_object_id", 2, pkg))
+}
+
+func TestTransferOwnership(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	tests := []struct {
+		name      string
+		matched   int64
+		expectErr error
+	}{
+		{"success", 1, nil},
+		{"not_member", 0, ErrMembershipConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": id, "ownerid": 1, "members": 2},
+				bson.M{"$set": bson.M{"ownerid": 2}, "$unset": bson.M{"roles.2": ""}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := setupRoomCollection(collection)
+
+			err := svc.TransferOwnership(membershipRoomID, 1, 2, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetMemberRole(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	tests := []struct {
+		name      string
+		role      string
+		update    bson.M
+		matched   int64
+		expectErr error
+	}{
+		{"moderator", model.RoleModerator, bson.M{"$set": bson.M{"roles.2": model.RoleModerator}}, 1, nil},
+		{"read_only", model.RoleReadOnly, bson.M{"$set": bson.M{"roles.2": model.RoleReadOnly}}, 1, nil},
+		{"member", model.RoleMember, bson.M{"$unset": bson.M{"roles.2": ""}}, 1, nil},
+		{"not_member", model.RoleModerator, bson.M{"$set": bson.M{"roles.2": model.RoleModerator}}, 0, ErrNotRoomMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": id, "ownerid": bson.M{"$ne": 2}, "members": 2},
+				tt.update,
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := setupRoomCollection(collection)
+
+			err := svc.SetMemberRole(membershipRoomID, 2, tt.role, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package mongo_svc
+
+import (
+	"errors"
+	"fmt"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrPinConflict = errors.New("message is already pinned or the pin limit has been reached")
+	ErrPinNotFound = errors.New("pin not found")
+)
+
+// 既にピン留めされている場合や上限に達している場合は ErrPinConflict を返す
+func (m *MongoSvcStruct) PinMessage(roomID string, pin model.RoomPin, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	// 上限の件数目の要素が存在しないことを条件にして、同時に追加されても上限を超えないようにする
+	filter := bson.M{
+		"_id":            id,
+		"pins.messageid": bson.M{"$ne": pin.MessageID},
+		fmt.Sprintf("pins.%d", model.MaxRoomPins-1): bson.M{"$exists": false},
+	}
+	update := bson.M{"$push": bson.M{"pins": bson.M{"$each": []model.RoomPin{pin}, "$position": 0}}}
+
+	result, err := collection.UpdateOne(mongo.MongoPkgStruct.Ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPinConflict
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) UnpinMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return err
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "pins.messageid": messageID},
+		bson.M{"$pull": bson.M{"pins": bson.M{"messageid": messageID}}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPinNotFound
+	}
+
+	return nil
+}
+
+// 削除済みのメッセージと存在しない ID は結果に含めない。並び順は呼び出し元で決める
+func (m *MongoSvcStruct) GetChatMessagesByIDs(messageIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	ids := make([]primitive.ObjectID, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		id, err := primitive.ObjectIDFromHex(messageID)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return []model.ChatMessage{}, nil
+	}
+
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ChatMessageCollectionName)
+
+	opts := options.Find().SetProjection(bson.M{"revisions": 0})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedat": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	messages := []model.ChatMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var message model.ChatMessage
+		if err := cursor.Decode(&message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
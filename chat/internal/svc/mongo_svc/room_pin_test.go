@@ -0,0 +1,119 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPinMessage(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	pin := model.RoomPin{MessageID: "message1", PinnedBy: 1, PinnedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name      string
+		matched   int64
+		updateErr error
+		expectErr error
+	}{
+		{"success", 1, nil, nil},
+		{"conflict", 0, nil, ErrPinConflict},
+		{"update_error", 0, assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 既にピン留めされていないことと上限に達していないことを条件にする
+			filter := bson.M{
+				"_id":            roomID,
+				"pins.messageid": bson.M{"$ne": "message1"},
+				"pins.49":        bson.M{"$exists": false},
+			}
+			update := bson.M{"$push": bson.M{"pins": bson.M{"$each": []model.RoomPin{pin}, "$position": 0}}}
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything, filter, update).
+				Return(&mongo.UpdateResult{MatchedCount: tt.matched}, tt.updateErr)
+			svc, pkg := newWebhookTestSvc(model.RoomCollectionName, mongoCollectionMock)
+
+			err := svc.PinMessage(roomID.Hex(), pin, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUnpinMessage(t *testing.T) {
+	roomID := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		matched   int64
+		expectErr error
+	}{
+		{"success", 1, nil},
+		{"not_found", 0, ErrPinNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": roomID, "pins.messageid": "message1"},
+				bson.M{"$pull": bson.M{"pins": bson.M{"messageid": "message1"}}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := newWebhookTestSvc(model.RoomCollectionName, mongoCollectionMock)
+
+			err := svc.UnpinMessage(roomID.Hex(), "message1", pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetChatMessagesByIDs(t *testing.T) {
+	messageID := primitive.NewObjectID()
+	message := model.ChatMessage{ID: messageID, RoomID: "room1", Message: "hello"}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.ChatMessage) = message
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	// 不正な ID は無視し、削除済みのメッセージは含めない
+	mongoCollectionMock.On("FindWithOptions", mock.Anything,
+		bson.M{"_id": bson.M{"$in": []primitive.ObjectID{messageID}}, "deletedat": nil}, mock.Anything,
+	).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.ChatMessageCollectionName, mongoCollectionMock)
+
+	messages, err := svc.GetChatMessagesByIDs([]string{messageID.Hex(), "invalid"}, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ChatMessage{message}, messages)
+}
+
+func TestGetChatMessagesByIDsEmpty(t *testing.T) {
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	svc, pkg := newWebhookTestSvc(model.ChatMessageCollectionName, mongoCollectionMock)
+
+	messages, err := svc.GetChatMessagesByIDs([]string{"invalid"}, pkg)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+	mongoCollectionMock.AssertNotCalled(t, "FindWithOptions", mock.Anything, mock.Anything, mock.Anything)
+}
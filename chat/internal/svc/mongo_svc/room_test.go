@@ -0,0 +1,182 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpdateRoomFields(t *testing.T) {
+	name := "renamed"
+	topic := ""
+	isPrivate := true
+
+	assert.True(t, UpdateRoomFields{}.Empty())
+	retentionDays := 30
+	fields := UpdateRoomFields{Name: &name, Topic: &topic, IsPrivate: &isPrivate, RetentionDays: &retentionDays}
+	assert.False(t, fields.Empty())
+	// 保持日数を変更した場合は既存のメッセージへの反映を定期削除に任せる
+	assert.Equal(t, bson.M{"name": "renamed", "topic": "", "isprivate": true, "retentiondays": 30, "restamppending": true}, fields.set())
+}
+
+func TestUpdateRoom(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	description := "new description"
+
+	tests := []struct {
+		name      string
+		matched   int64
+		expectErr error
+	}{
+		{"success", 1, nil},
+		{"archived", 0, ErrRoomArchived},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("UpdateOne", mock.Anything,
+				bson.M{"_id": id, "archivedat": nil},
+				bson.M{"$set": bson.M{"description": description, "updatedat": now}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := setupRoomCollection(collection)
+
+			err := svc.UpdateRoom(membershipRoomID, UpdateRoomFields{Description: &description}, now, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestArchiveRoom(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		archive   bool
+		filter    bson.M
+		update    bson.M
+		matched   int64
+		expectErr error
+	}{
+		{"archive", true, bson.M{"_id": id, "archivedat": nil}, bson.M{"$set": bson.M{"archivedat": now, "updatedat": now}}, 1, nil},
+		{"already_archived", true, bson.M{"_id": id, "archivedat": nil}, bson.M{"$set": bson.M{"archivedat": now, "updatedat": now}}, 0, ErrRoomArchived},
+		{"unarchive", false, bson.M{"_id": id, "archivedat": bson.M{"$ne": nil}}, bson.M{"$set": bson.M{"updatedat": now}, "$unset": bson.M{"archivedat": ""}}, 1, nil},
+		{"not_archived", false, bson.M{"_id": id, "archivedat": bson.M{"$ne": nil}}, bson.M{"$set": bson.M{"updatedat": now}, "$unset": bson.M{"archivedat": ""}}, 0, ErrRoomNotArchived},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := new(mock_mongo_pkg.MongoCollectionMock)
+			collection.On("UpdateOne", mock.Anything, tt.filter, tt.update).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := setupRoomCollection(collection)
+
+			var err error
+			if tt.archive {
+				err = svc.ArchiveRoom(membershipRoomID, now, pkg)
+			} else {
+				err = svc.UnarchiveRoom(membershipRoomID, now, pkg)
+			}
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeleteRoom(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex(membershipRoomID)
+
+	tests := []struct {
+		name           string
+		transactionErr error
+		deletedCount   int64
+		deleteManyErr  error
+		expectErr      error
+	}{
+		{"success", nil, 1, nil, nil},
+		{"not_found", nil, 0, nil, ErrRoomNotFound},
+		{"cascade_error", nil, 1, assert.AnError, assert.AnError},
+		{"transaction_error", assert.AnError, 1, nil, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("WithTransaction", mock.Anything).Return(tt.transactionErr)
+
+			roomCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			roomCollection.On("DeleteOne", mock.Anything, bson.M{"_id": id}).Return(&mongo.DeleteResult{DeletedCount: tt.deletedCount}, nil)
+			mongoDatabaseMock.On("Collection", model.RoomCollectionName).Return(roomCollection)
+
+			scopedCollections := map[string]*mock_mongo_pkg.MongoCollectionMock{}
+			for _, name := range roomScopedCollectionNames {
+				collection := new(mock_mongo_pkg.MongoCollectionMock)
+				collection.On("DeleteMany", mock.Anything, bson.M{"roomid": membershipRoomID}).Return(&mongo.DeleteResult{}, tt.deleteManyErr)
+				mongoDatabaseMock.On("Collection", name).Return(collection)
+				scopedCollections[name] = collection
+			}
+
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.ChatMessage) = model.ChatMessage{Attachments: []model.Attachment{{StorageKey: "rooms/r1/attachments/a1"}}}
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+			scopedCollections[model.ChatMessageCollectionName].On("FindWithOptions", mock.Anything, bson.M{
+				"roomid":        membershipRoomID,
+				"attachments.0": bson.M{"$exists": true},
+			}, mock.Anything).Return(mongoCursorMock, nil)
+			exportCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			exportCursorMock.On("Next", mock.Anything).Return(true).Once()
+			exportCursorMock.On("Next", mock.Anything).Return(false).Once()
+			exportCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.RoomExport) = model.RoomExport{ID: primitive.NewObjectID(), StorageKey: "rooms/r1/exports/e1.jsonl"}
+			}).Return(nil)
+			exportCursorMock.On("Close", mock.Anything).Return(nil)
+			scopedCollections[model.RoomExportCollectionName].On("FindWithOptions", mock.Anything, bson.M{"roomid": membershipRoomID}, mock.Anything).
+				Return(exportCursorMock, nil)
+			blobCollection := new(mock_mongo_pkg.MongoCollectionMock)
+			blobCollection.On("InsertOne", mock.Anything, mock.MatchedBy(func(deletion model.BlobDeletion) bool {
+				return assert.ObjectsAreEqual([]string{"rooms/r1/attachments/a1", "rooms/r1/exports/e1.jsonl"}, deletion.StorageKeys)
+			})).Return("", nil)
+			mongoDatabaseMock.On("Collection", model.BlobDeletionCollectionName).Return(blobCollection)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			svc := NewMongoSvc(mongoDatabaseMock)
+
+			err := svc.DeleteRoom(membershipRoomID, setupInitMock(false, "chatapp", mongoPkgStruct))
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+				// ルームに紐づくコレクションもすべて削除され、添付ファイルとエクスポートしたファイルは削除待ちになる
+				for _, collection := range scopedCollections {
+					collection.AssertExpectations(t)
+				}
+				blobCollection.AssertExpectations(t)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("failed webhook delivery not found")
+)
+
+var roomWebhookIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}},
+	Options: options.Index().SetName("roomid"),
+}
+
+// 送信待ちの取得用
+var dueWebhookDeliveryIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "status", Value: 1}, {Key: "nextattemptat", Value: 1}},
+	Options: options.Index().SetName("status_nextattemptat"),
+}
+
+// デッドレターの一覧用
+var roomWebhookDeliveryIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}, {Key: "status", Value: 1}, {Key: "_id", Value: -1}},
+	Options: options.Index().SetName("roomid_status_id"),
+}
+
+func (m *MongoSvcStruct) CreateRoomWebhook(webhook model.RoomWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomWebhookCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, roomWebhookIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return collection.InsertOne(mongo.MongoPkgStruct.Ctx, webhook)
+}
+
+func (m *MongoSvcStruct) GetRoomWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	return m.findRoomWebhooks(bson.M{"roomid": roomID}, mongo_pkg)
+}
+
+// 送信時に最新の URL と鍵を使うため ID で取得する（削除された Webhook は含まれない）
+func (m *MongoSvcStruct) GetRoomWebhooksByIDs(webhookIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	ids := []primitive.ObjectID{}
+	for _, webhookID := range webhookIDs {
+		id, err := primitive.ObjectIDFromHex(webhookID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return m.findRoomWebhooks(bson.M{"_id": bson.M{"$in": ids}}, mongo_pkg)
+}
+
+func (m *MongoSvcStruct) findRoomWebhooks(filter bson.M, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomWebhookCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, roomWebhookIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	webhooks := []model.RoomWebhook{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var webhook model.RoomWebhook
+		if err := cursor.Decode(&webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+// Webhook を削除する。送信待ちの配信は送信時に失敗として扱う
+func (m *MongoSvcStruct) DeleteRoomWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.RoomWebhookCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return ErrWebhookNotFound
+	}
+
+	result, err := collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (m *MongoSvcStruct) InsertWebhookDeliveries(deliveries []model.WebhookDelivery, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.WebhookDeliveryCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, dueWebhookDeliveryIndex)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if _, err := collection.InsertOne(mongo.MongoPkgStruct.Ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 送信時刻を過ぎた配信を取得し、lease の間は他のワーカーが取得しないようにする
+func (m *MongoSvcStruct) ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.WebhookDeliveryCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, dueWebhookDeliveryIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "nextattemptat", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{
+		"status":        model.DeliveryPending,
+		"nextattemptat": bson.M{"$lte": now},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	var due []model.WebhookDelivery
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var delivery model.WebhookDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return nil, err
+		}
+		due = append(due, delivery)
+	}
+
+	claimed := []model.WebhookDelivery{}
+	for _, delivery := range due {
+		// 読み取った後に他のワーカーが取得していないことを送信時刻で確認する
+		result, err := collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": delivery.ID, "status": model.DeliveryPending, "nextattemptat": delivery.NextAttemptAt},
+			bson.M{"$set": bson.M{"nextattemptat": now.Add(lease)}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, delivery)
+	}
+
+	return claimed, nil
+}
+
+// 送信の試行を記録する。status が pending の場合は nextAttemptAt に再試行する
+func (m *MongoSvcStruct) RecordWebhookAttempt(deliveryID string, attempt model.WebhookAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.WebhookDeliveryCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"status": status, "nextattemptat": nextAttemptAt}
+	if status == model.DeliveryDelivered {
+		set["deliveredat"] = attempt.At
+	}
+	_, err = collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$push": bson.M{"attempts": attempt},
+			"$inc":  bson.M{"attemptcount": 1},
+			"$set":  set,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ルームの配信を状態で絞り込んで新しい順に取得する
+func (m *MongoSvcStruct) GetWebhookDeliveries(roomID string, status string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.WebhookDeliveryCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, roomWebhookDeliveryIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID, "status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	deliveries := []model.WebhookDelivery{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var delivery model.WebhookDelivery
+		if err := cursor.Decode(&delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// デッドレターになった配信を送信待ちに戻す（試行回数は数え直す）
+func (m *MongoSvcStruct) RedeliverWebhookDelivery(roomID string, deliveryID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.WebhookDeliveryCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "roomid": roomID, "status": model.DeliveryFailed},
+		bson.M{"$set": bson.M{"status": model.DeliveryPending, "attemptcount": 0, "nextattemptat": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
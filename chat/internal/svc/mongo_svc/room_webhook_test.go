@@ -0,0 +1,183 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newWebhookTestSvc(collectionName string, collection *mock_mongo_pkg.MongoCollectionMock) (*MongoSvcStruct, mongo_pkg.MongoPkgInterface) {
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", collectionName).Return(collection)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	return NewMongoSvc(mongoDatabaseMock), setupInitMock(false, "chatapp", mongoPkgStruct)
+}
+
+func TestDeleteRoomWebhook(t *testing.T) {
+	webhookID := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		webhookID string
+		deleted   int64
+		deleteErr error
+		expectErr error
+		returnErr bool
+	}{
+		{"success", webhookID.Hex(), 1, nil, nil, false},
+		{"not_found", webhookID.Hex(), 0, nil, ErrWebhookNotFound, true},
+		{"invalid_id", "invalid", 0, nil, ErrWebhookNotFound, true},
+		{"delete_error", webhookID.Hex(), 0, assert.AnError, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			// 別のルームの Webhook は削除できない
+			mongoCollectionMock.On("DeleteOne", mock.Anything, bson.M{"_id": webhookID, "roomid": "room1"}).
+				Return(&mongo.DeleteResult{DeletedCount: tt.deleted}, tt.deleteErr)
+			svc, pkg := newWebhookTestSvc(model.RoomWebhookCollectionName, mongoCollectionMock)
+
+			err := svc.DeleteRoomWebhook("room1", tt.webhookID, pkg)
+			if tt.returnErr {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetRoomWebhooksByIDs(t *testing.T) {
+	webhook := model.RoomWebhook{ID: primitive.NewObjectID(), RoomID: "room1", URL: "https://example.com/hook", Secret: "s"}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.RoomWebhook) = webhook
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, roomWebhookIndex).Return("", nil)
+	mongoCollectionMock.On("Find", mock.Anything, bson.M{"_id": bson.M{"$in": []primitive.ObjectID{webhook.ID}}}).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.RoomWebhookCollectionName, mongoCollectionMock)
+
+	webhooks, err := svc.GetRoomWebhooksByIDs([]string{webhook.ID.Hex()}, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.RoomWebhook{webhook}, webhooks)
+
+	_, err = svc.GetRoomWebhooksByIDs([]string{"invalid"}, pkg)
+	assert.Error(t, err)
+}
+
+func TestClaimDueWebhookDeliveries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := model.WebhookDelivery{ID: primitive.NewObjectID(), Status: model.DeliveryPending, NextAttemptAt: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name        string
+		matched     int64
+		expectCount int
+	}{
+		{"claimed", 1, 1},
+		// 他のワーカーが先に取得した場合
+		{"taken", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+			mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+			mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.WebhookDelivery) = due
+			}).Return(nil)
+			mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, dueWebhookDeliveryIndex).Return("", nil)
+			mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{
+				"status":        model.DeliveryPending,
+				"nextattemptat": bson.M{"$lte": now},
+			}, mock.Anything).Return(mongoCursorMock, nil)
+			mongoCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": due.ID, "status": model.DeliveryPending, "nextattemptat": due.NextAttemptAt},
+				bson.M{"$set": bson.M{"nextattemptat": now.Add(time.Minute)}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := newWebhookTestSvc(model.WebhookDeliveryCollectionName, mongoCollectionMock)
+
+			claimed, err := svc.ClaimDueWebhookDeliveries(now, time.Minute, 10, pkg)
+			assert.NoError(t, err)
+			assert.Len(t, claimed, tt.expectCount)
+		})
+	}
+}
+
+func TestRecordWebhookAttempt(t *testing.T) {
+	deliveryID := primitive.NewObjectID()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	attempt := model.WebhookAttempt{At: now, StatusCode: 200}
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": deliveryID}, bson.M{
+		"$push": bson.M{"attempts": attempt},
+		"$inc":  bson.M{"attemptcount": 1},
+		"$set":  bson.M{"status": model.DeliveryDelivered, "nextattemptat": now, "deliveredat": now},
+	}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	svc, pkg := newWebhookTestSvc(model.WebhookDeliveryCollectionName, mongoCollectionMock)
+
+	assert.NoError(t, svc.RecordWebhookAttempt(deliveryID.Hex(), attempt, model.DeliveryDelivered, now, pkg))
+	assert.Error(t, svc.RecordWebhookAttempt("invalid", attempt, model.DeliveryDelivered, now, pkg))
+	mongoCollectionMock.AssertExpectations(t)
+}
+
+func TestRedeliverWebhookDelivery(t *testing.T) {
+	deliveryID := primitive.NewObjectID()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		deliveryID string
+		matched    int64
+		expectErr  error
+	}{
+		{"success", deliveryID.Hex(), 1, nil},
+		// 失敗していない配信は再送しない
+		{"not_failed", deliveryID.Hex(), 0, ErrWebhookDeliveryNotFound},
+		{"invalid_id", "invalid", 0, ErrWebhookDeliveryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": deliveryID, "roomid": "room1", "status": model.DeliveryFailed},
+				bson.M{"$set": bson.M{"status": model.DeliveryPending, "attemptcount": 0, "nextattemptat": now}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := newWebhookTestSvc(model.WebhookDeliveryCollectionName, mongoCollectionMock)
+
+			err := svc.RedeliverWebhookDelivery("room1", tt.deliveryID, now, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrSavedMessageNotFound = errors.New("saved message not found")
+
+// 同じメッセージは1回だけ保存する
+var savedMessageIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "userid", Value: 1}, {Key: "messageid", Value: 1}},
+	Options: options.Index().SetName("userid_messageid").SetUnique(true),
+}
+
+var savedMessageListIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "userid", Value: 1}, {Key: "createdat", Value: -1}},
+	Options: options.Index().SetName("userid_createdat"),
+}
+
+// 保存済みの場合は最初に保存した日時を残す
+func (m *MongoSvcStruct) SaveMessage(saved model.SavedMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SavedMessageCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, savedMessageIndex)
+	if err != nil {
+		return err
+	}
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, savedMessageListIndex)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"userid": saved.UserID, "messageid": saved.MessageID}
+	update := bson.M{"$setOnInsert": bson.M{"roomid": saved.RoomID, "createdat": saved.CreatedAt}}
+	_, err = collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, filter, update, options.Update().SetUpsert(true))
+	// 同時に保存した場合は片方が一意制約で失敗するが、保存済みなので成功として扱う
+	if isDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// 指定したルームの保存済みメッセージを新しく保存した順に返す
+func (m *MongoSvcStruct) GetSavedMessages(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SavedMessage, int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SavedMessageCollectionName)
+
+	filter := bson.M{"userid": userID, "roomid": bson.M{"$in": roomIDs}}
+	total, err := collection.CountDocuments(mongo.MongoPkgStruct.Ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	saved := []model.SavedMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var item model.SavedMessage
+		if err := cursor.Decode(&item); err != nil {
+			return nil, 0, err
+		}
+		saved = append(saved, item)
+	}
+
+	return saved, total, nil
+}
+
+func (m *MongoSvcStruct) DeleteSavedMessage(userID int, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SavedMessageCollectionName)
+
+	result, err := collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"userid": userID, "messageid": messageID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSavedMessageNotFound
+	}
+
+	return nil
+}
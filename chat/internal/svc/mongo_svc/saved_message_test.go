@@ -0,0 +1,100 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSaveMessage(t *testing.T) {
+	saved := model.SavedMessage{UserID: 1, RoomID: "room1", MessageID: "message1", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name      string
+		upsertErr error
+		expectErr error
+	}{
+		{"success", nil, nil},
+		// 同時に保存された場合も保存済みとして成功にする
+		{"duplicate", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, nil},
+		{"upsert_error", assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, savedMessageIndex).Return("", nil)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, savedMessageListIndex).Return("", nil)
+			mongoCollectionMock.On("UpdateOneWithOptions", mock.Anything,
+				bson.M{"userid": 1, "messageid": "message1"},
+				bson.M{"$setOnInsert": bson.M{"roomid": "room1", "createdat": saved.CreatedAt}},
+				mock.Anything,
+			).Return(&mongo.UpdateResult{}, tt.upsertErr)
+			svc, pkg := newWebhookTestSvc(model.SavedMessageCollectionName, mongoCollectionMock)
+
+			err := svc.SaveMessage(saved, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetSavedMessages(t *testing.T) {
+	saved := model.SavedMessage{ID: primitive.NewObjectID(), UserID: 1, RoomID: "room1", MessageID: "message1"}
+	filter := bson.M{"userid": 1, "roomid": bson.M{"$in": []string{"room1"}}}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.SavedMessage) = saved
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CountDocuments", mock.Anything, filter).Return(int64(1), nil)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, filter, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.SavedMessageCollectionName, mongoCollectionMock)
+
+	items, total, err := svc.GetSavedMessages(1, []string{"room1"}, 1, 20, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, []model.SavedMessage{saved}, items)
+}
+
+func TestDeleteSavedMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		deleted   int64
+		expectErr error
+	}{
+		{"success", 1, nil},
+		{"not_found", 0, ErrSavedMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("DeleteOne", mock.Anything, bson.M{"userid": 1, "messageid": "message1"}).
+				Return(&mongo.DeleteResult{DeletedCount: tt.deleted}, nil)
+			svc, pkg := newWebhookTestSvc(model.SavedMessageCollectionName, mongoCollectionMock)
+
+			err := svc.DeleteSavedMessage(1, "message1", pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
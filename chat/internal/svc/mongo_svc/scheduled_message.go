@@ -0,0 +1,224 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrScheduledMessageNotFound = errors.New("pending scheduled message not found")
+var ErrScheduledLeaseExpired = errors.New("scheduled message lease expired before completion")
+
+var dueScheduledMessageIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "status", Value: 1}, {Key: "sendat", Value: 1}},
+	Options: options.Index().SetName("status_sendat"),
+}
+
+var userScheduledMessageIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "userid", Value: 1}, {Key: "status", Value: 1}, {Key: "sendat", Value: 1}},
+	Options: options.Index().SetName("userid_status_sendat"),
+}
+
+func (m *MongoSvcStruct) CreateScheduledMessage(scheduled model.ScheduledMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ScheduledMessageCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, dueScheduledMessageIndex)
+	if err != nil {
+		return "", err
+	}
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, userScheduledMessageIndex)
+	if err != nil {
+		return "", err
+	}
+
+	return collection.InsertOne(mongo.MongoPkgStruct.Ctx, scheduled)
+}
+
+// ユーザーの未実行の予約を実行する日時の順に取得する
+func (m *MongoSvcStruct) GetPendingScheduledMessages(userID int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ScheduledMessage, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ScheduledMessageCollectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "sendat", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"userid": userID, "status": model.ScheduledPending}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	scheduled := []model.ScheduledMessage{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var item model.ScheduledMessage
+		if err := cursor.Decode(&item); err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, item)
+	}
+
+	return scheduled, nil
+}
+
+// 未実行の予約のみ取り消せる
+func (m *MongoSvcStruct) CancelScheduledMessage(userID int, scheduledID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ScheduledMessageCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(scheduledID)
+	if err != nil {
+		return ErrScheduledMessageNotFound
+	}
+
+	result, err := collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "userid": userID, "status": model.ScheduledPending})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrScheduledMessageNotFound
+	}
+
+	return nil
+}
+
+// 実行日時を過ぎた予約を1件処理中にして取得する。予約が無い場合は false を返す
+// 待機中から処理中への変更は1つのワーカーしか成功しないため、複数のレプリカがあっても1回しか実行しない。
+// 取得した時点から lease を数えるので、1件ずつ取得して実行すれば lease が切れる前に完了できる
+func (m *MongoSvcStruct) ClaimDueScheduledMessage(now time.Time, lease time.Duration, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ScheduledMessage, bool, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.ScheduledMessage{}, false, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ScheduledMessageCollectionName)
+
+	if err := m.createIndexOnce(mongo, model.ScheduledMessageCollectionName, dueScheduledMessageIndex); err != nil {
+		return model.ScheduledMessage{}, false, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "sendat", Value: 1}}).
+		SetLimit(1)
+	leaseUntil := now.Add(lease)
+	for {
+		cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{
+			"status": model.ScheduledPending,
+			"sendat": bson.M{"$lte": now},
+		}, opts)
+		if err != nil {
+			return model.ScheduledMessage{}, false, err
+		}
+		var scheduled model.ScheduledMessage
+		found := cursor.Next(mongo.MongoPkgStruct.Ctx)
+		if found {
+			err = cursor.Decode(&scheduled)
+		}
+		cursor.Close(mongo.MongoPkgStruct.Ctx)
+		if err != nil {
+			return model.ScheduledMessage{}, false, err
+		}
+		if !found {
+			return model.ScheduledMessage{}, false, nil
+		}
+
+		result, err := collection.UpdateOne(
+			mongo.MongoPkgStruct.Ctx,
+			bson.M{"_id": scheduled.ID, "status": model.ScheduledPending},
+			bson.M{"$set": bson.M{"status": model.ScheduledProcessing, "leaseuntil": leaseUntil}},
+		)
+		if err != nil {
+			return model.ScheduledMessage{}, false, err
+		}
+		// 他のワーカーが先に取得した場合は次の予約を探す
+		if result.MatchedCount == 0 {
+			continue
+		}
+		scheduled.Status = model.ScheduledProcessing
+		scheduled.LeaseUntil = &leaseUntil
+		return scheduled, true, nil
+	}
+}
+
+// 処理中の予約を送信済みか失敗にする。lease が切れて失敗にされた予約は変更しない
+func (m *MongoSvcStruct) CompleteScheduledMessage(scheduledID string, status string, postedMessageID string, errMessage string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ScheduledMessageCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(scheduledID)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{"status": status, "completedat": now}
+	if postedMessageID != "" {
+		set["postedmessageid"] = postedMessageID
+	}
+	if errMessage != "" {
+		set["error"] = errMessage
+	}
+	result, err := collection.UpdateOne(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"_id": id, "status": model.ScheduledProcessing},
+		bson.M{"$set": set, "$unset": bson.M{"leaseuntil": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrScheduledLeaseExpired
+	}
+
+	return nil
+}
+
+// lease が切れた処理中の予約を失敗にする。
+// 投稿されたかどうか分からないため、重複して投稿しないように再実行はしない
+func (m *MongoSvcStruct) ExpireScheduledMessageLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return 0, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.ScheduledMessageCollectionName)
+
+	result, err := collection.UpdateMany(
+		mongo.MongoPkgStruct.Ctx,
+		bson.M{"status": model.ScheduledProcessing, "leaseuntil": bson.M{"$lt": now}},
+		bson.M{
+			"$set":   bson.M{"status": model.ScheduledFailed, "error": "lease expired before the scheduled message completed", "completedat": now},
+			"$unset": bson.M{"leaseuntil": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,181 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateScheduledMessage(t *testing.T) {
+	scheduled := model.ScheduledMessage{Kind: model.ScheduledKindMessage, RoomID: "room1", UserID: 1, Message: "hi", Status: model.ScheduledPending}
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, dueScheduledMessageIndex).Return("", nil)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, userScheduledMessageIndex).Return("", nil)
+	mongoCollectionMock.On("InsertOne", mock.Anything, scheduled).Return("scheduled1", nil)
+	svc, pkg := newWebhookTestSvc(model.ScheduledMessageCollectionName, mongoCollectionMock)
+
+	id, err := svc.CreateScheduledMessage(scheduled, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, "scheduled1", id)
+}
+
+func TestGetPendingScheduledMessages(t *testing.T) {
+	scheduled := model.ScheduledMessage{ID: primitive.NewObjectID(), UserID: 1, Status: model.ScheduledPending}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.ScheduledMessage) = scheduled
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"userid": 1, "status": model.ScheduledPending}, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.ScheduledMessageCollectionName, mongoCollectionMock)
+
+	items, err := svc.GetPendingScheduledMessages(1, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ScheduledMessage{scheduled}, items)
+}
+
+func TestCancelScheduledMessage(t *testing.T) {
+	scheduledID := primitive.NewObjectID()
+
+	tests := []struct {
+		name        string
+		scheduledID string
+		deleted     int64
+		expectErr   error
+	}{
+		{"success", scheduledID.Hex(), 1, nil},
+		// 他のユーザーの予約や実行済みの予約は取り消せない
+		{"not_found", scheduledID.Hex(), 0, ErrScheduledMessageNotFound},
+		{"invalid_id", "invalid", 0, ErrScheduledMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("DeleteOne", mock.Anything, bson.M{"_id": scheduledID, "userid": 1, "status": model.ScheduledPending}).
+				Return(&mongo.DeleteResult{DeletedCount: tt.deleted}, nil)
+			svc, pkg := newWebhookTestSvc(model.ScheduledMessageCollectionName, mongoCollectionMock)
+
+			err := svc.CancelScheduledMessage(1, tt.scheduledID, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClaimDueScheduledMessage(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	taken := model.ScheduledMessage{ID: primitive.NewObjectID(), Status: model.ScheduledPending, SendAt: now.Add(-time.Minute)}
+	next := model.ScheduledMessage{ID: primitive.NewObjectID(), Status: model.ScheduledPending, SendAt: now}
+
+	cursorFor := func(scheduled *model.ScheduledMessage) *mock_mongo_pkg.MongoCursorMock {
+		mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+		mongoCursorMock.On("Next", mock.Anything).Return(scheduled != nil).Once()
+		if scheduled != nil {
+			mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*model.ScheduledMessage) = *scheduled
+			}).Return(nil).Once()
+		}
+		mongoCursorMock.On("Close", mock.Anything).Return(nil)
+		return mongoCursorMock
+	}
+
+	leaseUntil := now.Add(time.Minute)
+	due := bson.M{"status": model.ScheduledPending, "sendat": bson.M{"$lte": now}}
+	claim := bson.M{"$set": bson.M{"status": model.ScheduledProcessing, "leaseuntil": leaseUntil}}
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, dueScheduledMessageIndex).Return("", nil)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, due, mock.Anything).Return(cursorFor(&taken), nil).Once()
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, due, mock.Anything).Return(cursorFor(&next), nil).Once()
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, due, mock.Anything).Return(cursorFor(nil), nil).Once()
+	// 他のワーカーが先に取得した予約は飛ばして次の予約を取得する
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": taken.ID, "status": model.ScheduledPending}, claim).
+		Return(&mongo.UpdateResult{MatchedCount: 0}, nil)
+	mongoCollectionMock.On("UpdateOne", mock.Anything, bson.M{"_id": next.ID, "status": model.ScheduledPending}, claim).
+		Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	svc, pkg := newWebhookTestSvc(model.ScheduledMessageCollectionName, mongoCollectionMock)
+
+	claimed, ok, err := svc.ClaimDueScheduledMessage(now, time.Minute, pkg)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, next.ID, claimed.ID)
+	assert.Equal(t, model.ScheduledProcessing, claimed.Status)
+	assert.Equal(t, leaseUntil, *claimed.LeaseUntil)
+
+	// 実行日時を過ぎた予約が無い場合
+	_, ok, err = svc.ClaimDueScheduledMessage(now, time.Minute, pkg)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCompleteScheduledMessage(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	scheduledID := primitive.NewObjectID()
+
+	tests := []struct {
+		name            string
+		status          string
+		postedMessageID string
+		errMessage      string
+		expectSet       bson.M
+		matched         int64
+		expectErr       error
+	}{
+		{"sent", model.ScheduledSent, "message1", "", bson.M{"status": model.ScheduledSent, "completedat": now, "postedmessageid": "message1"}, 1, nil},
+		{"failed", model.ScheduledFailed, "", "boom", bson.M{"status": model.ScheduledFailed, "completedat": now, "error": "boom"}, 1, nil},
+		// lease が切れて失敗にされた予約は変更しない
+		{"lease_expired", model.ScheduledSent, "message1", "", bson.M{"status": model.ScheduledSent, "completedat": now, "postedmessageid": "message1"}, 0, ErrScheduledLeaseExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("UpdateOne", mock.Anything,
+				bson.M{"_id": scheduledID, "status": model.ScheduledProcessing},
+				bson.M{"$set": tt.expectSet, "$unset": bson.M{"leaseuntil": ""}},
+			).Return(&mongo.UpdateResult{MatchedCount: tt.matched}, nil)
+			svc, pkg := newWebhookTestSvc(model.ScheduledMessageCollectionName, mongoCollectionMock)
+
+			err := svc.CompleteScheduledMessage(scheduledID.Hex(), tt.status, tt.postedMessageID, tt.errMessage, now, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExpireScheduledMessageLeases(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("UpdateMany", mock.Anything,
+		bson.M{"status": model.ScheduledProcessing, "leaseuntil": bson.M{"$lt": now}},
+		mock.MatchedBy(func(update bson.M) bool {
+			return update["$set"].(bson.M)["status"] == model.ScheduledFailed
+		}),
+	).Return(&mongo.UpdateResult{ModifiedCount: 2}, nil)
+	svc, pkg := newWebhookTestSvc(model.ScheduledMessageCollectionName, mongoCollectionMock)
+
+	expired, err := svc.ExpireScheduledMessageLeases(now, pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), expired)
+}
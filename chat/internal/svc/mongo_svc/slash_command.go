@@ -0,0 +1,122 @@
+package mongo_svc
+
+import (
+	"errors"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrSlashCommandNotFound = errors.New("slash command not found")
+	ErrSlashCommandExists   = errors.New("slash command already exists")
+)
+
+// ルームごとにコマンド名を一意にする
+var slashCommandIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "roomid", Value: 1}, {Key: "name", Value: 1}},
+	Options: options.Index().SetName("roomid_name").SetUnique(true),
+}
+
+func (m *MongoSvcStruct) CreateSlashCommand(command model.SlashCommand, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return "", err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SlashCommandCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, slashCommandIndex)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := collection.InsertOne(mongo.MongoPkgStruct.Ctx, command)
+	if isDuplicateKeyError(err) {
+		return "", ErrSlashCommandExists
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (m *MongoSvcStruct) GetSlashCommands(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SlashCommand, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SlashCommandCollectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := collection.FindWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	commands := []model.SlashCommand{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var command model.SlashCommand
+		if err := cursor.Decode(&command); err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
+
+func (m *MongoSvcStruct) GetSlashCommand(roomID string, name string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.SlashCommand, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return model.SlashCommand{}, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SlashCommandCollectionName)
+
+	var command model.SlashCommand
+	err = collection.FindOne(mongo.MongoPkgStruct.Ctx, bson.M{"roomid": roomID, "name": name}, &command)
+	if errors.Is(err, errNoDocuments) {
+		return model.SlashCommand{}, ErrSlashCommandNotFound
+	}
+	if err != nil {
+		return model.SlashCommand{}, err
+	}
+
+	return command, nil
+}
+
+func (m *MongoSvcStruct) DeleteSlashCommand(roomID string, commandID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.SlashCommandCollectionName)
+
+	id, err := primitive.ObjectIDFromHex(commandID)
+	if err != nil {
+		return ErrSlashCommandNotFound
+	}
+
+	result, err := collection.DeleteOne(mongo.MongoPkgStruct.Ctx, bson.M{"_id": id, "roomid": roomID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSlashCommandNotFound
+	}
+
+	return nil
+}
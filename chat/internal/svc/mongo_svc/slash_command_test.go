@@ -0,0 +1,127 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateSlashCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		insertErr error
+		expectErr error
+	}{
+		{"success", nil, nil},
+		{"duplicate", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, ErrSlashCommandExists},
+		{"insert_error", assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := model.SlashCommand{RoomID: "room1", Name: "deploy", URL: "https://example.com/deploy"}
+
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, slashCommandIndex).Return("", nil)
+			mongoCollectionMock.On("InsertOne", mock.Anything, command).Return("command1", tt.insertErr)
+			svc, pkg := newWebhookTestSvc(model.SlashCommandCollectionName, mongoCollectionMock)
+
+			id, err := svc.CreateSlashCommand(command, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "command1", id)
+		})
+	}
+}
+
+func TestGetSlashCommands(t *testing.T) {
+	command := model.SlashCommand{ID: primitive.NewObjectID(), RoomID: "room1", Name: "deploy"}
+
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*model.SlashCommand) = command
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("FindWithOptions", mock.Anything, bson.M{"roomid": "room1"}, mock.Anything).Return(mongoCursorMock, nil)
+	svc, pkg := newWebhookTestSvc(model.SlashCommandCollectionName, mongoCollectionMock)
+
+	commands, err := svc.GetSlashCommands("room1", pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.SlashCommand{command}, commands)
+}
+
+func TestGetSlashCommand(t *testing.T) {
+	found := model.SlashCommand{ID: primitive.NewObjectID(), RoomID: "room1", Name: "deploy"}
+
+	tests := []struct {
+		name      string
+		findErr   error
+		expectErr error
+	}{
+		{"success", nil, nil},
+		{"not_found", mongo.ErrNoDocuments, ErrSlashCommandNotFound},
+		{"find_error", assert.AnError, assert.AnError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("FindOne", mock.Anything, bson.M{"roomid": "room1", "name": "deploy"}, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(2).(*model.SlashCommand) = found
+			}).Return(tt.findErr)
+			svc, pkg := newWebhookTestSvc(model.SlashCommandCollectionName, mongoCollectionMock)
+
+			command, err := svc.GetSlashCommand("room1", "deploy", pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, found, command)
+		})
+	}
+}
+
+func TestDeleteSlashCommand(t *testing.T) {
+	commandID := primitive.NewObjectID()
+
+	tests := []struct {
+		name      string
+		commandID string
+		deleted   int64
+		expectErr error
+	}{
+		{"success", commandID.Hex(), 1, nil},
+		{"not_found", commandID.Hex(), 0, ErrSlashCommandNotFound},
+		{"invalid_id", "invalid", 0, ErrSlashCommandNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("DeleteOne", mock.Anything, bson.M{"_id": commandID, "roomid": "room1"}).
+				Return(&mongo.DeleteResult{DeletedCount: tt.deleted}, nil)
+			svc, pkg := newWebhookTestSvc(model.SlashCommandCollectionName, mongoCollectionMock)
+
+			err := svc.DeleteSlashCommand("room1", tt.commandID, pkg)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package mongo_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var userIDIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "userid", Value: 1}},
+	Options: options.Index().SetName("userid").SetUnique(true),
+}
+
+// メンションの解決用（同じ名前のユーザーが複数いる場合もある）
+var userHandleIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "handle", Value: 1}},
+	Options: options.Index().SetName("handle"),
+}
+
+// ユーザーの情報を記録する（既にある場合はメールアドレスと名前を更新する）
+func (m *MongoSvcStruct) UpsertUser(user model.User, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.UserCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, userIDIndex)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"email":     user.Email,
+			"handle":    user.Handle,
+			"updatedat": time.Now(),
+		},
+	}
+	// 同じユーザーの同時リクエストでは後から来た upsert が一意制約に引っかかるが、内容は同じなので無視する
+	_, err = collection.UpdateOneWithOptions(mongo.MongoPkgStruct.Ctx, bson.M{"userid": user.UserID}, update, options.Update().SetUpsert(true))
+	if err != nil && !isDuplicateKeyError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// 指定されたユーザーの中から名前が一致するものを取得する
+func (m *MongoSvcStruct) GetUsersByHandles(handles []string, userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.UserCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, userHandleIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{
+		"handle": bson.M{"$in": handles},
+		"userid": bson.M{"$in": userIDs},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	users := []model.User{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// 指定されたユーザーの情報を取得する（記録されていないユーザーは含まれない）
+func (m *MongoSvcStruct) GetUsersByIDs(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.UserCollectionName)
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"userid": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	users := []model.User{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+// ルームのメンバー以外も含めて名前でユーザーを探す（同じ名前のユーザーが複数いる場合もある）
+func (m *MongoSvcStruct) FindUsersByHandle(handle string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	mongo, err := Init(mongo_pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer mongo.MongoPkgStruct.Cancel()
+	collection := mongo.MongoPkgStruct.Db.Collection(model.UserCollectionName)
+
+	_, err = collection.CreateIndex(mongo.MongoPkgStruct.Ctx, userHandleIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(mongo.MongoPkgStruct.Ctx, bson.M{"handle": handle})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(mongo.MongoPkgStruct.Ctx)
+
+	users := []model.User{}
+	for cursor.Next(mongo.MongoPkgStruct.Ctx) {
+		var user model.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
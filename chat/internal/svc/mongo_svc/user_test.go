@@ -0,0 +1,119 @@
+package mongo_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestUpsertUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		initErr   bool
+		indexErr  error
+		updateErr error
+		returnErr bool
+	}{
+		{"success", false, nil, nil, false},
+		{"error", true, nil, nil, true},
+		{"index_error", false, assert.AnError, nil, true},
+		{"update_error", false, nil, assert.AnError, true},
+		{"concurrent_upsert", false, nil, mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+			mongoCollectionMock.On("CreateIndex", mock.Anything, userIDIndex).Return("", tt.indexErr)
+			mongoCollectionMock.On("UpdateOneWithOptions", mock.Anything, bson.M{"userid": 12345}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["email"] == "test.user@example.com" && set["handle"] == "test.user"
+			}), mock.Anything).Return(&mongo.UpdateResult{}, tt.updateErr)
+			mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+			mongoDatabaseMock.On("Collection", model.UserCollectionName).Return(mongoCollectionMock)
+
+			mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+				Ctx:    context.TODO(),
+				Db:     mongoDatabaseMock,
+				Cancel: func() {},
+			}
+			mongoPkgMock := setupInitMock(tt.initErr, "chatapp", mongoPkgStruct)
+			mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+			err := mockSvcStruct.UpsertUser(model.User{UserID: 12345, Email: "test.user@example.com", Handle: "test.user"}, mongoPkgMock)
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetUsersByHandles(t *testing.T) {
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		user := args.Get(0).(*model.User)
+		*user = model.User{UserID: 2, Handle: "alice"}
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, userHandleIndex).Return("", nil)
+	mongoCollectionMock.On("Find", mock.Anything, bson.M{
+		"handle": bson.M{"$in": []string{"alice"}},
+		"userid": bson.M{"$in": []int{1, 2}},
+	}).Return(mongoCursorMock, nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.UserCollectionName).Return(mongoCollectionMock)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+	users, err := mockSvcStruct.GetUsersByHandles([]string{"alice"}, []int{1, 2}, mongoPkgMock)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.User{{UserID: 2, Handle: "alice"}}, users)
+}
+
+func TestFindUsersByHandle(t *testing.T) {
+	mongoCursorMock := new(mock_mongo_pkg.MongoCursorMock)
+	mongoCursorMock.On("Next", mock.Anything).Return(true).Once()
+	mongoCursorMock.On("Next", mock.Anything).Return(false).Once()
+	mongoCursorMock.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		user := args.Get(0).(*model.User)
+		*user = model.User{UserID: 2, Handle: "alice"}
+	}).Return(nil)
+	mongoCursorMock.On("Close", mock.Anything).Return(nil)
+
+	mongoCollectionMock := new(mock_mongo_pkg.MongoCollectionMock)
+	mongoCollectionMock.On("CreateIndex", mock.Anything, userHandleIndex).Return("", nil)
+	mongoCollectionMock.On("Find", mock.Anything, bson.M{"handle": "alice"}).Return(mongoCursorMock, nil)
+	mongoDatabaseMock := new(mock_mongo_pkg.MongoDatabaseMock)
+	mongoDatabaseMock.On("Collection", model.UserCollectionName).Return(mongoCollectionMock)
+
+	mongoPkgStruct := &mongo_pkg.MongoPkgStruct{
+		Ctx:    context.TODO(),
+		Db:     mongoDatabaseMock,
+		Cancel: func() {},
+	}
+	mongoPkgMock := setupInitMock(false, "chatapp", mongoPkgStruct)
+	mockSvcStruct := NewMongoSvc(mongoDatabaseMock)
+
+	users, err := mockSvcStruct.FindUsersByHandle("alice", mongoPkgMock)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.User{{UserID: 2, Handle: "alice"}}, users)
+}
@@ -0,0 +1,251 @@
+package notification_svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/notifier_pkg"
+	"microservices/chat/pkg/retry_pkg"
+	"time"
+)
+
+type NotificationSvcInterface interface {
+	Enqueue(messageID string, message model.ChatMessage, room model.Room, replyToUserID int) error
+	BuildDigests() error
+	DeliverDigests() error
+}
+
+type NotificationSvcStruct struct {
+	MongoSvc     mongo_svc.MongoSvcInterface
+	MongoPkg     mongo_pkg.MongoPkgInterface
+	Clock        clock_svc.ClockInterface
+	Notifiers    map[string]notifier_pkg.Notifier // 配信チャネルごとの送信方法
+	DigestWindow time.Duration                    // 最初の通知からダイジェストにまとめるまで待つ時間
+
+	MaxAttempts int           // これを超えて失敗した配信は諦める
+	BaseBackoff time.Duration // 再試行の間隔は失敗するごとに倍にする
+	MaxBackoff  time.Duration
+	Lease       time.Duration // 配信中の配信を他のワーカーが取得しない時間
+	BatchSize   int
+}
+
+func NewNotificationSvc(
+	mongoSvc mongo_svc.MongoSvcInterface,
+	mongoPkg mongo_pkg.MongoPkgInterface,
+	clock clock_svc.ClockInterface,
+	notifiers map[string]notifier_pkg.Notifier,
+	digestWindow time.Duration,
+) *NotificationSvcStruct {
+	return &NotificationSvcStruct{
+		MongoSvc:     mongoSvc,
+		MongoPkg:     mongoPkg,
+		Clock:        clock,
+		Notifiers:    notifiers,
+		DigestWindow: digestWindow,
+		MaxAttempts:  5,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+		Lease:        2 * time.Minute,
+		BatchSize:    50,
+	}
+}
+
+// 投稿されたメッセージから通知するユーザーを決めて通知を登録する。
+// 1人に複数の理由がある場合はメンション、返信、ダイレクトメッセージの順に1件だけ通知する
+func (s *NotificationSvcStruct) Enqueue(messageID string, message model.ChatMessage, room model.Room, replyToUserID int) error {
+	kinds := map[int]string{}
+	var recipients []int
+	add := func(userID int, kind string) {
+		if userID == 0 || userID == message.UserID || kinds[userID] != "" {
+			return
+		}
+		// 退出したユーザーには通知しない
+		for _, member := range room.Members {
+			if member == userID {
+				kinds[userID] = kind
+				recipients = append(recipients, userID)
+				return
+			}
+		}
+	}
+	for _, userID := range message.MentionedUserIds {
+		add(userID, model.NotificationMention)
+	}
+	add(replyToUserID, model.NotificationReply)
+	if room.IsDirect() {
+		for _, userID := range room.Members {
+			add(userID, model.NotificationDirect)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	settings, err := s.MongoSvc.GetNotificationSettings(recipients, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	now := s.Clock.Now()
+	var notifications []model.Notification
+	for _, userID := range recipients {
+		if settings[userID].Mutes(message.RoomID) {
+			continue
+		}
+		notifications = append(notifications, model.Notification{
+			UserID:    userID,
+			Kind:      kinds[userID],
+			RoomID:    message.RoomID,
+			MessageID: messageID,
+			ActorID:   message.UserID,
+			Snippet:   model.MessageSnippet(message.Message),
+			CreatedAt: now,
+		})
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	return s.MongoSvc.InsertNotifications(notifications, s.MongoPkg)
+}
+
+// 最初の通知から DigestWindow が経過したユーザーの通知を1通の配信にまとめる。
+// おやすみモード中のユーザーは終わるまでまとめずに残す
+func (s *NotificationSvcStruct) BuildDigests() error {
+	now := s.Clock.Now()
+	userIDs, err := s.MongoSvc.GetPendingNotificationUsers(now.Add(-s.DigestWindow), s.BatchSize, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	settings, err := s.MongoSvc.GetNotificationSettings(userIDs, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, userID := range userIDs {
+		if settings[userID].InDoNotDisturb(now) {
+			continue
+		}
+		if _, _, err := s.MongoSvc.CreateNotificationDelivery(userID, now, s.MongoPkg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 配信時刻になったダイジェストを送る。失敗した場合は間隔を空けて再試行する
+func (s *NotificationSvcStruct) DeliverDigests() error {
+	now := s.Clock.Now()
+	deliveries, err := s.MongoSvc.ClaimDueDeliveries(now, s.Lease, s.BatchSize, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	var userIDs []int
+	for _, delivery := range deliveries {
+		userIDs = append(userIDs, delivery.UserID)
+	}
+	settings, err := s.MongoSvc.GetNotificationSettings(userIDs, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	users, err := s.MongoSvc.GetUsersByIDs(userIDs, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	emails := map[int]string{}
+	for _, user := range users {
+		emails[user.UserID] = user.Email
+	}
+
+	var errs []error
+	for _, delivery := range deliveries {
+		recipient := notifier_pkg.Recipient{
+			UserID:     delivery.UserID,
+			Email:      emails[delivery.UserID],
+			WebhookURL: settings[delivery.UserID].WebhookURL,
+		}
+		if err := s.deliver(now, delivery, settings[delivery.UserID].DeliveryChannel(), recipient); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *NotificationSvcStruct) deliver(now time.Time, delivery model.NotificationDelivery, channel string, recipient notifier_pkg.Recipient) error {
+	notifications, err := s.MongoSvc.GetDeliveryNotifications(delivery.ID.Hex(), s.MongoPkg)
+	if err != nil {
+		// 取得できなかった場合はリース切れの後にやり直す
+		return err
+	}
+
+	// ダイジェストにまとめるまでの間に削除されたメッセージは送らない
+	var messageIDs []string
+	for _, notification := range notifications {
+		messageIDs = append(messageIDs, notification.MessageID)
+	}
+	messages, err := s.MongoSvc.GetChatMessagesByIDs(messageIDs, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, message := range messages {
+		exists[message.ID.Hex()] = true
+	}
+
+	digest := notifier_pkg.Digest{UserID: delivery.UserID}
+	for _, notification := range notifications {
+		if !exists[notification.MessageID] {
+			continue
+		}
+		digest.Items = append(digest.Items, notifier_pkg.DigestItem{
+			Kind:      notification.Kind,
+			RoomID:    notification.RoomID,
+			MessageID: notification.MessageID,
+			ActorID:   notification.ActorID,
+			Snippet:   notification.Snippet,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+
+	var sendErr error
+	permanent := false
+	// 全て削除されていた場合は送らずに配信済みにする
+	if len(digest.Items) > 0 {
+		if notifier, ok := s.Notifiers[channel]; !ok {
+			sendErr = fmt.Errorf("notifier for channel %q is not configured", channel)
+			permanent = true
+		} else {
+			sendErr = notifier.Notify(context.Background(), recipient, digest)
+			permanent = errors.Is(sendErr, notifier_pkg.ErrNoAddress)
+		}
+	}
+
+	attempt := model.DeliveryAttempt{At: now, Channel: channel}
+	status := model.DeliveryDelivered
+	nextAttemptAt := now
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		attempts := len(delivery.Attempts) + 1
+		if permanent || attempts >= s.MaxAttempts {
+			status = model.DeliveryFailed
+			log.Printf("giving up notification delivery %s to user %d after %d attempts: %v", delivery.ID.Hex(), delivery.UserID, attempts, sendErr)
+		} else {
+			status = model.DeliveryPending
+			nextAttemptAt = now.Add(retry_pkg.Backoff(s.BaseBackoff, s.MaxBackoff, attempts))
+		}
+	}
+
+	return s.MongoSvc.RecordDeliveryAttempt(delivery.ID.Hex(), attempt, status, nextAttemptAt, s.MongoPkg)
+}
@@ -0,0 +1,195 @@
+package notification_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/notifier_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestEnqueue(t *testing.T) {
+	room := model.Room{Members: []int{1, 2, 3, 4}}
+	direct := model.Room{Kind: model.RoomKindDirect, Members: []int{1, 2}}
+
+	tests := []struct {
+		name        string
+		message     model.ChatMessage
+		room        model.Room
+		replyTo     int
+		settings    map[int]model.NotificationSettings
+		expectKinds map[int]string
+	}{
+		{"mention", model.ChatMessage{RoomID: "room1", UserID: 1, MentionedUserIds: []int{2, 3}}, room, 0, nil,
+			map[int]string{2: model.NotificationMention, 3: model.NotificationMention}},
+		// メンションと返信の両方に該当する場合はメンションとして1件だけ通知する
+		{"mention_and_reply", model.ChatMessage{RoomID: "room1", UserID: 1, MentionedUserIds: []int{2}}, room, 2, nil,
+			map[int]string{2: model.NotificationMention}},
+		{"reply", model.ChatMessage{RoomID: "room1", UserID: 1}, room, 4, nil,
+			map[int]string{4: model.NotificationReply}},
+		{"reply_to_self", model.ChatMessage{RoomID: "room1", UserID: 1}, room, 1, nil, nil},
+		{"reply_to_former_member", model.ChatMessage{RoomID: "room1", UserID: 1}, room, 99, nil, nil},
+		{"direct", model.ChatMessage{RoomID: "dm1", UserID: 1}, direct, 0, nil,
+			map[int]string{2: model.NotificationDirect}},
+		{"room_muted", model.ChatMessage{RoomID: "room1", UserID: 1, MentionedUserIds: []int{2, 3}}, room, 0,
+			map[int]model.NotificationSettings{2: {UserID: 2, MutedRooms: []string{"room1"}}},
+			map[int]string{3: model.NotificationMention}},
+		{"mute_all", model.ChatMessage{RoomID: "dm1", UserID: 1}, direct, 0,
+			map[int]model.NotificationSettings{2: {UserID: 2, MuteAll: true}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			settings := tt.settings
+			if settings == nil {
+				settings = map[int]model.NotificationSettings{}
+			}
+			mongoSvcMock.On("GetNotificationSettings", mock.Anything, mongoPkgMock).Return(settings, nil)
+			var inserted []model.Notification
+			mongoSvcMock.On("InsertNotifications", mock.Anything, mongoPkgMock).Run(func(args mock.Arguments) {
+				inserted = args.Get(0).([]model.Notification)
+			}).Return(nil)
+
+			svc := NewNotificationSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil, time.Minute)
+			tt.message.Message = "hello\nworld"
+			assert.NoError(t, svc.Enqueue("m1", tt.message, tt.room, tt.replyTo))
+
+			if tt.expectKinds == nil {
+				mongoSvcMock.AssertNotCalled(t, "InsertNotifications", mock.Anything, mock.Anything)
+				return
+			}
+			kinds := map[int]string{}
+			for _, n := range inserted {
+				kinds[n.UserID] = n.Kind
+				assert.Equal(t, "m1", n.MessageID)
+				assert.Equal(t, tt.message.RoomID, n.RoomID)
+				assert.Equal(t, 1, n.ActorID)
+				assert.Equal(t, "hello world", n.Snippet)
+				assert.Equal(t, now, n.CreatedAt)
+			}
+			assert.Equal(t, tt.expectKinds, kinds)
+		})
+	}
+}
+
+func TestEnqueueSettingsError(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("GetNotificationSettings", []int{2}, mongoPkgMock).Return(map[int]model.NotificationSettings{}, assert.AnError)
+
+	svc := NewNotificationSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil, time.Minute)
+	err := svc.Enqueue("m1", model.ChatMessage{RoomID: "room1", UserID: 1, MentionedUserIds: []int{2}}, model.Room{Members: []int{1, 2}}, 0)
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestBuildDigests(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("GetPendingNotificationUsers", now.Add(-5*time.Minute), 50, mongoPkgMock).Return([]int{1, 2, 3}, nil)
+	mongoSvcMock.On("GetNotificationSettings", []int{1, 2, 3}, mongoPkgMock).Return(map[int]model.NotificationSettings{
+		// 12:00 UTC はおやすみモードの時間帯
+		2: {UserID: 2, DNDStart: "11:00", DNDEnd: "13:00"},
+		3: {UserID: 3, DNDStart: "22:00", DNDEnd: "07:00"},
+	}, nil)
+	mongoSvcMock.On("CreateNotificationDelivery", 1, now, mongoPkgMock).Return(model.NotificationDelivery{}, true, nil)
+	mongoSvcMock.On("CreateNotificationDelivery", 3, now, mongoPkgMock).Return(model.NotificationDelivery{}, false, assert.AnError)
+
+	svc := NewNotificationSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil, 5*time.Minute)
+	err := svc.BuildDigests()
+
+	// 1人の失敗で他のユーザーのダイジェストを止めない
+	assert.ErrorIs(t, err, assert.AnError)
+	mongoSvcMock.AssertCalled(t, "CreateNotificationDelivery", 1, now, mongoPkgMock)
+	mongoSvcMock.AssertNotCalled(t, "CreateNotificationDelivery", 2, mock.Anything, mock.Anything)
+}
+
+func TestDeliverDigests(t *testing.T) {
+	m1 := primitive.NewObjectID()
+	m2 := primitive.NewObjectID()
+	notifications := []model.Notification{
+		{UserID: 2, Kind: model.NotificationMention, RoomID: "room1", MessageID: m1.Hex(), ActorID: 1, Snippet: "hi", CreatedAt: now},
+		// 通知の後に削除されたメッセージは送らない
+		{UserID: 2, Kind: model.NotificationReply, RoomID: "room1", MessageID: m2.Hex(), ActorID: 3, Snippet: "deleted", CreatedAt: now},
+	}
+
+	tests := []struct {
+		name          string
+		settings      model.NotificationSettings
+		email         string
+		attempts      int
+		notifyErr     error
+		expectStatus  string
+		expectNext    time.Time
+		expectChannel string
+		expectSent    bool
+		existing      []model.ChatMessage
+	}{
+		{"email", model.NotificationSettings{}, "bob@example.com", 0, nil, model.DeliveryDelivered, now, model.NotificationChannelEmail, true, []model.ChatMessage{{ID: m1}}},
+		{"webhook", model.NotificationSettings{Channel: model.NotificationChannelWebhook, WebhookURL: "https://example.com/hook"}, "", 0, nil, model.DeliveryDelivered, now, model.NotificationChannelWebhook, true, []model.ChatMessage{{ID: m1}}},
+		{"retry", model.NotificationSettings{}, "bob@example.com", 0, assert.AnError, model.DeliveryPending, now.Add(30 * time.Second), model.NotificationChannelEmail, false, []model.ChatMessage{{ID: m1}}},
+		// 失敗するごとに間隔を倍にする
+		{"retry_backoff", model.NotificationSettings{}, "bob@example.com", 2, assert.AnError, model.DeliveryPending, now.Add(2 * time.Minute), model.NotificationChannelEmail, false, []model.ChatMessage{{ID: m1}}},
+		{"give_up", model.NotificationSettings{}, "bob@example.com", 4, assert.AnError, model.DeliveryFailed, now, model.NotificationChannelEmail, false, []model.ChatMessage{{ID: m1}}},
+		// 宛先が無い場合は再試行しない
+		{"no_address", model.NotificationSettings{}, "", 0, notifier_pkg.ErrNoAddress, model.DeliveryFailed, now, model.NotificationChannelEmail, false, []model.ChatMessage{{ID: m1}}},
+		{"unknown_channel", model.NotificationSettings{Channel: "sms"}, "bob@example.com", 0, nil, model.DeliveryFailed, now, "sms", false, []model.ChatMessage{{ID: m1}}},
+		// 全て削除されていた場合は送らずに配信済みにする
+		{"all_deleted", model.NotificationSettings{}, "bob@example.com", 0, nil, model.DeliveryDelivered, now, model.NotificationChannelEmail, false, []model.ChatMessage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := model.NotificationDelivery{
+				ID:       primitive.NewObjectID(),
+				UserID:   2,
+				Status:   model.DeliveryPending,
+				Attempts: make([]model.DeliveryAttempt, tt.attempts),
+			}
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("ClaimDueDeliveries", now, 2*time.Minute, 50, mongoPkgMock).Return([]model.NotificationDelivery{delivery}, nil)
+			tt.settings.UserID = 2
+			mongoSvcMock.On("GetNotificationSettings", []int{2}, mongoPkgMock).Return(map[int]model.NotificationSettings{2: tt.settings}, nil)
+			mongoSvcMock.On("GetUsersByIDs", []int{2}, mongoPkgMock).Return([]model.User{{UserID: 2, Email: tt.email}}, nil)
+			mongoSvcMock.On("GetDeliveryNotifications", delivery.ID.Hex(), mongoPkgMock).Return(notifications, nil)
+			mongoSvcMock.On("GetChatMessagesByIDs", []string{m1.Hex(), m2.Hex()}, mongoPkgMock).Return(tt.existing, nil)
+			mongoSvcMock.On("RecordDeliveryAttempt", delivery.ID.Hex(), mock.MatchedBy(func(attempt model.DeliveryAttempt) bool {
+				return attempt.At == now && attempt.Channel == tt.expectChannel && (attempt.Error == "") == (tt.expectStatus == model.DeliveryDelivered)
+			}), tt.expectStatus, tt.expectNext, mongoPkgMock).Return(nil)
+
+			email := notifier_pkg.NewMemoryNotifier()
+			email.Err = tt.notifyErr
+			webhook := notifier_pkg.NewMemoryNotifier()
+			notifiers := map[string]notifier_pkg.Notifier{
+				model.NotificationChannelEmail:   email,
+				model.NotificationChannelWebhook: webhook,
+			}
+
+			svc := NewNotificationSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, notifiers, time.Minute)
+			assert.NoError(t, svc.DeliverDigests())
+			mongoSvcMock.AssertExpectations(t)
+
+			sent := append(email.Sent(), webhook.Sent()...)
+			if !tt.expectSent {
+				assert.Empty(t, sent)
+				return
+			}
+			if assert.Len(t, sent, 1) {
+				assert.Equal(t, notifier_pkg.Recipient{UserID: 2, Email: tt.email, WebhookURL: tt.settings.WebhookURL}, sent[0].Recipient)
+				assert.Equal(t, []notifier_pkg.DigestItem{
+					{Kind: model.NotificationMention, RoomID: "room1", MessageID: m1.Hex(), ActorID: 1, Snippet: "hi", CreatedAt: now},
+				}, sent[0].Digest.Items)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package presence_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/pkg/ttlstore_pkg"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PresenceSvcInterface interface {
+	// オフライン以外の状態は TTL の間だけ保持する。オフラインの場合はすぐに削除する
+	SetStatus(ctx context.Context, userID int, status string) error
+	// 状態が無いユーザーはオフラインとして返す
+	GetStatuses(ctx context.Context, userIDs []int) (map[int]string, error)
+	SetTyping(ctx context.Context, roomID string, userID int, typing bool) error
+	GetTyping(ctx context.Context, roomID string) ([]int, error)
+}
+
+type PresenceSvcStruct struct {
+	Store ttlstore_pkg.Store
+	Clock clock_svc.ClockInterface
+
+	PresenceTTL time.Duration // ハートビートの間隔より長くする
+	TypingTTL   time.Duration // 入力の終了が送られなくても表示が残り続けないようにする
+}
+
+func NewPresenceSvc(store ttlstore_pkg.Store, clock clock_svc.ClockInterface, presenceTTL time.Duration, typingTTL time.Duration) *PresenceSvcStruct {
+	return &PresenceSvcStruct{
+		Store:       store,
+		Clock:       clock,
+		PresenceTTL: presenceTTL,
+		TypingTTL:   typingTTL,
+	}
+}
+
+func presenceKey(userID int) string {
+	return "presence:" + strconv.Itoa(userID)
+}
+
+func typingPrefix(roomID string) string {
+	return "typing:" + roomID + ":"
+}
+
+func (s *PresenceSvcStruct) SetStatus(ctx context.Context, userID int, status string) error {
+	if status == model.PresenceOffline {
+		return s.Store.Delete(ctx, presenceKey(userID))
+	}
+	return s.Store.Set(ctx, presenceKey(userID), status, s.PresenceTTL, s.Clock.Now())
+}
+
+func (s *PresenceSvcStruct) GetStatuses(ctx context.Context, userIDs []int) (map[int]string, error) {
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, presenceKey(userID))
+	}
+	values, err := s.Store.GetMany(ctx, keys, s.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[int]string{}
+	for _, userID := range userIDs {
+		status, ok := values[presenceKey(userID)]
+		if !ok {
+			status = model.PresenceOffline
+		}
+		statuses[userID] = status
+	}
+	return statuses, nil
+}
+
+func (s *PresenceSvcStruct) SetTyping(ctx context.Context, roomID string, userID int, typing bool) error {
+	key := typingPrefix(roomID) + strconv.Itoa(userID)
+	if !typing {
+		return s.Store.Delete(ctx, key)
+	}
+	return s.Store.Set(ctx, key, "1", s.TypingTTL, s.Clock.Now())
+}
+
+// 入力中のユーザーを ID 順に返す
+func (s *PresenceSvcStruct) GetTyping(ctx context.Context, roomID string) ([]int, error) {
+	prefix := typingPrefix(roomID)
+	values, err := s.Store.ListPrefix(ctx, prefix, s.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []int{}
+	for key := range values {
+		userID, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+		if err != nil {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
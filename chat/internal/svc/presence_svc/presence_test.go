@@ -0,0 +1,62 @@
+package presence_svc
+
+import (
+	"context"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/ttlstore_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresence(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := ttlstore_pkg.NewMemoryStore()
+	svc := NewPresenceSvc(store, mock_clock_svc.FixedClock{FixedTime: now}, time.Minute, 5*time.Second)
+
+	assert.NoError(t, svc.SetStatus(ctx, 1, model.PresenceOnline))
+	assert.NoError(t, svc.SetStatus(ctx, 2, model.PresenceAway))
+	assert.NoError(t, svc.SetStatus(ctx, 3, model.PresenceOnline))
+	assert.NoError(t, svc.SetStatus(ctx, 3, model.PresenceOffline))
+
+	statuses, err := svc.GetStatuses(ctx, []int{1, 2, 3, 4})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{
+		1: model.PresenceOnline,
+		2: model.PresenceAway,
+		3: model.PresenceOffline,
+		4: model.PresenceOffline,
+	}, statuses)
+
+	// ハートビートが途絶えたらオフラインになる
+	later := NewPresenceSvc(store, mock_clock_svc.FixedClock{FixedTime: now.Add(time.Minute)}, time.Minute, 5*time.Second)
+	statuses, err = later.GetStatuses(ctx, []int{1})
+	assert.NoError(t, err)
+	assert.Equal(t, model.PresenceOffline, statuses[1])
+}
+
+func TestTyping(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := ttlstore_pkg.NewMemoryStore()
+	svc := NewPresenceSvc(store, mock_clock_svc.FixedClock{FixedTime: now}, time.Minute, 5*time.Second)
+
+	assert.NoError(t, svc.SetTyping(ctx, "room1", 12, true))
+	assert.NoError(t, svc.SetTyping(ctx, "room1", 3, true))
+	assert.NoError(t, svc.SetTyping(ctx, "room10", 4, true))
+	assert.NoError(t, svc.SetTyping(ctx, "room1", 5, true))
+	assert.NoError(t, svc.SetTyping(ctx, "room1", 5, false))
+
+	// 前方一致で別のルームを含めない
+	userIDs, err := svc.GetTyping(ctx, "room1")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 12}, userIDs)
+
+	later := NewPresenceSvc(store, mock_clock_svc.FixedClock{FixedTime: now.Add(5 * time.Second)}, time.Minute, 5*time.Second)
+	userIDs, err = later.GetTyping(ctx, "room1")
+	assert.NoError(t, err)
+	assert.Empty(t, userIDs)
+}
@@ -0,0 +1,203 @@
+package purge_svc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/storage_pkg"
+	"time"
+)
+
+type PurgeSvcInterface interface {
+	PurgeDeletedChatMessages() error
+	PurgeExpiredChatMessages() error
+	PurgeAttachmentBlobs() error
+}
+
+type PurgeSvcStruct struct {
+	MongoSvc  mongo_svc.MongoSvcInterface
+	MongoPkg  mongo_pkg.MongoPkgInterface
+	Clock     clock_svc.ClockInterface
+	Storage   storage_pkg.BlobStorageInterface
+	Retention time.Duration // 論理削除されたメッセージを保持する期間
+	BatchSize int           // メッセージを一度に削除する件数
+}
+
+func NewPurgeSvc(
+	mongoSvc mongo_svc.MongoSvcInterface,
+	mongoPkg mongo_pkg.MongoPkgInterface,
+	clock clock_svc.ClockInterface,
+	storage storage_pkg.BlobStorageInterface,
+	retention time.Duration,
+) *PurgeSvcStruct {
+	return &PurgeSvcStruct{
+		MongoSvc:  mongoSvc,
+		MongoPkg:  mongoPkg,
+		Clock:     clock,
+		Storage:   storage,
+		Retention: retention,
+		BatchSize: 500,
+	}
+}
+
+// 論理削除から保持期間を過ぎたメッセージを BatchSize 件ずつ削除する
+func (s *PurgeSvcStruct) PurgeDeletedChatMessages() error {
+	before := s.Clock.Now().Add(-s.Retention)
+
+	var total int64
+	var purgeErr error
+	for {
+		count, err := s.MongoSvc.PurgeDeletedChatMessages(before, s.BatchSize, s.MongoPkg)
+		total += count
+		if err != nil {
+			purgeErr = err
+			break
+		}
+		if count < int64(s.BatchSize) {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("purged %d deleted chat messages (deleted before %s)", total, before)
+	}
+	return purgeErr
+}
+
+// 保持期間が設定されたルームごとに、期間を過ぎたメッセージを BatchSize 件ずつ削除する
+// TTL インデックスによる削除は遅延することがあり、ピン留めなどの参照も残るため定期的に実行する
+// 保持期間が変更されたルームは、先に既存のメッセージの削除予定日時を BatchSize 件ずつ計算し直す
+// エクスポートしたファイルにも同じ保持期間を適用する
+func (s *PurgeSvcStruct) PurgeExpiredChatMessages() error {
+	now := s.Clock.Now()
+	var errs []error
+	if err := s.restampRooms(now); err != nil {
+		errs = append(errs, err)
+	}
+
+	rooms, err := s.MongoSvc.GetRetentionRooms(s.MongoPkg)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	for _, room := range rooms {
+		if err := s.purgeRoom(room, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *PurgeSvcStruct) restampRooms(now time.Time) error {
+	rooms, err := s.MongoSvc.GetRestampPendingRooms(s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, room := range rooms {
+		roomID := room.ID.Hex()
+		var total int64
+		for {
+			count, err := s.MongoSvc.RestampChatMessageExpiry(roomID, room.RetentionDays, now, s.BatchSize, s.MongoPkg)
+			total += count
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			if count < int64(s.BatchSize) {
+				break
+			}
+		}
+		if total > 0 {
+			log.Printf("restamped expiry of %d chat messages in room %s (retention %d days)", total, roomID, room.RetentionDays)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *PurgeSvcStruct) purgeRoom(room model.Room, now time.Time) error {
+	roomID := room.ID.Hex()
+	before := model.RetentionCutoff(now, room.RetentionDays)
+
+	var total int64
+	for {
+		count, err := s.MongoSvc.PurgeExpiredChatMessages(roomID, before, s.BatchSize, s.MongoPkg)
+		total += count
+		if err != nil {
+			return err
+		}
+		if count < int64(s.BatchSize) {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("purged %d expired chat messages in room %s (posted before %s)", total, roomID, before)
+	}
+
+	// TTL インデックスで先に削除されたメッセージへの参照も取り除く
+	total = 0
+	for {
+		count, err := s.MongoSvc.PurgeExpiredChatMessageReferences(roomID, before, s.BatchSize, s.MongoPkg)
+		total += count
+		if err != nil {
+			return err
+		}
+		if count < int64(s.BatchSize) {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("purged %d references to expired chat messages in room %s", total, roomID)
+	}
+
+	count, err := s.MongoSvc.PurgeExpiredRoomExports(roomID, before, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("purged %d expired room exports in room %s", count, roomID)
+	}
+	return nil
+}
+
+// 物理削除したメッセージの添付ファイルをストレージから削除する
+// 削除に失敗したものは削除待ちに残し、次の実行で再度削除する
+func (s *PurgeSvcStruct) PurgeAttachmentBlobs() error {
+	deletions, err := s.MongoSvc.GetBlobDeletions(s.BatchSize, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var errs []error
+	var total int
+	for _, deletion := range deletions {
+		if err := s.deleteBlobs(ctx, deletion.StorageKeys); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.MongoSvc.DeleteBlobDeletion(deletion.ID.Hex(), s.MongoPkg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		total += len(deletion.StorageKeys)
+	}
+	if total > 0 {
+		log.Printf("purged %d attachment blobs", total)
+	}
+	return errors.Join(errs...)
+}
+
+// 削除済みのキーを再度削除してもエラーにならないので、途中で失敗しても最初からやり直せる
+func (s *PurgeSvcStruct) deleteBlobs(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := s.Storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
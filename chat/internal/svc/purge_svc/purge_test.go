@@ -0,0 +1,212 @@
+package purge_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_storage_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPurgeDeletedChatMessages(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		counts    []int64 // 1回の削除ごとの件数
+		purgeErr  error
+		returnErr bool
+	}{
+		// BatchSize 件削除できた場合は残りがなくなるまで繰り返す
+		{"multiple_batches", []int64{2, 2, 1}, nil, false},
+		{"nothing_to_purge", []int64{0}, nil, false},
+		{"error", []int64{2}, assert.AnError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			for _, count := range tt.counts {
+				mongoSvcMock.On("PurgeDeletedChatMessages", before, 2, mongoPkgMock).Return(count, nil).Once()
+			}
+			if tt.purgeErr != nil {
+				mongoSvcMock.On("PurgeDeletedChatMessages", before, 2, mongoPkgMock).Return(int64(0), tt.purgeErr).Once()
+			}
+
+			svc := NewPurgeSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, new(mock_storage_pkg.BlobStorageMock), 24*time.Hour)
+			svc.BatchSize = 2
+			err := svc.PurgeDeletedChatMessages()
+
+			if (err != nil) != tt.returnErr {
+				t.Errorf("PurgeDeletedChatMessages() [%s] error = %v", tt.name, err)
+			}
+			mongoSvcMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPurgeExpiredChatMessages(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	room1 := model.Room{ID: primitive.NewObjectID(), RetentionDays: 30}
+	room2 := model.Room{ID: primitive.NewObjectID(), RetentionDays: 7}
+
+	tests := []struct {
+		name        string
+		roomsErr    error
+		room1Counts []int64 // 1回の削除ごとの件数
+		room1Err    error
+		refCounts   []int64 // 1回の参照の削除ごとの件数
+		returnErr   bool
+	}{
+		// BatchSize 件削除できた場合は残りがなくなるまで繰り返す
+		{"multiple_batches", nil, []int64{2, 2, 1}, nil, []int64{0}, false},
+		// TTL インデックスで削除済みのメッセージへの参照も取り除く
+		{"nothing_to_purge", nil, []int64{0}, nil, []int64{2, 1}, false},
+		// 失敗したルームがあっても他のルームは削除する
+		{"room_error", nil, nil, assert.AnError, nil, true},
+		{"rooms_error", assert.AnError, nil, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("GetRestampPendingRooms", mongoPkgMock).Return([]model.Room{}, nil)
+			mongoSvcMock.On("GetRetentionRooms", mongoPkgMock).Return([]model.Room{room1, room2}, tt.roomsErr)
+			before1 := now.Add(-30 * 24 * time.Hour)
+			for _, count := range tt.room1Counts {
+				mongoSvcMock.On("PurgeExpiredChatMessages", room1.ID.Hex(), before1, 2, mongoPkgMock).Return(count, nil).Once()
+			}
+			if tt.room1Err != nil {
+				mongoSvcMock.On("PurgeExpiredChatMessages", room1.ID.Hex(), before1, 2, mongoPkgMock).Return(int64(0), tt.room1Err).Once()
+			}
+			for _, count := range tt.refCounts {
+				mongoSvcMock.On("PurgeExpiredChatMessageReferences", room1.ID.Hex(), before1, 2, mongoPkgMock).Return(count, nil).Once()
+			}
+			mongoSvcMock.On("PurgeExpiredChatMessages", room2.ID.Hex(), now.Add(-7*24*time.Hour), 2, mongoPkgMock).Return(int64(1), nil)
+			mongoSvcMock.On("PurgeExpiredChatMessageReferences", room2.ID.Hex(), now.Add(-7*24*time.Hour), 2, mongoPkgMock).Return(int64(0), nil)
+			// エクスポートも同じ保持期間で削除する
+			if tt.room1Err == nil {
+				mongoSvcMock.On("PurgeExpiredRoomExports", room1.ID.Hex(), before1, mongoPkgMock).Return(int64(1), nil)
+			}
+			mongoSvcMock.On("PurgeExpiredRoomExports", room2.ID.Hex(), now.Add(-7*24*time.Hour), mongoPkgMock).Return(int64(0), nil)
+
+			svc := NewPurgeSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, new(mock_storage_pkg.BlobStorageMock), 24*time.Hour)
+			svc.BatchSize = 2
+			err := svc.PurgeExpiredChatMessages()
+
+			if (err != nil) != tt.returnErr {
+				t.Errorf("PurgeExpiredChatMessages() [%s] error = %v", tt.name, err)
+			}
+			if tt.roomsErr != nil {
+				mongoSvcMock.AssertNotCalled(t, "PurgeExpiredChatMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			mongoSvcMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPurgeExpiredChatMessagesRestamp(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	room1 := model.Room{ID: primitive.NewObjectID(), RetentionDays: 30}
+	room2 := model.Room{ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name        string
+		roomsErr    error
+		room1Counts []int64 // 1回の計算し直しごとの件数
+		room1Err    error
+		returnErr   bool
+	}{
+		// BatchSize 件計算し直せた場合は残りがなくなるまで繰り返す
+		{"multiple_batches", nil, []int64{2, 2, 1}, nil, false},
+		// 失敗しても保持期間を過ぎたメッセージの削除は続ける
+		{"room_error", nil, nil, assert.AnError, true},
+		{"rooms_error", assert.AnError, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("GetRestampPendingRooms", mongoPkgMock).Return([]model.Room{room1, room2}, tt.roomsErr)
+			for _, count := range tt.room1Counts {
+				mongoSvcMock.On("RestampChatMessageExpiry", room1.ID.Hex(), 30, now, 2, mongoPkgMock).Return(count, nil).Once()
+			}
+			if tt.room1Err != nil {
+				mongoSvcMock.On("RestampChatMessageExpiry", room1.ID.Hex(), 30, now, 2, mongoPkgMock).Return(int64(0), tt.room1Err).Once()
+			}
+			mongoSvcMock.On("RestampChatMessageExpiry", room2.ID.Hex(), 0, now, 2, mongoPkgMock).Return(int64(0), nil)
+			mongoSvcMock.On("GetRetentionRooms", mongoPkgMock).Return([]model.Room{room1}, nil)
+			mongoSvcMock.On("PurgeExpiredChatMessages", room1.ID.Hex(), now.Add(-30*24*time.Hour), 2, mongoPkgMock).Return(int64(0), nil)
+			mongoSvcMock.On("PurgeExpiredChatMessageReferences", room1.ID.Hex(), now.Add(-30*24*time.Hour), 2, mongoPkgMock).Return(int64(0), nil)
+			mongoSvcMock.On("PurgeExpiredRoomExports", room1.ID.Hex(), now.Add(-30*24*time.Hour), mongoPkgMock).Return(int64(0), nil)
+
+			svc := NewPurgeSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, new(mock_storage_pkg.BlobStorageMock), 24*time.Hour)
+			svc.BatchSize = 2
+			err := svc.PurgeExpiredChatMessages()
+
+			if (err != nil) != tt.returnErr {
+				t.Errorf("PurgeExpiredChatMessages() [%s] error = %v", tt.name, err)
+			}
+			mongoSvcMock.AssertCalled(t, "PurgeExpiredChatMessages", room1.ID.Hex(), now.Add(-30*24*time.Hour), 2, mongoPkgMock)
+			if tt.roomsErr != nil {
+				mongoSvcMock.AssertNotCalled(t, "RestampChatMessageExpiry", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			mongoSvcMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPurgeAttachmentBlobs(t *testing.T) {
+	deletion1 := model.BlobDeletion{ID: primitive.NewObjectID(), StorageKeys: []string{"rooms/r1/attachments/a1", "rooms/r1/attachments/a1/thumbnails/160"}}
+	deletion2 := model.BlobDeletion{ID: primitive.NewObjectID(), StorageKeys: []string{"rooms/r1/attachments/a2"}}
+
+	tests := []struct {
+		name         string
+		getErr       error
+		storageErr   error // deletion2 のキーの削除結果
+		expectDelete []string
+		returnErr    bool
+	}{
+		{"success", nil, nil, []string{deletion1.ID.Hex(), deletion2.ID.Hex()}, false},
+		// 削除に失敗したものは削除待ちに残し、他は削除する
+		{"storage_error", nil, assert.AnError, []string{deletion1.ID.Hex()}, true},
+		{"get_error", assert.AnError, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("GetBlobDeletions", 500, mongoPkgMock).Return([]model.BlobDeletion{deletion1, deletion2}, tt.getErr)
+			mongoSvcMock.On("DeleteBlobDeletion", mock.Anything, mongoPkgMock).Return(nil)
+
+			storageMock := new(mock_storage_pkg.BlobStorageMock)
+			storageMock.On("Delete", mock.Anything, "rooms/r1/attachments/a1").Return(nil)
+			storageMock.On("Delete", mock.Anything, "rooms/r1/attachments/a1/thumbnails/160").Return(nil)
+			storageMock.On("Delete", mock.Anything, "rooms/r1/attachments/a2").Return(tt.storageErr)
+
+			svc := NewPurgeSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{}, storageMock, 24*time.Hour)
+			err := svc.PurgeAttachmentBlobs()
+			if (err != nil) != tt.returnErr {
+				t.Errorf("PurgeAttachmentBlobs() [%s] error = %v", tt.name, err)
+			}
+
+			mongoSvcMock.AssertNumberOfCalls(t, "DeleteBlobDeletion", len(tt.expectDelete))
+			for _, id := range tt.expectDelete {
+				mongoSvcMock.AssertCalled(t, "DeleteBlobDeletion", id, mongoPkgMock)
+			}
+		})
+	}
+}
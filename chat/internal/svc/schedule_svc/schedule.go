@@ -0,0 +1,134 @@
+package schedule_svc
+
+import (
+	"errors"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"time"
+)
+
+var (
+	ErrMessageDeleted = errors.New("message has been deleted")
+	ErrNotRoomMember  = errors.New("user is no longer a member of the room")
+)
+
+type ScheduleSvcInterface interface {
+	RunScheduledMessages() error
+}
+
+// 予約投稿を通常の投稿と同じ処理で投稿する（メンションの解決・通知・Webhook を含む）
+type PosterInterface interface {
+	PostScheduledMessage(scheduled model.ScheduledMessage) (string, error)
+}
+
+type ScheduleSvcStruct struct {
+	MongoSvc mongo_svc.MongoSvcInterface
+	MongoPkg mongo_pkg.MongoPkgInterface
+	Clock    clock_svc.ClockInterface
+	Poster   PosterInterface
+
+	Lease     time.Duration // これを過ぎても完了しない予約は失敗にする
+	BatchSize int
+}
+
+func NewScheduleSvc(
+	mongoSvc mongo_svc.MongoSvcInterface,
+	mongoPkg mongo_pkg.MongoPkgInterface,
+	clock clock_svc.ClockInterface,
+	poster PosterInterface,
+) *ScheduleSvcStruct {
+	return &ScheduleSvcStruct{
+		MongoSvc:  mongoSvc,
+		MongoPkg:  mongoPkg,
+		Clock:     clock,
+		Poster:    poster,
+		Lease:     time.Minute,
+		BatchSize: 50,
+	}
+}
+
+// 実行日時になった予約投稿とリマインダーを実行する。失敗した予約は再実行せずに失敗として残す
+func (s *ScheduleSvcStruct) RunScheduledMessages() error {
+	now := s.Clock.Now()
+	expired, err := s.MongoSvc.ExpireScheduledMessageLeases(now, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		log.Printf("marked %d scheduled messages as failed after their lease expired", expired)
+	}
+
+	// まとめて取得すると後の予約の実行前に lease が切れるので、1件ずつ取得して実行する
+	var errs []error
+	for i := 0; i < s.BatchSize; i++ {
+		scheduled, ok, err := s.MongoSvc.ClaimDueScheduledMessage(s.Clock.Now(), s.Lease, s.MongoPkg)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if !ok {
+			break
+		}
+		if err := s.run(scheduled); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ScheduleSvcStruct) run(scheduled model.ScheduledMessage) error {
+	now := s.Clock.Now()
+	var postedMessageID string
+	var err error
+	switch scheduled.Kind {
+	case model.ScheduledKindMessage:
+		postedMessageID, err = s.Poster.PostScheduledMessage(scheduled)
+	case model.ScheduledKindReminder:
+		err = s.remind(now, scheduled)
+	default:
+		err = errors.New("unknown scheduled message kind: " + scheduled.Kind)
+	}
+
+	status, errMessage := model.ScheduledSent, ""
+	if err != nil {
+		log.Printf("scheduled %s %s for user %d failed: %v", scheduled.Kind, scheduled.ID.Hex(), scheduled.UserID, err)
+		status, errMessage = model.ScheduledFailed, err.Error()
+	}
+	err = s.MongoSvc.CompleteScheduledMessage(scheduled.ID.Hex(), status, postedMessageID, errMessage, now, s.MongoPkg)
+	if errors.Is(err, mongo_svc.ErrScheduledLeaseExpired) {
+		// lease が切れて失敗にされた後に完了した。投稿は済んでいる場合があるので記録に残す
+		log.Printf("scheduled %s %s for user %d completed as %s after its lease expired (posted message %q)", scheduled.Kind, scheduled.ID.Hex(), scheduled.UserID, status, postedMessageID)
+	}
+	return err
+}
+
+// リマインダーは本人への通知として登録し、通知の設定に従って配信する
+func (s *ScheduleSvcStruct) remind(now time.Time, scheduled model.ScheduledMessage) error {
+	message, err := s.MongoSvc.GetChatMessage(scheduled.MessageID, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if message.DeletedAt != nil {
+		return ErrMessageDeleted
+	}
+	room, err := s.MongoSvc.GetRoomByID(message.RoomID, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if room.RoleOf(scheduled.UserID) == "" {
+		return ErrNotRoomMember
+	}
+
+	return s.MongoSvc.InsertNotifications([]model.Notification{{
+		UserID:    scheduled.UserID,
+		Kind:      model.NotificationReminder,
+		RoomID:    message.RoomID,
+		MessageID: scheduled.MessageID,
+		ActorID:   message.UserID,
+		Snippet:   model.MessageSnippet(message.Message),
+		CreatedAt: now,
+	}}, s.MongoPkg)
+}
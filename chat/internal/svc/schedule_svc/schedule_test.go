@@ -0,0 +1,165 @@
+package schedule_svc
+
+import (
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"microservices/chat/tests/mocks/svc/mock_schedule_svc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRunScheduledMessages(t *testing.T) {
+	tests := []struct {
+		name         string
+		postID       string
+		postErr      error
+		expectStatus string
+		expectPosted string
+		expectError  string
+	}{
+		{"sent", "message1", nil, model.ScheduledSent, "message1", ""},
+		// 投稿に失敗した予約は再実行しない
+		{"failed", "", assert.AnError, model.ScheduledFailed, "", assert.AnError.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := model.ScheduledMessage{ID: primitive.NewObjectID(), Kind: model.ScheduledKindMessage, RoomID: "room1", UserID: 1, Message: "hi"}
+
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("ExpireScheduledMessageLeases", now, mongoPkgMock).Return(int64(0), nil)
+			mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(scheduled, true, nil).Once()
+			mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(model.ScheduledMessage{}, false, nil).Once()
+			mongoSvcMock.On("CompleteScheduledMessage", scheduled.ID.Hex(), tt.expectStatus, tt.expectPosted, tt.expectError, now, mongoPkgMock).Return(nil)
+			poster := new(mock_schedule_svc.PosterMock)
+			poster.On("PostScheduledMessage", scheduled).Return(tt.postID, tt.postErr)
+
+			svc := NewScheduleSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, poster)
+			assert.NoError(t, svc.RunScheduledMessages())
+			mongoSvcMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunScheduledMessagesAdvancesClock(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	poster := new(mock_schedule_svc.PosterMock)
+	clock := &mock_clock_svc.FixedClock{FixedTime: now}
+	svc := NewScheduleSvc(mongoSvcMock, mongoPkgMock, clock, poster)
+
+	// 実行日時より前は何も取得しない
+	mongoSvcMock.On("ExpireScheduledMessageLeases", mock.Anything, mongoPkgMock).Return(int64(0), nil)
+	mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(model.ScheduledMessage{}, false, nil).Once()
+	assert.NoError(t, svc.RunScheduledMessages())
+	poster.AssertNotCalled(t, "PostScheduledMessage", mock.Anything)
+
+	later := now.Add(2 * time.Hour)
+	clock.FixedTime = later
+	scheduled := model.ScheduledMessage{ID: primitive.NewObjectID(), Kind: model.ScheduledKindMessage, SendAt: later}
+	mongoSvcMock.On("ClaimDueScheduledMessage", later, time.Minute, mongoPkgMock).Return(scheduled, true, nil).Once()
+	mongoSvcMock.On("ClaimDueScheduledMessage", later, time.Minute, mongoPkgMock).Return(model.ScheduledMessage{}, false, nil).Once()
+	mongoSvcMock.On("CompleteScheduledMessage", scheduled.ID.Hex(), model.ScheduledSent, "message1", "", later, mongoPkgMock).Return(nil)
+	poster.On("PostScheduledMessage", scheduled).Return("message1", nil)
+	assert.NoError(t, svc.RunScheduledMessages())
+	poster.AssertNumberOfCalls(t, "PostScheduledMessage", 1)
+}
+
+func TestRunScheduledReminders(t *testing.T) {
+	deletedAt := now
+	messageID := primitive.NewObjectID()
+	room := model.Room{OwnerID: 2, Members: []int{1, 2}}
+
+	tests := []struct {
+		name         string
+		message      model.ChatMessage
+		room         model.Room
+		expectStatus string
+		expectError  string
+	}{
+		{"sent", model.ChatMessage{ID: messageID, RoomID: "room1", UserID: 2, Message: "deploy at 5"}, room, model.ScheduledSent, ""},
+		{"deleted_message", model.ChatMessage{ID: messageID, RoomID: "room1", DeletedAt: &deletedAt}, room, model.ScheduledFailed, ErrMessageDeleted.Error()},
+		{"left_room", model.ChatMessage{ID: messageID, RoomID: "room1"}, model.Room{OwnerID: 2, Members: []int{2}}, model.ScheduledFailed, ErrNotRoomMember.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled := model.ScheduledMessage{ID: primitive.NewObjectID(), Kind: model.ScheduledKindReminder, RoomID: "room1", UserID: 1, MessageID: messageID.Hex()}
+
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("ExpireScheduledMessageLeases", now, mongoPkgMock).Return(int64(0), nil)
+			mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(scheduled, true, nil).Once()
+			mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(model.ScheduledMessage{}, false, nil).Once()
+			mongoSvcMock.On("GetChatMessage", messageID.Hex(), mongoPkgMock).Return(tt.message, nil)
+			mongoSvcMock.On("GetRoomByID", "room1", mongoPkgMock).Return(tt.room, nil)
+			var inserted []model.Notification
+			mongoSvcMock.On("InsertNotifications", mock.Anything, mongoPkgMock).Run(func(args mock.Arguments) {
+				inserted = args.Get(0).([]model.Notification)
+			}).Return(nil)
+			mongoSvcMock.On("CompleteScheduledMessage", scheduled.ID.Hex(), tt.expectStatus, "", tt.expectError, now, mongoPkgMock).Return(nil)
+
+			svc := NewScheduleSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil)
+			assert.NoError(t, svc.RunScheduledMessages())
+			mongoSvcMock.AssertCalled(t, "CompleteScheduledMessage", scheduled.ID.Hex(), tt.expectStatus, "", tt.expectError, now, mongoPkgMock)
+
+			if tt.expectStatus == model.ScheduledSent {
+				assert.Equal(t, []model.Notification{{
+					UserID:    1,
+					Kind:      model.NotificationReminder,
+					RoomID:    "room1",
+					MessageID: messageID.Hex(),
+					ActorID:   2,
+					Snippet:   "deploy at 5",
+					CreatedAt: now,
+				}}, inserted)
+			} else {
+				assert.Empty(t, inserted)
+			}
+		})
+	}
+}
+
+func TestRunScheduledMessagesClaimError(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("ExpireScheduledMessageLeases", now, mongoPkgMock).Return(int64(1), nil)
+	mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(model.ScheduledMessage{}, false, assert.AnError)
+
+	svc := NewScheduleSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil)
+	assert.ErrorIs(t, svc.RunScheduledMessages(), assert.AnError)
+	mongoSvcMock.AssertNumberOfCalls(t, "ClaimDueScheduledMessage", 1)
+}
+
+// 1件ずつ lease を取得し、lease が切れた後に完了した予約はエラーとして返す
+func TestRunScheduledMessagesLeaseExpired(t *testing.T) {
+	expired := model.ScheduledMessage{ID: primitive.NewObjectID(), Kind: model.ScheduledKindMessage}
+	next := model.ScheduledMessage{ID: primitive.NewObjectID(), Kind: model.ScheduledKindMessage}
+
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("ExpireScheduledMessageLeases", now, mongoPkgMock).Return(int64(0), nil)
+	mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(expired, true, nil).Once()
+	mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(next, true, nil).Once()
+	mongoSvcMock.On("ClaimDueScheduledMessage", now, time.Minute, mongoPkgMock).Return(model.ScheduledMessage{}, false, nil).Once()
+	mongoSvcMock.On("CompleteScheduledMessage", expired.ID.Hex(), model.ScheduledSent, "message1", "", now, mongoPkgMock).Return(mongo_svc.ErrScheduledLeaseExpired)
+	mongoSvcMock.On("CompleteScheduledMessage", next.ID.Hex(), model.ScheduledSent, "message2", "", now, mongoPkgMock).Return(nil)
+	poster := new(mock_schedule_svc.PosterMock)
+	poster.On("PostScheduledMessage", expired).Return("message1", nil)
+	poster.On("PostScheduledMessage", next).Return("message2", nil)
+
+	svc := NewScheduleSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, poster)
+	assert.ErrorIs(t, svc.RunScheduledMessages(), mongo_svc.ErrScheduledLeaseExpired)
+	// 失敗しても残りの予約は実行する
+	mongoSvcMock.AssertExpectations(t)
+}
@@ -0,0 +1,145 @@
+package thumbnail_svc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 左半分が赤、右半分が青の画像
+func halfImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// SOI の直後に Orientation だけを持つ EXIF（APP1）を差し込んだ JPEG
+func encodeJPEGWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	exif := []byte{0xFF, 0xE1}
+	exif = binary.BigEndian.AppendUint16(exif, uint16(len(segment)+2))
+	exif = append(exif, segment...)
+	return append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+}
+
+func TestJpegOrientation(t *testing.T) {
+	img := halfImage(8, 8)
+	for orientation := uint16(1); orientation <= 8; orientation++ {
+		assert.Equal(t, int(orientation), jpegOrientation(encodeJPEGWithOrientation(t, img, orientation)))
+	}
+
+	var plain bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&plain, img, nil))
+	assert.Equal(t, 1, jpegOrientation(plain.Bytes()))
+	assert.Equal(t, 1, jpegOrientation([]byte("not a jpeg")))
+	assert.Equal(t, 1, jpegOrientation(encodeJPEGWithOrientation(t, img, 9)))
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name             string
+		width, height    int
+		size             int
+		expectW, expectH int
+	}{
+		{"landscape", 1000, 500, 160, 160, 80},
+		{"portrait", 500, 1000, 160, 80, 160},
+		{"smaller_than_size", 100, 50, 160, 100, 50},
+		{"thin", 5000, 1, 160, 160, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := fit(tt.width, tt.height, tt.size)
+			assert.Equal(t, tt.expectW, w)
+			assert.Equal(t, tt.expectH, h)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	images, contentType, err := Render(encodePNG(t, halfImage(1000, 500)), []int{160, 480})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	if assert.Len(t, images, 2) {
+		assert.Equal(t, 160, images[0].Width)
+		assert.Equal(t, 80, images[0].Height)
+		assert.Equal(t, 480, images[1].Width)
+		assert.Equal(t, 240, images[1].Height)
+
+		decoded, err := png.Decode(bytes.NewReader(images[0].Data))
+		assert.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 160, 80), decoded.Bounds())
+		r, _, b, _ := decoded.At(10, 40).RGBA()
+		assert.True(t, r > b)
+	}
+}
+
+func TestRenderJPEGOrientation(t *testing.T) {
+	// 時計回りに90度回転して表示する写真
+	data := encodeJPEGWithOrientation(t, halfImage(40, 20), 6)
+
+	images, contentType, err := Render(data, []int{480})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+	if !assert.Len(t, images, 1) {
+		return
+	}
+	assert.Equal(t, 20, images[0].Width)
+	assert.Equal(t, 40, images[0].Height)
+	// EXIF は取り除かれている
+	assert.NotContains(t, string(images[0].Data), "Exif")
+	assert.Equal(t, 1, jpegOrientation(images[0].Data))
+
+	// 回転後は上半分が赤、下半分が青になる
+	decoded, err := jpeg.Decode(bytes.NewReader(images[0].Data))
+	assert.NoError(t, err)
+	r, _, b, _ := decoded.At(10, 5).RGBA()
+	assert.True(t, r > b)
+	r, _, b, _ = decoded.At(10, 35).RGBA()
+	assert.True(t, b > r)
+}
+
+func TestRenderNotPreviewable(t *testing.T) {
+	_, _, err := Render([]byte("not an image"), DefaultSizes)
+	assert.ErrorIs(t, err, ErrNotPreviewable)
+
+	// IHDR の幅と高さだけを書き換えた巨大な画像
+	huge := encodePNG(t, halfImage(2, 2))
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 10000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	_, _, err = Render(huge, DefaultSizes)
+	assert.ErrorIs(t, err, ErrNotPreviewable)
+}
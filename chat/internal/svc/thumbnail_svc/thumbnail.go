@@ -0,0 +1,177 @@
+package thumbnail_svc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/storage_pkg"
+)
+
+// 生成するサムネイルの長辺の上限
+var DefaultSizes = []int{160, 480}
+
+// 展開後のメモリ使用量を抑えるため、これより画素数の多い画像は扱わない
+const maxPixels = 40_000_000
+
+const jpegQuality = 85
+
+// プレビューを作れない画像（デコードできない・大きすぎるなど）
+var ErrNotPreviewable = errors.New("attachment is not previewable")
+
+type ThumbnailSvcInterface interface {
+	GenerateThumbnails() error
+}
+
+type ThumbnailSvcStruct struct {
+	MongoSvc  mongo_svc.MongoSvcInterface
+	MongoPkg  mongo_pkg.MongoPkgInterface
+	Storage   storage_pkg.BlobStorageInterface
+	Sizes     []int
+	BatchSize int // 1回の実行で処理するメッセージ数
+}
+
+func NewThumbnailSvc(
+	mongoSvc mongo_svc.MongoSvcInterface,
+	mongoPkg mongo_pkg.MongoPkgInterface,
+	storage storage_pkg.BlobStorageInterface,
+	sizes []int,
+	batchSize int,
+) *ThumbnailSvcStruct {
+	return &ThumbnailSvcStruct{
+		MongoSvc:  mongoSvc,
+		MongoPkg:  mongoPkg,
+		Storage:   storage,
+		Sizes:     sizes,
+		BatchSize: batchSize,
+	}
+}
+
+// 生成待ちの添付画像のサムネイルを作る。
+// 同じ添付ファイルを複数回処理しても同じキーに上書きするだけなので、複数のワーカーが動いていても問題ない
+func (s *ThumbnailSvcStruct) GenerateThumbnails() error {
+	messages, err := s.MongoSvc.GetPendingPreviewMessages(s.BatchSize, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, message := range messages {
+		for _, attachment := range message.Attachments {
+			if attachment.PreviewStatus != model.PreviewPending {
+				continue
+			}
+			status := model.PreviewReady
+			thumbnails, err := s.createThumbnails(context.Background(), attachment)
+			if errors.Is(err, ErrNotPreviewable) || errors.Is(err, storage_pkg.ErrBlobNotFound) {
+				// 投稿自体は成功しているので、プレビューなしとして扱う
+				log.Printf("attachment %s of message %s is not previewable: %v", attachment.ID, message.ID.Hex(), err)
+				status = model.PreviewUnavailable
+				thumbnails = nil
+			} else if err != nil {
+				// ストレージの一時的なエラーなどは次回やり直す
+				errs = append(errs, err)
+				continue
+			}
+			if err := s.MongoSvc.SetAttachmentPreview(message.ID.Hex(), attachment.ID, status, thumbnails, s.MongoPkg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ThumbnailSvcStruct) createThumbnails(ctx context.Context, attachment model.Attachment) ([]model.Thumbnail, error) {
+	body, err := s.Storage.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	images, contentType, err := Render(data, s.Sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnails := make([]model.Thumbnail, 0, len(images))
+	for i, encoded := range images {
+		thumbnail := model.Thumbnail{
+			Size:        s.Sizes[i],
+			Width:       encoded.Width,
+			Height:      encoded.Height,
+			ContentType: contentType,
+			StorageKey:  model.ThumbnailStorageKey(attachment.StorageKey, s.Sizes[i]),
+		}
+		if err := s.Storage.Put(ctx, thumbnail.StorageKey, bytes.NewReader(encoded.Data), int64(len(encoded.Data)), contentType); err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+	return thumbnails, nil
+}
+
+// エンコード済みのサムネイル
+type EncodedImage struct {
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// 画像を sizes ごとに縮小してエンコードする。
+// 再エンコードするので EXIF などのメタデータは含まれない（向きだけは反映する）
+func Render(data []byte, sizes []int) ([]EncodedImage, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrNotPreviewable, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d", ErrNotPreviewable, config.Width, config.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrNotPreviewable, err)
+	}
+
+	orientation := 1
+	contentType := "image/png"
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+		contentType = "image/jpeg"
+	}
+
+	src := toRGBA(decoded)
+	width, height := orientedSize(src.Bounds().Dx(), src.Bounds().Dy(), orientation)
+
+	images := make([]EncodedImage, 0, len(sizes))
+	for _, size := range sizes {
+		// 向きを補正した後の大きさで収めてから、元の向きで縮小する
+		dstWidth, dstHeight := fit(width, height, size)
+		srcWidth, srcHeight := orientedSize(dstWidth, dstHeight, orientation)
+		resized := orient(resize(src, srcWidth, srcHeight), orientation)
+
+		var buf bytes.Buffer
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		images = append(images, EncodedImage{Width: dstWidth, Height: dstHeight, Data: buf.Bytes()})
+	}
+	return images, contentType, nil
+}
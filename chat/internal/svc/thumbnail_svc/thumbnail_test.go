@@ -0,0 +1,97 @@
+package thumbnail_svc
+
+import (
+	"bytes"
+	"io"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/storage_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_storage_pkg"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGenerateThumbnails(t *testing.T) {
+	messageID := primitive.NewObjectID()
+	pending := model.Attachment{ID: "a1", StorageKey: "rooms/r1/attachments/a1", PreviewStatus: model.PreviewPending}
+	png := encodePNG(t, halfImage(1000, 500))
+
+	tests := []struct {
+		name         string
+		attachments  []model.Attachment
+		body         []byte
+		getErr       error
+		putErr       error
+		setErr       error
+		expectStatus string
+		expectSet    bool
+		returnErr    bool
+	}{
+		{"success", []model.Attachment{pending}, png, nil, nil, nil, model.PreviewReady, true, false},
+		// デコードできない画像は投稿を失敗させずにプレビューなしにする
+		{"undecodable", []model.Attachment{pending}, []byte("broken"), nil, nil, nil, model.PreviewUnavailable, true, false},
+		{"blob_not_found", []model.Attachment{pending}, nil, storage_pkg.ErrBlobNotFound, nil, nil, model.PreviewUnavailable, true, false},
+		{"get_error", []model.Attachment{pending}, nil, assert.AnError, nil, nil, "", false, true},
+		{"put_error", []model.Attachment{pending}, png, nil, assert.AnError, nil, "", false, true},
+		{"set_error", []model.Attachment{pending}, png, nil, nil, assert.AnError, model.PreviewReady, true, true},
+		{"not_pending", []model.Attachment{{ID: "a2", ContentType: "application/pdf"}}, nil, nil, nil, nil, "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("GetPendingPreviewMessages", 10, mongoPkgMock).Return([]model.ChatMessage{
+				{ID: messageID, Attachments: tt.attachments},
+			}, nil)
+			var thumbnails []model.Thumbnail
+			mongoSvcMock.On("SetAttachmentPreview", messageID.Hex(), "a1", tt.expectStatus, mock.Anything, mongoPkgMock).Run(func(args mock.Arguments) {
+				thumbnails = args.Get(3).([]model.Thumbnail)
+			}).Return(tt.setErr)
+
+			storage := new(mock_storage_pkg.BlobStorageMock)
+			var body io.ReadCloser
+			if tt.body != nil {
+				body = io.NopCloser(bytes.NewReader(tt.body))
+			}
+			storage.On("Get", mock.Anything, pending.StorageKey).Return(body, tt.getErr)
+			storage.On("Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything, "image/png").Return(tt.putErr)
+
+			svc := NewThumbnailSvc(mongoSvcMock, mongoPkgMock, storage, []int{160, 480}, 10)
+			err := svc.GenerateThumbnails()
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if !tt.expectSet {
+				mongoSvcMock.AssertNotCalled(t, "SetAttachmentPreview", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			if tt.expectStatus != model.PreviewReady {
+				assert.Empty(t, thumbnails)
+				return
+			}
+			assert.Equal(t, []model.Thumbnail{
+				{Size: 160, Width: 160, Height: 80, ContentType: "image/png", StorageKey: "rooms/r1/attachments/a1/thumbnails/160"},
+				{Size: 480, Width: 480, Height: 240, ContentType: "image/png", StorageKey: "rooms/r1/attachments/a1/thumbnails/480"},
+			}, thumbnails)
+			storage.AssertCalled(t, "Put", mock.Anything, "rooms/r1/attachments/a1/thumbnails/160", mock.Anything, mock.Anything, "image/png")
+			storage.AssertCalled(t, "Put", mock.Anything, "rooms/r1/attachments/a1/thumbnails/480", mock.Anything, mock.Anything, "image/png")
+		})
+	}
+}
+
+func TestGenerateThumbnailsListError(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("GetPendingPreviewMessages", 10, mongoPkgMock).Return([]model.ChatMessage{}, assert.AnError)
+
+	svc := NewThumbnailSvc(mongoSvcMock, mongoPkgMock, new(mock_storage_pkg.BlobStorageMock), DefaultSizes, 10)
+	assert.ErrorIs(t, svc.GenerateThumbnails(), assert.AnError)
+}
@@ -0,0 +1,158 @@
+package webhook_svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/clock_svc"
+	"microservices/chat/internal/svc/mongo_svc"
+	"microservices/chat/pkg/mongo_pkg"
+	"microservices/chat/pkg/retry_pkg"
+	"microservices/chat/pkg/webhook_pkg"
+	"time"
+)
+
+type WebhookSvcInterface interface {
+	Publish(roomID string, event string, data interface{}) error
+	DeliverWebhooks() error
+}
+
+type WebhookSvcStruct struct {
+	MongoSvc mongo_svc.MongoSvcInterface
+	MongoPkg mongo_pkg.MongoPkgInterface
+	Clock    clock_svc.ClockInterface
+	Sender   webhook_pkg.SenderInterface
+
+	MaxAttempts int           // これを超えて失敗した配信はデッドレターにする
+	BaseBackoff time.Duration // 再試行の間隔は失敗するごとに倍にする
+	MaxBackoff  time.Duration
+	Lease       time.Duration // 送信中の配信を他のワーカーが取得しない時間
+	BatchSize   int
+}
+
+func NewWebhookSvc(
+	mongoSvc mongo_svc.MongoSvcInterface,
+	mongoPkg mongo_pkg.MongoPkgInterface,
+	clock clock_svc.ClockInterface,
+	sender webhook_pkg.SenderInterface,
+) *WebhookSvcStruct {
+	return &WebhookSvcStruct{
+		MongoSvc:    mongoSvc,
+		MongoPkg:    mongoPkg,
+		Clock:       clock,
+		Sender:      sender,
+		MaxAttempts: 8,
+		BaseBackoff: 10 * time.Second,
+		MaxBackoff:  time.Hour,
+		Lease:       time.Minute,
+		BatchSize:   50,
+	}
+}
+
+// イベントを購読している Webhook ごとに配信を登録する（送信はワーカーが行う）
+func (s *WebhookSvcStruct) Publish(roomID string, event string, data interface{}) error {
+	webhooks, err := s.MongoSvc.GetRoomWebhooks(roomID, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+
+	now := s.Clock.Now()
+	var payload []byte
+	var deliveries []model.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(model.WebhookEvent{Event: event, RoomID: roomID, CreatedAt: now, Data: data})
+			if err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			WebhookID:     webhook.ID.Hex(),
+			RoomID:        roomID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			Attempts:      []model.WebhookAttempt{},
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.MongoSvc.InsertWebhookDeliveries(deliveries, s.MongoPkg)
+}
+
+// 送信時刻になった配信を送る。失敗した場合は間隔を空けて再試行する
+func (s *WebhookSvcStruct) DeliverWebhooks() error {
+	now := s.Clock.Now()
+	deliveries, err := s.MongoSvc.ClaimDueWebhookDeliveries(now, s.Lease, s.BatchSize, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	var webhookIDs []string
+	for _, delivery := range deliveries {
+		webhookIDs = append(webhookIDs, delivery.WebhookID)
+	}
+	webhooks, err := s.MongoSvc.GetRoomWebhooksByIDs(webhookIDs, s.MongoPkg)
+	if err != nil {
+		return err
+	}
+	byID := map[string]model.RoomWebhook{}
+	for _, webhook := range webhooks {
+		byID[webhook.ID.Hex()] = webhook
+	}
+
+	var errs []error
+	for _, delivery := range deliveries {
+		if err := s.deliver(now, delivery, byID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *WebhookSvcStruct) deliver(now time.Time, delivery model.WebhookDelivery, webhooks map[string]model.RoomWebhook) error {
+	attempt := model.WebhookAttempt{At: now}
+	attempts := delivery.AttemptCount + 1
+
+	webhook, ok := webhooks[delivery.WebhookID]
+	if !ok {
+		// 削除された Webhook には送らない
+		attempt.Error = "webhook has been deleted"
+		return s.MongoSvc.RecordWebhookAttempt(delivery.ID.Hex(), attempt, model.DeliveryFailed, now, s.MongoPkg)
+	}
+
+	statusCode, err := s.Sender.Send(context.Background(), webhook_pkg.Request{
+		URL:        webhook.URL,
+		Secret:     webhook.Secret,
+		Event:      delivery.Event,
+		DeliveryID: delivery.ID.Hex(),
+		Body:       []byte(delivery.Payload),
+	}, now)
+	attempt.StatusCode = statusCode
+
+	status := model.DeliveryDelivered
+	nextAttemptAt := now
+	if err != nil {
+		attempt.Error = err.Error()
+		if attempts >= s.MaxAttempts {
+			status = model.DeliveryFailed
+			log.Printf("webhook delivery %s to %s moved to dead letters after %d attempts: %v", delivery.ID.Hex(), webhook.URL, attempts, err)
+		} else {
+			status = model.DeliveryPending
+			nextAttemptAt = now.Add(retry_pkg.Backoff(s.BaseBackoff, s.MaxBackoff, attempts))
+		}
+	}
+
+	return s.MongoSvc.RecordWebhookAttempt(delivery.ID.Hex(), attempt, status, nextAttemptAt, s.MongoPkg)
+}
@@ -0,0 +1,138 @@
+package webhook_svc
+
+import (
+	"encoding/json"
+	"microservices/chat/internal/model"
+	"microservices/chat/pkg/webhook_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_mongo_pkg"
+	"microservices/chat/tests/mocks/pkg/mock_webhook_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
+	"microservices/chat/tests/mocks/svc/mock_mongo_svc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestPublish(t *testing.T) {
+	all := model.RoomWebhook{ID: primitive.NewObjectID(), RoomID: "room1"}
+	messages := model.RoomWebhook{ID: primitive.NewObjectID(), RoomID: "room1", Events: []string{model.WebhookMessageCreated}}
+	members := model.RoomWebhook{ID: primitive.NewObjectID(), RoomID: "room1", Events: []string{model.WebhookMemberJoined}}
+
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("GetRoomWebhooks", "room1", mongoPkgMock).Return([]model.RoomWebhook{all, messages, members}, nil)
+	var inserted []model.WebhookDelivery
+	mongoSvcMock.On("InsertWebhookDeliveries", mock.Anything, mongoPkgMock).Run(func(args mock.Arguments) {
+		inserted = args.Get(0).([]model.WebhookDelivery)
+	}).Return(nil)
+
+	svc := NewWebhookSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil)
+	err := svc.Publish("room1", model.WebhookMessageCreated, model.WebhookMessageData{MessageID: "m1", UserID: 1, Message: "hi"})
+	assert.NoError(t, err)
+
+	// イベントを購読していない Webhook には登録しない
+	if assert.Len(t, inserted, 2) {
+		assert.Equal(t, all.ID.Hex(), inserted[0].WebhookID)
+		assert.Equal(t, messages.ID.Hex(), inserted[1].WebhookID)
+		assert.Equal(t, model.DeliveryPending, inserted[0].Status)
+		assert.Equal(t, now, inserted[0].NextAttemptAt)
+
+		var payload map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(inserted[0].Payload), &payload))
+		assert.Equal(t, "message.created", payload["event"])
+		assert.Equal(t, "room1", payload["room_id"])
+		assert.Equal(t, map[string]interface{}{"message_id": "m1", "user_id": float64(1), "message": "hi"}, payload["data"])
+	}
+}
+
+func TestPublishNoSubscribers(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("GetRoomWebhooks", "room1", mongoPkgMock).Return([]model.RoomWebhook{
+		{ID: primitive.NewObjectID(), Events: []string{model.WebhookMemberLeft}},
+	}, nil)
+
+	svc := NewWebhookSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil)
+	assert.NoError(t, svc.Publish("room1", model.WebhookMessageCreated, nil))
+	mongoSvcMock.AssertNotCalled(t, "InsertWebhookDeliveries", mock.Anything, mock.Anything)
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	webhook := model.RoomWebhook{ID: primitive.NewObjectID(), RoomID: "room1", URL: "https://example.com/hook", Secret: "secret"}
+
+	tests := []struct {
+		name         string
+		attemptCount int
+		deleted      bool
+		statusCode   int
+		sendErr      error
+		expectStatus string
+		expectNext   time.Time
+		expectSend   bool
+	}{
+		{"delivered", 0, false, 200, nil, model.DeliveryDelivered, now, true},
+		{"retry", 0, false, 500, assert.AnError, model.DeliveryPending, now.Add(10 * time.Second), true},
+		// 失敗するごとに間隔を倍にする
+		{"retry_backoff", 3, false, 500, assert.AnError, model.DeliveryPending, now.Add(80 * time.Second), true},
+		{"dead_letter", 7, false, 0, assert.AnError, model.DeliveryFailed, now, true},
+		{"webhook_deleted", 0, true, 0, nil, model.DeliveryFailed, now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := model.WebhookDelivery{
+				ID:           primitive.NewObjectID(),
+				WebhookID:    webhook.ID.Hex(),
+				RoomID:       "room1",
+				Event:        model.WebhookMessageCreated,
+				Payload:      `{"event":"message.created"}`,
+				Status:       model.DeliveryPending,
+				AttemptCount: tt.attemptCount,
+			}
+			webhooks := []model.RoomWebhook{webhook}
+			if tt.deleted {
+				webhooks = []model.RoomWebhook{}
+			}
+
+			mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+			mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+			mongoSvcMock.On("ClaimDueWebhookDeliveries", now, time.Minute, 50, mongoPkgMock).Return([]model.WebhookDelivery{delivery}, nil)
+			mongoSvcMock.On("GetRoomWebhooksByIDs", []string{webhook.ID.Hex()}, mongoPkgMock).Return(webhooks, nil)
+			mongoSvcMock.On("RecordWebhookAttempt", delivery.ID.Hex(), mock.MatchedBy(func(attempt model.WebhookAttempt) bool {
+				return attempt.At == now && attempt.StatusCode == tt.statusCode && (attempt.Error == "") == (tt.expectStatus == model.DeliveryDelivered)
+			}), tt.expectStatus, tt.expectNext, mongoPkgMock).Return(nil)
+
+			sender := new(mock_webhook_pkg.SenderMock)
+			sender.On("Send", mock.Anything, webhook_pkg.Request{
+				URL:        webhook.URL,
+				Secret:     "secret",
+				Event:      model.WebhookMessageCreated,
+				DeliveryID: delivery.ID.Hex(),
+				Body:       []byte(delivery.Payload),
+			}, now).Return(tt.statusCode, tt.sendErr)
+
+			svc := NewWebhookSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, sender)
+			assert.NoError(t, svc.DeliverWebhooks())
+			mongoSvcMock.AssertExpectations(t)
+			if tt.expectSend {
+				sender.AssertExpectations(t)
+			} else {
+				sender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestDeliverWebhooksClaimError(t *testing.T) {
+	mongoPkgMock := new(mock_mongo_pkg.MongoPkgMock)
+	mongoSvcMock := new(mock_mongo_svc.MongoSvcMock)
+	mongoSvcMock.On("ClaimDueWebhookDeliveries", now, time.Minute, 50, mongoPkgMock).Return([]model.WebhookDelivery{}, assert.AnError)
+
+	svc := NewWebhookSvc(mongoSvcMock, mongoPkgMock, mock_clock_svc.FixedClock{FixedTime: now}, nil)
+	assert.ErrorIs(t, svc.DeliverWebhooks(), assert.AnError)
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// 一定間隔でジョブを実行するバックグラウンドワーカー
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Job      func() error
+}
+
+func NewWorker(name string, interval time.Duration, job func() error) *Worker {
+	return &Worker{
+		Name:     name,
+		Interval: interval,
+		Job:      job,
+	}
+}
+
+// ctx がキャンセルされるまで Interval ごとに Job を実行する
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Job(); err != nil {
+				log.Printf("⚠️ worker %s failed: %v", w.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerRun(t *testing.T) {
+	var count int32
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := NewWorker("test", time.Millisecond, func() error {
+		// エラーを返しても止まらずに次の実行が行われること
+		if atomic.AddInt32(&count, 1) == 3 {
+			cancel()
+		}
+		return errors.New("job failed")
+	})
+
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after context was canceled")
+	}
+
+	if atomic.LoadInt32(&count) < 3 {
+		t.Errorf("expected job to run at least 3 times, got %d", count)
+	}
+}
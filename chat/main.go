@@ -1,19 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"microservices/chat/internal/app"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
-func SetupRouter() *gin.Engine {
+func SetupRouter(app *app.App) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
-	app := app.NewApp()
 	app.InitRoutes(r)
 
 	return r
@@ -31,11 +36,35 @@ func main() {
 		mode = gin.DebugMode // fallback
 	}
 	gin.SetMode(mode)
-	r := SetupRouter()
+	app := app.NewApp()
+	r := SetupRouter(app)
+
+	// SIGINT / SIGTERM で停止する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 削除済みメッセージのパージなどのバックグラウンドジョブ
+	app.StartWorkers(ctx)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8081"
 	}
-	r.Run(":" + port)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	// 処理中のリクエストを待ってから MongoDB との接続を切断する
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("⚠️ サーバーの停止に失敗:", err)
+	}
+	if err := app.Close(shutdownCtx); err != nil {
+		log.Println("⚠️ MongoDBの切断に失敗:", err)
+	}
 }
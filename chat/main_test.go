@@ -1,12 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"microservices/chat/internal/app"
 	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/import_svc"
+	"microservices/chat/internal/svc/purge_svc"
+	"microservices/chat/internal/svc/schedule_svc"
+	"microservices/chat/pkg/storage_pkg"
+	"microservices/chat/tests/mocks/svc/mock_clock_svc"
 	"microservices/chat/tests/test_funcs"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -17,6 +27,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var testServer *http.Server
@@ -35,7 +46,7 @@ func TestMain(m *testing.M) {
 
 	// 起動前のセットアップ
 	gin.SetMode(gin.TestMode)
-	r := SetupRouter()
+	r := SetupRouter(app.NewApp())
 	testServer = &http.Server{
 		Addr:    ":8881",
 		Handler: r,
@@ -446,17 +457,1341 @@ func TestDeleteChatMessage(t *testing.T) {
 	bodyString := string(bodyBytes)
 	assert.Contains(t, bodyString, "Message deleted successfully")
 
-	// メッセージが削除されていることを確認
+	// メッセージが論理削除されていることを確認
 	exist, err = testMongoStruct.ExistContents(model.ChatMessageCollectionName, bson.M{
-		"_id": chat.InsertedID,
+		"_id":       chat.InsertedID,
+		"deletedat": bson.M{"$ne": nil},
+		"deletedby": userId,
 	})
 	assert.NoError(t, err)
-	assert.False(t, exist)
+	assert.True(t, exist)
+
+	// 履歴には本文を含まないトゥームストーンとして返されることを確認
+	loadResp, loadClose := request("GET", "/load_chat/"+roomId, nil, t)
+	defer loadClose()
+	assert.Equal(t, http.StatusOK, loadResp.StatusCode)
+	loadBodyBytes, err := io.ReadAll(loadResp.Body)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(loadBodyBytes), "Message to be deleted")
+	assert.Contains(t, string(loadBodyBytes), "Noise message")
 
 	// ノイズメッセージが削除されていないことを確認
 	exist, err = testMongoStruct.ExistContents(model.ChatMessageCollectionName, bson.M{
-		"_id": noiseChat.InsertedID,
+		"_id":       noiseChat.InsertedID,
+		"deletedat": nil,
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestSearchChatMessages(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	// 参加済みのルームと、参加していないルームを作成
+	joinedRoom, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:    "JoinedRoom",
+		OwnerID: userId,
+		Members: []int{userId},
+	})
+	assert.NoError(t, err)
+	otherRoom, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:    "OtherRoom",
+		OwnerID: 99999,
+		Members: []int{99999},
+	})
+	assert.NoError(t, err)
+
+	chatMessages := []model.ChatMessage{
+		{RoomID: joinedRoom.InsertedID.(primitive.ObjectID).Hex(), UserID: userId, Message: "deploy finished", CreatedAt: time.Now()},
+		{RoomID: joinedRoom.InsertedID.(primitive.ObjectID).Hex(), UserID: userId, Message: "lunch time", CreatedAt: time.Now()},
+		{RoomID: otherRoom.InsertedID.(primitive.ObjectID).Hex(), UserID: 99999, Message: "secret deploy plan", CreatedAt: time.Now()},
+	}
+	for _, chat := range chatMessages {
+		_, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, chat)
+		assert.NoError(t, err)
+	}
+
+	resp, close := request("GET", "/search?q=deploy", nil, t)
+	defer close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	bodyBytes, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	bodyString := string(bodyBytes)
+	assert.Contains(t, bodyString, "deploy finished")
+	assert.Contains(t, bodyString, `"total":1`)
+	assert.NotContains(t, bodyString, "lunch time")
+	assert.NotContains(t, bodyString, "secret deploy plan")
+}
+
+func TestEditChatMessage(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	// 事前にルームを作成
+	createRoom := model.Room{
+		Name:      "EditChatRoom",
+		OwnerID:   userId,
+		IsPrivate: false,
+		Members:   []int{userId},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+
+	createChat := model.ChatMessage{
+		RoomID:    room.InsertedID.(primitive.ObjectID).Hex(),
+		UserID:    userId,
+		Message:   "Message before edit",
+		CreatedAt: time.Now(),
+	}
+	chat, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, createChat)
+	assert.NoError(t, err)
+
+	chatId := chat.InsertedID.(primitive.ObjectID).Hex()
+	body := strings.NewReader(`{"message":"Message after edit"}`)
+	resp, close := request("PATCH", "/messages/"+chatId, body, t)
+	defer close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	bodyBytes, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), "Message edited successfully")
+
+	// 本文が更新され、編集前の本文が履歴に残っていることを確認
+	exist, err := testMongoStruct.ExistContents(model.ChatMessageCollectionName, bson.M{
+		"_id":                chat.InsertedID,
+		"message":            "Message after edit",
+		"edited":             true,
+		"revisions.message":  "Message before edit",
+		"revisions.editedby": userId,
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestThreadReplies(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	// 事前にルームとスレッドの起点となるメッセージを作成
+	createRoom := model.Room{
+		Name:      "ThreadRoom",
+		OwnerID:   userId,
+		IsPrivate: false,
+		Members:   []int{userId},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	createChat := model.ChatMessage{
+		RoomID:    roomId,
+		UserID:    userId,
+		Message:   "Thread root message",
+		CreatedAt: time.Now(),
+	}
+	rootChat, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, createChat)
+	assert.NoError(t, err)
+	rootId := rootChat.InsertedID.(primitive.ObjectID).Hex()
+
+	body := strings.NewReader(`{"room_id":"` + roomId + `","message":"Reply message","reply_to":"` + rootId + `"}`)
+	resp, close := request("POST", "/post_chat_message", body, t)
+	defer close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 起点のメッセージに返信数が反映されていることを確認
+	exist, err := testMongoStruct.ExistContents(model.ChatMessageCollectionName, bson.M{
+		"_id":         rootChat.InsertedID,
+		"replycount":  1,
+		"lastreplyat": bson.M{"$ne": nil},
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	threadResp, threadClose := request("GET", "/messages/"+rootId+"/thread", nil, t)
+	defer threadClose()
+	assert.Equal(t, http.StatusOK, threadResp.StatusCode)
+	bodyBytes, err := io.ReadAll(threadResp.Body)
+	assert.NoError(t, err)
+	bodyString := string(bodyBytes)
+	assert.Contains(t, bodyString, "Thread root message")
+	assert.Contains(t, bodyString, "Reply message")
+	assert.Contains(t, bodyString, `"total":1`)
+}
+
+func TestReactions(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	// 事前にルームとメッセージを作成
+	createRoom := model.Room{
+		Name:      "ReactionRoom",
+		OwnerID:   userId,
+		IsPrivate: false,
+		Members:   []int{userId},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	createChat := model.ChatMessage{
+		RoomID:    roomId,
+		UserID:    userId,
+		Message:   "Reaction target",
+		CreatedAt: time.Now(),
+	}
+	chat, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, createChat)
+	assert.NoError(t, err)
+	chatId := chat.InsertedID.(primitive.ObjectID).Hex()
+
+	// 同じリアクションを2回追加しても1件として扱われる
+	for i := 0; i < 2; i++ {
+		resp, close := request("POST", "/messages/"+chatId+"/reactions", strings.NewReader(`{"emoji":"👍"}`), t)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		close()
+	}
+
+	loadResp, loadClose := request("GET", "/load_chat/"+roomId, nil, t)
+	defer loadClose()
+	assert.Equal(t, http.StatusOK, loadResp.StatusCode)
+	bodyBytes, err := io.ReadAll(loadResp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), `"👍":1`)
+
+	deleteResp, deleteClose := request("DELETE", "/messages/"+chatId+"/reactions/"+url.PathEscape("👍"), nil, t)
+	defer deleteClose()
+	assert.Equal(t, http.StatusOK, deleteResp.StatusCode)
+
+	exist, err := testMongoStruct.ExistContents(model.ChatMessageCollectionName, bson.M{
+		"_id":         chat.InsertedID,
+		"reactions.👍": bson.M{"$size": 0},
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestReadUpTo(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	createRoom := model.Room{
+		Name:      "UnreadRoom",
+		OwnerID:   userId,
+		IsPrivate: false,
+		Members:   []int{userId, 99999},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	// 他のユーザーのメッセージを3件作成
+	var chatIds []string
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		chat, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, model.ChatMessage{
+			RoomID:    roomId,
+			UserID:    99999,
+			Message:   fmt.Sprintf("Unread message %d", i),
+			CreatedAt: now.Add(time.Duration(i) * time.Second),
+		})
+		assert.NoError(t, err)
+		chatIds = append(chatIds, chat.InsertedID.(primitive.ObjectID).Hex())
+	}
+
+	listResp, listClose := request("GET", "/room_list?target=joined", nil, t)
+	defer listClose()
+	bodyBytes, err := io.ReadAll(listResp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), `"UnreadCount":3`)
+
+	resp, close := request("POST", "/rooms/"+roomId+"/read_up_to", strings.NewReader(`{"message_id":"`+chatIds[1]+`"}`), t)
+	defer close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 古いメッセージを指定しても既読位置は戻らない
+	oldResp, oldClose := request("POST", "/rooms/"+roomId+"/read_up_to", strings.NewReader(`{"message_id":"`+chatIds[0]+`"}`), t)
+	defer oldClose()
+	assert.Equal(t, http.StatusOK, oldResp.StatusCode)
+
+	listResp2, listClose2 := request("GET", "/room_list?target=joined", nil, t)
+	defer listClose2()
+	bodyBytes, err = io.ReadAll(listResp2.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), `"UnreadCount":1`)
+}
+
+func TestPrivateRoomInvite(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	createRoom := model.Room{
+		Name:      "PrivateInviteRoom",
+		OwnerID:   99999,
+		IsPrivate: true,
+		Members:   []int{99999},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	// 招待なしでは参加できない
+	joinResp, joinClose := request("POST", "/room_join", strings.NewReader(`{"room_id":"`+roomId+`"}`), t)
+	defer joinClose()
+	assert.Equal(t, http.StatusForbidden, joinResp.StatusCode)
+
+	_, err = testMongoStruct.DB.Collection(model.RoomInviteCollectionName).InsertOne(testMongoStruct.Ctx, model.RoomInvite{
+		RoomID:    roomId,
+		Code:      "e2e_invite_code",
+		CreatedBy: 99999,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		MaxUses:   1,
+	})
+	assert.NoError(t, err)
+
+	resp, close := request("POST", "/invites/e2e_invite_code/accept", nil, t)
+	defer close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	exist, err := testMongoStruct.ExistContents(model.RoomCollectionName, bson.M{"_id": room.InsertedID, "members": userId})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	unknownResp, unknownClose := request("POST", "/invites/unknown_code/accept", nil, t)
+	defer unknownClose()
+	assert.Equal(t, http.StatusNotFound, unknownResp.StatusCode)
+}
+
+func TestPrivateRoomJoinRequest(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	createRoom := model.Room{
+		Name:      "PrivateRequestRoom",
+		OwnerID:   99999,
+		IsPrivate: true,
+		Members:   []int{99999},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	resp, close := request("POST", "/rooms/"+roomId+"/join_requests", strings.NewReader(`{"message":"Please let me in"}`), t)
+	defer close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 保留中の申請は重複して作成できない
+	dupResp, dupClose := request("POST", "/rooms/"+roomId+"/join_requests", strings.NewReader(`{}`), t)
+	defer dupClose()
+	assert.Equal(t, http.StatusConflict, dupResp.StatusCode)
+
+	// オーナー以外は申請を承認できない
+	var joinRequest model.JoinRequest
+	err = testMongoStruct.DB.Collection(model.JoinRequestCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"roomid": roomId}).Decode(&joinRequest)
+	assert.NoError(t, err)
+	approveResp, approveClose := request("POST", "/rooms/"+roomId+"/join_requests/"+joinRequest.ID.Hex()+"/approve", nil, t)
+	defer approveClose()
+	assert.Equal(t, http.StatusForbidden, approveResp.StatusCode)
+}
+
+func TestRoomMembership(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	createRoom := model.Room{
+		Name:      "MembershipRoom",
+		OwnerID:   userId,
+		IsPrivate: false,
+		Members:   []int{userId, 99999, 88888},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	kickResp, kickClose := request("POST", "/rooms/"+roomId+"/kick", strings.NewReader(`{"user_id":88888}`), t)
+	defer kickClose()
+	assert.Equal(t, http.StatusOK, kickResp.StatusCode)
+
+	banResp, banClose := request("POST", "/rooms/"+roomId+"/ban", strings.NewReader(`{"user_id":77777}`), t)
+	defer banClose()
+	assert.Equal(t, http.StatusOK, banResp.StatusCode)
+
+	exist, err := testMongoStruct.ExistContents(model.RoomCollectionName, bson.M{
+		"_id":     room.InsertedID,
+		"members": []int{userId, 99999},
+		"banned":  77777,
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	// オーナーが退出すると残っているメンバーにオーナーが引き継がれる
+	leaveResp, leaveClose := request("POST", "/rooms/"+roomId+"/leave", nil, t)
+	defer leaveClose()
+	assert.Equal(t, http.StatusOK, leaveResp.StatusCode)
+	bodyBytes, err := io.ReadAll(leaveResp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), `"new_owner_id":99999`)
+
+	exist, err = testMongoStruct.ExistContents(model.RoomCollectionName, bson.M{
+		"_id":     room.InsertedID,
+		"ownerid": 99999,
+		"members": []int{99999},
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
+
+func TestBannedUserCannotJoin(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	createRoom := model.Room{
+		Name:    "BannedRoom",
+		OwnerID: 99999,
+		Members: []int{99999},
+		Banned:  []int{userId},
+	}
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, createRoom)
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	resp, close := request("POST", "/room_join", strings.NewReader(`{"room_id":"`+roomId+`"}`), t)
+	defer close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestRoomRoles(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	ownedRoom, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:    "OwnedRoom",
+		OwnerID: userId,
+		Members: []int{userId, 99999},
+	})
+	assert.NoError(t, err)
+	ownedRoomId := ownedRoom.InsertedID.(primitive.ObjectID).Hex()
+
+	roleResp, roleClose := request("PUT", "/rooms/"+ownedRoomId+"/roles", strings.NewReader(`{"user_id":99999,"role":"moderator"}`), t)
+	defer roleClose()
+	assert.Equal(t, http.StatusOK, roleResp.StatusCode)
+
+	exist, err := testMongoStruct.ExistContents(model.RoomCollectionName, bson.M{
+		"_id":         ownedRoom.InsertedID,
+		"roles.99999": model.RoleModerator,
+	})
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	// 閲覧のみの役割では投稿できない
+	readOnlyRoom, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:    "ReadOnlyRoom",
+		OwnerID: 99999,
+		Members: []int{99999, userId},
+		Roles:   map[string]string{model.RoleKey(userId): model.RoleReadOnly},
+	})
+	assert.NoError(t, err)
+	readOnlyRoomId := readOnlyRoom.InsertedID.(primitive.ObjectID).Hex()
+
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+readOnlyRoomId+`","message":"hello"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusForbidden, postResp.StatusCode)
+
+	loadResp, loadClose := request("GET", "/load_chat/"+readOnlyRoomId, nil, t)
+	defer loadClose()
+	assert.Equal(t, http.StatusOK, loadResp.StatusCode)
+}
+
+func TestRoomSettingsAndArchive(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:    "SettingsRoom",
+		OwnerID: userId,
+		Members: []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	patchResp, patchClose := request("PATCH", "/rooms/"+roomId, strings.NewReader(`{"name":"Renamed","description":"about","topic":"release","avatar_url":"https://example.com/a.png"}`), t)
+	defer patchClose()
+	assert.Equal(t, http.StatusOK, patchResp.StatusCode)
+
+	exist, err := testMongoStruct.ExistContents(model.RoomCollectionName, bson.M{
+		"_id":         room.InsertedID,
+		"name":        "Renamed",
+		"description": "about",
+		"topic":       "release",
+		"avatarurl":   "https://example.com/a.png",
 	})
 	assert.NoError(t, err)
 	assert.True(t, exist)
+
+	archiveResp, archiveClose := request("POST", "/rooms/"+roomId+"/archive", nil, t)
+	defer archiveClose()
+	assert.Equal(t, http.StatusOK, archiveResp.StatusCode)
+
+	// アーカイブ済みのルームは all には表示されず、joined には IsArchived 付きで表示される
+	allResp, allClose := request("GET", "/room_list?target=all", nil, t)
+	defer allClose()
+	allBody, err := io.ReadAll(allResp.Body)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(allBody), roomId)
+
+	joinedResp, joinedClose := request("GET", "/room_list?target=joined", nil, t)
+	defer joinedClose()
+	joinedBody, err := io.ReadAll(joinedResp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(joinedBody), `"IsArchived":true`)
+
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"hello"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusForbidden, postResp.StatusCode)
+
+	unarchiveResp, unarchiveClose := request("POST", "/rooms/"+roomId+"/unarchive", nil, t)
+	defer unarchiveClose()
+	assert.Equal(t, http.StatusOK, unarchiveResp.StatusCode)
+}
+
+func TestDeleteRoom(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	room, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:    "DeleteRoom",
+		OwnerID: userId,
+		Members: []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := room.InsertedID.(primitive.ObjectID).Hex()
+
+	_, err = testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, model.ChatMessage{
+		RoomID:    roomId,
+		UserID:    userId,
+		Message:   "to be deleted",
+		CreatedAt: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	deleteResp, deleteClose := request("DELETE", "/rooms/"+roomId, nil, t)
+	defer deleteClose()
+	assert.Equal(t, http.StatusOK, deleteResp.StatusCode)
+
+	exist, err := testMongoStruct.ExistContents(model.RoomCollectionName, bson.M{"_id": room.InsertedID})
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	exist, err = testMongoStruct.ExistContents(model.ChatMessageCollectionName, bson.M{"roomid": roomId})
+	assert.NoError(t, err)
+	assert.False(t, exist)
+}
+
+func TestDirectMessages(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	firstResp, firstClose := request("POST", "/dms", strings.NewReader(`{"user_id":99999}`), t)
+	defer firstClose()
+	assert.Equal(t, http.StatusOK, firstResp.StatusCode)
+	var first struct {
+		RoomID  string `json:"room_id"`
+		Created bool   `json:"created"`
+	}
+	assert.NoError(t, json.NewDecoder(firstResp.Body).Decode(&first))
+	assert.True(t, first.Created)
+
+	// 同じ相手とのダイレクトメッセージは作り直さない
+	secondResp, secondClose := request("POST", "/dms", strings.NewReader(`{"user_ids":[99999]}`), t)
+	defer secondClose()
+	var second struct {
+		RoomID  string `json:"room_id"`
+		Created bool   `json:"created"`
+	}
+	assert.NoError(t, json.NewDecoder(secondResp.Body).Decode(&second))
+	assert.False(t, second.Created)
+	assert.Equal(t, first.RoomID, second.RoomID)
+
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+first.RoomID+`","message":"hi"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusOK, postResp.StatusCode)
+
+	// ダイレクトメッセージは公開ルームの一覧には表示されない
+	allResp, allClose := request("GET", "/room_list?target=all", nil, t)
+	defer allClose()
+	allBody, err := io.ReadAll(allResp.Body)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(allBody), first.RoomID)
+
+	directResp, directClose := request("GET", "/room_list?target=direct", nil, t)
+	defer directClose()
+	directBody, err := io.ReadAll(directResp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(directBody), first.RoomID)
+	assert.Contains(t, string(directBody), `"UnreadCount":0`)
+}
+
+func TestRoomDiscovery(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	base := time.Now().Add(-time.Hour)
+	variations := []model.Room{
+		{Name: "Go Lounge", OwnerID: 77777, CreatedAt: base, Members: []int{77777, 88888, 99999}},
+		{Name: "golang-jp", OwnerID: 77777, CreatedAt: base.Add(time.Minute), Members: []int{77777}},
+		{Name: "Random", OwnerID: 77777, CreatedAt: base.Add(2 * time.Minute), Members: []int{77777, userId}},
+	}
+	roomIds := make([]string, len(variations))
+	for i, room := range variations {
+		inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, room)
+		assert.NoError(t, err)
+		roomIds[i] = inserted.InsertedID.(primitive.ObjectID).Hex()
+	}
+
+	type roomList struct {
+		Rooms []struct {
+			ID          string
+			LastMessage *struct {
+				UserID  int
+				Snippet string
+			}
+		} `json:"rooms"`
+		NextCursor string `json:"next_cursor"`
+	}
+	list := func(query string) roomList {
+		resp, close := request("GET", "/room_list?"+query, nil, t)
+		defer close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var result roomList
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+
+	// 名前の部分一致（大文字小文字を区別しない）
+	search := list("q=go&sort=created")
+	assert.Len(t, search.Rooms, 2)
+	assert.Equal(t, roomIds[1], search.Rooms[0].ID)
+	assert.Equal(t, roomIds[0], search.Rooms[1].ID)
+
+	// メンバー数の多い順にカーソルで1件ずつ取得する
+	var paged []string
+	cursor := ""
+	for i := 0; i < len(variations); i++ {
+		page := list("sort=members&limit=1&cursor=" + cursor)
+		assert.Len(t, page.Rooms, 1)
+		paged = append(paged, page.Rooms[0].ID)
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{roomIds[0], roomIds[2], roomIds[1]}, paged)
+	assert.Empty(t, cursor)
+
+	// 投稿するとアクティビティ順の先頭になり、最新メッセージが表示される
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomIds[2]+`","message":"hello\nworld"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusOK, postResp.StatusCode)
+
+	activity := list("sort=activity")
+	assert.Equal(t, roomIds[2], activity.Rooms[0].ID)
+	if assert.NotNil(t, activity.Rooms[0].LastMessage) {
+		assert.Equal(t, userId, activity.Rooms[0].LastMessage.UserID)
+		assert.Equal(t, "hello world", activity.Rooms[0].LastMessage.Snippet)
+	}
+	assert.Nil(t, activity.Rooms[1].LastMessage)
+
+	invalidResp, invalidClose := request("GET", "/room_list?cursor=broken", nil, t)
+	defer invalidClose()
+	assert.Equal(t, http.StatusBadRequest, invalidResp.StatusCode)
+}
+
+func TestAttachments(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Attachments",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("files", "notes.txt")
+	assert.NoError(t, err)
+	part.Write([]byte("attachment body"))
+	writer.WriteField("message", "see attached")
+	writer.Close()
+
+	req, err := http.NewRequest("POST", baseURL+"/rooms/"+roomId+"/attachments", body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+createJwt(t))
+	req.Header.Set("X-CSRF-Token", createCsrf())
+	uploadResp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer uploadResp.Body.Close()
+	assert.Equal(t, http.StatusOK, uploadResp.StatusCode)
+
+	var uploaded struct {
+		MessageID   string             `json:"message_id"`
+		Attachments []model.Attachment `json:"attachments"`
+	}
+	assert.NoError(t, json.NewDecoder(uploadResp.Body).Decode(&uploaded))
+	if !assert.Len(t, uploaded.Attachments, 1) {
+		return
+	}
+	assert.Equal(t, "text/plain", uploaded.Attachments[0].ContentType)
+
+	urlResp, urlClose := request("GET", "/messages/"+uploaded.MessageID+"/attachments/"+uploaded.Attachments[0].ID, nil, t)
+	defer urlClose()
+	assert.Equal(t, http.StatusOK, urlResp.StatusCode)
+	var signed struct {
+		URL string `json:"url"`
+	}
+	assert.NoError(t, json.NewDecoder(urlResp.Body).Decode(&signed))
+
+	// 署名付きURLは認証なしでダウンロードできる
+	downloadResp, err := http.Get(baseURL + signed.URL)
+	assert.NoError(t, err)
+	defer downloadResp.Body.Close()
+	assert.Equal(t, http.StatusOK, downloadResp.StatusCode)
+	downloaded, err := io.ReadAll(downloadResp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "attachment body", string(downloaded))
+
+	tamperedResp, err := http.Get(baseURL + strings.Replace(signed.URL, "signature=", "signature=0", 1))
+	assert.NoError(t, err)
+	defer tamperedResp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, tamperedResp.StatusCode)
+}
+
+func TestMentions(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Mentions",
+		OwnerID:   99999,
+		CreatedAt: time.Now(),
+		Members:   []int{99999, userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+	// ユーザーの記録はプロセス内でキャッシュされるため、users コレクションはテストごとに削除しない
+	_, err = testMongoStruct.DB.Collection(model.UserCollectionName).UpdateOne(testMongoStruct.Ctx,
+		bson.M{"userid": 99999},
+		bson.M{"$set": bson.M{"email": "bob@example.com", "handle": "bob"}},
+		options.Update().SetUpsert(true),
+	)
+	assert.NoError(t, err)
+
+	// メンバーの @all は無視され、@bob だけが解決される
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"@bob @all @nobody hello"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusOK, postResp.StatusCode)
+	var posted struct {
+		MessageID string `json:"message_id"`
+	}
+	assert.NoError(t, json.NewDecoder(postResp.Body).Decode(&posted))
+	postedId, _ := primitive.ObjectIDFromHex(posted.MessageID)
+	var message model.ChatMessage
+	assert.NoError(t, testMongoStruct.DB.Collection(model.ChatMessageCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"_id": postedId}).Decode(&message))
+	assert.Equal(t, []int{99999}, message.MentionedUserIds)
+
+	// 認証済みのユーザーはメンションできるように記録される
+	var user model.User
+	assert.NoError(t, testMongoStruct.DB.Collection(model.UserCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"userid": userId}).Decode(&user))
+	assert.Equal(t, "testuser", user.Handle)
+
+	_, err = testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, model.ChatMessage{
+		RoomID:           roomId,
+		UserID:           99999,
+		Message:          "@testuser ping",
+		CreatedAt:        time.Now(),
+		MentionedUserIds: []int{userId},
+	})
+	assert.NoError(t, err)
+
+	mentionsResp, mentionsClose := request("GET", "/mentions", nil, t)
+	defer mentionsClose()
+	assert.Equal(t, http.StatusOK, mentionsResp.StatusCode)
+	var mentions struct {
+		Mentions []struct {
+			Message  string
+			RoomName string
+			IsUnread bool
+		} `json:"mentions"`
+		Total int `json:"total"`
+	}
+	assert.NoError(t, json.NewDecoder(mentionsResp.Body).Decode(&mentions))
+	assert.Equal(t, 1, mentions.Total)
+	if assert.Len(t, mentions.Mentions, 1) {
+		assert.Equal(t, "@testuser ping", mentions.Mentions[0].Message)
+		assert.Equal(t, "Mentions", mentions.Mentions[0].RoomName)
+		assert.True(t, mentions.Mentions[0].IsUnread)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Notifications",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId, 99999, 99998},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+	for id, handle := range map[int]string{99999: "bob", 99998: "carol"} {
+		_, err = testMongoStruct.DB.Collection(model.UserCollectionName).UpdateOne(testMongoStruct.Ctx,
+			bson.M{"userid": id},
+			bson.M{"$set": bson.M{"email": handle + "@example.com", "handle": handle}},
+			options.Update().SetUpsert(true),
+		)
+		assert.NoError(t, err)
+	}
+	// carol はこのルームをミュートしている
+	_, err = testMongoStruct.DB.Collection(model.NotificationSettingsCollectionName).InsertOne(testMongoStruct.Ctx, model.NotificationSettings{
+		UserID:     99998,
+		MutedRooms: []string{roomId},
+	})
+	assert.NoError(t, err)
+
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"@bob @carol lunch?"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusOK, postResp.StatusCode)
+
+	var notifications []model.Notification
+	cursor, err := testMongoStruct.DB.Collection(model.NotificationCollectionName).Find(testMongoStruct.Ctx, bson.M{"roomid": roomId})
+	assert.NoError(t, err)
+	assert.NoError(t, cursor.All(testMongoStruct.Ctx, &notifications))
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, 99999, notifications[0].UserID)
+		assert.Equal(t, model.NotificationMention, notifications[0].Kind)
+		assert.Equal(t, userId, notifications[0].ActorID)
+		assert.Equal(t, "", notifications[0].DeliveryID)
+	}
+
+	// 自分の通知設定
+	settingsResp, settingsClose := request("PUT", "/me/notification_settings", strings.NewReader(`{"channel":"webhook","webhook_url":"https://example.com/hook","dnd_start":"22:00","dnd_end":"07:00","time_zone":"Asia/Tokyo"}`), t)
+	defer settingsClose()
+	assert.Equal(t, http.StatusOK, settingsResp.StatusCode)
+
+	invalidResp, invalidClose := request("PUT", "/me/notification_settings", strings.NewReader(`{"channel":"webhook"}`), t)
+	defer invalidClose()
+	assert.Equal(t, http.StatusBadRequest, invalidResp.StatusCode)
+
+	muteResp, muteClose := request("POST", "/rooms/"+roomId+"/mute", nil, t)
+	defer muteClose()
+	assert.Equal(t, http.StatusOK, muteResp.StatusCode)
+
+	getResp, getClose := request("GET", "/me/notification_settings", nil, t)
+	defer getClose()
+	assert.Equal(t, http.StatusOK, getResp.StatusCode)
+	var got struct {
+		Settings model.NotificationSettings `json:"settings"`
+	}
+	assert.NoError(t, json.NewDecoder(getResp.Body).Decode(&got))
+	assert.Equal(t, model.NotificationChannelWebhook, got.Settings.Channel)
+	assert.Equal(t, "22:00", got.Settings.DNDStart)
+	assert.Equal(t, []string{roomId}, got.Settings.MutedRooms)
+
+	unmuteResp, unmuteClose := request("DELETE", "/rooms/"+roomId+"/mute", nil, t)
+	defer unmuteClose()
+	assert.Equal(t, http.StatusOK, unmuteResp.StatusCode)
+	var settings model.NotificationSettings
+	assert.NoError(t, testMongoStruct.DB.Collection(model.NotificationSettingsCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"userid": userId}).Decode(&settings))
+	assert.Empty(t, settings.MutedRooms)
+	assert.Equal(t, "https://example.com/hook", settings.WebhookURL)
+}
+
+func TestRoomWebhooks(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Webhooks",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId, 99999},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	invalidResp, invalidClose := request("POST", "/rooms/"+roomId+"/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["room.deleted"]}`), t)
+	defer invalidClose()
+	assert.Equal(t, http.StatusBadRequest, invalidResp.StatusCode)
+
+	createResp, createClose := request("POST", "/rooms/"+roomId+"/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["message.created"]}`), t)
+	defer createClose()
+	assert.Equal(t, http.StatusOK, createResp.StatusCode)
+	var created struct {
+		WebhookID string `json:"webhook_id"`
+		Secret    string `json:"secret"`
+	}
+	assert.NoError(t, json.NewDecoder(createResp.Body).Decode(&created))
+	assert.NotEmpty(t, created.Secret)
+
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"hello hooks"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusOK, postResp.StatusCode)
+
+	// 購読していないイベントは配信しない
+	leaveResp, leaveClose := request("POST", "/rooms/"+roomId+"/leave", nil, t)
+	defer leaveClose()
+	assert.Equal(t, http.StatusOK, leaveResp.StatusCode)
+
+	var deliveries []model.WebhookDelivery
+	cursor, err := testMongoStruct.DB.Collection(model.WebhookDeliveryCollectionName).Find(testMongoStruct.Ctx, bson.M{"roomid": roomId})
+	assert.NoError(t, err)
+	assert.NoError(t, cursor.All(testMongoStruct.Ctx, &deliveries))
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, model.WebhookMessageCreated, deliveries[0].Event)
+		assert.Equal(t, model.DeliveryPending, deliveries[0].Status)
+		assert.Contains(t, deliveries[0].Payload, "hello hooks")
+	}
+}
+
+func TestIncomingWebhooks(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Builds",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	createResp, createClose := request("POST", "/rooms/"+roomId+"/incoming_webhooks", strings.NewReader(`{"name":"CI"}`), t)
+	defer createClose()
+	assert.Equal(t, http.StatusOK, createResp.StatusCode)
+	var created struct {
+		URL string `json:"url"`
+	}
+	assert.NoError(t, json.NewDecoder(createResp.Body).Decode(&created))
+
+	// CSRF トークンもログインも無しで投稿できる
+	hookResp, err := http.Post(baseURL+created.URL, "application/json", strings.NewReader(`{"text":"build #42 passed"}`))
+	assert.NoError(t, err)
+	defer hookResp.Body.Close()
+	assert.Equal(t, http.StatusOK, hookResp.StatusCode)
+
+	var message model.ChatMessage
+	assert.NoError(t, testMongoStruct.DB.Collection(model.ChatMessageCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"roomid": roomId}).Decode(&message))
+	assert.Equal(t, "build #42 passed", message.Message)
+	assert.Equal(t, "CI", message.BotName)
+	assert.Equal(t, 0, message.UserID)
+
+	unknownResp, err := http.Post(baseURL+"/hooks/unknown", "application/json", strings.NewReader(`{"text":"hi"}`))
+	assert.NoError(t, err)
+	defer unknownResp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, unknownResp.StatusCode)
+}
+
+func TestSlashCommands(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Commands",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	topicResp, topicClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"/topic Release day"}`), t)
+	defer topicClose()
+	assert.Equal(t, http.StatusOK, topicResp.StatusCode)
+
+	var room model.Room
+	assert.NoError(t, testMongoStruct.DB.Collection(model.RoomCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"_id": inserted.InsertedID}).Decode(&room))
+	assert.Equal(t, "Release day", room.Topic)
+
+	// トピックの変更はボットとして投稿され、コマンドの本文は投稿されない
+	var messages []model.ChatMessage
+	cursor, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).Find(testMongoStruct.Ctx, bson.M{"roomid": roomId})
+	assert.NoError(t, err)
+	assert.NoError(t, cursor.All(testMongoStruct.Ctx, &messages))
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "Topic changed to: Release day", messages[0].Message)
+		assert.Equal(t, "/topic", messages[0].BotName)
+	}
+
+	muteResp, muteClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"/mute"}`), t)
+	defer muteClose()
+	assert.Equal(t, http.StatusOK, muteResp.StatusCode)
+	var muted struct {
+		ResponseType string `json:"response_type"`
+	}
+	assert.NoError(t, json.NewDecoder(muteResp.Body).Decode(&muted))
+	assert.Equal(t, model.SlashResponseEphemeral, muted.ResponseType)
+
+	unknownResp, unknownClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"/deploy prod"}`), t)
+	defer unknownClose()
+	assert.Equal(t, http.StatusBadRequest, unknownResp.StatusCode)
+
+	createResp, createClose := request("POST", "/rooms/"+roomId+"/commands", strings.NewReader(`{"name":"deploy","url":"https://example.com/deploy"}`), t)
+	defer createClose()
+	assert.Equal(t, http.StatusOK, createResp.StatusCode)
+}
+
+func TestPresence(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Presence",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	heartbeatResp, heartbeatClose := request("POST", "/presence", strings.NewReader(`{"status":"away"}`), t)
+	defer heartbeatClose()
+	assert.Equal(t, http.StatusOK, heartbeatResp.StatusCode)
+
+	typingResp, typingClose := request("POST", "/rooms/"+roomId+"/typing", strings.NewReader(`{"typing":true}`), t)
+	defer typingClose()
+	assert.Equal(t, http.StatusOK, typingResp.StatusCode)
+
+	presenceResp, presenceClose := request("GET", "/rooms/"+roomId+"/presence", nil, t)
+	defer presenceClose()
+	assert.Equal(t, http.StatusOK, presenceResp.StatusCode)
+	var presence struct {
+		Online []model.MemberPresence `json:"online"`
+		Typing []int                  `json:"typing"`
+	}
+	assert.NoError(t, json.NewDecoder(presenceResp.Body).Decode(&presence))
+	assert.Equal(t, []model.MemberPresence{{UserID: userId, Status: model.PresenceAway}}, presence.Online)
+	// 自分自身の入力中は返さない
+	assert.Empty(t, presence.Typing)
+
+	offlineResp, offlineClose := request("POST", "/presence", strings.NewReader(`{"status":"offline"}`), t)
+	defer offlineClose()
+	assert.Equal(t, http.StatusOK, offlineResp.StatusCode)
+}
+
+func TestPinsAndSavedMessages(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Pins",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	message, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, model.ChatMessage{
+		RoomID:    roomId,
+		UserID:    userId,
+		Message:   "Read the onboarding doc",
+		CreatedAt: time.Now(),
+	})
+	assert.NoError(t, err)
+	messageId := message.InsertedID.(primitive.ObjectID).Hex()
+
+	pinResp, pinClose := request("POST", "/rooms/"+roomId+"/pins", strings.NewReader(`{"message_id":"`+messageId+`"}`), t)
+	defer pinClose()
+	assert.Equal(t, http.StatusOK, pinResp.StatusCode)
+
+	duplicateResp, duplicateClose := request("POST", "/rooms/"+roomId+"/pins", strings.NewReader(`{"message_id":"`+messageId+`"}`), t)
+	defer duplicateClose()
+	assert.Equal(t, http.StatusConflict, duplicateResp.StatusCode)
+
+	pinsResp, pinsClose := request("GET", "/rooms/"+roomId+"/pins", nil, t)
+	defer pinsClose()
+	assert.Equal(t, http.StatusOK, pinsResp.StatusCode)
+	var pins struct {
+		Pins []struct {
+			Message  string
+			PinnedBy int
+		} `json:"pins"`
+	}
+	assert.NoError(t, json.NewDecoder(pinsResp.Body).Decode(&pins))
+	if assert.Len(t, pins.Pins, 1) {
+		assert.Equal(t, "Read the onboarding doc", pins.Pins[0].Message)
+		assert.Equal(t, userId, pins.Pins[0].PinnedBy)
+	}
+
+	// ルーム情報にもピン留めが含まれる
+	loadResp, loadClose := request("GET", "/load_chat/"+roomId, nil, t)
+	defer loadClose()
+	var loaded struct {
+		Room struct {
+			Pins []struct{ MessageID string }
+		} `json:"room"`
+	}
+	assert.NoError(t, json.NewDecoder(loadResp.Body).Decode(&loaded))
+	if assert.Len(t, loaded.Room.Pins, 1) {
+		assert.Equal(t, messageId, loaded.Room.Pins[0].MessageID)
+	}
+
+	saveResp, saveClose := request("POST", "/me/saved", strings.NewReader(`{"message_id":"`+messageId+`"}`), t)
+	defer saveClose()
+	assert.Equal(t, http.StatusOK, saveResp.StatusCode)
+
+	savedResp, savedClose := request("GET", "/me/saved", nil, t)
+	defer savedClose()
+	var saved struct {
+		Saved []struct {
+			Message  string
+			RoomName string
+		} `json:"saved"`
+		Total int64 `json:"total"`
+	}
+	assert.NoError(t, json.NewDecoder(savedResp.Body).Decode(&saved))
+	assert.Equal(t, int64(1), saved.Total)
+	if assert.Len(t, saved.Saved, 1) {
+		assert.Equal(t, "Pins", saved.Saved[0].RoomName)
+	}
+
+	unpinResp, unpinClose := request("DELETE", "/rooms/"+roomId+"/pins/"+messageId, nil, t)
+	defer unpinClose()
+	assert.Equal(t, http.StatusOK, unpinResp.StatusCode)
+
+	unsaveResp, unsaveClose := request("DELETE", "/me/saved/"+messageId, nil, t)
+	defer unsaveClose()
+	assert.Equal(t, http.StatusOK, unsaveResp.StatusCode)
+}
+
+func TestScheduledMessagesAndReminders(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Scheduled",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	message, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, model.ChatMessage{
+		RoomID:    roomId,
+		UserID:    userId,
+		Message:   "Deploy the release",
+		CreatedAt: time.Now(),
+	})
+	assert.NoError(t, err)
+	messageId := message.InsertedID.(primitive.ObjectID).Hex()
+
+	sendAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	scheduleResp, scheduleClose := request("POST", "/rooms/"+roomId+"/scheduled", strings.NewReader(`{"message":"Standup starts now","send_at":"`+sendAt+`"}`), t)
+	defer scheduleClose()
+	assert.Equal(t, http.StatusOK, scheduleResp.StatusCode)
+
+	remindResp, remindClose := request("POST", "/messages/"+messageId+"/remind", strings.NewReader(`{"in":"2h"}`), t)
+	defer remindClose()
+	assert.Equal(t, http.StatusOK, remindResp.StatusCode)
+
+	listResp, listClose := request("GET", "/me/scheduled", nil, t)
+	defer listClose()
+	var list struct {
+		Scheduled []model.ScheduledMessage `json:"scheduled"`
+	}
+	assert.NoError(t, json.NewDecoder(listResp.Body).Decode(&list))
+	assert.Len(t, list.Scheduled, 2)
+
+	// 時計を進めてスケジューラーを実行する
+	a := app.NewApp()
+	clock := &mock_clock_svc.FixedClock{FixedTime: time.Now().Add(30 * time.Minute)}
+	scheduler := schedule_svc.NewScheduleSvc(a.Handlers.MongoSvc, a.Handlers.MongoPkg, clock, a.Handlers)
+	assert.NoError(t, scheduler.RunScheduledMessages())
+	posted, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).CountDocuments(testMongoStruct.Ctx, bson.M{"roomid": roomId})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), posted)
+
+	clock.FixedTime = time.Now().Add(3 * time.Hour)
+	assert.NoError(t, scheduler.RunScheduledMessages())
+	// 2回目の実行では同じ予約を再び実行しない
+	assert.NoError(t, scheduler.RunScheduledMessages())
+
+	posted, err = testMongoStruct.DB.Collection(model.ChatMessageCollectionName).CountDocuments(testMongoStruct.Ctx, bson.M{"roomid": roomId, "message": "Standup starts now"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), posted)
+
+	reminders, err := testMongoStruct.DB.Collection(model.NotificationCollectionName).CountDocuments(testMongoStruct.Ctx, bson.M{"userid": userId, "kind": model.NotificationReminder, "messageid": messageId})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), reminders)
+
+	sent, err := testMongoStruct.DB.Collection(model.ScheduledMessageCollectionName).CountDocuments(testMongoStruct.Ctx, bson.M{"status": model.ScheduledSent})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), sent)
+}
+
+func TestMessageRetention(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Retention",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	old, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).InsertOne(testMongoStruct.Ctx, model.ChatMessage{
+		RoomID:    roomId,
+		UserID:    userId,
+		Message:   "Old message",
+		CreatedAt: time.Now().Add(-40 * 24 * time.Hour),
+	})
+	assert.NoError(t, err)
+	oldId := old.InsertedID.(primitive.ObjectID).Hex()
+
+	saveResp, saveClose := request("POST", "/me/saved", strings.NewReader(`{"message_id":"`+oldId+`"}`), t)
+	defer saveClose()
+	assert.Equal(t, http.StatusOK, saveResp.StatusCode)
+
+	// 設定する前に削除される件数を確認する
+	previewResp, previewClose := request("GET", "/rooms/"+roomId+"/retention?retention_days=30", nil, t)
+	defer previewClose()
+	var preview struct {
+		RetentionDays   int   `json:"retention_days"`
+		ExpiredMessages int64 `json:"expired_messages"`
+	}
+	assert.NoError(t, json.NewDecoder(previewResp.Body).Decode(&preview))
+	assert.Equal(t, 30, preview.RetentionDays)
+	assert.Equal(t, int64(1), preview.ExpiredMessages)
+
+	updateResp, updateClose := request("PATCH", "/rooms/"+roomId, strings.NewReader(`{"retention_days":30}`), t)
+	defer updateClose()
+	assert.Equal(t, http.StatusOK, updateResp.StatusCode)
+
+	postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"New message"}`), t)
+	defer postClose()
+	assert.Equal(t, http.StatusOK, postResp.StatusCode)
+
+	a := app.NewApp()
+	purger := purge_svc.NewPurgeSvc(a.Handlers.MongoSvc, a.Handlers.MongoPkg, mock_clock_svc.FixedClock{FixedTime: time.Now()}, storage_pkg.NewLocalStorage(t.TempDir()), time.Hour)
+	assert.NoError(t, purger.PurgeExpiredChatMessages())
+
+	var remaining []model.ChatMessage
+	cursor, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).Find(testMongoStruct.Ctx, bson.M{"roomid": roomId})
+	assert.NoError(t, err)
+	assert.NoError(t, cursor.All(testMongoStruct.Ctx, &remaining))
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "New message", remaining[0].Message)
+	// 新しいメッセージには投稿時に削除予定日時が設定される。保持期間を過ぎた既存のメッセージは
+	// TTL インデックスではなく定期削除で削除される
+	assert.NotNil(t, remaining[0].ExpiresAt)
+
+	saved, err := testMongoStruct.DB.Collection(model.SavedMessageCollectionName).CountDocuments(testMongoStruct.Ctx, bson.M{"messageid": oldId})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), saved)
+
+	reportResp, reportClose := request("GET", "/rooms/"+roomId+"/retention", nil, t)
+	defer reportClose()
+	var report struct {
+		ExpiredMessages int64 `json:"expired_messages"`
+	}
+	assert.NoError(t, json.NewDecoder(reportResp.Body).Decode(&report))
+	assert.Equal(t, int64(0), report.ExpiredMessages)
+
+	// 保持期間を解除すると削除予定日時も取り除く
+	clearResp, clearClose := request("PATCH", "/rooms/"+roomId, strings.NewReader(`{"retention_days":0}`), t)
+	defer clearClose()
+	assert.Equal(t, http.StatusOK, clearResp.StatusCode)
+	assert.NoError(t, purger.PurgeExpiredChatMessages())
+	expiring, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).CountDocuments(testMongoStruct.Ctx, bson.M{"roomid": roomId, "expiresat": bson.M{"$exists": true}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), expiring)
+}
+
+func TestRoomExports(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	inserted, err := testMongoStruct.DB.Collection(model.RoomCollectionName).InsertOne(testMongoStruct.Ctx, model.Room{
+		Name:      "Exports",
+		OwnerID:   userId,
+		CreatedAt: time.Now(),
+		Members:   []int{userId},
+	})
+	assert.NoError(t, err)
+	roomId := inserted.InsertedID.(primitive.ObjectID).Hex()
+
+	for _, message := range []string{"first", "second"} {
+		postResp, postClose := request("POST", "/post_chat_message", strings.NewReader(`{"room_id":"`+roomId+`","message":"`+message+`"}`), t)
+		defer postClose()
+		assert.Equal(t, http.StatusOK, postResp.StatusCode)
+	}
+
+	createResp, createClose := request("POST", "/rooms/"+roomId+"/exports", strings.NewReader(`{"format":"csv"}`), t)
+	defer createClose()
+	assert.Equal(t, http.StatusOK, createResp.StatusCode)
+	var created struct {
+		ExportID string `json:"export_id"`
+	}
+	assert.NoError(t, json.NewDecoder(createResp.Body).Decode(&created))
+
+	// 処理中は新しいエクスポートを受け付けない
+	conflictResp, conflictClose := request("POST", "/rooms/"+roomId+"/exports", strings.NewReader(`{}`), t)
+	defer conflictClose()
+	assert.Equal(t, http.StatusConflict, conflictResp.StatusCode)
+
+	notReadyResp, notReadyClose := request("GET", "/rooms/"+roomId+"/exports/"+created.ExportID+"/download", nil, t)
+	defer notReadyClose()
+	assert.Equal(t, http.StatusConflict, notReadyResp.StatusCode)
+
+	assert.NoError(t, app.NewApp().Handlers.ExportSvc.RunRoomExports())
+
+	statusResp, statusClose := request("GET", "/rooms/"+roomId+"/exports/"+created.ExportID, nil, t)
+	defer statusClose()
+	var status struct {
+		Export struct {
+			Status       string
+			MessageCount int64
+			DownloadURL  string
+		} `json:"export"`
+	}
+	assert.NoError(t, json.NewDecoder(statusResp.Body).Decode(&status))
+	assert.Equal(t, model.ExportCompleted, status.Export.Status)
+	assert.Equal(t, int64(2), status.Export.MessageCount)
+
+	downloadResp, downloadClose := request("GET", status.Export.DownloadURL, nil, t)
+	defer downloadClose()
+	assert.Equal(t, http.StatusOK, downloadResp.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", downloadResp.Header.Get("Content-Type"))
+	downloaded, err := io.ReadAll(downloadResp.Body)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(downloaded)), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[1], "first")
+		assert.Contains(t, lines[2], "second")
+	}
+}
+
+func TestImportHistory(t *testing.T) {
+	testMongoStruct.MongoCleanUp()
+
+	history := `{"id":"m1","room":"migrated","user":"alice","created_at":"2020-01-01T00:00:00Z","message":"first"}
+{"id":"m2","room":"migrated","user":"bob","created_at":"2020-01-01T00:01:00Z","message":"reply","parent_id":"m1"}
+`
+	mapping := import_svc.UserMapping{"alice": userId, "bob": 23456}
+
+	a := app.NewApp()
+	importer := import_svc.NewImportSvc(a.Handlers.MongoSvc, a.Handlers.MongoPkg)
+	result, err := importer.Import(import_svc.NewJSONLSource(strings.NewReader(history), ""), mapping, userId)
+	assert.NoError(t, err)
+	assert.Equal(t, import_svc.Result{Rooms: 1, Imported: 2}, result)
+
+	// もう一度取り込んでも重複しない
+	result, err = importer.Import(import_svc.NewJSONLSource(strings.NewReader(history), ""), mapping, userId)
+	assert.NoError(t, err)
+	assert.Equal(t, import_svc.Result{Rooms: 1, Skipped: 2}, result)
+
+	var room model.Room
+	assert.NoError(t, testMongoStruct.DB.Collection(model.RoomCollectionName).FindOne(testMongoStruct.Ctx, bson.M{"externalid": "jsonl:migrated"}).Decode(&room))
+	assert.ElementsMatch(t, []int{userId, 23456}, room.Members)
+	if assert.NotNil(t, room.LastActivityAt) {
+		assert.True(t, room.LastActivityAt.Equal(time.Date(2020, 1, 1, 0, 1, 0, 0, time.UTC)))
+	}
+
+	var messages []model.ChatMessage
+	cursor, err := testMongoStruct.DB.Collection(model.ChatMessageCollectionName).Find(testMongoStruct.Ctx, bson.M{"roomid": room.ID.Hex()}, options.Find().SetSort(bson.M{"_id": 1}))
+	assert.NoError(t, err)
+	assert.NoError(t, cursor.All(testMongoStruct.Ctx, &messages))
+	if assert.Len(t, messages, 2) {
+		// 元の投稿日時のまま取り込む
+		assert.True(t, messages[0].CreatedAt.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+		assert.Equal(t, 1, messages[0].ReplyCount)
+		assert.Equal(t, messages[0].ID.Hex(), messages[1].ParentID)
+		assert.Equal(t, 23456, messages[1].UserID)
+	}
+
+	threadResp, threadClose := request("GET", "/messages/"+messages[0].ID.Hex()+"/thread", nil, t)
+	defer threadClose()
+	assert.Equal(t, http.StatusOK, threadResp.StatusCode)
 }
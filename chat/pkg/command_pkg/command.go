@@ -0,0 +1,91 @@
+package command_pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"microservices/chat/pkg/netguard_pkg"
+	"microservices/chat/pkg/webhook_pkg"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 外部のコマンドの応答の上限
+const maxResponseSize = 64 << 10
+
+// 外部のコマンドに送る内容
+type Payload struct {
+	CommandID string `json:"command_id"`
+	Command   string `json:"command"`
+	Text      string `json:"text"` // コマンド名より後の引数
+	RoomID    string `json:"room_id"`
+	UserID    int    `json:"user_id"`
+}
+
+// 外部のコマンドの応答
+type Response struct {
+	ResponseType string `json:"response_type"` // ephemeral（既定）か in_channel
+	Text         string `json:"text"`
+}
+
+type Request struct {
+	URL     string
+	Secret  string
+	Payload Payload
+}
+
+type ClientInterface interface {
+	// 2xx 以外の応答や JSON でない応答はエラーとして返す
+	Invoke(ctx context.Context, req Request, now time.Time) (Response, error)
+}
+
+type Client struct {
+	HTTPClient *http.Client
+}
+
+func NewClient(timeout time.Duration) *Client {
+	return &Client{HTTPClient: &http.Client{
+		Timeout:   timeout,
+		Transport: netguard_pkg.NewTransport(),
+		// リダイレクト先には署名付きの本文を送らない
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Webhook と同じ形式で署名して送る
+func (c *Client) Invoke(ctx context.Context, req Request, now time.Time) (Response, error) {
+	body, err := json.Marshal(req.Payload)
+	if err != nil {
+		return Response{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	timestamp := now.Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(webhook_pkg.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(webhook_pkg.SignatureHeader, webhook_pkg.Sign(req.Secret, timestamp, body))
+
+	resp, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Response{}, fmt.Errorf("command responded with status %d", resp.StatusCode)
+	}
+
+	var response Response
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&response); err != nil {
+		return Response{}, fmt.Errorf("invalid command response: %w", err)
+	}
+	return response, nil
+}
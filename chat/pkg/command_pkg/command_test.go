@@ -0,0 +1,77 @@
+package command_pkg
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"microservices/chat/pkg/netguard_pkg"
+	"microservices/chat/pkg/webhook_pkg"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoke(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	payload := Payload{CommandID: "c1", Command: "deploy", Text: "prod", RoomID: "room1", UserID: 1}
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		expect    Response
+		returnErr bool
+	}{
+		{"success", http.StatusOK, `{"response_type":"in_channel","text":"deploying"}`, Response{ResponseType: "in_channel", Text: "deploying"}, false},
+		{"error_status", http.StatusInternalServerError, `{}`, Response{}, true},
+		{"invalid_json", http.StatusOK, `deploying`, Response{}, true},
+		// リダイレクトには従わない
+		{"redirect", http.StatusFound, ``, Response{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				var got Payload
+				assert.NoError(t, json.Unmarshal(body, &got))
+				assert.Equal(t, payload, got)
+				timestamp, _ := strconv.ParseInt(r.Header.Get(webhook_pkg.TimestampHeader), 10, 64)
+				assert.Equal(t, now.Unix(), timestamp)
+				assert.True(t, webhook_pkg.Verify("secret", timestamp, body, r.Header.Get(webhook_pkg.SignatureHeader)))
+
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			// httptest のサーバーはループバックで待ち受けるので接続先を制限しない
+			client := NewClient(time.Second)
+			client.HTTPClient.Transport = http.DefaultTransport
+			resp, err := client.Invoke(context.Background(), Request{URL: server.URL, Secret: "secret", Payload: payload}, now)
+			if tt.returnErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expect, resp)
+		})
+	}
+}
+
+func TestInvokeInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach an internal address")
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Invoke(context.Background(), Request{URL: server.URL, Secret: "secret"}, time.Now())
+	assert.ErrorIs(t, err, netguard_pkg.ErrForbiddenAddress)
+}
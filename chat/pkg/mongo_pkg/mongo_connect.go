@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -20,48 +21,74 @@ type MongoPkgInterface interface {
 	NewMongoConnect(database string) (*MongoPkgStruct, error)
 }
 
-type MongoPkg struct{}
+// mongo.Client はコネクションプールを持つので、プロセス内で1つを共有する
+type MongoPkg struct {
+	mu     sync.Mutex
+	client *mongo.Client
+}
 
 func NewMongoPkg() *MongoPkg {
 	return &MongoPkg{}
 }
 
-func (m *MongoPkg) connect() (*mongo.Client, context.Context, context.CancelFunc, error) {
-	// タイムアウト付きのcontext
+// 初回の呼び出しで接続し、以降は同じクライアントを返す（接続に失敗した場合は次回やり直す）
+func (m *MongoPkg) connect() (*mongo.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client != nil {
+		return m.client, nil
+	}
+
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
 
 	mongoURI := os.Getenv("MONGODB_URI")
 	clientOptions := options.Client().ApplyURI(mongoURI)
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		defer cancelFunc()
-		return nil, nil, nil, err
+		return nil, err
 	}
 
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		defer cancelFunc()
-		return nil, nil, nil, err
+		client.Disconnect(context.Background())
+		return nil, err
 	}
 
 	fmt.Println("Connected to MongoDB!")
 
-	return client, ctx, cancelFunc, nil
+	m.client = client
+	return client, nil
 }
 
+// 共有しているクライアントの接続を使い、呼び出しごとにタイムアウト付きの context を作る
 func (m *MongoPkg) NewMongoConnect(database string) (*MongoPkgStruct, error) {
-	client, ctx, cancelFunc, err := m.connect()
+	client, err := m.connect()
 	if err != nil {
 		return nil, err
 	}
 
+	// タイムアウト付きのcontext
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+
 	mongoPkgStruct := &MongoPkgStruct{}
 	mongoPkgStruct.Ctx = ctx
 	mongoPkgStruct.Cancel = cancelFunc
 	mongoClient := &RealMongoClient{client: client}
 	mongoPkgStruct.Db = mongoClient.Database(database)
-	fmt.Println("Connected to MongoDB!")
 
 	return mongoPkgStruct, nil
 }
+
+// 共有しているクライアントを切断する。切断後に NewMongoConnect を呼ぶと接続し直す
+func (m *MongoPkg) Close(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client == nil {
+		return nil
+	}
+	err := m.client.Disconnect(ctx)
+	m.client = nil
+	return err
+}
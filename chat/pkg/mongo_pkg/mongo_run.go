@@ -6,6 +6,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Mongo Cursor
@@ -34,6 +35,7 @@ func (r *RealMongoCursor) Close(ctx context.Context) error {
 // Mongo Database
 type MongoDatabaseInterface interface {
 	Collection(name string) MongoCollectionInterface
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type RealMongoDatabase struct {
@@ -45,6 +47,21 @@ func (r *RealMongoDatabase) Collection(name string) MongoCollectionInterface {
 	return &RealMongoCollection{coll: r.db.Collection(name)}
 }
 
+// fn 内のコレクション操作に渡された ctx を使うと、同じトランザクションで実行される
+// トランザクションはレプリカセット構成の MongoDB でのみ利用できる
+func (r *RealMongoDatabase) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := r.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
+
 // Mongo Collection
 type MongoCollectionInterface interface {
 	InsertOne(ctx context.Context, document interface{}) (string, error)
@@ -53,6 +70,12 @@ type MongoCollectionInterface interface {
 	UpdateOne(ctx context.Context, filter interface{}, update interface{}) (*mongo.UpdateResult, error)
 	UpdateMany(ctx context.Context, filter interface{}, update interface{}) (*mongo.UpdateResult, error)
 	DeleteOne(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error)
+	FindWithOptions(ctx context.Context, filter interface{}, opts *options.FindOptions) (cursor MongoCursorInterface, err error)
+	CountDocuments(ctx context.Context, filter interface{}) (int64, error)
+	CreateIndex(ctx context.Context, index mongo.IndexModel) (string, error)
+	DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error)
+	UpdateOneWithOptions(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error)
+	Aggregate(ctx context.Context, pipeline interface{}) (cursor MongoCursorInterface, err error)
 }
 
 type RealMongoCollection struct {
@@ -94,6 +117,32 @@ func (r *RealMongoCollection) DeleteOne(ctx context.Context, filter interface{})
 	return r.coll.DeleteOne(ctx, filter)
 }
 
+func (r *RealMongoCollection) FindWithOptions(ctx context.Context, filter interface{}, opts *options.FindOptions) (cursor MongoCursorInterface, err error) {
+	cursor, err = r.coll.Find(ctx, filter, opts)
+	return
+}
+
+func (r *RealMongoCollection) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	return r.coll.CountDocuments(ctx, filter)
+}
+
+func (r *RealMongoCollection) CreateIndex(ctx context.Context, index mongo.IndexModel) (string, error) {
+	return r.coll.Indexes().CreateOne(ctx, index)
+}
+
+func (r *RealMongoCollection) DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	return r.coll.DeleteMany(ctx, filter)
+}
+
+func (r *RealMongoCollection) UpdateOneWithOptions(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return r.coll.UpdateOne(ctx, filter, update, opts)
+}
+
+func (r *RealMongoCollection) Aggregate(ctx context.Context, pipeline interface{}) (cursor MongoCursorInterface, err error) {
+	cursor, err = r.coll.Aggregate(ctx, pipeline)
+	return
+}
+
 // Mongo Client
 type RealMongoClient struct {
 	client *mongo.Client
@@ -0,0 +1,73 @@
+package netguard_pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// 利用者が指定した URL から内部のネットワークに接続されないよう、接続先のアドレスを制限する
+var ErrForbiddenAddress = errors.New("address is not allowed")
+
+// ループバック、プライベート、リンクローカル、未指定のアドレスには接続しない
+func CheckIP(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// 登録時の確認。名前解決できたアドレスに制限されたものが含まれる場合はエラーを返す。
+// 名前解決の結果は接続時に変わり得るので、接続時にも Control で確認する
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return CheckIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		// 解決できない名前は接続時に確認する
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := CheckIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// net.Dialer の Control に渡す。名前解決の後の実際の接続先を確認するので DNS リバインディングも防げる
+func Control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return CheckIP(ip)
+}
+
+// 接続先を制限する Transport。プロキシを経由すると接続先を確認できないため使わない
+func NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}).DialContext
+	return transport
+}
@@ -0,0 +1,81 @@
+package netguard_pkg
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIP(t *testing.T) {
+	tests := []struct {
+		ip        string
+		expectErr bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.0.0.1", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"fd00::1", true},
+		// クラウドのメタデータサーバー
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"::ffff:127.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			err := CheckIP(net.ParseIP(tt.ip))
+			if tt.expectErr {
+				assert.ErrorIs(t, err, ErrForbiddenAddress)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		expectErr bool
+	}{
+		{"public_ip", "https://93.184.216.34/hook", false},
+		{"loopback", "http://127.0.0.1:8080/hook", true},
+		{"localhost", "http://localhost/hook", true},
+		{"metadata", "http://169.254.169.254/latest/meta-data", true},
+		{"ipv6_loopback", "http://[::1]/hook", true},
+		{"invalid_scheme", "ftp://example.com/hook", true},
+		{"no_host", "https:///hook", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckURL(context.Background(), tt.url)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// 登録後に名前解決の結果が変わった場合も接続しない
+	client := &http.Client{Transport: NewTransport()}
+	_, err := client.Get(server.URL)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
@@ -0,0 +1,71 @@
+package notifier_pkg
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPConfig struct {
+	Addr     string // host:port
+	From     string
+	Username string // 空の場合は認証しない
+	Password string
+}
+
+// SMTP でテキストメールを送る
+type EmailNotifier struct {
+	Config   SMTPConfig
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewEmailNotifier(config SMTPConfig) *EmailNotifier {
+	return &EmailNotifier{Config: config, SendMail: smtp.SendMail}
+}
+
+// ヘッダーインジェクションを防ぐため改行を取り除く
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+func digestSubject(digest Digest) string {
+	if len(digest.Items) == 1 {
+		return "You have a new notification"
+	}
+	return fmt.Sprintf("You have %d new notifications", len(digest.Items))
+}
+
+func digestText(digest Digest) string {
+	var b strings.Builder
+	for _, item := range digest.Items {
+		fmt.Fprintf(&b, "[%s] user %d in room %s at %s\r\n", item.Kind, item.ActorID, item.RoomID, item.CreatedAt.Format("2006-01-02 15:04 MST"))
+		fmt.Fprintf(&b, "%s\r\n\r\n", item.Snippet)
+	}
+	return b.String()
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, recipient Recipient, digest Digest) error {
+	if recipient.Email == "" {
+		return ErrNoAddress
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	to := headerValue(recipient.Email)
+	msg := "From: " + headerValue(n.Config.From) + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + digestSubject(digest) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		digestText(digest)
+
+	var auth smtp.Auth
+	if n.Config.Username != "" {
+		host, _, _ := strings.Cut(n.Config.Addr, ":")
+		auth = smtp.PlainAuth("", n.Config.Username, n.Config.Password, host)
+	}
+	return n.SendMail(n.Config.Addr, auth, n.Config.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,36 @@
+package notifier_pkg
+
+import (
+	"context"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailNotifier(t *testing.T) {
+	var addr, from string
+	var to []string
+	var msg string
+	notifier := NewEmailNotifier(SMTPConfig{Addr: "smtp.example.com:587", From: "chat@example.com"})
+	notifier.SendMail = func(a string, auth smtp.Auth, f string, t []string, m []byte) error {
+		addr, from, to, msg = a, f, t, string(m)
+		return nil
+	}
+
+	// 改行でヘッダーを差し込めない
+	err := notifier.Notify(context.Background(), Recipient{UserID: 2, Email: "bob@example.com\r\nBcc: eve@example.com"}, testDigest)
+	assert.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:587", addr)
+	assert.Equal(t, "chat@example.com", from)
+	assert.Equal(t, []string{"bob@example.comBcc: eve@example.com"}, to)
+	assert.NotContains(t, msg, "\r\nBcc:")
+	assert.Contains(t, msg, "Subject: You have 2 new notifications\r\n")
+	assert.Contains(t, msg, "hi @bob")
+	assert.Contains(t, msg, "[reply] user 3 in room room1")
+
+	err = notifier.Notify(context.Background(), Recipient{UserID: 2}, testDigest)
+	assert.ErrorIs(t, err, ErrNoAddress)
+
+	assert.Equal(t, "You have a new notification", digestSubject(Digest{Items: testDigest.Items[:1]}))
+}
@@ -0,0 +1,38 @@
+package notifier_pkg
+
+import (
+	"context"
+	"sync"
+)
+
+type SentDigest struct {
+	Recipient Recipient
+	Digest    Digest
+}
+
+// 送信した内容をメモリに保持する（テストや開発用）
+type MemoryNotifier struct {
+	mu   sync.Mutex
+	sent []SentDigest
+	Err  error // 設定した場合は送信に失敗する
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+func (n *MemoryNotifier) Notify(ctx context.Context, recipient Recipient, digest Digest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.Err != nil {
+		return n.Err
+	}
+	n.sent = append(n.sent, SentDigest{Recipient: recipient, Digest: digest})
+	return nil
+}
+
+func (n *MemoryNotifier) Sent() []SentDigest {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]SentDigest{}, n.sent...)
+}
@@ -0,0 +1,18 @@
+package notifier_pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryNotifier(t *testing.T) {
+	notifier := NewMemoryNotifier()
+	assert.NoError(t, notifier.Notify(context.Background(), Recipient{UserID: 2}, testDigest))
+	assert.Equal(t, []SentDigest{{Recipient: Recipient{UserID: 2}, Digest: testDigest}}, notifier.Sent())
+
+	notifier.Err = assert.AnError
+	assert.ErrorIs(t, notifier.Notify(context.Background(), Recipient{UserID: 2}, testDigest), assert.AnError)
+	assert.Len(t, notifier.Sent(), 1)
+}
@@ -0,0 +1,37 @@
+package notifier_pkg
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 通知の送り先（チャネルに応じて使う項目が異なる）
+type Recipient struct {
+	UserID     int
+	Email      string
+	WebhookURL string
+}
+
+// まとめて送る通知の1件
+type DigestItem struct {
+	Kind      string    `json:"kind"` // mention / direct / reply / reminder
+	RoomID    string    `json:"room_id"`
+	MessageID string    `json:"message_id"`
+	ActorID   int       `json:"actor_id"` // 投稿したユーザー
+	Snippet   string    `json:"snippet"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Digest struct {
+	UserID int          `json:"user_id"`
+	Items  []DigestItem `json:"items"`
+}
+
+// 送り先のアドレスが無い（再試行しても送れない）
+var ErrNoAddress = errors.New("recipient has no address for this channel")
+
+// 通知の配信チャネル
+type Notifier interface {
+	Notify(ctx context.Context, recipient Recipient, digest Digest) error
+}
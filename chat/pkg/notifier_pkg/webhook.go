@@ -0,0 +1,49 @@
+package notifier_pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"time"
+)
+
+// ユーザーが登録したURLに JSON を POST する
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{Client: &http.Client{Timeout: timeout, Transport: netguard_pkg.NewTransport()}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, recipient Recipient, digest Digest) error {
+	if recipient.WebhookURL == "" {
+		return ErrNoAddress
+	}
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, recipient.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
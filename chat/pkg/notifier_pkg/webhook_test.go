@@ -0,0 +1,79 @@
+package notifier_pkg
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDigest = Digest{
+	UserID: 2,
+	Items: []DigestItem{
+		{Kind: "mention", RoomID: "room1", MessageID: "m1", ActorID: 1, Snippet: "hi @bob", CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{Kind: "reply", RoomID: "room1", MessageID: "m2", ActorID: 3, Snippet: "agreed", CreatedAt: time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)},
+	},
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		noURL     bool
+		expectErr error
+		returnErr bool
+	}{
+		{"success", http.StatusNoContent, false, nil, false},
+		{"server_error", http.StatusInternalServerError, false, nil, true},
+		{"no_url", http.StatusOK, true, ErrNoAddress, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received Digest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				body, _ := io.ReadAll(r.Body)
+				assert.NoError(t, json.Unmarshal(body, &received))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			recipient := Recipient{UserID: 2, WebhookURL: server.URL}
+			if tt.noURL {
+				recipient.WebhookURL = ""
+			}
+			// httptest のサーバーはループバックで待ち受けるので接続先を制限しない
+			notifier := NewWebhookNotifier(time.Second)
+			notifier.Client.Transport = http.DefaultTransport
+			err := notifier.Notify(context.Background(), recipient, testDigest)
+			if tt.expectErr != nil {
+				assert.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testDigest, received)
+		})
+	}
+}
+
+func TestWebhookNotifierInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach an internal address")
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(time.Second).Notify(context.Background(), Recipient{UserID: 2, WebhookURL: server.URL}, testDigest)
+	assert.ErrorIs(t, err, netguard_pkg.ErrForbiddenAddress)
+}
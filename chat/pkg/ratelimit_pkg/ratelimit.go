@@ -0,0 +1,60 @@
+package ratelimit_pkg
+
+import (
+	"sync"
+	"time"
+)
+
+type Limiter interface {
+	// key ごとの回数を数え、上限を超えた場合は false を返す
+	Allow(key string, now time.Time) bool
+}
+
+type counter struct {
+	start time.Time
+	count int
+}
+
+// 固定ウィンドウで回数を数える（プロセスごとに保持するため、レプリカ数だけ上限が増える）
+type MemoryLimiter struct {
+	Limit  int
+	Window time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*counter
+	lastSweep time.Time
+}
+
+func NewMemoryLimiter(limit int, window time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{
+		Limit:   limit,
+		Window:  window,
+		windows: map[string]*counter{},
+	}
+}
+
+func (l *MemoryLimiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 期限切れのウィンドウを定期的に捨て、使われなくなったキーが残り続けないようにする
+	if now.Sub(l.lastSweep) >= l.Window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.Window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.Window {
+		w = &counter{start: now}
+		l.windows[key] = w
+	}
+	if w.count >= l.Limit {
+		return false
+	}
+	w.count++
+	return true
+}
@@ -0,0 +1,33 @@
+package ratelimit_pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter(2, time.Minute)
+
+	assert.True(t, limiter.Allow("a", now))
+	assert.True(t, limiter.Allow("a", now.Add(time.Second)))
+	assert.False(t, limiter.Allow("a", now.Add(2*time.Second)))
+	// キーごとに数える
+	assert.True(t, limiter.Allow("b", now.Add(2*time.Second)))
+	// ウィンドウが変われば再び許可する
+	assert.True(t, limiter.Allow("a", now.Add(time.Minute)))
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter(1, time.Minute)
+
+	limiter.Allow("a", now)
+	limiter.Allow("b", now.Add(30*time.Second))
+	limiter.Allow("c", now.Add(time.Minute))
+
+	assert.Len(t, limiter.windows, 2)
+	assert.NotContains(t, limiter.windows, "a")
+}
@@ -0,0 +1,15 @@
+package retry_pkg
+
+import "time"
+
+// attempts 回失敗した後の再試行までの間隔。失敗するごとに base を倍にし、max で打ち切る
+func Backoff(base time.Duration, max time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return min(d, max)
+}
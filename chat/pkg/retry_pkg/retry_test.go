@@ -0,0 +1,24 @@
+package retry_pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expect   time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expect, Backoff(30*time.Second, time.Hour, tt.attempts))
+	}
+}
@@ -0,0 +1,40 @@
+package storage_pkg
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	storage := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	err := storage.Put(ctx, "rooms/room1/file1", strings.NewReader("hello"), 5, "text/plain")
+	assert.NoError(t, err)
+
+	body, err := storage.Get(ctx, "rooms/room1/file1")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "hello", string(data))
+
+	assert.NoError(t, storage.Delete(ctx, "rooms/room1/file1"))
+	// 削除済みのキーを再度削除してもエラーにしない
+	assert.NoError(t, storage.Delete(ctx, "rooms/room1/file1"))
+
+	_, err = storage.Get(ctx, "rooms/room1/file1")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestLocalStorageInvalidKey(t *testing.T) {
+	storage := NewLocalStorage(t.TempDir())
+
+	for _, key := range []string{"", "/", "../outside", "rooms/../../outside"} {
+		err := storage.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain")
+		assert.Error(t, err, key)
+	}
+}
@@ -0,0 +1,99 @@
+package storage_pkg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 署名を検証してオブジェクトをメモリに保持する S3 の代わりのサーバー
+type fakeS3 struct {
+	mu      sync.Mutex
+	config  S3Config
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	expected, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	SignV4(expected, f.config, signedAt)
+	if r.Header.Get("Authorization") != expected.Header.Get("Authorization") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "SignatureDoesNotMatch")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setupS3(t *testing.T, serverConfig S3Config) *S3Storage {
+	server := httptest.NewServer(&fakeS3{config: serverConfig, objects: map[string][]byte{}})
+	t.Cleanup(server.Close)
+
+	storage, err := NewS3Storage(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "attachments",
+		Region:          "us-east-1",
+		AccessKeyID:     "access",
+		SecretAccessKey: "secret",
+	})
+	assert.NoError(t, err)
+	return storage
+}
+
+func TestS3Storage(t *testing.T) {
+	storage := setupS3(t, S3Config{Region: "us-east-1", SecretAccessKey: "secret", AccessKeyID: "access"})
+	ctx := context.Background()
+
+	err := storage.Put(ctx, "rooms/room1/file name.txt", strings.NewReader("hello"), 5, "text/plain")
+	assert.NoError(t, err)
+
+	body, err := storage.Get(ctx, "rooms/room1/file name.txt")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "hello", string(data))
+
+	assert.NoError(t, storage.Delete(ctx, "rooms/room1/file name.txt"))
+	_, err = storage.Get(ctx, "rooms/room1/file name.txt")
+	assert.ErrorIs(t, err, ErrBlobNotFound)
+}
+
+func TestS3StorageSignatureMismatch(t *testing.T) {
+	storage := setupS3(t, S3Config{Region: "us-east-1", SecretAccessKey: "other", AccessKeyID: "access"})
+
+	err := storage.Put(context.Background(), "rooms/room1/file1", strings.NewReader("hello"), 5, "text/plain")
+	assert.ErrorContains(t, err, "SignatureDoesNotMatch")
+}
+
+func TestNewS3StorageRequiresBucket(t *testing.T) {
+	_, err := NewS3Storage(S3Config{Endpoint: "http://localhost:9000"})
+	assert.Error(t, err)
+}
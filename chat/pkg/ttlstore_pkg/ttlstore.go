@@ -0,0 +1,89 @@
+package ttlstore_pkg
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 期限付きのキーと値を保持する（期限切れの値は返さない）
+// now はメモリ上の実装で期限の判定に使う（Redis などの実装では無視してよい）
+type Store interface {
+	Set(ctx context.Context, key string, value string, ttl time.Duration, now time.Time) error
+	Delete(ctx context.Context, key string) error
+	GetMany(ctx context.Context, keys []string, now time.Time) (map[string]string, error)
+	ListPrefix(ctx context.Context, prefix string, now time.Time) (map[string]string, error)
+}
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// プロセス内に保持する（レプリカ間では共有されない）
+type MemoryStore struct {
+	SweepInterval time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]entry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		SweepInterval: time.Minute,
+		entries:       map[string]entry{},
+	}
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value string, ttl time.Duration, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 期限切れの値を定期的に捨て、参照されなくなったキーが残り続けないようにする
+	if now.Sub(s.lastSweep) >= s.SweepInterval {
+		for k, e := range s.entries {
+			if !now.Before(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.entries[key] = entry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) GetMany(ctx context.Context, keys []string, now time.Time) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := map[string]string{}
+	for _, key := range keys {
+		if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+			values[key] = e.value
+		}
+	}
+	return values, nil
+}
+
+func (s *MemoryStore) ListPrefix(ctx context.Context, prefix string, now time.Time) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := map[string]string{}
+	for key, e := range s.entries {
+		if strings.HasPrefix(key, prefix) && now.Before(e.expiresAt) {
+			values[key] = e.value
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,51 @@
+package ttlstore_pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+
+	assert.NoError(t, store.Set(ctx, "typing:room1:1", "1", 5*time.Second, now))
+	assert.NoError(t, store.Set(ctx, "typing:room1:2", "1", 10*time.Second, now))
+	assert.NoError(t, store.Set(ctx, "typing:room2:3", "1", 10*time.Second, now))
+	assert.NoError(t, store.Set(ctx, "presence:1", "online", time.Minute, now))
+
+	values, err := store.GetMany(ctx, []string{"presence:1", "presence:2"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"presence:1": "online"}, values)
+
+	values, err = store.ListPrefix(ctx, "typing:room1:", now)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"typing:room1:1": "1", "typing:room1:2": "1"}, values)
+
+	// 期限切れの値は返さない
+	values, err = store.ListPrefix(ctx, "typing:room1:", now.Add(5*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"typing:room1:2": "1"}, values)
+
+	assert.NoError(t, store.Delete(ctx, "presence:1"))
+	values, err = store.GetMany(ctx, []string{"presence:1"}, now)
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+
+	store.Set(ctx, "a", "1", time.Second, now)
+	store.Set(ctx, "b", "1", time.Hour, now.Add(time.Second))
+	store.Set(ctx, "c", "1", time.Second, now.Add(time.Minute))
+
+	assert.Len(t, store.entries, 2)
+	assert.NotContains(t, store.entries, "a")
+}
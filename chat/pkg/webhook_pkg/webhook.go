@@ -0,0 +1,89 @@
+package webhook_pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 受信側が検証に使うヘッダー
+const (
+	EventHeader     = "X-Chat-Event"
+	DeliveryHeader  = "X-Chat-Delivery"
+	TimestampHeader = "X-Chat-Timestamp"
+	SignatureHeader = "X-Chat-Signature"
+)
+
+// "タイムスタンプ.本文" の HMAC-SHA256。タイムスタンプを含めることで古いリクエストの再送を検出できる
+func Sign(secret string, timestamp int64, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
+
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID string
+	Body       []byte
+}
+
+type SenderInterface interface {
+	// 2xx 以外の応答はエラーとして返す（応答が無い場合のステータスは0）
+	Send(ctx context.Context, req Request, now time.Time) (int, error)
+}
+
+type Sender struct {
+	Client *http.Client
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{Client: &http.Client{
+		Timeout:   timeout,
+		Transport: netguard_pkg.NewTransport(),
+		// リダイレクト先には署名付きの本文を送らない
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (s *Sender) Send(ctx context.Context, req Request, now time.Time) (int, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(EventHeader, req.Event)
+	httpReq.Header.Set(DeliveryHeader, req.DeliveryID)
+	httpReq.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := s.Client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,98 @@
+package webhook_pkg
+
+import (
+	"context"
+	"io"
+	"microservices/chat/pkg/netguard_pkg"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"message.created"}`)
+	signature := Sign("secret", 1700000000, body)
+
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	assert.True(t, Verify("secret", 1700000000, body, signature))
+	assert.False(t, Verify("other", 1700000000, body, signature))
+	assert.False(t, Verify("secret", 1700000001, body, signature))
+	assert.False(t, Verify("secret", 1700000000, []byte(`{}`), signature))
+}
+
+func TestSend(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"message.created"}`)
+
+	tests := []struct {
+		name       string
+		status     int
+		redirect   bool
+		expectCode int
+		returnErr  bool
+	}{
+		{"success", http.StatusOK, false, http.StatusOK, false},
+		{"server_error", http.StatusBadGateway, false, http.StatusBadGateway, true},
+		// リダイレクトには従わない
+		{"redirect", http.StatusOK, true, http.StatusFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *http.Request
+			var receivedBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.redirect && r.URL.Path != "/moved" {
+					http.Redirect(w, r, "/moved", http.StatusFound)
+					return
+				}
+				received = r
+				receivedBody, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			// httptest のサーバーはループバックで待ち受けるので接続先を制限しない
+			sender := NewSender(time.Second)
+			sender.Client.Transport = http.DefaultTransport
+			code, err := sender.Send(context.Background(), Request{
+				URL:        server.URL,
+				Secret:     "secret",
+				Event:      "message.created",
+				DeliveryID: "d1",
+				Body:       body,
+			}, now)
+			assert.Equal(t, tt.expectCode, code)
+			if tt.returnErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.redirect {
+				assert.Nil(t, received)
+				return
+			}
+
+			assert.Equal(t, body, receivedBody)
+			assert.Equal(t, "message.created", received.Header.Get(EventHeader))
+			assert.Equal(t, "d1", received.Header.Get(DeliveryHeader))
+			assert.Equal(t, strconv.FormatInt(now.Unix(), 10), received.Header.Get(TimestampHeader))
+			assert.True(t, Verify("secret", now.Unix(), receivedBody, received.Header.Get(SignatureHeader)))
+		})
+	}
+}
+
+func TestSendInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request must not reach an internal address")
+	}))
+	defer server.Close()
+
+	code, err := NewSender(time.Second).Send(context.Background(), Request{URL: server.URL, Secret: "secret"}, time.Now())
+	assert.Equal(t, 0, code)
+	assert.ErrorIs(t, err, netguard_pkg.ErrForbiddenAddress)
+}
@@ -0,0 +1,18 @@
+package mock_command_pkg
+
+import (
+	"context"
+	"microservices/chat/pkg/command_pkg"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ClientMock struct {
+	mock.Mock
+}
+
+func (m *ClientMock) Invoke(ctx context.Context, req command_pkg.Request, now time.Time) (command_pkg.Response, error) {
+	args := m.Called(ctx, req, now)
+	return args.Get(0).(command_pkg.Response), args.Error(1)
+}
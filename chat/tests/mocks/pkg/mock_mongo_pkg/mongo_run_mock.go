@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoCursorMock struct {
@@ -36,6 +37,14 @@ func (m *MongoDatabaseMock) Collection(name string) mongo_pkg.MongoCollectionInt
 	return args.Get(0).(mongo_pkg.MongoCollectionInterface)
 }
 
+func (m *MongoDatabaseMock) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	args := m.Called(ctx)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
+
 type MongoCollectionMock struct {
 	mock.Mock
 }
@@ -70,6 +79,31 @@ func (m *MongoCollectionMock) DeleteOne(ctx context.Context, filter interface{})
 	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
 }
 
+func (m *MongoCollectionMock) FindWithOptions(ctx context.Context, filter interface{}, opts *options.FindOptions) (cursor mongo_pkg.MongoCursorInterface, err error) {
+	args := m.Called(ctx, filter, opts)
+	return args.Get(0).(mongo_pkg.MongoCursorInterface), args.Error(1)
+}
+
+func (m *MongoCollectionMock) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoCollectionMock) CreateIndex(ctx context.Context, index mongo.IndexModel) (string, error) {
+	args := m.Called(ctx, index)
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (m *MongoCollectionMock) DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
+}
+
+func (m *MongoCollectionMock) UpdateOneWithOptions(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
+	args := m.Called(ctx, filter, update, opts)
+	return args.Get(0).(*mongo.UpdateResult), args.Error(1)
+}
+
 type MongoPkgStructMock struct {
 	Ctx context.Context
 	Db  mongo_pkg.MongoDatabaseInterface
@@ -112,3 +146,38 @@ func (m *MongoCollectionInsertErrorMock) DeleteOne(ctx context.Context, filter i
 	args := m.Called(ctx, filter)
 	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
 }
+
+func (m *MongoCollectionInsertErrorMock) FindWithOptions(ctx context.Context, filter interface{}, opts *options.FindOptions) (cursor mongo_pkg.MongoCursorInterface, err error) {
+	args := m.Called(ctx, filter, opts)
+	return args.Get(0).(mongo_pkg.MongoCursorInterface), args.Error(1)
+}
+
+func (m *MongoCollectionInsertErrorMock) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoCollectionInsertErrorMock) CreateIndex(ctx context.Context, index mongo.IndexModel) (string, error) {
+	args := m.Called(ctx, index)
+	return args.Get(0).(string), args.Error(1)
+}
+
+func (m *MongoCollectionInsertErrorMock) DeleteMany(ctx context.Context, filter interface{}) (*mongo.DeleteResult, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*mongo.DeleteResult), args.Error(1)
+}
+
+func (m *MongoCollectionInsertErrorMock) UpdateOneWithOptions(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
+	args := m.Called(ctx, filter, update, opts)
+	return args.Get(0).(*mongo.UpdateResult), args.Error(1)
+}
+
+func (m *MongoCollectionMock) Aggregate(ctx context.Context, pipeline interface{}) (cursor mongo_pkg.MongoCursorInterface, err error) {
+	args := m.Called(ctx, pipeline)
+	return args.Get(0).(mongo_pkg.MongoCursorInterface), args.Error(1)
+}
+
+func (m *MongoCollectionInsertErrorMock) Aggregate(ctx context.Context, pipeline interface{}) (cursor mongo_pkg.MongoCursorInterface, err error) {
+	args := m.Called(ctx, pipeline)
+	return args.Get(0).(mongo_pkg.MongoCursorInterface), args.Error(1)
+}
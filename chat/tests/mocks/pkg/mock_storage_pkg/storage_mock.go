@@ -0,0 +1,30 @@
+package mock_storage_pkg
+
+import (
+	"context"
+	"io"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type BlobStorageMock struct {
+	mock.Mock
+}
+
+func (m *BlobStorageMock) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	args := m.Called(ctx, key, body, size, contentType)
+	return args.Error(0)
+}
+
+func (m *BlobStorageMock) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	args := m.Called(ctx, key)
+	if body, ok := args.Get(0).(io.ReadCloser); ok {
+		return body, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *BlobStorageMock) Delete(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
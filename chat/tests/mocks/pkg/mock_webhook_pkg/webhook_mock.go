@@ -0,0 +1,18 @@
+package mock_webhook_pkg
+
+import (
+	"context"
+	"microservices/chat/pkg/webhook_pkg"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SenderMock struct {
+	mock.Mock
+}
+
+func (m *SenderMock) Send(ctx context.Context, req webhook_pkg.Request, now time.Time) (int, error) {
+	args := m.Called(ctx, req, now)
+	return args.Int(0), args.Error(1)
+}
@@ -0,0 +1,65 @@
+package mock_attachment_svc
+
+import (
+	"context"
+	"io"
+	"microservices/chat/internal/model"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type AttachmentSvcMock struct {
+	mock.Mock
+}
+
+func (m *AttachmentSvcMock) Store(ctx context.Context, roomID string, name string, size int64, body io.Reader) (model.Attachment, error) {
+	args := m.Called(ctx, roomID, name, size, body)
+	return args.Get(0).(model.Attachment), args.Error(1)
+}
+
+func (m *AttachmentSvcMock) Open(ctx context.Context, attachment model.Attachment) (io.ReadCloser, error) {
+	args := m.Called(ctx, attachment)
+	if body, ok := args.Get(0).(io.ReadCloser); ok {
+		return body, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *AttachmentSvcMock) Remove(ctx context.Context, attachment model.Attachment) error {
+	args := m.Called(ctx, attachment)
+	return args.Error(0)
+}
+
+func (m *AttachmentSvcMock) SignURL(messageID string, attachmentID string) (string, time.Time) {
+	args := m.Called(messageID, attachmentID)
+	return args.String(0), args.Get(1).(time.Time)
+}
+
+func (m *AttachmentSvcMock) VerifyURL(messageID string, attachmentID string, expires string, signature string) error {
+	args := m.Called(messageID, attachmentID, expires, signature)
+	return args.Error(0)
+}
+
+func (m *AttachmentSvcMock) OpenThumbnail(ctx context.Context, thumbnail model.Thumbnail) (io.ReadCloser, error) {
+	args := m.Called(ctx, thumbnail)
+	if body, ok := args.Get(0).(io.ReadCloser); ok {
+		return body, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *AttachmentSvcMock) SignThumbnailURL(messageID string, attachmentID string, size int) string {
+	args := m.Called(messageID, attachmentID, size)
+	return args.String(0)
+}
+
+func (m *AttachmentSvcMock) VerifyThumbnailURL(messageID string, attachmentID string, size int, expires string, signature string) error {
+	args := m.Called(messageID, attachmentID, size, expires, signature)
+	return args.Error(0)
+}
+
+func (m *AttachmentSvcMock) MaxSize() int64 {
+	args := m.Called()
+	return args.Get(0).(int64)
+}
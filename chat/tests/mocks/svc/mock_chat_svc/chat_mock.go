@@ -20,3 +20,8 @@ func (m *ChatSvcMock) GetRoomInfo(room model.Room, userId int) chat_svc.Room {
 	args := m.Called(room, userId)
 	return args.Get(0).(chat_svc.Room)
 }
+
+func (m *ChatSvcMock) ConvertSearchResults(hits []model.ChatMessageSearchHit, keyword string) []chat_svc.SearchResult {
+	args := m.Called(hits, keyword)
+	return args.Get(0).([]chat_svc.SearchResult)
+}
@@ -0,0 +1,11 @@
+package mock_clock_svc
+
+import "time"
+
+type FixedClock struct {
+	FixedTime time.Time
+}
+
+func (f FixedClock) Now() time.Time {
+	return f.FixedTime
+}
@@ -0,0 +1,26 @@
+package mock_export_svc
+
+import (
+	"context"
+	"io"
+	"microservices/chat/internal/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ExportSvcMock struct {
+	mock.Mock
+}
+
+func (m *ExportSvcMock) RunRoomExports() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *ExportSvcMock) Open(ctx context.Context, export model.RoomExport) (io.ReadCloser, error) {
+	args := m.Called(ctx, export)
+	if body, ok := args.Get(0).(io.ReadCloser); ok {
+		return body, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -2,7 +2,9 @@ package mock_mongo_svc
 
 import (
 	"microservices/chat/internal/model"
+	"microservices/chat/internal/svc/mongo_svc"
 	"microservices/chat/pkg/mongo_pkg"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -31,9 +33,9 @@ func (m *MongoSvcMock) GetRooms(userID int, target string, mongo_pkg mongo_pkg.M
 	return args.Get(0).([]model.Room), args.Error(1)
 }
 
-func (m *MongoSvcMock) PostChatMessage(roomID string, userID int, message string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
-	args := m.Called(roomID, userID, message, mongo_pkg)
-	return args.Error(0)
+func (m *MongoSvcMock) PostChatMessage(chatMessage model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(chatMessage, mongo_pkg)
+	return args.String(0), args.Error(1)
 }
 
 func (m *MongoSvcMock) GetChatMessages(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
@@ -51,11 +53,476 @@ func (m *MongoSvcMock) GetChatMessageByID(roomID string, messageID string, mongo
 	return args.Get(0).(model.ChatMessage), args.Error(1)
 }
 
-func (m *MongoSvcMock) DeleteChatMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+func (m *MongoSvcMock) DeleteChatMessage(roomID string, messageID string, deletedBy int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, messageID, deletedBy, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) SearchChatMessages(query mongo_svc.SearchChatMessagesQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessageSearchHit, int64, error) {
+	args := m.Called(query, mongo_pkg)
+	return args.Get(0).([]model.ChatMessageSearchHit), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMock) GetChatMessage(messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ChatMessage, error) {
+	args := m.Called(messageID, mongo_pkg)
+	return args.Get(0).(model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMock) EditChatMessage(original model.ChatMessage, message string, editorID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(original, message, editorID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) PurgeDeletedChatMessages(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(before, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetThreadMessages(rootID string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error) {
+	args := m.Called(rootID, page, limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMock) AddReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(messageID, emoji, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) RemoveReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(messageID, emoji, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) UpdateReadCursor(roomID string, userID int, message model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, message, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetReadCursors(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]model.ReadCursor, error) {
+	args := m.Called(userID, roomIDs, mongo_pkg)
+	return args.Get(0).(map[string]model.ReadCursor), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetUnreadCounts(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]int64, error) {
+	args := m.Called(userID, roomIDs, mongo_pkg)
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) CreateRoomInvite(invite model.RoomInvite, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(invite, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) AcceptRoomInvite(code string, userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomInvite, error) {
+	args := m.Called(code, userID, now, mongo_pkg)
+	return args.Get(0).(model.RoomInvite), args.Error(1)
+}
+
+func (m *MongoSvcMock) CreateJoinRequest(request model.JoinRequest, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(request, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetJoinRequests(roomID string, status string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.JoinRequest, error) {
+	args := m.Called(roomID, status, mongo_pkg)
+	return args.Get(0).([]model.JoinRequest), args.Error(1)
+}
+
+func (m *MongoSvcMock) DecideJoinRequest(roomID string, requestID string, status string, deciderID int, mongo_pkg mongo_pkg.MongoPkgInterface) (model.JoinRequest, error) {
+	args := m.Called(roomID, requestID, status, deciderID, mongo_pkg)
+	return args.Get(0).(model.JoinRequest), args.Error(1)
+}
+
+func (m *MongoSvcMock) LeaveRoom(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) (int, error) {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) KickMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) BanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) UnbanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) TransferOwnership(roomID string, fromUserID int, toUserID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, fromUserID, toUserID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) SetMemberRole(roomID string, userID int, role string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, role, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) UpdateRoom(roomID string, fields mongo_svc.UpdateRoomFields, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, fields, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) ArchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) UnarchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) DeleteRoom(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetOrCreateDirectRoom(userIDs []int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, bool, error) {
+	args := m.Called(userIDs, now, mongo_pkg)
+	return args.Get(0).(model.Room), args.Bool(1), args.Error(2)
+}
+
+func (m *MongoSvcMock) ListRooms(query mongo_svc.RoomListQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, string, error) {
+	args := m.Called(query, mongo_pkg)
+	return args.Get(0).([]model.Room), args.String(1), args.Error(2)
+}
+
+func (m *MongoSvcMock) GetPendingPreviewMessages(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMock) SetAttachmentPreview(messageID string, attachmentID string, status string, thumbnails []model.Thumbnail, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(messageID, attachmentID, status, thumbnails, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) UpsertUser(user model.User, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(user, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetUsersByHandles(handles []string, userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	args := m.Called(handles, userIDs, mongo_pkg)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetMentions(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error) {
+	args := m.Called(userID, roomIDs, page, limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMock) GetActiveReaders(roomID string, since time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error) {
+	args := m.Called(roomID, since, mongo_pkg)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetUsersByIDs(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	args := m.Called(userIDs, mongo_pkg)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetNotificationSettings(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) (map[int]model.NotificationSettings, error) {
+	args := m.Called(userIDs, mongo_pkg)
+	return args.Get(0).(map[int]model.NotificationSettings), args.Error(1)
+}
+
+func (m *MongoSvcMock) UpdateNotificationSettings(settings model.NotificationSettings, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(settings, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) SetRoomMuted(userID int, roomID string, muted bool, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(userID, roomID, muted, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) InsertNotifications(notifications []model.Notification, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(notifications, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetPendingNotificationUsers(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error) {
+	args := m.Called(before, limit, mongo_pkg)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MongoSvcMock) CreateNotificationDelivery(userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.NotificationDelivery, bool, error) {
+	args := m.Called(userID, now, mongo_pkg)
+	return args.Get(0).(model.NotificationDelivery), args.Bool(1), args.Error(2)
+}
+
+func (m *MongoSvcMock) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.NotificationDelivery, error) {
+	args := m.Called(now, lease, limit, mongo_pkg)
+	return args.Get(0).([]model.NotificationDelivery), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetDeliveryNotifications(deliveryID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Notification, error) {
+	args := m.Called(deliveryID, mongo_pkg)
+	return args.Get(0).([]model.Notification), args.Error(1)
+}
+
+func (m *MongoSvcMock) RecordDeliveryAttempt(deliveryID string, attempt model.DeliveryAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deliveryID, attempt, status, nextAttemptAt, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) CreateRoomWebhook(webhook model.RoomWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(webhook, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetRoomWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Get(0).([]model.RoomWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetRoomWebhooksByIDs(webhookIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	args := m.Called(webhookIDs, mongo_pkg)
+	return args.Get(0).([]model.RoomWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMock) DeleteRoomWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, webhookID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) InsertWebhookDeliveries(deliveries []model.WebhookDelivery, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deliveries, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error) {
+	args := m.Called(now, lease, limit, mongo_pkg)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MongoSvcMock) RecordWebhookAttempt(deliveryID string, attempt model.WebhookAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deliveryID, attempt, status, nextAttemptAt, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetWebhookDeliveries(roomID string, status string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error) {
+	args := m.Called(roomID, status, limit, mongo_pkg)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MongoSvcMock) RedeliverWebhookDelivery(roomID string, deliveryID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, deliveryID, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) CreateIncomingWebhook(webhook model.IncomingWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(webhook, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetIncomingWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.IncomingWebhook, error) {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Get(0).([]model.IncomingWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetIncomingWebhookByToken(tokenHash string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.IncomingWebhook, error) {
+	args := m.Called(tokenHash, mongo_pkg)
+	return args.Get(0).(model.IncomingWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMock) DeleteIncomingWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, webhookID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) FindUsersByHandle(handle string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	args := m.Called(handle, mongo_pkg)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MongoSvcMock) CreateSlashCommand(command model.SlashCommand, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(command, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetSlashCommands(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SlashCommand, error) {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Get(0).([]model.SlashCommand), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetSlashCommand(roomID string, name string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.SlashCommand, error) {
+	args := m.Called(roomID, name, mongo_pkg)
+	return args.Get(0).(model.SlashCommand), args.Error(1)
+}
+
+func (m *MongoSvcMock) DeleteSlashCommand(roomID string, commandID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, commandID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) PinMessage(roomID string, pin model.RoomPin, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, pin, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) UnpinMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
 	args := m.Called(roomID, messageID, mongo_pkg)
 	return args.Error(0)
 }
 
+func (m *MongoSvcMock) GetChatMessagesByIDs(messageIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(messageIDs, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMock) SaveMessage(saved model.SavedMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(saved, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetSavedMessages(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SavedMessage, int64, error) {
+	args := m.Called(userID, roomIDs, page, limit, mongo_pkg)
+	return args.Get(0).([]model.SavedMessage), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMock) DeleteSavedMessage(userID int, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(userID, messageID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) CreateScheduledMessage(scheduled model.ScheduledMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(scheduled, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetPendingScheduledMessages(userID int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ScheduledMessage, error) {
+	args := m.Called(userID, mongo_pkg)
+	return args.Get(0).([]model.ScheduledMessage), args.Error(1)
+}
+
+func (m *MongoSvcMock) CancelScheduledMessage(userID int, scheduledID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(userID, scheduledID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) ClaimDueScheduledMessage(now time.Time, lease time.Duration, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ScheduledMessage, bool, error) {
+	args := m.Called(now, lease, mongo_pkg)
+	return args.Get(0).(model.ScheduledMessage), args.Bool(1), args.Error(2)
+}
+
+func (m *MongoSvcMock) CompleteScheduledMessage(scheduledID string, status string, postedMessageID string, errMessage string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(scheduledID, status, postedMessageID, errMessage, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) ExpireScheduledMessageLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(now, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetRetentionRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
+	args := m.Called(mongo_pkg)
+	return args.Get(0).([]model.Room), args.Error(1)
+}
+
+func (m *MongoSvcMock) CountExpiredChatMessages(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) PurgeExpiredChatMessages(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) PurgeExpiredChatMessageReferences(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) CreateRoomExport(export model.RoomExport, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(export, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetRoomExports(roomID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error) {
+	args := m.Called(roomID, limit, mongo_pkg)
+	return args.Get(0).([]model.RoomExport), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetRoomExport(roomID string, exportID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomExport, error) {
+	args := m.Called(roomID, exportID, mongo_pkg)
+	return args.Get(0).(model.RoomExport), args.Error(1)
+}
+
+func (m *MongoSvcMock) ClaimPendingRoomExports(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error) {
+	args := m.Called(now, lease, limit, mongo_pkg)
+	return args.Get(0).([]model.RoomExport), args.Error(1)
+}
+
+func (m *MongoSvcMock) ExtendRoomExportLease(exportID string, leaseUntil time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(exportID, leaseUntil, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) CompleteRoomExport(export model.RoomExport, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(export, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) ExpireRoomExportLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(now, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) PurgeExpiredRoomExports(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetChatMessagesAfter(roomID string, afterID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(roomID, afterID, limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMock) UpsertImportedRoom(room model.Room, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, error) {
+	args := m.Called(room, mongo_pkg)
+	return args.Get(0).(model.Room), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetImportedChatMessages(roomID string, externalIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(roomID, externalIDs, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMock) ImportChatMessages(roomID string, messages []model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, messages, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMock) GetBlobDeletions(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.BlobDeletion, error) {
+	args := m.Called(limit, mongo_pkg)
+	return args.Get(0).([]model.BlobDeletion), args.Error(1)
+}
+
+func (m *MongoSvcMock) DeleteBlobDeletion(deletionID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deletionID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMock) GetRestampPendingRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
+	args := m.Called(mongo_pkg)
+	return args.Get(0).([]model.Room), args.Error(1)
+}
+
+func (m *MongoSvcMock) RestampChatMessageExpiry(roomID string, retentionDays int, now time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, retentionDays, now, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 type MongoSvcMockWithErrorMock struct {
 	mock.Mock
 }
@@ -78,9 +545,9 @@ func (m *MongoSvcMockWithErrorMock) GetRooms(userID int, target string, mongo_pk
 	return args.Get(0).([]model.Room), args.Error(1)
 }
 
-func (m *MongoSvcMockWithErrorMock) PostChatMessage(roomID string, userID int, message string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
-	args := m.Called(roomID, userID, message, mongo_pkg)
-	return args.Error(0)
+func (m *MongoSvcMockWithErrorMock) PostChatMessage(chatMessage model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(chatMessage, mongo_pkg)
+	return args.String(0), args.Error(1)
 }
 
 func (m *MongoSvcMockWithErrorMock) GetChatMessages(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
@@ -98,7 +565,472 @@ func (m *MongoSvcMockWithErrorMock) GetChatMessageByID(roomID string, messageID
 	return args.Get(0).(model.ChatMessage), args.Error(1)
 }
 
-func (m *MongoSvcMockWithErrorMock) DeleteChatMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+func (m *MongoSvcMockWithErrorMock) DeleteChatMessage(roomID string, messageID string, deletedBy int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, messageID, deletedBy, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) SearchChatMessages(query mongo_svc.SearchChatMessagesQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessageSearchHit, int64, error) {
+	args := m.Called(query, mongo_pkg)
+	return args.Get(0).([]model.ChatMessageSearchHit), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetChatMessage(messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ChatMessage, error) {
+	args := m.Called(messageID, mongo_pkg)
+	return args.Get(0).(model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) EditChatMessage(original model.ChatMessage, message string, editorID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(original, message, editorID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) PurgeDeletedChatMessages(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(before, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetThreadMessages(rootID string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error) {
+	args := m.Called(rootID, page, limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) AddReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(messageID, emoji, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) RemoveReaction(messageID string, emoji string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(messageID, emoji, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) UpdateReadCursor(roomID string, userID int, message model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, message, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetReadCursors(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]model.ReadCursor, error) {
+	args := m.Called(userID, roomIDs, mongo_pkg)
+	return args.Get(0).(map[string]model.ReadCursor), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetUnreadCounts(userID int, roomIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) (map[string]int64, error) {
+	args := m.Called(userID, roomIDs, mongo_pkg)
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateRoomInvite(invite model.RoomInvite, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(invite, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) AcceptRoomInvite(code string, userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomInvite, error) {
+	args := m.Called(code, userID, now, mongo_pkg)
+	return args.Get(0).(model.RoomInvite), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateJoinRequest(request model.JoinRequest, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(request, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetJoinRequests(roomID string, status string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.JoinRequest, error) {
+	args := m.Called(roomID, status, mongo_pkg)
+	return args.Get(0).([]model.JoinRequest), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) DecideJoinRequest(roomID string, requestID string, status string, deciderID int, mongo_pkg mongo_pkg.MongoPkgInterface) (model.JoinRequest, error) {
+	args := m.Called(roomID, requestID, status, deciderID, mongo_pkg)
+	return args.Get(0).(model.JoinRequest), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) LeaveRoom(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) (int, error) {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) KickMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) BanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) UnbanMember(roomID string, userID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) TransferOwnership(roomID string, fromUserID int, toUserID int, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, fromUserID, toUserID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) SetMemberRole(roomID string, userID int, role string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, userID, role, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) UpdateRoom(roomID string, fields mongo_svc.UpdateRoomFields, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, fields, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) ArchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) UnarchiveRoom(roomID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) DeleteRoom(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetOrCreateDirectRoom(userIDs []int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, bool, error) {
+	args := m.Called(userIDs, now, mongo_pkg)
+	return args.Get(0).(model.Room), args.Bool(1), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) ListRooms(query mongo_svc.RoomListQuery, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, string, error) {
+	args := m.Called(query, mongo_pkg)
+	return args.Get(0).([]model.Room), args.String(1), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetPendingPreviewMessages(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) SetAttachmentPreview(messageID string, attachmentID string, status string, thumbnails []model.Thumbnail, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(messageID, attachmentID, status, thumbnails, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) UpsertUser(user model.User, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(user, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetUsersByHandles(handles []string, userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	args := m.Called(handles, userIDs, mongo_pkg)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetMentions(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, int64, error) {
+	args := m.Called(userID, roomIDs, page, limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetActiveReaders(roomID string, since time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error) {
+	args := m.Called(roomID, since, mongo_pkg)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetUsersByIDs(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	args := m.Called(userIDs, mongo_pkg)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetNotificationSettings(userIDs []int, mongo_pkg mongo_pkg.MongoPkgInterface) (map[int]model.NotificationSettings, error) {
+	args := m.Called(userIDs, mongo_pkg)
+	return args.Get(0).(map[int]model.NotificationSettings), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) UpdateNotificationSettings(settings model.NotificationSettings, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(settings, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) SetRoomMuted(userID int, roomID string, muted bool, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(userID, roomID, muted, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) InsertNotifications(notifications []model.Notification, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(notifications, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetPendingNotificationUsers(before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]int, error) {
+	args := m.Called(before, limit, mongo_pkg)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateNotificationDelivery(userID int, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (model.NotificationDelivery, bool, error) {
+	args := m.Called(userID, now, mongo_pkg)
+	return args.Get(0).(model.NotificationDelivery), args.Bool(1), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.NotificationDelivery, error) {
+	args := m.Called(now, lease, limit, mongo_pkg)
+	return args.Get(0).([]model.NotificationDelivery), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetDeliveryNotifications(deliveryID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Notification, error) {
+	args := m.Called(deliveryID, mongo_pkg)
+	return args.Get(0).([]model.Notification), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) RecordDeliveryAttempt(deliveryID string, attempt model.DeliveryAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deliveryID, attempt, status, nextAttemptAt, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateRoomWebhook(webhook model.RoomWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(webhook, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetRoomWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Get(0).([]model.RoomWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetRoomWebhooksByIDs(webhookIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomWebhook, error) {
+	args := m.Called(webhookIDs, mongo_pkg)
+	return args.Get(0).([]model.RoomWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) DeleteRoomWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, webhookID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) InsertWebhookDeliveries(deliveries []model.WebhookDelivery, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deliveries, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) ClaimDueWebhookDeliveries(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error) {
+	args := m.Called(now, lease, limit, mongo_pkg)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) RecordWebhookAttempt(deliveryID string, attempt model.WebhookAttempt, status string, nextAttemptAt time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deliveryID, attempt, status, nextAttemptAt, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetWebhookDeliveries(roomID string, status string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.WebhookDelivery, error) {
+	args := m.Called(roomID, status, limit, mongo_pkg)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) RedeliverWebhookDelivery(roomID string, deliveryID string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, deliveryID, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateIncomingWebhook(webhook model.IncomingWebhook, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(webhook, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetIncomingWebhooks(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.IncomingWebhook, error) {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Get(0).([]model.IncomingWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetIncomingWebhookByToken(tokenHash string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.IncomingWebhook, error) {
+	args := m.Called(tokenHash, mongo_pkg)
+	return args.Get(0).(model.IncomingWebhook), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) DeleteIncomingWebhook(roomID string, webhookID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, webhookID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) FindUsersByHandle(handle string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.User, error) {
+	args := m.Called(handle, mongo_pkg)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateSlashCommand(command model.SlashCommand, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(command, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetSlashCommands(roomID string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SlashCommand, error) {
+	args := m.Called(roomID, mongo_pkg)
+	return args.Get(0).([]model.SlashCommand), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetSlashCommand(roomID string, name string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.SlashCommand, error) {
+	args := m.Called(roomID, name, mongo_pkg)
+	return args.Get(0).(model.SlashCommand), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) DeleteSlashCommand(roomID string, commandID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, commandID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) PinMessage(roomID string, pin model.RoomPin, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(roomID, pin, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) UnpinMessage(roomID string, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
 	args := m.Called(roomID, messageID, mongo_pkg)
 	return args.Error(0)
 }
+
+func (m *MongoSvcMockWithErrorMock) GetChatMessagesByIDs(messageIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(messageIDs, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) SaveMessage(saved model.SavedMessage, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(saved, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetSavedMessages(userID int, roomIDs []string, page int, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.SavedMessage, int64, error) {
+	args := m.Called(userID, roomIDs, page, limit, mongo_pkg)
+	return args.Get(0).([]model.SavedMessage), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) DeleteSavedMessage(userID int, messageID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(userID, messageID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateScheduledMessage(scheduled model.ScheduledMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(scheduled, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetPendingScheduledMessages(userID int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ScheduledMessage, error) {
+	args := m.Called(userID, mongo_pkg)
+	return args.Get(0).([]model.ScheduledMessage), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CancelScheduledMessage(userID int, scheduledID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(userID, scheduledID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) ClaimDueScheduledMessage(now time.Time, lease time.Duration, mongo_pkg mongo_pkg.MongoPkgInterface) (model.ScheduledMessage, bool, error) {
+	args := m.Called(now, lease, mongo_pkg)
+	return args.Get(0).(model.ScheduledMessage), args.Bool(1), args.Error(2)
+}
+
+func (m *MongoSvcMockWithErrorMock) CompleteScheduledMessage(scheduledID string, status string, postedMessageID string, errMessage string, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(scheduledID, status, postedMessageID, errMessage, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) ExpireScheduledMessageLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(now, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetRetentionRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
+	args := m.Called(mongo_pkg)
+	return args.Get(0).([]model.Room), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CountExpiredChatMessages(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) PurgeExpiredChatMessages(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) PurgeExpiredChatMessageReferences(roomID string, before time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) CreateRoomExport(export model.RoomExport, mongo_pkg mongo_pkg.MongoPkgInterface) (string, error) {
+	args := m.Called(export, mongo_pkg)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetRoomExports(roomID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error) {
+	args := m.Called(roomID, limit, mongo_pkg)
+	return args.Get(0).([]model.RoomExport), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetRoomExport(roomID string, exportID string, mongo_pkg mongo_pkg.MongoPkgInterface) (model.RoomExport, error) {
+	args := m.Called(roomID, exportID, mongo_pkg)
+	return args.Get(0).(model.RoomExport), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) ClaimPendingRoomExports(now time.Time, lease time.Duration, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.RoomExport, error) {
+	args := m.Called(now, lease, limit, mongo_pkg)
+	return args.Get(0).([]model.RoomExport), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) ExtendRoomExportLease(exportID string, leaseUntil time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(exportID, leaseUntil, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) CompleteRoomExport(export model.RoomExport, now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(export, now, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) ExpireRoomExportLeases(now time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(now, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) PurgeExpiredRoomExports(roomID string, before time.Time, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, before, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetChatMessagesAfter(roomID string, afterID string, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(roomID, afterID, limit, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) UpsertImportedRoom(room model.Room, mongo_pkg mongo_pkg.MongoPkgInterface) (model.Room, error) {
+	args := m.Called(room, mongo_pkg)
+	return args.Get(0).(model.Room), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetImportedChatMessages(roomID string, externalIDs []string, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.ChatMessage, error) {
+	args := m.Called(roomID, externalIDs, mongo_pkg)
+	return args.Get(0).([]model.ChatMessage), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) ImportChatMessages(roomID string, messages []model.ChatMessage, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, messages, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetBlobDeletions(limit int, mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.BlobDeletion, error) {
+	args := m.Called(limit, mongo_pkg)
+	return args.Get(0).([]model.BlobDeletion), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) DeleteBlobDeletion(deletionID string, mongo_pkg mongo_pkg.MongoPkgInterface) error {
+	args := m.Called(deletionID, mongo_pkg)
+	return args.Error(0)
+}
+
+func (m *MongoSvcMockWithErrorMock) GetRestampPendingRooms(mongo_pkg mongo_pkg.MongoPkgInterface) ([]model.Room, error) {
+	args := m.Called(mongo_pkg)
+	return args.Get(0).([]model.Room), args.Error(1)
+}
+
+func (m *MongoSvcMockWithErrorMock) RestampChatMessageExpiry(roomID string, retentionDays int, now time.Time, limit int, mongo_pkg mongo_pkg.MongoPkgInterface) (int64, error) {
+	args := m.Called(roomID, retentionDays, now, limit, mongo_pkg)
+	return args.Get(0).(int64), args.Error(1)
+}
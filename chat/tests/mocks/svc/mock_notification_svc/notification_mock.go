@@ -0,0 +1,26 @@
+package mock_notification_svc
+
+import (
+	"microservices/chat/internal/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationSvcMock struct {
+	mock.Mock
+}
+
+func (m *NotificationSvcMock) Enqueue(messageID string, message model.ChatMessage, room model.Room, replyToUserID int) error {
+	args := m.Called(messageID, message, room, replyToUserID)
+	return args.Error(0)
+}
+
+func (m *NotificationSvcMock) BuildDigests() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *NotificationSvcMock) DeliverDigests() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -0,0 +1,31 @@
+package mock_presence_svc
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PresenceSvcMock struct {
+	mock.Mock
+}
+
+func (m *PresenceSvcMock) SetStatus(ctx context.Context, userID int, status string) error {
+	args := m.Called(ctx, userID, status)
+	return args.Error(0)
+}
+
+func (m *PresenceSvcMock) GetStatuses(ctx context.Context, userIDs []int) (map[int]string, error) {
+	args := m.Called(ctx, userIDs)
+	return args.Get(0).(map[int]string), args.Error(1)
+}
+
+func (m *PresenceSvcMock) SetTyping(ctx context.Context, roomID string, userID int, typing bool) error {
+	args := m.Called(ctx, roomID, userID, typing)
+	return args.Error(0)
+}
+
+func (m *PresenceSvcMock) GetTyping(ctx context.Context, roomID string) ([]int, error) {
+	args := m.Called(ctx, roomID)
+	return args.Get(0).([]int), args.Error(1)
+}
@@ -0,0 +1,16 @@
+package mock_schedule_svc
+
+import (
+	"microservices/chat/internal/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PosterMock struct {
+	mock.Mock
+}
+
+func (m *PosterMock) PostScheduledMessage(scheduled model.ScheduledMessage) (string, error) {
+	args := m.Called(scheduled)
+	return args.String(0), args.Error(1)
+}
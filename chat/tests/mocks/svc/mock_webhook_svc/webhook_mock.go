@@ -0,0 +1,19 @@
+package mock_webhook_svc
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookSvcMock struct {
+	mock.Mock
+}
+
+func (m *WebhookSvcMock) Publish(roomID string, event string, data interface{}) error {
+	args := m.Called(roomID, event, data)
+	return args.Error(0)
+}
+
+func (m *WebhookSvcMock) DeliverWebhooks() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -49,6 +49,58 @@ func (m *TestMongoStruct) MongoCleanUp() error {
 	if err != nil {
 		return err
 	}
+	err = m.DB.Collection(model.ReadCursorCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.RoomInviteCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.JoinRequestCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.NotificationCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.NotificationSettingsCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.NotificationDeliveryCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.RoomWebhookCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.WebhookDeliveryCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.IncomingWebhookCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.SlashCommandCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.SavedMessageCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.ScheduledMessageCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
+	err = m.DB.Collection(model.RoomExportCollectionName).Drop(m.Ctx)
+	if err != nil {
+		return err
+	}
 
 	fmt.Println("MongoDB cleaned up for tests.")
 	return nil